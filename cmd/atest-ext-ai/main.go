@@ -19,7 +19,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -27,15 +30,21 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/linuxsuren/api-testing/pkg/testing/remote"
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
 	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/grpcx"
+	"github.com/linuxsuren/atest-ext-ai/pkg/httpx"
 	"github.com/linuxsuren/atest-ext-ai/pkg/plugin"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
 )
 
 type listenerConfig struct {
@@ -65,6 +74,17 @@ func (l listenerConfig) Display() string {
 }
 
 func main() {
+	flags := parseCLIFlags(os.Args[1:])
+	if flags.validateConfig != "" {
+		os.Exit(runValidateConfig(flags.validateConfig, os.Stdout))
+	}
+	if err := configureCABundle(); err != nil {
+		log.Fatalf("FATAL: %v\nTroubleshooting: Point %s at a readable PEM file of CA certificates", err, httpx.CABundleEnv)
+	}
+	if flags.doctor {
+		os.Exit(runDoctor(os.Stdout))
+	}
+
 	// Configure memory optimization
 	configureMemorySettings()
 
@@ -113,12 +133,17 @@ func main() {
 		log.Panicf("FATAL: Failed to initialize AI plugin service: %v\nTroubleshooting: Check configuration file, AI service connectivity, and logs above for details", err)
 	}
 	log.Println("✓ AI plugin service initialized successfully")
+	aiPlugin.SetListenAddress(listenCfg.URI())
 
 	// Create gRPC server with enhanced configuration
 	log.Printf("Step 4/4: Registering gRPC server...")
 	grpcServer := createGRPCServer()
 	remote.RegisterLoaderServer(grpcServer, aiPlugin)
-	log.Println("✓ gRPC server configured with LoaderServer")
+	plugin.RegisterBenchmarkStreamServer(grpcServer, aiPlugin)
+	log.Println("✓ gRPC server configured with LoaderServer and BenchmarkStream")
+	if registerReflection(grpcServer, aiPlugin.Environment()) {
+		log.Println("✓ gRPC reflection enabled")
+	}
 
 	// Handle graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -128,16 +153,16 @@ func main() {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
+	// SIGHUP reloads the configuration without restarting the process
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go watchReloadSignals(ctx, reloadChan, aiPlugin)
+
 	go func() {
 		sig := <-signalChan
 		log.Printf("\n=== Received signal: %v, initiating graceful shutdown ===", sig)
 
-		// Shutdown AI plugin first
-		log.Println("Shutting down AI plugin service...")
-		aiPlugin.Shutdown()
-		log.Println("✓ AI plugin service shutdown completed")
-
-		// Stop gRPC server gracefully with timeout
+		// Stop accepting new RPCs; GracefulStop returns once running RPCs have finished
 		log.Println("Stopping gRPC server...")
 		done := make(chan struct{})
 		go func() {
@@ -145,6 +170,11 @@ func main() {
 			close(done)
 		}()
 
+		// Drain in-flight generations before the AI clients are closed
+		log.Println("Shutting down AI plugin service...")
+		aiPlugin.Shutdown()
+		log.Println("✓ AI plugin service shutdown completed")
+
 		// Force shutdown if graceful shutdown takes too long
 		select {
 		case <-done:
@@ -173,6 +203,30 @@ func main() {
 
 }
 
+// configReloader is implemented by services that can re-read their configuration.
+type configReloader interface {
+	ReloadConfig() error
+}
+
+// watchReloadSignals reloads the configuration each time a signal arrives until
+// ctx is cancelled. A failed reload is logged and the previous configuration
+// keeps serving.
+func watchReloadSignals(ctx context.Context, sigCh <-chan os.Signal, reloader configReloader) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigCh:
+			log.Printf("=== Received signal: %v, reloading configuration ===", sig)
+			if err := reloader.ReloadConfig(); err != nil {
+				log.Printf("⚠ Configuration reload failed, keeping previous configuration: %v", err)
+				continue
+			}
+			log.Println("✓ Configuration reloaded successfully")
+		}
+	}
+}
+
 // resolveListenerConfig determines the appropriate listener settings based on
 // environment variables and operating system defaults.
 func resolveListenerConfig() listenerConfig {
@@ -333,6 +387,19 @@ func createListener(cfg listenerConfig) (net.Listener, error) {
 	return listener, nil
 }
 
+// configureCABundle makes every provider transport trust the PEM bundle named by AI_PLUGIN_CA_BUNDLE
+func configureCABundle() error {
+	path := strings.TrimSpace(os.Getenv(httpx.CABundleEnv))
+	if path == "" {
+		return nil
+	}
+	if err := httpx.LoadCABundle(path); err != nil {
+		return err
+	}
+	log.Printf("Trusting additional CA certificates from %s: %s", httpx.CABundleEnv, path)
+	return nil
+}
+
 // configureMemorySettings optimizes Go runtime for limited memory environments
 func configureMemorySettings() {
 	// Set aggressive garbage collection for memory-constrained environments
@@ -352,6 +419,134 @@ func configureMemorySettings() {
 	)
 }
 
+// reflectionEnabled reports whether gRPC reflection should be served.
+// AI_PLUGIN_GRPC_REFLECTION wins when set; otherwise only development environments enable it.
+func reflectionEnabled(environment string) bool {
+	if raw := os.Getenv("AI_PLUGIN_GRPC_REFLECTION"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err == nil {
+			return enabled
+		}
+		log.Printf("Warning: invalid AI_PLUGIN_GRPC_REFLECTION value %q, falling back to environment default", raw)
+	}
+	switch strings.ToLower(strings.TrimSpace(environment)) {
+	case "development", "dev":
+		return true
+	default:
+		return false
+	}
+}
+
+// registerReflection registers the reflection service when enabled and reports whether it did
+func registerReflection(server *grpc.Server, environment string) bool {
+	if !reflectionEnabled(environment) {
+		return false
+	}
+	reflection.Register(server)
+	return true
+}
+
+// messageSizeLimit reads a gRPC message size limit in bytes from env, defaulting to 4MB
+func messageSizeLimit(envName string) int {
+	raw := strings.TrimSpace(os.Getenv(envName))
+	if raw == "" {
+		return constants.DefaultGRPCMaxMsgSize
+	}
+	size, err := strconv.Atoi(raw)
+	if err != nil || size <= 0 {
+		log.Printf("Warning: invalid %s value %q, using default %d bytes", envName, raw, constants.DefaultGRPCMaxMsgSize)
+		return constants.DefaultGRPCMaxMsgSize
+	}
+	return size
+}
+
+// cliFlags holds the one-shot command line modes; with none set the plugin serves gRPC
+type cliFlags struct {
+	validateConfig string
+	doctor         bool
+}
+
+// parseCLIFlags reads --validate-config and --doctor; other arguments are ignored
+func parseCLIFlags(args []string) cliFlags {
+	var parsed cliFlags
+	flags := flag.NewFlagSet("atest-ext-ai", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&parsed.validateConfig, "validate-config", "", "validate the config file at this path and exit")
+	flags.BoolVar(&parsed.doctor, "doctor", false, "print a self-diagnostic report and exit")
+	if err := flags.Parse(args); err != nil {
+		log.Printf("Warning: ignoring command line arguments: %v", err)
+	}
+	return parsed
+}
+
+// runDoctor prints the plugin's self-diagnostic report as JSON without opening a listener.
+// It returns the process exit code.
+func runDoctor(out io.Writer) int {
+	aiPlugin, err := plugin.NewAIPluginService()
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "Failed to initialize AI plugin service: %v\n", err)
+		return 1
+	}
+	defer aiPlugin.Shutdown()
+	aiPlugin.SetListenAddress(resolveListenerConfig().URI())
+
+	report, err := json.MarshalIndent(aiPlugin.Doctor(context.Background()), "", "  ")
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "Failed to encode doctor report: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintln(out, string(report))
+	return 0
+}
+
+// runValidateConfig loads and validates a config file without opening sockets or creating clients.
+// It returns the process exit code.
+func runValidateConfig(path string, out io.Writer) int {
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "Failed to load %s: %v\n", path, err)
+		return 1
+	}
+
+	result := cfg.Validate()
+	for _, warning := range result.Warnings {
+		_, _ = fmt.Fprintf(out, "warning: %s: %s\n", warning.Field, warning.Message)
+	}
+	for _, issue := range result.Errors {
+		_, _ = fmt.Fprintf(out, "error: %s: %s\n", issue.Field, issue.Message)
+	}
+	if result.HasErrors() {
+		_, _ = fmt.Fprintf(out, "%s is invalid: %d error(s), %d warning(s)\n", path, len(result.Errors), len(result.Warnings))
+		return 1
+	}
+	_, _ = fmt.Fprintf(out, "%s is valid (%d warning(s))\n", path, len(result.Warnings))
+	return 0
+}
+
+// keepaliveParams returns the server keepalive parameters, honouring AI_PLUGIN_KEEPALIVE_* overrides
+func keepaliveParams() keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		MaxConnectionIdle: keepaliveDuration("AI_PLUGIN_KEEPALIVE_MAX_CONNECTION_IDLE", constants.Keepalive.MaxConnectionIdle),
+		MaxConnectionAge:  keepaliveDuration("AI_PLUGIN_KEEPALIVE_MAX_CONNECTION_AGE", constants.Keepalive.MaxConnectionAge),
+		Time:              keepaliveDuration("AI_PLUGIN_KEEPALIVE_TIME", constants.Keepalive.Time),
+		Timeout:           keepaliveDuration("AI_PLUGIN_KEEPALIVE_TIMEOUT", constants.Keepalive.Timeout),
+	}
+}
+
+// keepaliveDuration reads a positive duration such as "30s" from envName, falling back to def
+func keepaliveDuration(envName string, def time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(envName))
+	if raw == "" {
+		return def
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		log.Printf("Warning: invalid %s value %q, using default %s", envName, raw, def)
+		return def
+	}
+	return value
+}
+
 // createGRPCServer creates a simple gRPC server for compatibility with older clients
 func createGRPCServer() *grpc.Server {
 	// Debug interceptor to log all incoming gRPC calls and connection info
@@ -372,8 +567,13 @@ func createGRPCServer() *grpc.Server {
 		return resp, err
 	}
 
-	// Use simple gRPC server configuration for maximum compatibility
+	// Use simple gRPC server configuration for maximum compatibility.
+	// Recovery runs outermost so panics anywhere below it never crash the plugin.
 	return grpc.NewServer(
-		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.ChainUnaryInterceptor(grpcx.RecoveryInterceptor(), unaryInterceptor),
+		grpc.ChainStreamInterceptor(grpcx.StreamRecoveryInterceptor()),
+		grpc.MaxRecvMsgSize(messageSizeLimit("AI_PLUGIN_MAX_RECV_MSG_SIZE")),
+		grpc.MaxSendMsgSize(messageSizeLimit("AI_PLUGIN_MAX_SEND_MSG_SIZE")),
+		grpc.KeepaliveParams(keepaliveParams()),
 	)
 }
@@ -0,0 +1,325 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/discovery"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/openai"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/httpx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type fakeReloader struct {
+	calls chan struct{}
+	err   error
+}
+
+func (f *fakeReloader) ReloadConfig() error {
+	f.calls <- struct{}{}
+	return f.err
+}
+
+func TestWatchReloadSignalsTriggersReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	reloader := &fakeReloader{calls: make(chan struct{}, 2), err: errors.New("boom")}
+	go watchReloadSignals(ctx, sigCh, reloader)
+
+	// A failing reload must not stop the watcher from handling later signals.
+	for i := 0; i < 2; i++ {
+		sigCh <- syscall.SIGHUP
+		select {
+		case <-reloader.calls:
+		case <-time.After(time.Second):
+			t.Fatalf("SIGHUP %d did not trigger a reload", i+1)
+		}
+	}
+}
+
+func TestRegisterReflectionOnlyWhenEnabled(t *testing.T) {
+	const reflectionService = "grpc.reflection.v1.ServerReflection"
+
+	tests := []struct {
+		name        string
+		envValue    string
+		environment string
+		expected    bool
+	}{
+		{name: "production default", environment: "production", expected: false},
+		{name: "development default", environment: "development", expected: true},
+		{name: "env enables in production", envValue: "true", environment: "production", expected: true},
+		{name: "env disables in development", envValue: "false", environment: "development", expected: false},
+		{name: "invalid env falls back", envValue: "maybe", environment: "production", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_PLUGIN_GRPC_REFLECTION", tt.envValue)
+
+			server := createGRPCServer()
+			defer server.Stop()
+
+			assert.Equal(t, tt.expected, registerReflection(server, tt.environment))
+			_, registered := server.GetServiceInfo()[reflectionService]
+			assert.Equal(t, tt.expected, registered)
+		})
+	}
+}
+
+func TestGRPCServerRecoversFromHandlerPanic(t *testing.T) {
+	server := createGRPCServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Panicker",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Panic",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Panicker/Panic"}
+				return interceptor(ctx, in, info, func(context.Context, any) (any, error) {
+					panic("provider exploded")
+				})
+			},
+		}},
+	}, struct{}{})
+
+	conn := dialBufconn(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		err := conn.Invoke(ctx, "/test.Panicker/Panic", &emptypb.Empty{}, &emptypb.Empty{})
+		require.Error(t, err)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, err.Error(), "provider exploded")
+	}
+}
+
+func TestGRPCServerRejectsOversizedMessages(t *testing.T) {
+	t.Setenv("AI_PLUGIN_MAX_RECV_MSG_SIZE", "1024")
+
+	server := createGRPCServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return in, nil
+			},
+		}},
+	}, struct{}{})
+	conn := dialBufconn(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := new(wrapperspb.StringValue)
+	require.NoError(t, conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String("small"), out))
+	assert.Equal(t, "small", out.GetValue())
+
+	err := conn.Invoke(ctx, "/test.Echo/Echo", wrapperspb.String(strings.Repeat("x", 4096)), out)
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestMessageSizeLimit(t *testing.T) {
+	t.Setenv("AI_PLUGIN_MAX_SEND_MSG_SIZE", "")
+	assert.Equal(t, constants.DefaultGRPCMaxMsgSize, messageSizeLimit("AI_PLUGIN_MAX_SEND_MSG_SIZE"))
+
+	t.Setenv("AI_PLUGIN_MAX_SEND_MSG_SIZE", "2048")
+	assert.Equal(t, 2048, messageSizeLimit("AI_PLUGIN_MAX_SEND_MSG_SIZE"))
+
+	t.Setenv("AI_PLUGIN_MAX_SEND_MSG_SIZE", "-1")
+	assert.Equal(t, constants.DefaultGRPCMaxMsgSize, messageSizeLimit("AI_PLUGIN_MAX_SEND_MSG_SIZE"))
+}
+
+func TestKeepaliveParams(t *testing.T) {
+	for _, name := range []string{
+		"AI_PLUGIN_KEEPALIVE_MAX_CONNECTION_IDLE",
+		"AI_PLUGIN_KEEPALIVE_MAX_CONNECTION_AGE",
+		"AI_PLUGIN_KEEPALIVE_TIME",
+		"AI_PLUGIN_KEEPALIVE_TIMEOUT",
+	} {
+		t.Setenv(name, "")
+	}
+	params := keepaliveParams()
+	assert.Equal(t, constants.Keepalive.MaxConnectionIdle, params.MaxConnectionIdle)
+	assert.Equal(t, constants.Keepalive.MaxConnectionAge, params.MaxConnectionAge)
+	assert.Equal(t, constants.Keepalive.Time, params.Time)
+	assert.Equal(t, constants.Keepalive.Timeout, params.Timeout)
+
+	t.Setenv("AI_PLUGIN_KEEPALIVE_MAX_CONNECTION_AGE", "2m")
+	t.Setenv("AI_PLUGIN_KEEPALIVE_TIMEOUT", "not-a-duration")
+	params = keepaliveParams()
+	assert.Equal(t, 2*time.Minute, params.MaxConnectionAge)
+	assert.Equal(t, constants.Keepalive.Timeout, params.Timeout, "invalid values fall back to the default")
+}
+
+func TestRunValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte(`
+ai:
+  default_service: ollama
+  services:
+    ollama:
+      enabled: true
+      provider: ollama
+      endpoint: http://localhost:11434
+      model: qwen2.5-coder:latest
+`), 0o600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte(`
+server:
+  port: 70000
+ai:
+  default_service: ollama
+  services:
+    ollama:
+      enabled: true
+      provider: ollama
+      endpoint: http://localhost:11434
+      model: qwen2.5-coder:latest
+`), 0o600))
+
+	var out strings.Builder
+	assert.Equal(t, 0, runValidateConfig(valid, &out), out.String())
+	assert.Contains(t, out.String(), "is valid")
+
+	out.Reset()
+	assert.Equal(t, 1, runValidateConfig(invalid, &out))
+	assert.Contains(t, out.String(), "error: server.port")
+
+	out.Reset()
+	assert.Equal(t, 1, runValidateConfig(filepath.Join(dir, "missing.yaml"), &out))
+	assert.Contains(t, out.String(), "Failed to load")
+}
+
+func TestParseCLIFlags(t *testing.T) {
+	flags := parseCLIFlags([]string{"--validate-config", "config.yaml"})
+	assert.Equal(t, "config.yaml", flags.validateConfig)
+	assert.False(t, flags.doctor)
+
+	flags = parseCLIFlags([]string{"--doctor"})
+	assert.True(t, flags.doctor)
+	assert.Empty(t, flags.validateConfig)
+
+	assert.Equal(t, cliFlags{}, parseCLIFlags(nil))
+}
+
+// dialBufconn serves server over an in-memory listener and returns a connected client
+func dialBufconn(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestConfigureCABundleTrustsProviders(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3.2:1b","model":"llama3.2:1b"}],"data":[{"id":"gpt-test","object":"model"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	untrusted := &http.Client{Transport: httpx.NewTransport()}
+	_, err := untrusted.Get(server.URL)
+	require.Error(t, err, "the self-signed server is not trusted by the system pool")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	t.Setenv(httpx.CABundleEnv, bundle)
+	require.NoError(t, configureCABundle())
+	t.Cleanup(httpx.ResetCABundle)
+
+	for _, cfg := range []*universal.Config{
+		{Provider: "ollama", Endpoint: server.URL, Model: "llama3.2:1b"},
+		{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", APIKey: "test"},
+	} {
+		client, err := universal.NewUniversalClient(cfg)
+		require.NoError(t, err)
+		health, err := client.HealthCheck(ctx)
+		require.NoError(t, err)
+		assert.True(t, health.Healthy, "%s: %s", cfg.Provider, health.Status)
+		require.NoError(t, client.Close())
+	}
+
+	openaiClient, err := openai.NewClient(&openai.Config{APIKey: "test", BaseURL: server.URL, Model: "gpt-test"})
+	require.NoError(t, err)
+	health, err := openaiClient.HealthCheck(ctx)
+	require.NoError(t, err)
+	assert.True(t, health.Healthy, "openai: %s", health.Status)
+
+	assert.True(t, discovery.NewOllamaDiscovery(server.URL).IsAvailable(ctx))
+}
+
+func TestConfigureCABundleRejectsInvalidBundle(t *testing.T) {
+	t.Setenv(httpx.CABundleEnv, "")
+	require.NoError(t, configureCABundle(), "an unset bundle keeps the system pool")
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+	t.Setenv(httpx.CABundleEnv, bundle)
+	require.ErrorIs(t, configureCABundle(), httpx.ErrNoCertificates)
+
+	t.Setenv(httpx.CABundleEnv, filepath.Join(t.TempDir(), "missing.pem"))
+	require.ErrorIs(t, configureCABundle(), os.ErrNotExist)
+}
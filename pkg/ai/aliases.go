@@ -0,0 +1,106 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+)
+
+// defaultModelAliases maps shorthand model names to concrete model ids
+var defaultModelAliases = map[string]string{
+	"gpt4":     "gpt-4",
+	"gpt4o":    "gpt-4o",
+	"gpt35":    "gpt-3.5-turbo",
+	"claude":   "claude-3-sonnet-20240229",
+	"deepseek": "deepseek-chat",
+}
+
+// resolveModelAlias returns the concrete model id for an alias; configured aliases take
+// precedence over the built-in ones and unknown models pass through unchanged.
+func resolveModelAlias(model string, configured map[string]string) string {
+	key := strings.ToLower(strings.TrimSpace(model))
+	if key == "" {
+		return model
+	}
+
+	resolved, ok := "", false
+	for alias, target := range configured {
+		if strings.ToLower(strings.TrimSpace(alias)) == key {
+			resolved, ok = target, true
+			break
+		}
+	}
+	if !ok {
+		resolved, ok = defaultModelAliases[key]
+	}
+	if !ok || resolved == model {
+		return model
+	}
+
+	logging.Logger.Debug("Resolved model alias", "alias", model, "model", resolved)
+	return resolved
+}
+
+// resolveModelOptions returns options whose model alias, if any, has been resolved
+func (g *SQLGenerator) resolveModelOptions(options *GenerateOptions) *GenerateOptions {
+	model := resolveModelAlias(options.Model, g.currentConfig().ModelAliases)
+	if model == options.Model {
+		return options
+	}
+	resolved := *options
+	resolved.Model = model
+	return &resolved
+}
+
+// checkModelAllowed rejects an explicitly requested model that the models allowlist of the service
+// serving the request does not contain: the default service, or the confidence fallback service.
+// An empty allowlist and the service's own model are not restricted, and neither are runtime
+// clients, which use the caller's provider and API key rather than the configured credentials.
+// Requests routed by the Manager are also checked against the service the Manager selects.
+func (g *SQLGenerator) checkModelAllowed(options *GenerateOptions) error {
+	model := strings.TrimSpace(options.Model)
+	if model == "" || (options.client == nil && options.Provider != "" && options.APIKey != "") {
+		return nil
+	}
+
+	name := g.servedBy(options)
+	service, ok := g.serviceConfig(name)
+	if !ok || len(service.Models) == 0 || model == service.Model || slices.Contains(service.Models, model) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q is not in the models list of service %q", ErrModelNotAllowed, model, name)
+}
+
+// serviceConfig returns the configured service with the given normalized name
+func (g *SQLGenerator) serviceConfig(name string) (config.AIService, bool) {
+	services := g.currentConfig().Services
+	if service, ok := services[name]; ok {
+		return service, true
+	}
+	for key, service := range services {
+		if providers.Normalize(key) == name {
+			return service, true
+		}
+	}
+	return config.AIService{}, false
+}
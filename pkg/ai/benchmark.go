@@ -0,0 +1,220 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+)
+
+const (
+	// defaultBenchmarkConcurrency bounds in-flight benchmark requests across all providers
+	defaultBenchmarkConcurrency = 4
+	// defaultBenchmarkTimeout bounds each benchmark request
+	defaultBenchmarkTimeout = 60 * time.Second
+)
+
+// ErrNoBenchmarkPrompts is returned when Benchmark is called without prompts
+var ErrNoBenchmarkPrompts = errors.New("at least one benchmark prompt is required")
+
+// BenchmarkOptions tunes a provider benchmark run
+type BenchmarkOptions struct {
+	Concurrency int           `json:"concurrency,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+}
+
+// BenchmarkResult summarizes one provider's benchmark run
+type BenchmarkResult struct {
+	Provider         string        `json:"provider"`
+	Requests         int           `json:"requests"`
+	Errors           int           `json:"errors"`
+	SuccessRate      float64       `json:"success_rate"`
+	AverageLatency   time.Duration `json:"average_latency"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	LastError        string        `json:"last_error,omitempty"`
+}
+
+// benchmarkStats accumulates one provider's benchmark run
+type benchmarkStats struct {
+	BenchmarkResult
+	totalLatency time.Duration
+	pending      int
+}
+
+// result finalizes the averaged fields of the run
+func (s *benchmarkStats) result() BenchmarkResult {
+	if succeeded := s.Requests - s.Errors; succeeded > 0 {
+		s.AverageLatency = s.totalLatency / time.Duration(succeeded)
+	}
+	if s.Requests > 0 {
+		s.SuccessRate = float64(s.Requests-s.Errors) / float64(s.Requests)
+	}
+	return s.BenchmarkResult
+}
+
+// Benchmark sends every prompt to every healthy provider and reports latency, errors and token usage.
+// Requests run concurrently up to options.Concurrency; unhealthy providers are skipped.
+func (m *Manager) Benchmark(ctx context.Context, prompts []string, options BenchmarkOptions) (map[string]BenchmarkResult, error) {
+	results := make(map[string]BenchmarkResult)
+	err := m.BenchmarkEach(ctx, prompts, options, func(result BenchmarkResult) error {
+		results[result.Provider] = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BenchmarkEach runs the same benchmark as Benchmark but calls emit with each provider's result as
+// soon as all of its prompts have completed. emit is never called concurrently. When emit returns an
+// error the outstanding requests are cancelled and that error is returned once they have stopped.
+func (m *Manager) BenchmarkEach(ctx context.Context, prompts []string, options BenchmarkOptions, emit func(BenchmarkResult) error) error {
+	if len(prompts) == 0 {
+		return ErrNoBenchmarkPrompts
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultBenchmarkConcurrency
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultBenchmarkTimeout
+	}
+
+	var providers []string
+	for name, health := range m.HealthCheckAll(ctx, false) {
+		if health != nil && health.Healthy {
+			providers = append(providers, name)
+		}
+	}
+	if len(providers) == 0 {
+		return ErrNoHealthyClients
+	}
+	sort.Strings(providers)
+
+	stats := make(map[string]*benchmarkStats, len(providers))
+	for _, name := range providers {
+		stats[name] = &benchmarkStats{BenchmarkResult: BenchmarkResult{Provider: name}, pending: len(prompts)}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, options.Concurrency)
+		// Every provider completes exactly once, so sends never block
+		completed = make(chan BenchmarkResult, len(providers))
+	)
+	// record updates the provider's stats and publishes its result after the last prompt
+	record := func(name string, update func(*benchmarkStats)) {
+		mu.Lock()
+		defer mu.Unlock()
+		s := stats[name]
+		s.Requests++
+		update(s)
+		if s.pending--; s.pending == 0 {
+			completed <- s.result()
+		}
+	}
+	for _, name := range providers {
+		client, err := m.GetClient(name)
+		if err != nil {
+			completed <- stats[name].result()
+			continue
+		}
+		for _, prompt := range prompts {
+			wg.Add(1)
+			go func(name string, client interfaces.AIClient, prompt string) {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-runCtx.Done():
+					record(name, func(s *benchmarkStats) {
+						s.Errors++
+						s.LastError = runCtx.Err().Error()
+					})
+					return
+				}
+
+				reqCtx, reqCancel := context.WithTimeout(runCtx, options.Timeout)
+				defer reqCancel()
+				start := time.Now()
+				resp, err := client.Generate(reqCtx, &interfaces.GenerateRequest{Prompt: prompt, MaxTokens: options.MaxTokens})
+				latency := time.Since(start)
+
+				record(name, func(s *benchmarkStats) {
+					if err != nil {
+						s.Errors++
+						s.LastError = err.Error()
+						return
+					}
+					s.totalLatency += latency
+					promptTokens, completionTokens := responseTokens(resp)
+					s.PromptTokens += promptTokens
+					s.CompletionTokens += completionTokens
+				})
+			}(name, client, prompt)
+		}
+	}
+
+	for range providers {
+		if err := emit(<-completed); err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+	}
+	return nil
+}
+
+// responseTokens reads token usage from OpenAI-style or Ollama-style response metadata
+func responseTokens(resp *interfaces.GenerateResponse) (int, int) {
+	if resp == nil || resp.Metadata == nil {
+		return 0, 0
+	}
+	prompt := metadataInt(resp.Metadata, "prompt_tokens")
+	if prompt == 0 {
+		prompt = metadataInt(resp.Metadata, "prompt_eval_count")
+	}
+	completion := metadataInt(resp.Metadata, "completion_tokens")
+	if completion == 0 {
+		completion = metadataInt(resp.Metadata, "eval_count")
+	}
+	return prompt, completion
+}
+
+// metadataInt returns an integer metadata value that may have been decoded as int or float64
+func metadataInt(metadata map[string]any, key string) int {
+	switch value := metadata[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"github.com/linuxsuren/atest-ext-ai/pkg/storage"
+)
+
+// resultCacheKeyPrefix namespaces result cache entries in the storage backend
+const resultCacheKeyPrefix = "cache/"
+
+// resultCache is a size-bounded LRU cache of generation results with a TTL. Entries are
+// kept in a storage backend while the LRU order lives in memory. Keys are namespaced by the
+// configuration that produced them: with a persistent backend, entries written by an earlier
+// process under the same configuration are reused until they expire, while entries of any
+// other configuration are deleted when the cache is created.
+type resultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	normalize  bool
+	backend    storage.Backend
+	// prefix is resultCacheKeyPrefix followed by the configuration namespace
+	prefix  string
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+// cacheEntry is the stored form of a cached result
+type cacheEntry struct {
+	Result    *GenerationResult `json:"result"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// newResultCache creates a cache on backend from configuration, or nil when caching is disabled.
+// namespace identifies the configuration; entries already stored under it are loaded into the
+// LRU order, so max_entries also bounds what an earlier process left behind.
+func newResultCache(cfg config.CacheConfig, backend storage.Backend, namespace string) *resultCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	cache := &resultCache{
+		ttl:        cfg.TTL.Duration,
+		maxEntries: cfg.MaxEntries,
+		normalize:  cfg.Normalize,
+		backend:    backend,
+		prefix:     resultCacheKeyPrefix + namespace + "/",
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+	cache.load()
+	return cache
+}
+
+// cacheNamespace derives the result cache namespace of an AI configuration, so a changed
+// configuration never serves results produced under the previous one
+func cacheNamespace(cfg config.AIConfig) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// load adopts the unexpired entries of the current namespace, most recently written first,
+// and deletes expired entries and entries of other namespaces
+func (c *resultCache) load() {
+	keys, err := c.backend.Keys(resultCacheKeyPrefix)
+	if err != nil {
+		logging.Logger.Warn("Failed to list cached generation results", "error", err)
+		return
+	}
+
+	type storedEntry struct {
+		key       string
+		expiresAt time.Time
+	}
+	var stored []storedEntry
+	for _, storageKey := range keys {
+		key, ok := strings.CutPrefix(storageKey, c.prefix)
+		if ok {
+			var entry cacheEntry
+			data, found, err := c.backend.Get(storageKey)
+			if err == nil && found && json.Unmarshal(data, &entry) == nil && entry.Result != nil &&
+				(c.ttl <= 0 || !c.now().After(entry.ExpiresAt)) {
+				stored = append(stored, storedEntry{key: key, expiresAt: entry.ExpiresAt})
+				continue
+			}
+		}
+		if err := c.backend.Delete(storageKey); err != nil {
+			logging.Logger.Warn("Failed to delete stale cached generation result", "error", err)
+		}
+	}
+
+	// Entries share one TTL, so a later expiry means a more recent write
+	sort.Slice(stored, func(i, j int) bool { return stored[i].expiresAt.Before(stored[j].expiresAt) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range stored {
+		c.touch(entry.key)
+	}
+}
+
+// key derives the cache key for a natural language query served by service with options
+func (c *resultCache) key(naturalLanguage, service string, options *GenerateOptions) string {
+	if c.normalize {
+		naturalLanguage = normalizePrompt(naturalLanguage)
+	}
+	return generationKey(naturalLanguage, service, options)
+}
+
+// generationKey identifies identical generation requests for caching and coalescing.
+// Runtime API keys only contribute a fingerprint, so credentials never end up in the key,
+// but results are not shared between callers using different keys. service names the provider
+// that answers the request, so changing the default provider does not serve its predecessor's results.
+func generationKey(naturalLanguage, service string, options *GenerateOptions) string {
+	keyParts := struct {
+		Prompt        string            `json:"prompt"`
+		DatabaseType  string            `json:"database_type"`
+		Model         string            `json:"model"`
+		Provider      string            `json:"provider"`
+		Service       string            `json:"service"`
+		Endpoint      string            `json:"endpoint"`
+		Schema        map[string]Table  `json:"schema"`
+		SchemaVersion string            `json:"schema_version"`
+		Context       []string          `json:"context"`
+		MaxTokens     int               `json:"max_tokens"`
+		Validate      bool              `json:"validate"`
+		Optimize      bool              `json:"optimize"`
+		Explain       bool              `json:"explain"`
+		SafetyMode    bool              `json:"safety_mode"`
+		CustomPrompts map[string]string `json:"custom_prompts"`
+		ExplainDSN    string            `json:"explain_dsn"`
+		Candidates    int               `json:"candidates"`
+		Seed          *int64            `json:"seed"`
+		Allowed       []string          `json:"allowed_statements"`
+		MinConfidence float64           `json:"min_confidence"`
+		Fallback      string            `json:"confidence_fallback_provider"`
+		AutoContinue  bool              `json:"auto_continue"`
+		ConfirmWrites bool              `json:"require_confirm_for_writes"`
+		FullTable     bool              `json:"acknowledge_full_table_write"`
+		APIKey        string            `json:"api_key_fingerprint"`
+	}{
+		Prompt:        naturalLanguage,
+		DatabaseType:  options.DatabaseType,
+		Model:         options.Model,
+		Provider:      options.Provider,
+		Service:       service,
+		Endpoint:      options.Endpoint,
+		Schema:        options.Schema,
+		SchemaVersion: options.SchemaVersion,
+		Context:       options.Context,
+		MaxTokens:     options.MaxTokens,
+		Validate:      options.ValidateSQL,
+		Optimize:      options.OptimizeQuery,
+		Explain:       options.IncludeExplanation,
+		SafetyMode:    options.SafetyMode,
+		CustomPrompts: options.CustomPrompts,
+		ExplainDSN:    options.ValidateAgainstDSN,
+		Candidates:    clampCandidates(options.Candidates),
+		Seed:          options.Seed,
+		Allowed:       options.AllowedStatements,
+		MinConfidence: options.MinConfidence,
+		Fallback:      options.ConfidenceFallbackProvider,
+		AutoContinue:  options.AutoContinue,
+		ConfirmWrites: options.RequireConfirmForWrites,
+		FullTable:     options.AcknowledgeFullTableWrite,
+		APIKey:        apiKeyFingerprint(options.APIKey),
+	}
+
+	// Marshalling plain structs and maps cannot fail; map keys are emitted sorted
+	data, _ := json.Marshal(keyParts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyFingerprint returns a digest identifying apiKey without revealing it, or "" for no key
+func apiKeyFingerprint(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("api-key:" + apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a copy of the cached result for key, if present and not expired
+func (c *resultCache) get(key string) (*GenerationResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok, err := c.backend.Get(c.prefix + key)
+	if err != nil {
+		logging.Logger.Warn("Failed to read cached generation result", "error", err)
+		return nil, false
+	}
+	if !ok {
+		c.forget(key)
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Result == nil {
+		c.remove(key)
+		return nil, false
+	}
+	if c.ttl > 0 && c.now().After(entry.ExpiresAt) {
+		c.remove(key)
+		return nil, false
+	}
+	c.touch(key)
+	return entry.Result, true
+}
+
+// put stores a copy of result under key, evicting the least recently used entry when full
+func (c *resultCache) put(key string, result *GenerationResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(cacheEntry{Result: result, ExpiresAt: c.now().Add(c.ttl)})
+	if err == nil {
+		err = c.backend.Set(c.prefix+key, data)
+	}
+	if err != nil {
+		logging.Logger.Warn("Failed to store generation result in cache", "error", err)
+		return
+	}
+	c.touch(key)
+}
+
+// touch marks key as most recently used and evicts the oldest entries beyond maxEntries
+func (c *resultCache) touch(key string) {
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[key] = c.order.PushFront(key)
+	}
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back().Value.(string))
+	}
+}
+
+// remove drops key from the backend and the LRU order
+func (c *resultCache) remove(key string) {
+	if err := c.backend.Delete(c.prefix + key); err != nil {
+		logging.Logger.Warn("Failed to evict cached generation result", "error", err)
+	}
+	c.forget(key)
+}
+
+// forget drops key from the LRU order only
+func (c *resultCache) forget(key string) {
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// len returns the number of cached entries
+func (c *resultCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// normalizePrompt lowercases the prompt, collapses whitespace and strips trailing punctuation
+func normalizePrompt(prompt string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+	return strings.TrimRightFunc(normalized, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// cloneGenerationResult deeply copies a result so cached and held entries are not mutated by callers
+func cloneGenerationResult(result *GenerationResult) *GenerationResult {
+	clone := *result
+	clone.Warnings = append([]string(nil), result.Warnings...)
+	clone.Suggestions = append([]string(nil), result.Suggestions...)
+	clone.ValidationResults = append([]ValidationResult(nil), result.ValidationResults...)
+	clone.Metadata.TablesInvolved = append([]string(nil), result.Metadata.TablesInvolved...)
+	clone.Metadata.DebugInfo = append([]string(nil), result.Metadata.DebugInfo...)
+	clone.Alternatives = append([]CandidateSQL(nil), result.Alternatives...)
+	for i := range clone.Alternatives {
+		clone.Alternatives[i].Warnings = append([]string(nil), clone.Alternatives[i].Warnings...)
+		clone.Alternatives[i].ValidationResults = append([]ValidationResult(nil), clone.Alternatives[i].ValidationResults...)
+	}
+	if result.RenderedPrompt != nil {
+		prompt := *result.RenderedPrompt
+		clone.RenderedPrompt = &prompt
+	}
+	return &clone
+}
@@ -24,8 +24,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 	"github.com/linuxsuren/atest-ext-ai/pkg/config"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+)
+
+const (
+	// generationProbePrompt is the fixed request used to verify a provider can generate SQL
+	generationProbePrompt = "Count the rows in the users table."
+	// healthStatusUnknown marks a provider whose health could not be determined
+	healthStatusUnknown = "unknown"
+	// generationProbeTimeout bounds how long a generation probe may take
+	generationProbeTimeout = 10 * time.Second
 )
 
 // CapabilitiesRequest defines the request structure for capability queries
@@ -34,6 +45,8 @@ type CapabilitiesRequest struct {
 	IncludeDatabases bool `json:"include_databases"`
 	IncludeFeatures  bool `json:"include_features"`
 	CheckHealth      bool `json:"check_health"`
+	// ProbeGeneration runs a tiny fixed generation against the primary provider
+	ProbeGeneration bool `json:"probe_generation"`
 }
 
 // CapabilitiesResponse defines the complete capability information for the AI plugin
@@ -56,6 +69,23 @@ type CapabilityMetadata struct {
 	FeatureCount  int       `json:"feature_count"`
 	HealthChecked bool      `json:"health_checked"`
 	GeneratedAt   time.Time `json:"generated_at"`
+	// ProvidersFailed counts providers whose capabilities could not be detected
+	ProvidersFailed int `json:"providers_failed"`
+	// Degraded is set when some, but not all, providers failed
+	Degraded bool `json:"degraded"`
+	// SkippedServices lists enabled services that failed to initialize and why
+	SkippedServices map[string]string `json:"skipped_services,omitempty"`
+	// GenerationProbe is only set when the request asked for a probe; it is never cached
+	GenerationProbe *GenerationProbe `json:"generation_probe,omitempty"`
+}
+
+// GenerationProbe reports the outcome of a sample generation against the primary provider
+type GenerationProbe struct {
+	Provider string        `json:"provider,omitempty"`
+	Success  bool          `json:"success"`
+	SQL      string        `json:"sql,omitempty"`
+	Latency  time.Duration `json:"latency"`
+	Error    string        `json:"error,omitempty"`
 }
 
 // ModelCapability represents the capabilities of an AI model
@@ -113,6 +143,11 @@ type HealthInfo struct {
 	LastCheck    time.Time     `json:"last_check"`
 	Errors       []string      `json:"errors,omitempty"`
 	Message      string        `json:"message,omitempty"`
+	LatencyEMA   time.Duration `json:"latency_ema,omitempty"`
+	Demoted      bool          `json:"demoted,omitempty"`
+	P50Ms        float64       `json:"p50_ms,omitempty"`
+	P95Ms        float64       `json:"p95_ms,omitempty"`
+	CircuitState string        `json:"circuit_state,omitempty"`
 }
 
 // ResourceLimits defines the resource constraints and limits
@@ -198,6 +233,19 @@ func NewCapabilityDetector(cfg config.AIConfig, manager *Manager) *CapabilityDet
 
 // GetCapabilities returns the comprehensive capability information
 func (d *CapabilityDetector) GetCapabilities(ctx context.Context, req *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	response, err := d.getCapabilities(ctx, req)
+	if err != nil || !req.ProbeGeneration {
+		return response, err
+	}
+
+	// Copy before attaching the probe so the cached response stays probe-free
+	probed := *response
+	probed.Metadata.GenerationProbe = d.probeGeneration(ctx)
+	return &probed, nil
+}
+
+// getCapabilities returns capability information, served from cache when still valid
+func (d *CapabilityDetector) getCapabilities(ctx context.Context, req *CapabilitiesRequest) (*CapabilitiesResponse, error) {
 	d.mu.RLock()
 	// Check if we have cached data that's still valid
 	if d.cache.isValid() {
@@ -231,12 +279,19 @@ func (d *CapabilityDetector) GetCapabilities(ctx context.Context, req *Capabilit
 
 	// Collect models if requested
 	if req.IncludeModels {
-		models, err := d.detectModelCapabilities(ctx)
+		models, failed, err := d.detectModelCapabilities(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to detect model capabilities: %w", err)
 		}
 		response.Models = models
 		metadata.ModelCount = len(models)
+		metadata.ProvidersFailed = failed
+		metadata.Degraded = failed > 0
+	}
+
+	if d.manager != nil {
+		metadata.SkippedServices = d.manager.SkippedServices()
+		metadata.Degraded = metadata.Degraded || len(metadata.SkippedServices) > 0
 	}
 
 	// Collect database capabilities if requested
@@ -273,8 +328,52 @@ func (d *CapabilityDetector) GetCapabilities(ctx context.Context, req *Capabilit
 	return response, nil
 }
 
-// detectModelCapabilities discovers available AI models and their capabilities
-func (d *CapabilityDetector) detectModelCapabilities(ctx context.Context) ([]ModelCapability, error) {
+// durationMillis converts a duration to fractional milliseconds
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// probeGeneration runs a fixed natural language to SQL request against the primary provider.
+// Failures are reported in the probe rather than failing the capabilities call.
+func (d *CapabilityDetector) probeGeneration(ctx context.Context) *GenerationProbe {
+	probe := &GenerationProbe{Provider: d.config.DefaultService}
+	if d.manager == nil {
+		probe.Error = "AI manager not initialized"
+		return probe
+	}
+	client := d.manager.GetPrimaryClient()
+	if client == nil {
+		probe.Error = "no AI provider configured"
+		return probe
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, generationProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := client.Generate(probeCtx, &interfaces.GenerateRequest{
+		Prompt:       generationProbePrompt,
+		SystemPrompt: "You are a SQL generator. Reply with a single SQL statement only.",
+		MaxTokens:    64,
+	})
+	probe.Latency = time.Since(start)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+
+	probe.SQL = cleanSQLText(resp.Text)
+	if probe.SQL == "" {
+		probe.Error = "provider returned an empty response"
+		return probe
+	}
+	probe.Success = true
+	return probe
+}
+
+// detectModelCapabilities discovers available AI models and their capabilities.
+// It returns the number of failed providers and only errors when every provider failed.
+func (d *CapabilityDetector) detectModelCapabilities(ctx context.Context) ([]ModelCapability, int, error) {
 	var capabilities []ModelCapability
 	var errs []error
 
@@ -290,7 +389,7 @@ func (d *CapabilityDetector) detectModelCapabilities(ctx context.Context) ([]Mod
 				MaxTokens:   4096,
 				ContextSize: 4096,
 			},
-		}, nil
+		}, 0, nil
 	}
 
 	// Get all available clients
@@ -312,37 +411,100 @@ func (d *CapabilityDetector) detectModelCapabilities(ctx context.Context) ([]Mod
 
 		// Convert provider capabilities to our format
 		for _, model := range clientCaps.Models {
-			capability := ModelCapability{
-				Name:        model.ID,
-				Provider:    clientCaps.Provider,
-				Available:   true,
-				Features:    model.Capabilities,
-				MaxTokens:   model.MaxTokens,
-				ContextSize: model.MaxTokens,
-				Metadata: map[string]string{
-					"description": model.Description,
-					"name":        model.Name,
-				},
-			}
-
-			// Add cost information if available
-			if model.InputCostPer1K > 0 || model.OutputCostPer1K > 0 {
-				capability.CostPer1K = &CostInfo{
-					InputCost:  model.InputCostPer1K,
-					OutputCost: model.OutputCostPer1K,
-					Currency:   "USD",
-				}
-			}
-
+			capability := modelCapability(clientCaps.Provider, model)
+			applyModelOverride(&capability, d.config.Models)
 			capabilities = append(capabilities, capability)
 		}
 	}
 
+	if len(errs) > 0 && len(errs) == len(clients) {
+		return capabilities, len(errs), errors.Join(errs...)
+	}
 	if len(errs) > 0 {
-		return capabilities, errors.Join(errs...)
+		logging.Logger.Warn("Some providers failed capability detection",
+			"failed", len(errs), "total", len(clients), "error", errors.Join(errs...))
 	}
 
-	return capabilities, nil
+	return capabilities, len(errs), nil
+}
+
+// ModelDetails returns the capability of one model offered by provider, with ai.models overrides
+// applied. Model aliases are resolved first.
+func (d *CapabilityDetector) ModelDetails(ctx context.Context, provider, model string) (*ModelCapability, error) {
+	if d.manager == nil {
+		return nil, fmt.Errorf("%w: %s", ErrClientNotFound, provider)
+	}
+	client, err := d.manager.GetClient(providers.Normalize(provider))
+	if err != nil {
+		return nil, err
+	}
+
+	clientCaps, err := client.GetCapabilities(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s capability detection failed: %w", provider, err)
+	}
+
+	modelID := resolveModelAlias(model, d.config.ModelAliases)
+	for _, info := range clientCaps.Models {
+		if info.ID == modelID {
+			capability := modelCapability(clientCaps.Provider, info)
+			applyModelOverride(&capability, d.config.Models)
+			return &capability, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: provider %s does not offer %s", ErrModelNotFound, provider, modelID)
+}
+
+// modelCapability converts a provider's model information into a ModelCapability
+func modelCapability(provider string, model interfaces.ModelInfo) ModelCapability {
+	capability := ModelCapability{
+		Name:        model.ID,
+		Provider:    provider,
+		Available:   true,
+		Features:    model.Capabilities,
+		MaxTokens:   model.MaxTokens,
+		ContextSize: model.MaxTokens,
+		Metadata: map[string]string{
+			"description": model.Description,
+			"name":        model.Name,
+		},
+	}
+
+	// Add cost information if available
+	if model.InputCostPer1K > 0 || model.OutputCostPer1K > 0 {
+		capability.CostPer1K = &CostInfo{
+			InputCost:  model.InputCostPer1K,
+			OutputCost: model.OutputCostPer1K,
+			Currency:   "USD",
+		}
+	}
+	return capability
+}
+
+// applyModelOverride replaces detected values with the configured ai.models entry, if any
+func applyModelOverride(capability *ModelCapability, overrides map[string]config.ModelOverride) {
+	override, ok := overrides[capability.Name]
+	if !ok {
+		return
+	}
+
+	if override.MaxTokens > 0 {
+		capability.MaxTokens = override.MaxTokens
+	}
+	if override.ContextSize > 0 {
+		capability.ContextSize = override.ContextSize
+	}
+	if override.InputCostPer1K > 0 || override.OutputCostPer1K > 0 {
+		if capability.CostPer1K == nil {
+			capability.CostPer1K = &CostInfo{Currency: "USD"}
+		}
+		if override.InputCostPer1K > 0 {
+			capability.CostPer1K.InputCost = override.InputCostPer1K
+		}
+		if override.OutputCostPer1K > 0 {
+			capability.CostPer1K.OutputCost = override.OutputCostPer1K
+		}
+	}
 }
 
 // detectDatabaseCapabilities returns supported database types and features
@@ -367,6 +529,12 @@ func (d *CapabilityDetector) detectDatabaseCapabilities() []DatabaseCapability {
 			Features:  []string{"joins", "subqueries", "cte", "window-functions", "json-functions"},
 			Supported: true,
 		},
+		{
+			Type:      "snowflake",
+			Versions:  []string{"current"},
+			Features:  []string{"joins", "subqueries", "cte", "window-functions", "qualify", "semi-structured"},
+			Supported: true,
+		},
 		{
 			Type:        "oracle",
 			Versions:    []string{"11g", "12c", "19c", "21c"},
@@ -466,11 +634,39 @@ func (d *CapabilityDetector) performHealthChecks(ctx context.Context) (*HealthSt
 	report.Components["config"] = d.checkConfigHealth()
 
 	// Check provider health
-	d.healthChecker.mu.RLock()
-	for name, client := range d.healthChecker.providers {
-		report.Providers[name] = d.checkProviderHealth(ctx, client)
+	for name, health := range d.checkProvidersConcurrently(ctx) {
+		report.Providers[name] = health
+	}
+
+	// Attach generation latency averages tracked by the manager
+	if d.manager != nil {
+		for name, ema := range d.manager.LatencyEMA() {
+			health, ok := report.Providers[name]
+			if !ok {
+				continue
+			}
+			health.LatencyEMA = ema
+			health.Demoted = d.manager.isDemoted(name)
+			report.Providers[name] = health
+		}
+		for name, percentiles := range d.manager.LatencyPercentiles() {
+			health, ok := report.Providers[name]
+			if !ok {
+				continue
+			}
+			health.P50Ms = durationMillis(percentiles.P50)
+			health.P95Ms = durationMillis(percentiles.P95)
+			report.Providers[name] = health
+		}
+		for name, state := range d.manager.CircuitStates() {
+			health, ok := report.Providers[name]
+			if !ok {
+				continue
+			}
+			health.CircuitState = state
+			report.Providers[name] = health
+		}
 	}
-	d.healthChecker.mu.RUnlock()
 
 	// Determine overall health and collect error details
 	var errs []error
@@ -482,8 +678,12 @@ func (d *CapabilityDetector) performHealthChecks(ctx context.Context) (*HealthSt
 	}
 
 	for name, health := range report.Providers {
-		if !health.Healthy {
-			report.Overall = false
+		if health.Healthy {
+			continue
+		}
+		report.Overall = false
+		// Unresponsive providers are reported as unknown without failing the whole report
+		if health.Status != healthStatusUnknown {
 			errs = append(errs, fmt.Errorf("provider %s unhealthy: %s", name, summarizeHealth(health)))
 		}
 	}
@@ -495,6 +695,52 @@ func (d *CapabilityDetector) performHealthChecks(ctx context.Context) (*HealthSt
 	return report, nil
 }
 
+// checkProvidersConcurrently checks every provider in parallel and stops waiting after the
+// health check timeout; providers that have not answered by then are marked unknown.
+func (d *CapabilityDetector) checkProvidersConcurrently(ctx context.Context) map[string]HealthInfo {
+	d.healthChecker.mu.RLock()
+	providers := make(map[string]interfaces.AIClient, len(d.healthChecker.providers))
+	for name, client := range d.healthChecker.providers {
+		providers[name] = client
+	}
+	timeout := d.healthChecker.timeout
+	d.healthChecker.mu.RUnlock()
+
+	type providerHealth struct {
+		name   string
+		health HealthInfo
+	}
+	// Buffered so checks that finish after the deadline never block
+	results := make(chan providerHealth, len(providers))
+	for name, client := range providers {
+		go func(name string, client interfaces.AIClient) {
+			results <- providerHealth{name: name, health: d.checkProviderHealth(ctx, client)}
+		}(name, client)
+	}
+
+	healths := make(map[string]HealthInfo, len(providers))
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for len(healths) < len(providers) {
+		select {
+		case result := <-results:
+			healths[result.name] = result.health
+		case <-deadline.C:
+			for name := range providers {
+				if _, ok := healths[name]; !ok {
+					healths[name] = HealthInfo{
+						Status:       healthStatusUnknown,
+						ResponseTime: timeout,
+						LastCheck:    time.Now(),
+						Message:      fmt.Sprintf("Health check did not complete within %s", timeout),
+					}
+				}
+			}
+		}
+	}
+	return healths
+}
+
 func summarizeHealth(health HealthInfo) string {
 	if len(health.Errors) == 0 {
 		return health.Message
@@ -613,7 +859,7 @@ func (d *CapabilityDetector) checkProviderHealth(ctx context.Context, client int
 
 	if healthStatus == nil {
 		return HealthInfo{
-			Status:       "unknown",
+			Status:       healthStatusUnknown,
 			Healthy:      false,
 			ResponseTime: responseTime,
 			LastCheck:    time.Now(),
@@ -627,12 +873,18 @@ func (d *CapabilityDetector) checkProviderHealth(ctx context.Context, client int
 		status = "unhealthy"
 	}
 
+	message := healthStatus.Status
+	if mode, ok := healthStatus.Metadata["health_mode"].(string); ok && mode != "" {
+		message = fmt.Sprintf("%s [%s check]", message, mode)
+	}
+
 	return HealthInfo{
 		Status:       status,
 		Healthy:      healthStatus.Healthy,
 		ResponseTime: responseTime,
 		LastCheck:    time.Now(),
-		Message:      healthStatus.Status,
+		Errors:       healthStatus.Errors,
+		Message:      message,
 	}
 }
 
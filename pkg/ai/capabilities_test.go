@@ -0,0 +1,278 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAIClient is a configurable interfaces.AIClient used across package tests.
+type stubAIClient struct {
+	generate     func(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error)
+	capabilities *interfaces.Capabilities
+	capsErr      error
+	health       *interfaces.HealthStatus
+	healthErr    error
+	healthCheck  func(ctx context.Context) (*interfaces.HealthStatus, error)
+	closed       int
+}
+
+func (s *stubAIClient) Generate(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+	if s.generate != nil {
+		return s.generate(ctx, req)
+	}
+	return &interfaces.GenerateResponse{Text: "SELECT 1;", Model: req.Model}, nil
+}
+
+func (s *stubAIClient) GetCapabilities(context.Context) (*interfaces.Capabilities, error) {
+	if s.capsErr != nil {
+		return nil, s.capsErr
+	}
+	if s.capabilities != nil {
+		return s.capabilities, nil
+	}
+	return &interfaces.Capabilities{Provider: "stub"}, nil
+}
+
+func (s *stubAIClient) HealthCheck(ctx context.Context) (*interfaces.HealthStatus, error) {
+	if s.healthCheck != nil {
+		return s.healthCheck(ctx)
+	}
+	if s.healthErr != nil {
+		return nil, s.healthErr
+	}
+	if s.health != nil {
+		return s.health, nil
+	}
+	return &interfaces.HealthStatus{Healthy: true, Status: "ok"}, nil
+}
+
+func (s *stubAIClient) Close() error {
+	s.closed++
+	return nil
+}
+
+// newTestManager builds a Manager around pre-built clients without touching the network.
+func newTestManager(cfg config.AIConfig, clients map[string]interfaces.AIClient) *Manager {
+	return &Manager{clients: clients, config: cfg}
+}
+
+func TestDetectModelCapabilitiesAppliesOverrides(t *testing.T) {
+	client := &stubAIClient{capabilities: &interfaces.Capabilities{
+		Provider: "ollama",
+		Models: []interfaces.ModelInfo{
+			{ID: "custom-llm", MaxTokens: 2048, InputCostPer1K: 0.5},
+			{ID: "llama3", MaxTokens: 8192},
+		},
+	}}
+	cfg := config.AIConfig{
+		Models: map[string]config.ModelOverride{
+			"custom-llm": {MaxTokens: 4096, ContextSize: 32768, OutputCostPer1K: 0.25},
+		},
+	}
+	detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{"ollama": client}))
+
+	caps, _, err := detector.detectModelCapabilities(context.Background())
+	require.NoError(t, err)
+
+	byName := make(map[string]ModelCapability, len(caps))
+	for _, capability := range caps {
+		byName[capability.Name] = capability
+	}
+
+	overridden := byName["custom-llm"]
+	assert.Equal(t, 4096, overridden.MaxTokens)
+	assert.Equal(t, 32768, overridden.ContextSize)
+	require.NotNil(t, overridden.CostPer1K)
+	assert.Equal(t, 0.5, overridden.CostPer1K.InputCost, "unset override keeps detected cost")
+	assert.Equal(t, 0.25, overridden.CostPer1K.OutputCost)
+
+	untouched := byName["llama3"]
+	assert.Equal(t, 8192, untouched.MaxTokens)
+	assert.Equal(t, 8192, untouched.ContextSize)
+	assert.Nil(t, untouched.CostPer1K)
+}
+
+func TestGetCapabilitiesProbeGeneration(t *testing.T) {
+	cfg := config.AIConfig{DefaultService: "ollama"}
+
+	t.Run("success", func(t *testing.T) {
+		client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			assert.Equal(t, generationProbePrompt, req.Prompt)
+			return &interfaces.GenerateResponse{Text: "```sql\nSELECT COUNT(*) FROM users;\n```"}, nil
+		}}
+		detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{"ollama": client}))
+
+		resp, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{ProbeGeneration: true})
+		require.NoError(t, err)
+		probe := resp.Metadata.GenerationProbe
+		require.NotNil(t, probe)
+		assert.True(t, probe.Success)
+		assert.Equal(t, "SELECT COUNT(*) FROM users;", probe.SQL)
+		assert.Equal(t, "ollama", probe.Provider)
+		assert.Empty(t, probe.Error)
+
+		cached, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{})
+		require.NoError(t, err)
+		assert.Nil(t, cached.Metadata.GenerationProbe, "probe results are not cached")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return nil, errors.New("connection refused")
+		}}
+		detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{"ollama": client}))
+
+		resp, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{IncludeFeatures: true, ProbeGeneration: true})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.Features)
+		probe := resp.Metadata.GenerationProbe
+		require.NotNil(t, probe)
+		assert.False(t, probe.Success)
+		assert.Contains(t, probe.Error, "connection refused")
+	})
+}
+
+func TestGetCapabilitiesReportsPartialProviderFailure(t *testing.T) {
+	working := &stubAIClient{capabilities: &interfaces.Capabilities{
+		Provider: "ollama",
+		Models:   []interfaces.ModelInfo{{ID: "llama3", MaxTokens: 8192}},
+	}}
+	failing := &stubAIClient{capsErr: errors.New("connection refused")}
+	cfg := config.AIConfig{}
+
+	detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": working,
+		"openai": failing,
+	}))
+	resp, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{IncludeModels: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Metadata.ProvidersFailed)
+	assert.True(t, resp.Metadata.Degraded)
+
+	available := 0
+	for _, model := range resp.Models {
+		if model.Available {
+			available++
+		}
+	}
+	assert.Equal(t, 1, available, "working providers are still listed")
+
+	detector = NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{"openai": failing}))
+	_, err = detector.GetCapabilities(context.Background(), &CapabilitiesRequest{IncludeModels: true})
+	assert.Error(t, err, "a response with no working provider is an error")
+}
+
+func TestHealthChecksMarkHangingProviderUnknown(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	hanging := &stubAIClient{healthCheck: func(context.Context) (*interfaces.HealthStatus, error) {
+		// Ignores context cancellation, like a provider stuck on a dead connection
+		<-release
+		return &interfaces.HealthStatus{Healthy: true}, nil
+	}}
+	cfg := config.AIConfig{DefaultService: "ollama"}
+	detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": &stubAIClient{},
+		"openai": hanging,
+	}))
+	detector.healthChecker.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	resp, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{CheckHealth: true})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.True(t, resp.Health.Providers["ollama"].Healthy)
+	assert.Equal(t, "unknown", resp.Health.Providers["openai"].Status)
+	assert.False(t, resp.Health.Overall)
+}
+
+func TestCapabilitiesReportCircuitState(t *testing.T) {
+	failing := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: "ollama.local"}
+	}}
+	cfg := config.AIConfig{
+		DefaultService: "ollama",
+		Retry:          config.RetryConfig{MaxAttempts: constants.CircuitBreaker.FailureThreshold + 1, InitialDelay: config.Duration{Duration: time.Millisecond}},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{"ollama": failing, "openai": &stubAIClient{}})
+	detector := NewCapabilityDetector(cfg, manager)
+
+	_, err := manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users"})
+	require.NoError(t, err, "the request fails over once the ollama circuit opens")
+
+	resp, err := detector.GetCapabilities(context.Background(), &CapabilitiesRequest{CheckHealth: true})
+	require.NoError(t, err)
+	assert.True(t, resp.Health.Providers["ollama"].Healthy, "the health check itself still passes")
+	assert.Equal(t, CircuitOpen, resp.Health.Providers["ollama"].CircuitState)
+	assert.Equal(t, CircuitClosed, resp.Health.Providers["openai"].CircuitState)
+
+	manager.circuits.mu.Lock()
+	manager.circuits.circuits["ollama"].openedAt = time.Now().Add(-constants.CircuitBreaker.Cooldown)
+	manager.circuits.mu.Unlock()
+	assert.Equal(t, CircuitHalfOpen, manager.CircuitStates()["ollama"])
+}
+
+func TestCapabilitiesReportPercentilesFromEngineGenerations(t *testing.T) {
+	cfg := config.AIConfig{DefaultService: "ollama"}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{"ollama": &stubAIClient{
+		generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			time.Sleep(5 * time.Millisecond)
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		},
+	}})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+	_, err = engine.GenerateSQL(context.Background(), &GenerateSQLRequest{NaturalLanguage: "count users", DatabaseType: "mysql"})
+	require.NoError(t, err)
+
+	resp, err := NewCapabilityDetector(cfg, manager).GetCapabilities(context.Background(), &CapabilitiesRequest{CheckHealth: true})
+	require.NoError(t, err)
+	health := resp.Health.Providers["ollama"]
+	assert.GreaterOrEqual(t, health.P50Ms, float64(5))
+	assert.GreaterOrEqual(t, health.P95Ms, health.P50Ms)
+}
+
+func TestHealthInfoReportsHealthMode(t *testing.T) {
+	cfg := config.AIConfig{DefaultService: "ollama"}
+	detector := NewCapabilityDetector(cfg, newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": &stubAIClient{health: &interfaces.HealthStatus{Healthy: true, Status: "Healthy", Metadata: map[string]any{"health_mode": "light"}}},
+		"openai": &stubAIClient{health: &interfaces.HealthStatus{
+			Status:   "Unhealthy (generation failed)",
+			Errors:   []string{"status 403"},
+			Metadata: map[string]any{"health_mode": "deep"},
+		}},
+	}))
+
+	providers := detector.checkProvidersConcurrently(context.Background())
+	assert.Equal(t, "Healthy [light check]", providers["ollama"].Message)
+	openai := providers["openai"]
+	assert.False(t, openai.Healthy)
+	assert.Equal(t, "Unhealthy (generation failed) [deep check]", openai.Message)
+	assert.Equal(t, []string{"status 403"}, openai.Errors)
+}
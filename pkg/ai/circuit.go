@@ -0,0 +1,144 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"sync"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// Circuit states reported for each provider
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// circuitBreakers tracks consecutive generation failures per client. An open circuit
+// is skipped during client selection until the cooldown elapses; a single trial request
+// then runs half-open and either closes the circuit or reopens it. The zero value is ready to use.
+type circuitBreakers struct {
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	openedAt time.Time
+	// trial is set while the half-open trial request is in flight
+	trial bool
+}
+
+// state returns the circuit state of a client at the given time
+func (b *circuitBreakers) state(name string, now time.Time) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked(name, now)
+}
+
+func (b *circuitBreakers) stateLocked(name string, now time.Time) string {
+	c, ok := b.circuits[name]
+	if !ok || c.openedAt.IsZero() {
+		return CircuitClosed
+	}
+	if now.Sub(c.openedAt) < constants.CircuitBreaker.Cooldown {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+// allow reports whether a request may be sent to the client: its circuit is closed, or
+// half-open with no trial request in flight
+func (b *circuitBreakers) allow(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.allowLocked(name, time.Now())
+}
+
+func (b *circuitBreakers) allowLocked(name string, now time.Time) bool {
+	switch b.stateLocked(name, now) {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return !b.circuits[name].trial
+	default:
+		return true
+	}
+}
+
+// acquire is allow for the client a request is about to be sent to; on a half-open
+// circuit it claims the trial, so concurrent requests skip the client until release,
+// recordSuccess or recordFailure
+func (b *circuitBreakers) acquire(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if !b.allowLocked(name, now) {
+		return false
+	}
+	if b.stateLocked(name, now) == CircuitHalfOpen {
+		b.circuits[name].trial = true
+	}
+	return true
+}
+
+// release gives up a claimed trial without a verdict on the client's health
+func (b *circuitBreakers) release(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[name]; ok {
+		c.trial = false
+	}
+}
+
+// recordSuccess closes the client's circuit
+func (b *circuitBreakers) recordSuccess(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, name)
+}
+
+// recordFailure counts a failure and opens the circuit at the threshold or when a half-open trial fails
+func (b *circuitBreakers) recordFailure(name string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	c, ok := b.circuits[name]
+	if !ok {
+		c = &circuit{}
+		b.circuits[name] = c
+	}
+	halfOpen := b.stateLocked(name, now) == CircuitHalfOpen
+	c.trial = false
+	c.failures++
+	if halfOpen || c.failures >= constants.CircuitBreaker.FailureThreshold {
+		c.openedAt = now
+	}
+}
+
+// remove forgets the circuit of a client
+func (b *circuitBreakers) remove(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, name)
+}
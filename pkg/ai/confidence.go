@@ -0,0 +1,212 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+)
+
+// placeholderSQL is returned when no SQL could be extracted from a response
+const placeholderSQL = "SELECT 1 as placeholder;"
+
+// confidenceWeights are the terms of the confidence formula; unset config values use the defaults
+type confidenceWeights struct {
+	base, errorPenalty, warningPenalty, groundedBonus, ungroundedPenalty, complexityPenalty, truncatedPenalty float64
+}
+
+// newConfidenceWeights fills unset configuration values from constants.Confidence
+func newConfidenceWeights(cfg config.ConfidenceConfig) confidenceWeights {
+	orDefault := func(value *float64, fallback float64) float64 {
+		if value == nil {
+			return fallback
+		}
+		return *value
+	}
+	defaults := constants.Confidence
+	return confidenceWeights{
+		base:              orDefault(cfg.Base, defaults.Base),
+		errorPenalty:      orDefault(cfg.ErrorPenalty, defaults.ErrorPenalty),
+		warningPenalty:    orDefault(cfg.WarningPenalty, defaults.WarningPenalty),
+		groundedBonus:     orDefault(cfg.GroundedBonus, defaults.GroundedBonus),
+		ungroundedPenalty: orDefault(cfg.UngroundedPenalty, defaults.UngroundedPenalty),
+		complexityPenalty: orDefault(cfg.ComplexityPenalty, defaults.ComplexityPenalty),
+		truncatedPenalty:  orDefault(cfg.TruncatedPenalty, defaults.TruncatedPenalty),
+	}
+}
+
+// computeConfidence scores a generated statement between 0 and 1:
+//
+//	score = base
+//	      - truncated_penalty                 if the provider stopped at the token limit
+//	      - error_penalty   * validation errors
+//	      - warning_penalty * validation warnings
+//	      + grounded_bonus                    if a schema was given and every referenced table is in it
+//	      - ungrounded_penalty                if a schema was given and a referenced table is not in it
+//	      - complexity_penalty                for complex queries (twice for very complex ones)
+//
+// The result is clamped to [0, 1]; a response without extractable SQL scores 0.
+func computeConfidence(weights confidenceWeights, result *GenerationResult, aiResponse *interfaces.GenerateResponse, options *GenerateOptions) float64 {
+	if strings.TrimSpace(result.SQL) == "" || result.SQL == placeholderSQL {
+		return 0
+	}
+
+	score := weights.base
+	if isTruncatedResponse(aiResponse) {
+		score -= weights.truncatedPenalty
+	}
+	for _, validation := range result.ValidationResults {
+		switch validation.Level {
+		case "error":
+			score -= weights.errorPenalty
+		case "warning":
+			score -= weights.warningPenalty
+		}
+	}
+	if grounded, ok := schemaGrounded(result.Metadata.TablesInvolved, options.Schema); ok {
+		if grounded {
+			score += weights.groundedBonus
+		} else {
+			score -= weights.ungroundedPenalty
+		}
+	}
+	switch result.Metadata.Complexity {
+	case "complex":
+		score -= weights.complexityPenalty
+	case "very_complex":
+		score -= 2 * weights.complexityPenalty
+	}
+	return math.Max(0, math.Min(1, score))
+}
+
+// schemaGrounded reports whether every referenced table is in schema; ok is false without a schema or tables
+func schemaGrounded(tables []string, schema map[string]Table) (grounded, ok bool) {
+	if len(schema) == 0 || len(tables) == 0 {
+		return false, false
+	}
+	known := make(map[string]bool, len(schema))
+	for key, table := range schema {
+		known[strings.ToLower(key)] = true
+		if table.Name != "" {
+			known[strings.ToLower(table.Name)] = true
+		}
+	}
+	for _, table := range tables {
+		name := strings.Trim(table, "`\"[];()")
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = strings.Trim(name[dot+1:], "`\"[]")
+		}
+		if !known[strings.ToLower(name)] {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// maxConfidenceRetries caps the extra attempts made for GenerateOptions.MinConfidence
+const maxConfidenceRetries = 2
+
+// generateWithMinConfidence regenerates with a stricter prompt while the best result scores below
+// options.MinConfidence and retries remain, and returns the highest-scoring result
+func (g *SQLGenerator) generateWithMinConfidence(ctx context.Context, prompt string, options *GenerateOptions, dialect SQLDialect, requestID string, start time.Time) (*GenerationResult, error) {
+	best, err := g.generateFromPrompt(ctx, prompt, options, dialect, requestID, start)
+	if err != nil || options.MinConfidence <= 0 || best.ConfidenceScore >= options.MinConfidence {
+		return best, err
+	}
+
+	retryOptions := g.confidenceRetryOptions(options)
+	attempts := []string{fmt.Sprintf("%.2f", best.ConfidenceScore)}
+	for retry := 0; retry < maxConfidenceRetries && best.ConfidenceScore < options.MinConfidence; retry++ {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := g.generateFromPrompt(ctx, strictConfidencePrompt(prompt, best), retryOptions, dialect, requestID, start)
+		if err != nil {
+			logging.Logger.Warn("Confidence retry failed", "attempt", retry+1, "error", err)
+			attempts = append(attempts, "failed")
+			continue
+		}
+		attempts = append(attempts, fmt.Sprintf("%.2f", result.ConfidenceScore))
+		if result.ConfidenceScore > best.ConfidenceScore {
+			best = result
+		}
+	}
+
+	served := ""
+	if retryOptions.client != nil {
+		served = " via " + retryOptions.Provider
+	}
+	best.Metadata.DebugInfo = append(best.Metadata.DebugInfo, fmt.Sprintf("confidence retries%s: %d attempt(s) scored %s against a minimum of %.2f",
+		served, len(attempts), strings.Join(attempts, ", "), options.MinConfidence))
+	if best.ConfidenceScore < options.MinConfidence {
+		best.Warnings = append(best.Warnings, fmt.Sprintf("confidence %.2f is below the requested minimum %.2f", best.ConfidenceScore, options.MinConfidence))
+	}
+	return best, nil
+}
+
+// confidenceRetryOptions points retries at ConfidenceFallbackProvider when it names a configured
+// service; otherwise retries use the same provider
+func (g *SQLGenerator) confidenceRetryOptions(options *GenerateOptions) *GenerateOptions {
+	name := providers.Normalize(options.ConfidenceFallbackProvider)
+	if name == "" {
+		return options
+	}
+	g.clientMu.RLock()
+	lookup := g.namedClient
+	g.clientMu.RUnlock()
+	if lookup == nil {
+		logging.Logger.Warn("Confidence fallback provider ignored: no configured services available", "provider", name)
+		return options
+	}
+	client, err := lookup(name)
+	if err != nil {
+		logging.Logger.Warn("Confidence fallback provider unavailable, retrying with the same provider", "provider", name, "error", err)
+		return options
+	}
+	retryOptions := *options
+	retryOptions.Provider = name
+	retryOptions.APIKey = ""
+	retryOptions.Endpoint = ""
+	retryOptions.Model = ""
+	retryOptions.client = client
+	return &retryOptions
+}
+
+// strictConfidencePrompt repeats the request with the issues that lowered the previous score
+func strictConfidencePrompt(prompt string, previous *GenerationResult) string {
+	var builder strings.Builder
+	builder.WriteString(prompt)
+	builder.WriteString("\n\nA previous answer to this request was not reliable enough:\n")
+	builder.WriteString(strings.TrimSpace(previous.SQL))
+	for _, validation := range previous.ValidationResults {
+		if validation.Level == "error" || validation.Level == "warning" {
+			builder.WriteString("\n- ")
+			builder.WriteString(validation.Message)
+		}
+	}
+	builder.WriteString("\n\nAnswer again using only tables and columns from the schema, and return a single valid SQL statement.")
+	return builder.String()
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+)
+
+// ConfigDialect is a dialect declared in ai.custom_dialects. Optimization, formatting and
+// transforms come from its base dialect; reserved keywords, data types and functions are the
+// base dialect's plus the configured ones.
+type ConfigDialect struct {
+	name      string
+	base      SQLDialect
+	quote     string
+	keywords  []string
+	dataTypes []DataType
+	functions []Function
+}
+
+// NewConfigDialect builds a custom dialect on top of one of the built-in dialects
+func NewConfigDialect(name string, cfg config.CustomDialectConfig) (*ConfigDialect, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("dialect name must not be empty")
+	}
+	base, ok := NewSQLDialect(canonicalDialect(strings.ToLower(strings.TrimSpace(cfg.Base))))
+	if !ok {
+		return nil, NewUnsupportedDialectError(cfg.Base)
+	}
+
+	quote := `"`
+	if _, ok := base.(*MySQLDialect); ok {
+		quote = "`"
+	}
+
+	dialect := &ConfigDialect{name: name, base: base, quote: quote}
+	for _, keyword := range cfg.Keywords {
+		dialect.keywords = append(dialect.keywords, strings.ToUpper(strings.TrimSpace(keyword)))
+	}
+	for _, dataType := range cfg.DataTypes {
+		dialect.dataTypes = append(dialect.dataTypes, DataType{Name: strings.ToUpper(strings.TrimSpace(dataType)), Category: "custom"})
+	}
+	for _, function := range cfg.Functions {
+		dialect.functions = append(dialect.functions, Function{Name: strings.ToUpper(strings.TrimSpace(function)), Category: "custom"})
+	}
+	return dialect, nil
+}
+
+// Name implements SQLDialect.Name with the configured dialect name.
+func (d *ConfigDialect) Name() string {
+	return d.name
+}
+
+// ValidateSQL runs the base dialect's checks but reports reserved identifiers using this dialect's keywords.
+func (d *ConfigDialect) ValidateSQL(sql string) ([]ValidationResult, error) {
+	baseResults, err := d.base.ValidateSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ValidationResult, 0, len(baseResults))
+	for _, result := range baseResults {
+		if result.Type != "naming" {
+			results = append(results, result)
+		}
+	}
+	return append(results, reservedIdentifierResults(sql, d, d.quote)...), nil
+}
+
+// OptimizeSQL delegates to the base dialect.
+func (d *ConfigDialect) OptimizeSQL(sql string) (string, []string, error) {
+	return d.base.OptimizeSQL(sql)
+}
+
+// FormatSQL delegates to the base dialect.
+func (d *ConfigDialect) FormatSQL(sql string) (string, error) {
+	return d.base.FormatSQL(sql)
+}
+
+// GetDataTypes returns the base dialect's data types followed by the configured ones.
+func (d *ConfigDialect) GetDataTypes() []DataType {
+	return append(d.base.GetDataTypes(), d.dataTypes...)
+}
+
+// GetFunctions returns the base dialect's functions followed by the configured ones.
+func (d *ConfigDialect) GetFunctions() []Function {
+	return append(d.base.GetFunctions(), d.functions...)
+}
+
+// GetKeywords returns the base dialect's keywords followed by the configured ones.
+func (d *ConfigDialect) GetKeywords() []string {
+	return append(d.base.GetKeywords(), d.keywords...)
+}
+
+// TransformSQL delegates to the base dialect.
+func (d *ConfigDialect) TransformSQL(sql string, targetDialect string) (string, error) {
+	return d.base.TransformSQL(sql, targetDialect)
+}
+
+// ApplyPagination delegates to the base dialect.
+func (d *ConfigDialect) ApplyPagination(sql string, limit, offset int) (string, error) {
+	return d.base.ApplyPagination(sql, limit, offset)
+}
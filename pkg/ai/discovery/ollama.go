@@ -21,6 +21,7 @@ import (
 	"net/http"
 
 	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/httpx"
 )
 
 // OllamaDiscovery handles Ollama service discovery
@@ -40,7 +41,8 @@ func NewOllamaDiscovery(baseURL string) *OllamaDiscovery {
 	return &OllamaDiscovery{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: constants.Timeouts.Discovery,
+			Timeout:   constants.Timeouts.Discovery,
+			Transport: httpx.NewTransport(),
 		},
 	}
 }
@@ -1,6 +1,10 @@
 package ai
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
+)
 
 // normalizeProviderEndpoint trims provider endpoints to a canonical form so that
 // universal clients can safely append API paths without duplicating segments like /v1.
@@ -11,7 +15,7 @@ func normalizeProviderEndpoint(provider, endpoint string) string {
 	}
 
 	trimmed = strings.TrimRight(trimmed, "/")
-	normalized := strings.ToLower(strings.TrimSpace(provider))
+	normalized := providers.Normalize(provider)
 
 	if normalized == "openai" || normalized == "deepseek" {
 		for strings.HasSuffix(trimmed, "/v1") {
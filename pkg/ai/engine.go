@@ -22,8 +22,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 	"github.com/linuxsuren/atest-ext-ai/pkg/config"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
@@ -54,8 +56,15 @@ func IsProviderNotSupported(err error) bool {
 // Engine defines the interface for AI SQL generation
 type Engine interface {
 	GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*GenerateSQLResponse, error)
+	RegenerateSQL(ctx context.Context, req *RegenerateSQLRequest) (*GenerateSQLResponse, error)
 	GetCapabilities() *SQLCapabilities
 	IsHealthy() bool
+	// SetDefaultProvider switches the provider used for requests without runtime provider settings
+	SetDefaultProvider(ctx context.Context, name string) error
+	// OpenSession prepares schema once for generations that name the returned session id
+	OpenSession(schema map[string]Table) (string, error)
+	// CloseSession discards a session opened by OpenSession
+	CloseSession(id string) error
 	Close()
 }
 
@@ -65,17 +74,37 @@ type GenerateSQLRequest struct {
 	DatabaseType    string            `json:"database_type"`
 	Context         map[string]string `json:"context,omitempty"`
 	RuntimeAPIKey   string            `json:"-"`
+	// Template, when set, generates from the named query template instead of NaturalLanguage
+	Template       string            `json:"template,omitempty"`
+	TemplateParams map[string]string `json:"template_params,omitempty"`
+	// SessionID, when set, generates against the schema of an open session
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// RegenerateSQLRequest asks the engine to correct previously generated SQL using feedback
+type RegenerateSQLRequest struct {
+	PreviousSQL   string            `json:"previous_sql"`
+	Feedback      string            `json:"feedback"`
+	DatabaseType  string            `json:"database_type"`
+	Context       map[string]string `json:"context,omitempty"`
+	RuntimeAPIKey string            `json:"-"`
 }
 
 // GenerateSQLResponse represents an AI SQL generation response
 type GenerateSQLResponse struct {
-	SQL             string        `json:"sql"`
-	Explanation     string        `json:"explanation"`
-	ConfidenceScore float32       `json:"confidence_score"`
-	ProcessingTime  time.Duration `json:"processing_time"`
-	RequestID       string        `json:"request_id"`
-	ModelUsed       string        `json:"model_used"`
-	DebugInfo       []string      `json:"debug_info,omitempty"`
+	SQL             string          `json:"sql"`
+	Explanation     string          `json:"explanation"`
+	ConfidenceScore float32         `json:"confidence_score"`
+	ProcessingTime  time.Duration   `json:"processing_time"`
+	RequestID       string          `json:"request_id"`
+	ModelUsed       string          `json:"model_used"`
+	CacheHit        bool            `json:"cache_hit"`
+	ServedBy        string          `json:"served_by,omitempty"`
+	ABTestProvider  string          `json:"ab_test_provider,omitempty"`
+	DebugInfo       []string        `json:"debug_info,omitempty"`
+	Truncated       bool            `json:"truncated,omitempty"`
+	RenderedPrompt  *RenderedPrompt `json:"rendered_prompt,omitempty"`
+	Alternatives    []CandidateSQL  `json:"alternatives,omitempty"`
 }
 
 // SQLCapabilities represents AI engine capabilities for SQL generation
@@ -127,6 +156,7 @@ func NewEngine(cfg config.AIConfig) (Engine, error) {
 func newEngineFromManager(manager *Manager, cfg config.AIConfig) (Engine, error) {
 	var aiClient interfaces.AIClient
 	var err error
+	clientName := cfg.DefaultService
 
 	if cfg.DefaultService != "" {
 		aiClient, err = manager.GetClient(cfg.DefaultService)
@@ -136,8 +166,8 @@ func newEngineFromManager(manager *Manager, cfg config.AIConfig) (Engine, error)
 		}
 	} else {
 		clients := manager.GetAllClients()
-		for _, client := range clients {
-			aiClient = client
+		for name, client := range clients {
+			aiClient, clientName = client, name
 			break
 		}
 		if aiClient == nil {
@@ -145,11 +175,16 @@ func newEngineFromManager(manager *Manager, cfg config.AIConfig) (Engine, error)
 		}
 	}
 
-	generator, err := NewSQLGenerator(aiClient, cfg)
+	// The generator goes through the manager so fallback, A/B selection, latency tracking and
+	// circuit breaking apply to every request without runtime provider settings
+	router := &managerClient{manager: manager}
+	generator, err := NewSQLGenerator(router, cfg)
 	if err != nil {
 		logging.Logger.Error("Failed to create SQL generator", "error", err, "provider", cfg.DefaultService)
 		return nil, fmt.Errorf("failed to create SQL generator for provider '%s': %w", cfg.DefaultService, err)
 	}
+	generator.setDefaultClient(clientName, router)
+	generator.setClientLookup(manager.GetClient)
 
 	logging.Logger.Info("AI engine created successfully", "provider", cfg.DefaultService)
 	return &aiEngine{
@@ -199,15 +234,63 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 		return nil, fmt.Errorf("SQL generator not initialized")
 	}
 
+	options := e.buildGenerateOptions(req.DatabaseType, req.Context, req.RuntimeAPIKey)
+	if req.SessionID != "" {
+		var err error
+		if options, err = e.generator.sessionOptions(req.SessionID, options); err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate SQL using the generator
+	var result *GenerationResult
+	var err error
+	if req.Template != "" {
+		result, err = e.generator.GenerateFromTemplate(ctx, req.Template, req.TemplateParams, options)
+	} else {
+		result, err = e.generator.Generate(ctx, req.NaturalLanguage, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SQL: %w", err)
+	}
+	if result.Blocked {
+		return nil, blockedGenerationError(result)
+	}
+
+	return newGenerateSQLResponse(result), nil
+}
+
+// RegenerateSQL implements Engine.RegenerateSQL by re-prompting with the previous SQL and feedback
+func (e *aiEngine) RegenerateSQL(ctx context.Context, req *RegenerateSQLRequest) (*GenerateSQLResponse, error) {
+	if e.generator == nil {
+		return nil, fmt.Errorf("SQL generator not initialized")
+	}
+
+	options := e.buildGenerateOptions(req.DatabaseType, req.Context, req.RuntimeAPIKey)
+	previous := &GenerationResult{SQL: req.PreviousSQL}
+
+	result, err := e.generator.Regenerate(ctx, previous, req.Feedback, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to regenerate SQL: %w", err)
+	}
+	if result.Blocked {
+		return nil, blockedGenerationError(result)
+	}
+
+	return newGenerateSQLResponse(result), nil
+}
+
+// buildGenerateOptions converts request fields into generator options
+func (e *aiEngine) buildGenerateOptions(databaseType string, requestContext map[string]string, runtimeAPIKey string) *GenerateOptions {
 	// Get default max tokens from configuration
 	defaultMaxTokens := 2000 // fallback if config not available
-	if service, ok := e.config.Services[e.config.DefaultService]; ok && service.MaxTokens > 0 {
+	if service, ok := e.config.Services[e.defaultProvider()]; ok && service.MaxTokens > 0 {
 		defaultMaxTokens = service.MaxTokens
 	}
 
 	// Convert request to generator options
 	options := &GenerateOptions{
-		DatabaseType:       req.DatabaseType,
+		DatabaseType:       databaseType,
 		ValidateSQL:        true,
 		OptimizeQuery:      false,
 		IncludeExplanation: true,
@@ -215,15 +298,15 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 		MaxTokens:          defaultMaxTokens,
 	}
 
-	if req.RuntimeAPIKey != "" {
-		options.APIKey = req.RuntimeAPIKey
+	if runtimeAPIKey != "" {
+		options.APIKey = runtimeAPIKey
 	}
 
 	// Add context if provided and extract preferred_model and runtime config
 	var runtimeConfig map[string]interface{}
-	if len(req.Context) > 0 {
-		options.Context = make([]string, 0, len(req.Context))
-		for key, value := range req.Context {
+	if len(requestContext) > 0 {
+		options.Context = make([]string, 0, len(requestContext))
+		for key, value := range requestContext {
 			switch key {
 			case "preferred_model":
 				// Set the preferred model directly in options
@@ -242,11 +325,7 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 
 				// Extract configuration for dynamic client creation
 				if provider, ok := runtimeConfig["provider"].(string); ok {
-					// Map "local" to "ollama" for consistency
-					if provider == "local" {
-						provider = "ollama"
-					}
-					options.Provider = provider
+					options.Provider = providers.Normalize(provider)
 				}
 				if apiKey, ok := runtimeConfig["api_key"].(string); ok && apiKey != "" {
 					if options.APIKey == "" {
@@ -258,6 +337,42 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 				if endpoint, ok := runtimeConfig["endpoint"].(string); ok && endpoint != "" {
 					options.Endpoint = endpoint
 				}
+				if autoContinue, ok := runtimeConfig["auto_continue"].(bool); ok {
+					options.AutoContinue = autoContinue
+				}
+				if acknowledge, ok := runtimeConfig["acknowledge_full_table_write"].(bool); ok {
+					options.AcknowledgeFullTableWrite = acknowledge
+				}
+				if allowed, ok := runtimeConfig["allowed_statements"].([]any); ok {
+					// An empty list is ignored rather than lifting the configured allowlist
+					for _, statementType := range allowed {
+						if name, ok := statementType.(string); ok && strings.TrimSpace(name) != "" {
+							options.AllowedStatements = append(options.AllowedStatements, name)
+						}
+					}
+				}
+				if includePrompt, ok := runtimeConfig["include_prompt"].(bool); ok {
+					options.IncludePrompt = includePrompt
+				}
+				if candidates, ok := runtimeConfig["candidates"].(float64); ok {
+					options.Candidates = int(candidates)
+				}
+				if dsn, ok := runtimeConfig["validate_against_dsn"].(string); ok {
+					options.ValidateAgainstDSN = dsn
+				}
+				if minConfidence, ok := runtimeConfig["min_confidence"].(float64); ok {
+					options.MinConfidence = minConfidence
+				}
+				if fallback, ok := runtimeConfig["confidence_fallback_provider"].(string); ok {
+					options.ConfidenceFallbackProvider = fallback
+				}
+				if version, ok := runtimeConfig["schema_version"].(string); ok {
+					options.SchemaVersion = version
+				}
+				if maxRetries, ok := runtimeConfig["max_retries"].(float64); ok {
+					retries := int(maxRetries)
+					options.MaxRetries = &retries
+				}
 				if maxTokens, ok := runtimeConfig["max_tokens"].(float64); ok {
 					options.MaxTokens = int(maxTokens)
 				} else if maxTokens, ok := runtimeConfig["max_tokens"].(int); ok {
@@ -275,13 +390,29 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 		}
 	}
 
-	// Generate SQL using the generator
-	result, err := e.generator.Generate(ctx, req.NaturalLanguage, options)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate SQL: %w", err)
+	return options
+}
+
+// blockedGenerationError explains why read-only mode or SafetyMode rejected a generated statement
+func blockedGenerationError(result *GenerationResult) error {
+	for _, validation := range result.ValidationResults {
+		if validation.Level != "error" {
+			continue
+		}
+		switch validation.Type {
+		case "read_only":
+			return fmt.Errorf("%w: %s. %s", ErrReadOnlyViolation, validation.Message, validation.Suggestion)
+		case "safety":
+			return fmt.Errorf("%w: %s. %s", ErrFullTableWrite, validation.Message, validation.Suggestion)
+		case "statement_type":
+			return fmt.Errorf("%w: %s. %s", ErrStatementTypeNotAllowed, validation.Message, validation.Suggestion)
+		}
 	}
+	return ErrFullTableWrite
+}
 
-	// Convert generator result to engine response
+// newGenerateSQLResponse converts a generator result to an engine response
+func newGenerateSQLResponse(result *GenerationResult) *GenerateSQLResponse {
 	return &GenerateSQLResponse{
 		SQL:             result.SQL,
 		Explanation:     result.Explanation,
@@ -289,8 +420,14 @@ func (e *aiEngine) GenerateSQL(ctx context.Context, req *GenerateSQLRequest) (*G
 		ProcessingTime:  result.Metadata.ProcessingTime,
 		RequestID:       result.Metadata.RequestID,
 		ModelUsed:       result.Metadata.ModelUsed,
+		CacheHit:        result.Metadata.CacheHit,
+		ServedBy:        result.Metadata.ServedBy,
+		ABTestProvider:  result.Metadata.ABTestProvider,
 		DebugInfo:       addDebugInfo(result.Metadata.DebugInfo, fmt.Sprintf("Query complexity: %s", result.Metadata.Complexity)),
-	}, nil
+		Truncated:       result.Truncated,
+		RenderedPrompt:  result.RenderedPrompt,
+		Alternatives:    result.Alternatives,
+	}
 }
 
 // GetCapabilities implements Engine.GetCapabilities for AI engine
@@ -300,7 +437,7 @@ func (e *aiEngine) GetCapabilities() *SQLCapabilities {
 	}
 	// Fallback to basic capabilities
 	return &SQLCapabilities{
-		SupportedDatabases: []string{"mysql", "postgresql", "sqlite"},
+		SupportedDatabases: []string{"mysql", "postgresql", "sqlite", "snowflake"},
 		Features: []SQLFeature{
 			{
 				Name:        "SQL Generation",
@@ -311,14 +448,57 @@ func (e *aiEngine) GetCapabilities() *SQLCapabilities {
 	}
 }
 
+// SetDefaultProvider implements Engine.SetDefaultProvider via the manager, which the generator routes through
+func (e *aiEngine) SetDefaultProvider(ctx context.Context, name string) error {
+	if e.manager == nil || e.generator == nil {
+		return fmt.Errorf("AI engine is not initialized")
+	}
+	if err := e.manager.SetDefaultProvider(ctx, name); err != nil {
+		return err
+	}
+	e.generator.setDefaultClient(name, &managerClient{manager: e.manager})
+	return nil
+}
+
+// OpenSession implements Engine.OpenSession
+func (e *aiEngine) OpenSession(schema map[string]Table) (string, error) {
+	if e.generator == nil {
+		return "", fmt.Errorf("SQL generator not initialized")
+	}
+	return e.generator.OpenSession(schema)
+}
+
+// CloseSession implements Engine.CloseSession
+func (e *aiEngine) CloseSession(id string) error {
+	if e.generator == nil {
+		return fmt.Errorf("SQL generator not initialized")
+	}
+	return e.generator.CloseSession(id)
+}
+
+// defaultProvider returns the manager's current default, falling back to the configured one
+func (e *aiEngine) defaultProvider() string {
+	if e.manager != nil {
+		if name := e.manager.DefaultProvider(); name != "" {
+			return name
+		}
+	}
+	return e.config.DefaultService
+}
+
 // IsHealthy implements Engine.IsHealthy for AI engine
 func (e *aiEngine) IsHealthy() bool {
-	if e.manager != nil && e.aiClient != nil {
+	client := e.aiClient
+	if e.generator != nil {
+		// Follows SetDefaultProvider
+		client = e.generator.defaultClient()
+	}
+	if e.manager != nil && client != nil {
 		// Check if primary client is healthy
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		healthStatus, err := e.aiClient.HealthCheck(ctx)
+		healthStatus, err := client.HealthCheck(ctx)
 		return err == nil && healthStatus != nil && healthStatus.Healthy
 	}
 	return false
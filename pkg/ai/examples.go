@@ -0,0 +1,207 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// exampleStopWords are common words that say nothing about which query a prompt needs
+var exampleStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "all": true, "with": true, "from": true,
+	"that": true, "are": true, "which": true, "show": true, "list": true, "get": true,
+	"find": true, "give": true, "return": true, "me": true, "please": true,
+}
+
+// exampleMemory is a size-bounded store of past generations reused as few-shot examples
+type exampleMemory struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	maxEntries    int
+	maxExamples   int
+	minConfidence float64
+	entries       []*storedExample
+	now           func() time.Time
+}
+
+type storedExample struct {
+	schemaKey string
+	prompt    string
+	sql       string
+	keywords  map[string]bool
+	expiresAt time.Time
+}
+
+// newExampleMemory creates an example store from configuration, or nil when disabled
+func newExampleMemory(cfg config.ExampleMemoryConfig) *exampleMemory {
+	if !cfg.Enabled {
+		return nil
+	}
+	memory := &exampleMemory{
+		ttl:           cfg.TTL.Duration,
+		maxEntries:    cfg.MaxEntries,
+		maxExamples:   cfg.MaxExamples,
+		minConfidence: cfg.MinConfidence,
+		now:           time.Now,
+	}
+	if memory.ttl <= 0 {
+		memory.ttl = constants.ExampleMemory.TTL
+	}
+	if memory.maxEntries <= 0 {
+		memory.maxEntries = constants.ExampleMemory.MaxEntries
+	}
+	if memory.maxExamples <= 0 {
+		memory.maxExamples = constants.ExampleMemory.MaxExamples
+	}
+	if memory.minConfidence <= 0 {
+		memory.minConfidence = constants.ExampleMemory.MinConfidence
+	}
+	return memory
+}
+
+// exampleSchemaKey identifies the database type and schema an example was generated against
+func exampleSchemaKey(options *GenerateOptions) string {
+	sum := sha256.Sum256([]byte(options.DatabaseType + "\n" + schemaText(options)))
+	return hex.EncodeToString(sum[:])
+}
+
+// promptKeywords returns the distinct meaningful words of a prompt
+func promptKeywords(prompt string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	keywords := make(map[string]bool, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 3 || exampleStopWords[word] {
+			continue
+		}
+		keywords[word] = true
+	}
+	return keywords
+}
+
+// remember stores a successful result, evicting the oldest example when full
+func (m *exampleMemory) remember(naturalLanguage string, options *GenerateOptions, result *GenerationResult) {
+	if result.Blocked || result.Truncated || result.NeedsConfirmation ||
+		strings.TrimSpace(result.SQL) == "" || result.ConfidenceScore < m.minConfidence {
+		return
+	}
+
+	example := &storedExample{
+		schemaKey: exampleSchemaKey(options),
+		prompt:    naturalLanguage,
+		sql:       result.SQL,
+		keywords:  promptKeywords(naturalLanguage),
+	}
+	if len(example.keywords) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	example.expiresAt = m.now().Add(m.ttl)
+	normalized := normalizePrompt(naturalLanguage)
+	for i, existing := range m.entries {
+		if existing.schemaKey == example.schemaKey && normalizePrompt(existing.prompt) == normalized {
+			m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			break
+		}
+	}
+	m.entries = append(m.entries, example)
+	if len(m.entries) > m.maxEntries {
+		m.entries = append([]*storedExample(nil), m.entries[len(m.entries)-m.maxEntries:]...)
+	}
+}
+
+// similar returns the stored examples for the same schema ranked by keyword overlap with the prompt
+func (m *exampleMemory) similar(naturalLanguage string, options *GenerateOptions) []*storedExample {
+	keywords := promptKeywords(naturalLanguage)
+	if len(keywords) == 0 {
+		return nil
+	}
+	schemaKey := exampleSchemaKey(options)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	live := m.entries[:0]
+	for _, example := range m.entries {
+		if !now.After(example.expiresAt) {
+			live = append(live, example)
+		}
+	}
+	m.entries = live
+
+	type scoredExample struct {
+		example *storedExample
+		score   float64
+	}
+	// Walk newest first so the stable sort lets recent examples win ties
+	var matches []scoredExample
+	for i := len(live) - 1; i >= 0; i-- {
+		example := live[i]
+		if example.schemaKey != schemaKey {
+			continue
+		}
+		if score := keywordOverlap(keywords, example.keywords); score > 0 {
+			matches = append(matches, scoredExample{example: example, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > m.maxExamples {
+		matches = matches[:m.maxExamples]
+	}
+	examples := make([]*storedExample, len(matches))
+	for i, match := range matches {
+		examples[i] = match.example
+	}
+	return examples
+}
+
+// len returns the number of stored examples
+func (m *exampleMemory) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// keywordOverlap is the Jaccard similarity of two keyword sets
+func keywordOverlap(a, b map[string]bool) float64 {
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
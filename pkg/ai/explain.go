@@ -0,0 +1,96 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// explainDrivers maps database types to the database/sql driver names they are commonly registered under.
+// The drivers themselves are not linked in; the embedding binary must import them.
+var explainDrivers = map[string]string{
+	"mysql":      "mysql",
+	"postgresql": "postgres",
+	"postgres":   "postgres",
+	"sqlite":     "sqlite",
+	"snowflake":  "snowflake",
+}
+
+// explainValidation runs EXPLAIN for query against dsn and reports database errors as "db" validation
+// results. The DSN must be allowlisted, only a single SELECT statement is explained, and it runs
+// inside a read-only, timeout-bounded transaction that is always rolled back.
+func (g *SQLGenerator) explainValidation(ctx context.Context, databaseType, dsn, query string) []ValidationResult {
+	cfg := g.currentConfig().DBValidation
+	if !contains(cfg.AllowedDSNs, dsn) {
+		return []ValidationResult{dbValidationResult("warning", "EXPLAIN validation skipped: the DSN is not listed in ai.db_validation.allowed_dsns", "")}
+	}
+
+	body, _ := splitTerminator(strings.TrimSpace(query))
+	if _, write := nonReadStatement(body); write || g.detectQueryType(body) != "SELECT" || strings.Contains(body, ";") {
+		return []ValidationResult{dbValidationResult("info", "EXPLAIN validation only runs for a single SELECT statement", "")}
+	}
+
+	driver := cfg.Drivers[databaseType]
+	if driver == "" {
+		driver = explainDrivers[databaseType]
+	}
+	if driver == "" {
+		return []ValidationResult{dbValidationResult("warning", fmt.Sprintf("EXPLAIN validation skipped: no driver configured for %s", databaseType), "")}
+	}
+
+	timeout := cfg.Timeout.Duration
+	if timeout <= 0 {
+		timeout = constants.DBValidation.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return []ValidationResult{dbValidationResult("warning", fmt.Sprintf("EXPLAIN validation skipped: %v", err), "")}
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return []ValidationResult{dbValidationResult("warning", fmt.Sprintf("EXPLAIN validation skipped: %v", err), "")}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, "EXPLAIN "+body)
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		_ = rows.Close()
+	}
+	if err != nil {
+		return []ValidationResult{dbValidationResult("error", err.Error(), "Check the table and column names against the database schema")}
+	}
+	return nil
+}
+
+// dbValidationResult builds a validation result reported by the database
+func dbValidationResult(level, message, suggestion string) ValidationResult {
+	return ValidationResult{Type: "db", Level: level, Message: message, Suggestion: suggestion}
+}
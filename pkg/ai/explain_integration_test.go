@@ -0,0 +1,52 @@
+//go:build integration
+
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestExplainValidationAgainstSQLite(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "explain.db")
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{
+		DBValidation: config.DBValidationConfig{AllowedDSNs: []string{dsn}},
+	})
+	require.NoError(t, err)
+
+	require.Empty(t, generator.explainValidation(context.Background(), "sqlite", dsn, "SELECT id, name FROM users;"))
+
+	results := generator.explainValidation(context.Background(), "sqlite", dsn, "SELECT email FROM users;")
+	require.Len(t, results, 1)
+	require.Equal(t, "db", results[0].Type)
+	require.Equal(t, "error", results[0].Level)
+	require.Contains(t, results[0].Message, "email")
+}
@@ -19,32 +19,106 @@ package ai
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/models"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/pii"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/templates"
 	"github.com/linuxsuren/atest-ext-ai/pkg/config"
 	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"github.com/linuxsuren/atest-ext-ai/pkg/sqlutil"
+	"github.com/linuxsuren/atest-ext-ai/pkg/storage"
+	"golang.org/x/sync/singleflight"
 )
 
 // SQLGenerator handles SQL generation from natural language
 type SQLGenerator struct {
 	aiClient       interfaces.AIClient
+	aiClientName   string
+	namedClient    func(name string) (interfaces.AIClient, error)
+	clientMu       sync.RWMutex
+	runtimeClients map[string]*runtimeClientEntry
+	runtimeMu      sync.RWMutex
+	templates      *templates.Registry
+
+	// configMu guards the configuration, capabilities and everything derived from the configuration
+	configMu       sync.RWMutex
 	sqlDialects    map[string]SQLDialect
 	config         config.AIConfig
 	capabilities   *SQLCapabilities
-	runtimeClients map[string]*runtimeClientEntry
-	runtimeMu      sync.RWMutex
+	cache          *resultCache
+	storage        storage.Backend
+	examples       *exampleMemory
+	piiDetector    *pii.Detector
+	postProcessors []PostProcessor
+
+	// sessions keeps schemas prepared by OpenSession
+	sessions *sessionStore
+	// hooks are registered by WithHooks and never change afterwards
+	hooks Hooks
+
+	pendingConfirmations map[string]*pendingConfirmation
+	confirmMu            sync.Mutex
+
+	// inflight coalesces identical concurrent generations into one upstream call
+	inflight singleflight.Group
+
+	closeOnce sync.Once
+}
+
+// pendingConfirmation is a write statement waiting for the caller to confirm intent
+type pendingConfirmation struct {
+	result    *GenerationResult
+	expiresAt time.Time
 }
 
+// confirmationTTL bounds how long an unconfirmed write statement is retained
+const confirmationTTL = 10 * time.Minute
+
+// ErrConfirmationNotFound is returned when a confirmation token is unknown or expired
+var ErrConfirmationNotFound = errors.New("confirmation token not found or expired")
+
+// ErrFullTableWrite is returned when SafetyMode blocks an UPDATE or DELETE without a WHERE clause
+var ErrFullTableWrite = errors.New("full-table write blocked by safety mode")
+
+// ErrEmptyQuery is returned when the natural language query is empty or only whitespace
+var ErrEmptyQuery = errors.New("natural language query cannot be empty")
+
+// ErrQueryTooLong is returned when the natural language query exceeds ai.limits.max_prompt_bytes
+var ErrQueryTooLong = errors.New("natural language query is too long")
+
+// ErrGeneratedSQLTooLarge is returned when the extracted SQL exceeds ai.max_sql_bytes
+var ErrGeneratedSQLTooLarge = errors.New("generated SQL is too large")
+
+// ErrInvalidResponseEncoding is returned when a provider response is binary rather than UTF-8 text
+var ErrInvalidResponseEncoding = errors.New("AI response is not valid UTF-8 text")
+
+// ErrReadOnlyViolation is returned when read-only mode blocks a statement other than a query
+var ErrReadOnlyViolation = errors.New("statement blocked by read-only mode")
+
+// ErrStatementTypeNotAllowed is returned when the statement type allowlist or denylist blocks a statement
+var ErrStatementTypeNotAllowed = errors.New("statement type not allowed")
+
+// ErrModelNotAllowed is returned when a request asks for a model outside the service's models allowlist
+var ErrModelNotAllowed = errors.New("model not allowed")
+
 type runtimeClientEntry struct {
 	client            interfaces.AIClient
 	apiKeyFingerprint []byte
@@ -58,6 +132,8 @@ type Table struct {
 	ForeignKeys []ForeignKey      `json:"foreign_keys,omitempty"`
 	Indexes     []Index           `json:"indexes,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Importance ranks tables kept when the schema is trimmed to SchemaTokenBudget; higher is kept first
+	Importance int `json:"importance,omitempty"`
 }
 
 // Column represents a table column
@@ -105,6 +181,44 @@ type GenerateOptions struct {
 	IncludeExplanation bool              `json:"include_explanation"`
 	SafetyMode         bool              `json:"safety_mode"`
 	CustomPrompts      map[string]string `json:"custom_prompts,omitempty"`
+	AutoContinue       bool              `json:"auto_continue,omitempty"` // Re-prompt when the response hits the token limit
+	// RequireConfirmForWrites holds write statements until Confirm is called with the returned token
+	RequireConfirmForWrites bool `json:"require_confirm_for_writes,omitempty"`
+	// AcknowledgeFullTableWrite lets SafetyMode pass UPDATE/DELETE statements without a WHERE clause
+	AcknowledgeFullTableWrite bool `json:"acknowledge_full_table_write,omitempty"`
+	// AllowedStatements narrows ai.allowed_statement_types for this request; types outside the configured
+	// list stay blocked, an empty list is ignored and ai.denied_statement_types still applies
+	AllowedStatements []string `json:"allowed_statements,omitempty"`
+	// MaxRetries overrides the manager's retry count; 0 makes a single attempt and nil keeps the default
+	MaxRetries *int `json:"max_retries,omitempty"`
+	// SchemaTokenBudget trims the schema to roughly this many tokens, keeping tables named in the
+	// query first and then the most important ones; 0 sends the whole schema
+	SchemaTokenBudget int `json:"schema_token_budget,omitempty"`
+	// IncludePrompt returns the rendered prompt and system prompt in the result for debugging
+	IncludePrompt bool `json:"include_prompt,omitempty"`
+	// Candidates returns up to this many alternative queries (capped at maxCandidates); 0 and 1 return one
+	Candidates int `json:"candidates,omitempty"`
+	// Seed fixes sampling on providers that support it; required by GenerateReproducible
+	Seed *int64 `json:"seed,omitempty"`
+	// Timeout bounds this generation and takes precedence over the service and ai.timeout defaults
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// ValidateAgainstDSN runs EXPLAIN for the generated query and each kept candidate against this read-only database;
+	// it must be listed in ai.db_validation.allowed_dsns
+	ValidateAgainstDSN string `json:"validate_against_dsn,omitempty"`
+	// MinConfidence regenerates results scoring below it, up to maxConfidenceRetries more times,
+	// and returns the best one; 0 disables the retry
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	// ConfidenceFallbackProvider names the configured service that answers confidence retries;
+	// empty retries with the same provider
+	ConfidenceFallbackProvider string `json:"confidence_fallback_provider,omitempty"`
+	// SchemaVersion is part of the cache key, so bumping it after a migration invalidates
+	// results cached for the old schema even when Schema itself is unchanged
+	SchemaVersion string `json:"schema_version,omitempty"`
+
+	// client answers the request in place of the default client; set for confidence fallbacks
+	client interfaces.AIClient
+	// renderedSchema is Schema already rendered for the prompt; set for session generations
+	renderedSchema string
 }
 
 // GenerationResult contains the complete result of SQL generation
@@ -116,6 +230,39 @@ type GenerationResult struct {
 	Suggestions       []string           `json:"suggestions"`
 	Metadata          GenerationMetadata `json:"metadata"`
 	ValidationResults []ValidationResult `json:"validation_results,omitempty"`
+	Truncated         bool               `json:"truncated,omitempty"`
+	NeedsConfirmation bool               `json:"needs_confirmation,omitempty"`
+	// Blocked is set when SafetyMode rejected the statement; see ValidationResults for the reason
+	Blocked           bool   `json:"blocked,omitempty"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+	// RenderedPrompt is only set when GenerateOptions.IncludePrompt is requested
+	RenderedPrompt *RenderedPrompt `json:"rendered_prompt,omitempty"`
+	// Alternatives holds the other candidates when GenerateOptions.Candidates is above one
+	Alternatives []CandidateSQL `json:"alternatives,omitempty"`
+
+	// statement is SQL before ai.sql_prefix and ai.sql_suffix were added
+	statement string
+}
+
+// CandidateSQL is an alternative query, validated independently of the primary one. Blocked
+// candidates are never returned, and write candidates are held like the primary statement.
+type CandidateSQL struct {
+	SQL               string             `json:"sql"`
+	Explanation       string             `json:"explanation"`
+	ConfidenceScore   float64            `json:"confidence_score"`
+	Warnings          []string           `json:"warnings,omitempty"`
+	ValidationResults []ValidationResult `json:"validation_results,omitempty"`
+	NeedsConfirmation bool               `json:"needs_confirmation,omitempty"`
+	ConfirmationToken string             `json:"confirmation_token,omitempty"`
+}
+
+// maxCandidates bounds GenerateOptions.Candidates
+const maxCandidates = 5
+
+// RenderedPrompt is the request exactly as sent to the model, with secrets redacted
+type RenderedPrompt struct {
+	SystemPrompt string `json:"system_prompt"`
+	Prompt       string `json:"prompt"`
 }
 
 // GenerationMetadata contains metadata about the generation process
@@ -128,8 +275,17 @@ type GenerationMetadata struct {
 	TablesInvolved  []string      `json:"tables_involved,omitempty"`
 	Complexity      string        `json:"complexity"`
 	DebugInfo       []string      `json:"debug_info,omitempty"`
+	// CacheHit reports that the result came from the result cache without consulting a provider
+	CacheHit bool `json:"cache_hit"`
+	// ServedBy is ServedByCache for cache hits, otherwise the name of the provider that generated the result
+	ServedBy string `json:"served_by,omitempty"`
+	// ABTestProvider is the provider picked by A/B routing, empty when A/B testing is off
+	ABTestProvider string `json:"ab_test_provider,omitempty"`
 }
 
+// ServedByCache is the GenerationMetadata.ServedBy value of results served from the result cache
+const ServedByCache = "cache"
+
 // ValidationResult contains SQL validation information
 type ValidationResult struct {
 	Type       string `json:"type"`
@@ -141,24 +297,38 @@ type ValidationResult struct {
 }
 
 // NewSQLGenerator creates a new SQL generator instance
-func NewSQLGenerator(aiClient interfaces.AIClient, config config.AIConfig) (*SQLGenerator, error) {
+func NewSQLGenerator(aiClient interfaces.AIClient, config config.AIConfig, opts ...GeneratorOption) (*SQLGenerator, error) {
 	if aiClient == nil {
 		return nil, fmt.Errorf("AI client cannot be nil")
 	}
 
 	generator := &SQLGenerator{
 		aiClient:       aiClient,
-		config:         config,
-		sqlDialects:    make(map[string]SQLDialect),
 		runtimeClients: make(map[string]*runtimeClientEntry),
+		sessions:       newSessionStore(),
+	}
+	for _, opt := range opts {
+		opt(generator)
+	}
+	if err := generator.UpdateConfig(config); err != nil {
+		return nil, err
 	}
 
-	// Initialize SQL dialects
-	generator.initializeDialects()
+	// Load query templates; a broken templates directory only disables templates
+	generator.templates = templates.NewRegistry()
+	if config.TemplatesDir != "" {
+		registry, err := templates.LoadDir(config.TemplatesDir)
+		if err != nil {
+			logging.Logger.Warn("Failed to load query templates", "dir", config.TemplatesDir, "error", err)
+		} else {
+			generator.templates = registry
+			logging.Logger.Info("Query templates loaded", "dir", config.TemplatesDir, "count", len(registry.List()))
+		}
+	}
 
 	// Initialize capabilities
 	generator.capabilities = &SQLCapabilities{
-		SupportedDatabases: []string{"mysql", "postgresql", "sqlite"},
+		SupportedDatabases: []string{"mysql", "postgresql", "sqlite", "snowflake"},
 		Features: []SQLFeature{
 			{
 				Name:        "Natural Language to SQL",
@@ -168,7 +338,7 @@ func NewSQLGenerator(aiClient interfaces.AIClient, config config.AIConfig) (*SQL
 			{
 				Name:        "Multi-dialect Support",
 				Enabled:     true,
-				Description: "Support for MySQL, PostgreSQL, and SQLite",
+				Description: "Support for MySQL, PostgreSQL, SQLite, and Snowflake",
 			},
 			{
 				Name:        "Schema-aware Generation",
@@ -191,34 +361,359 @@ func NewSQLGenerator(aiClient interfaces.AIClient, config config.AIConfig) (*SQL
 	return generator, nil
 }
 
+// UpdateConfig swaps the generator configuration and rebuilds the dialects, PII detector,
+// post-processors, result cache and example memory derived from it. An invalid configuration
+// is rejected and leaves the current one in place. Query templates are only loaded once.
+func (g *SQLGenerator) UpdateConfig(cfg config.AIConfig) error {
+	var detector *pii.Detector
+	if cfg.PII.Mask {
+		rules := make([]pii.Rule, 0, len(cfg.PII.Rules))
+		for _, rule := range cfg.PII.Rules {
+			rules = append(rules, pii.Rule{Name: rule.Name, Pattern: rule.Pattern})
+		}
+		var err error
+		detector, err = pii.NewDetector(rules)
+		if err != nil {
+			return fmt.Errorf("invalid PII rules: %w", err)
+		}
+	}
+
+	postProcessors, err := newPostProcessors(cfg.PostProcess)
+	if err != nil {
+		return fmt.Errorf("invalid post_process configuration: %w", err)
+	}
+
+	backend, err := storage.New(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("invalid storage configuration: %w", err)
+	}
+
+	dialects := builtinDialects()
+	for name, dialectConfig := range cfg.CustomDialects {
+		dialect, err := NewConfigDialect(name, dialectConfig)
+		if err != nil {
+			return fmt.Errorf("invalid custom dialect %q: %w", name, err)
+		}
+		dialects[strings.ToLower(strings.TrimSpace(name))] = dialect
+	}
+
+	g.configMu.Lock()
+	defer g.configMu.Unlock()
+	g.config = cfg
+	g.sqlDialects = dialects
+	g.piiDetector = detector
+	g.postProcessors = postProcessors
+	// Cached results and examples may have been produced under settings that no longer apply
+	g.storage = backend
+	g.cache = newResultCache(cfg.Cache, backend, cacheNamespace(cfg))
+	g.examples = newExampleMemory(cfg.ExampleMemory)
+	return nil
+}
+
+// currentConfig returns the configuration in effect for the current request
+func (g *SQLGenerator) currentConfig() config.AIConfig {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	return g.config
+}
+
+// lookupDialect returns the dialect registered for databaseType
+func (g *SQLGenerator) lookupDialect(databaseType string) (SQLDialect, bool) {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	dialect, exists := g.sqlDialects[databaseType]
+	if !exists {
+		dialect, exists = g.sqlDialects[providers.Normalize(databaseType)]
+	}
+	return dialect, exists
+}
+
+// memories returns the result cache and example memory for the current configuration
+func (g *SQLGenerator) memories() (*resultCache, *exampleMemory) {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
+	return g.cache, g.examples
+}
+
 // Generate generates SQL from natural language input
 func (g *SQLGenerator) Generate(ctx context.Context, naturalLanguage string, options *GenerateOptions) (*GenerationResult, error) {
 	start := time.Now()
 	requestID := fmt.Sprintf("sql_%d", start.UnixNano())
 
-	if naturalLanguage == "" {
-		return nil, fmt.Errorf("natural language query cannot be empty")
+	naturalLanguage, err := NormalizeNaturalLanguage(naturalLanguage, g.currentConfig().Limits.MaxPromptBytes)
+	if err != nil {
+		return nil, err
 	}
 
 	if options == nil {
-		options = &GenerateOptions{
-			DatabaseType:       "mysql",
-			ValidateSQL:        true,
-			OptimizeQuery:      false,
-			IncludeExplanation: true,
-			SafetyMode:         true,
-			MaxTokens:          2000,
-		}
+		options = defaultGenerateOptions()
+	}
+	options = g.enforceReadOnly(options)
+	options = g.resolveModelOptions(options)
+	if err := g.checkModelAllowed(options); err != nil {
+		return nil, err
 	}
+	options = trimSchemaOptions(naturalLanguage, options)
+
+	ctx, cancel := g.generationContext(ctx, options)
+	defer cancel()
 
 	// Get SQL dialect
-	dialect, exists := g.sqlDialects[options.DatabaseType]
+	dialect, exists := g.lookupDialect(options.DatabaseType)
+	if !exists {
+		return nil, NewUnsupportedDialectError(options.DatabaseType)
+	}
+
+	// Prompt debugging skips cached results, which never carry the rendered prompt
+	cache, examples := g.memories()
+	var cacheKey string
+	if cache != nil {
+		cacheKey = cache.key(naturalLanguage, g.servedBy(options), options)
+		if cached, ok := cache.get(cacheKey); ok && !options.IncludePrompt {
+			cached.Metadata.CacheHit = true
+			cached.Metadata.ServedBy = ServedByCache
+			cached.Metadata.ABTestProvider = ""
+			cached.Metadata.DebugInfo = append(cached.Metadata.DebugInfo, "served from cache")
+			return cached, nil
+		}
+	}
+
+	generate := func() (any, error) {
+		// Prepare the prompt for AI; the raw query is used even when the cache key is normalized
+		prompt := g.buildPrompt(naturalLanguage, options, dialect)
+
+		result, cacheable, err := g.generateWithHooks(ctx, prompt, options, requestID, func(prompt string, options *GenerateOptions) (*GenerationResult, error) {
+			return g.generateWithMinConfidence(ctx, prompt, options, dialect, requestID, start)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !cacheable {
+			return result, nil
+		}
+		// Truncated, confirmation-gated and blocked results are not reusable
+		if cache != nil && !result.Truncated && !result.NeedsConfirmation && !result.Blocked {
+			cacheable := cloneGenerationResult(result)
+			cacheable.RenderedPrompt = nil
+			cache.put(cacheKey, cacheable)
+		}
+		if examples != nil {
+			examples.remember(naturalLanguage, options, result)
+		}
+		return result, nil
+	}
+
+	// Identical concurrent requests share one upstream call and each receive their own copy;
+	// a prompt-debugging request never shares a call whose result has the prompt stripped
+	flightKey := cacheKey
+	if flightKey == "" {
+		flightKey = generationKey(naturalLanguage, g.servedBy(options), options)
+	}
+	if options.IncludePrompt {
+		flightKey += "/prompt"
+	}
+	// A caller whose context ends stops waiting; the shared call carries on for the others
+	var flight singleflight.Result
+	select {
+	case flight = <-g.inflight.DoChan(flightKey, generate):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	value, err, shared := flight.Val, flight.Err, flight.Shared
+	if shared && err != nil && isContextError(err) && ctx.Err() == nil {
+		// The caller that led the shared call gave up; this caller is still waiting
+		value, err = generate()
+		shared = false
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := value.(*GenerationResult)
+	if shared {
+		result = cloneGenerationResult(result)
+		if err := g.reissueConfirmations(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// isContextError reports whether err was caused by a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// generationContext applies the generation deadline. GenerateOptions.Timeout always applies;
+// otherwise a context without a deadline gets the selected service's timeout, then ai.timeout.
+// Reasoning models without a service timeout get at least constants.Timeouts.Reasoning.
+func (g *SQLGenerator) generationContext(ctx context.Context, options *GenerateOptions) (context.Context, context.CancelFunc) {
+	if options.Timeout > 0 {
+		return context.WithTimeout(ctx, options.Timeout)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	cfg := g.currentConfig()
+	service := options.Provider
+	if service == "" {
+		service = cfg.DefaultService
+	}
+	timeout := cfg.Services[service].Timeout.Value()
+	if timeout <= 0 {
+		timeout = cfg.Timeout.Value()
+		model := options.Model
+		if model == "" {
+			model = cfg.Services[service].Model
+		}
+		if timeout > 0 && universal.IsReasoningModel(model) {
+			timeout = max(timeout, constants.Timeouts.Reasoning)
+		}
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// GenerateFromTemplate fills the named query template with params and generates SQL from it
+func (g *SQLGenerator) GenerateFromTemplate(ctx context.Context, templateName string, params map[string]string, options *GenerateOptions) (*GenerationResult, error) {
+	tmpl, err := g.templates.Resolve(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	naturalLanguage, err := tmpl.Render(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Generate(ctx, naturalLanguage, options)
+}
+
+// defaultClient returns the client used when a request does not carry runtime provider settings
+func (g *SQLGenerator) defaultClient() interfaces.AIClient {
+	g.clientMu.RLock()
+	defer g.clientMu.RUnlock()
+	return g.aiClient
+}
+
+// setDefaultClient replaces the client used for requests without runtime provider settings
+func (g *SQLGenerator) setDefaultClient(name string, client interfaces.AIClient) {
+	g.clientMu.Lock()
+	defer g.clientMu.Unlock()
+	g.aiClient = client
+	g.aiClientName = name
+}
+
+// setClientLookup lets confidence retries fall back to other configured services
+func (g *SQLGenerator) setClientLookup(lookup func(name string) (interfaces.AIClient, error)) {
+	g.clientMu.Lock()
+	defer g.clientMu.Unlock()
+	g.namedClient = lookup
+}
+
+// servedBy names the provider that answers a request: the runtime or fallback provider when the
+// request carries one, otherwise the default provider
+func (g *SQLGenerator) servedBy(options *GenerateOptions) string {
+	if options.Provider != "" && (options.APIKey != "" || options.client != nil) {
+		return providers.Normalize(options.Provider)
+	}
+	g.clientMu.RLock()
+	name := g.aiClientName
+	g.clientMu.RUnlock()
+	if name == "" {
+		name = g.currentConfig().DefaultService
+	}
+	return providers.Normalize(name)
+}
+
+// Templates returns the registry of query templates available to the generator
+func (g *SQLGenerator) Templates() *templates.Registry {
+	return g.templates
+}
+
+// Regenerate asks the AI to correct a previously generated query using user feedback
+func (g *SQLGenerator) Regenerate(ctx context.Context, previous *GenerationResult, feedback string, options *GenerateOptions) (*GenerationResult, error) {
+	start := time.Now()
+	requestID := fmt.Sprintf("sql_%d", start.UnixNano())
+
+	if previous == nil || strings.TrimSpace(previous.SQL) == "" {
+		return nil, fmt.Errorf("previous SQL cannot be empty")
+	}
+	if strings.TrimSpace(feedback) == "" {
+		return nil, fmt.Errorf("feedback cannot be empty")
+	}
+
+	if options == nil {
+		options = defaultGenerateOptions()
+	}
+	options = g.enforceReadOnly(options)
+	options = g.resolveModelOptions(options)
+	if err := g.checkModelAllowed(options); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := g.generationContext(ctx, options)
+	defer cancel()
+
+	dialect, exists := g.lookupDialect(options.DatabaseType)
 	if !exists {
-		return nil, fmt.Errorf("unsupported database type: %s", options.DatabaseType)
+		return nil, NewUnsupportedDialectError(options.DatabaseType)
+	}
+
+	previousSQL := previous.SQL
+	cfg := g.currentConfig()
+	if affix := newAffixProcessor(cfg.SQLPrefix, cfg.SQLSuffix); affix != nil {
+		// The model corrects the generated statement; the prefix and suffix are added again afterwards
+		previousSQL = affix.unwrap(previousSQL)
 	}
+	prompt := g.buildPrompt(buildRegenerationRequest(previousSQL, feedback), options, dialect)
 
-	// Prepare the prompt for AI
-	prompt := g.buildPrompt(naturalLanguage, options, dialect)
+	result, _, err := g.generateWithHooks(ctx, prompt, options, requestID, func(prompt string, options *GenerateOptions) (*GenerationResult, error) {
+		return g.generateFromPrompt(ctx, prompt, options, dialect, requestID, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	debugInfo := "regeneration with user feedback"
+	if previous.Metadata.RequestID != "" {
+		debugInfo = fmt.Sprintf("regeneration of %s with user feedback", previous.Metadata.RequestID)
+	}
+	result.Metadata.DebugInfo = append(result.Metadata.DebugInfo, debugInfo)
+	return result, nil
+}
+
+// buildRegenerationRequest describes the correction task so it can be embedded in the regular prompt
+func buildRegenerationRequest(previousSQL, feedback string) string {
+	var builder strings.Builder
+	builder.WriteString("The following SQL query was generated previously but is not correct:\n")
+	builder.WriteString(strings.TrimSpace(previousSQL))
+	builder.WriteString("\n\nUser feedback:\n")
+	builder.WriteString(strings.TrimSpace(feedback))
+	builder.WriteString("\n\nGenerate a corrected SQL query that addresses the feedback.")
+	return builder.String()
+}
+
+// defaultGenerateOptions returns the options used when the caller provides none
+func defaultGenerateOptions() *GenerateOptions {
+	return &GenerateOptions{
+		DatabaseType:       "mysql",
+		ValidateSQL:        true,
+		OptimizeQuery:      false,
+		IncludeExplanation: true,
+		SafetyMode:         true,
+		MaxTokens:          2000,
+	}
+}
+
+// generateFromPrompt sends a prepared prompt to the selected AI client and parses the response
+func (g *SQLGenerator) generateFromPrompt(ctx context.Context, prompt string, options *GenerateOptions, dialect SQLDialect, requestID string, start time.Time) (*GenerationResult, error) {
+	// Confidence retries may be answered by a fallback service with its own allowlist
+	if err := g.checkModelAllowed(options); err != nil {
+		return nil, err
+	}
 
 	// Create AI request
 	aiRequest := &interfaces.GenerateRequest{
@@ -226,71 +721,520 @@ func (g *SQLGenerator) Generate(ctx context.Context, naturalLanguage string, opt
 		Model:        options.Model,
 		MaxTokens:    options.MaxTokens,
 		SystemPrompt: g.getSystemPrompt(options.DatabaseType),
+		MaxRetries:   options.MaxRetries,
+		Candidates:   clampCandidates(options.Candidates),
+		Seed:         options.Seed,
+	}
+	if g.currentConfig().PromptCache.Enabled {
+		// The system prompt plus schema is the stable prefix shared by repeated requests
+		if schema := schemaText(options); schema != "" {
+			aiRequest.SystemPrompt += "\n\n" + strings.TrimSpace(schema)
+		}
+		aiRequest.CacheSystemPrompt = true
 	}
 
-	// Select AI client - use runtime client if provider/API key specified, otherwise use default
-	aiClient := g.aiClient
+	aiClient, err := g.clientFor(options)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if we need to create a runtime client with API key
-	if options.Provider != "" && options.APIKey != "" {
-		logging.Logger.Debug("Attempting to use runtime AI client",
+	// Call AI service
+	aiResponse, err := aiClient.Generate(ctx, aiRequest)
+	if err != nil {
+		return nil, fmt.Errorf("AI generation failed: %w", err)
+	}
+
+	truncated := isTruncatedResponse(aiResponse)
+	continuations := 0
+	for truncated && options.AutoContinue && continuations < maxAutoContinuations {
+		continuation, err := aiClient.Generate(ctx, buildContinuationRequest(aiRequest, aiResponse.Text))
+		if err != nil {
+			logging.Logger.Warn("Failed to continue truncated AI response", "error", err)
+			break
+		}
+		continuations++
+		aiResponse.Text += continuation.Text
+		truncated = isTruncatedResponse(continuation)
+	}
+
+	text, err := sanitizeResponseText(aiResponse.Text)
+	if err != nil {
+		return nil, err
+	}
+	aiResponse.Text = text
+
+	// Parse and validate the response
+	result := g.parseAIResponse(aiResponse, options, dialect, requestID, start)
+	if aiRequest.Candidates > 1 {
+		var primary bool
+		result, primary = g.selectCandidate(ctx, aiClient, aiRequest, aiResponse, result, options, dialect, requestID, start)
+		truncated = truncated && primary
+	}
+	if maxBytes := g.currentConfig().MaxSQLBytes; exceedsMaxSQLBytes(result, maxBytes) {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrGeneratedSQLTooLarge, len(result.SQL), maxBytes)
+	}
+	result.Metadata.ServedBy = g.servedBy(options)
+	if name, ok := aiResponse.Metadata[MetadataServedBy].(string); ok && name != "" && options.client == nil {
+		// The manager may have answered from a fallback or latency-preferred client
+		result.Metadata.ServedBy = providers.Normalize(name)
+	}
+	if name, ok := aiResponse.Metadata[MetadataABTestProvider].(string); ok && options.client == nil {
+		result.Metadata.ABTestProvider = name
+	}
+	result.Truncated = truncated
+	if truncated {
+		result.Warnings = append(result.Warnings, "AI response was truncated at the token limit; the SQL may be incomplete")
+	}
+	if continuations > 0 {
+		result.Metadata.DebugInfo = append(result.Metadata.DebugInfo,
+			fmt.Sprintf("response continued %d time(s) after truncation", continuations))
+	}
+
+	if options.IncludePrompt {
+		result.RenderedPrompt = g.renderPrompt(aiRequest, options)
+	}
+	if options.ValidateAgainstDSN != "" && !result.Blocked {
+		result.ValidationResults = append(result.ValidationResults,
+			g.explainValidation(ctx, options.DatabaseType, options.ValidateAgainstDSN, result.statement)...)
+	}
+
+	g.maskExplanation(result)
+	g.auditGeneration(result)
+
+	if kind, write := writeStatementKind(result.SQL); options.RequireConfirmForWrites && write {
+		if err := g.holdForConfirmation(result, kind); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// clientFor selects the AI client for options: the fallback client of a confidence retry, a runtime
+// client when a provider and API key are given, otherwise the default client
+func (g *SQLGenerator) clientFor(options *GenerateOptions) (interfaces.AIClient, error) {
+	if options.client != nil {
+		return options.client, nil
+	}
+	if options.Provider == "" || options.APIKey == "" {
+		return g.defaultClient(), nil
+	}
+	if err := validateRuntimeProvider(options); err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Debug("Attempting to use runtime AI client",
+		"provider", options.Provider,
+		"has_api_key", options.APIKey != "",
+		"endpoint", options.Endpoint)
+
+	runtimeClient, reused, err := g.getOrCreateRuntimeClient(options)
+	if err != nil {
+		logging.Logger.Error("Failed to prepare runtime client",
+			"provider", options.Provider,
+			"error", err)
+		return nil, fmt.Errorf("runtime client creation failed for provider %s: %w",
+			options.Provider, err)
+	}
+
+	if reused {
+		logging.Logger.Debug("Reusing cached runtime AI client",
 			"provider", options.Provider,
-			"has_api_key", options.APIKey != "",
 			"endpoint", options.Endpoint)
+	} else {
+		logging.Logger.Info("Runtime AI client created and cached",
+			"provider", options.Provider,
+			"endpoint", options.Endpoint)
+	}
+	return runtimeClient, nil
+}
 
-		runtimeClient, reused, err := g.getOrCreateRuntimeClient(options)
+// clampCandidates bounds the requested number of candidates to 1..maxCandidates
+func clampCandidates(candidates int) int {
+	return min(max(candidates, 1), maxCandidates)
+}
+
+// selectCandidate parses every candidate, requesting one more completion at a time when the
+// provider returned fewer than asked for, and returns the best one with the rest as alternatives.
+// Candidates within ai.max_sql_bytes win over oversized ones, unblocked candidates over blocked
+// ones, then the higher confidence wins. The flag reports whether the first response was selected.
+func (g *SQLGenerator) selectCandidate(ctx context.Context, aiClient interfaces.AIClient, aiRequest *interfaces.GenerateRequest, aiResponse *interfaces.GenerateResponse, first *GenerationResult, options *GenerateOptions, dialect SQLDialect, requestID string, start time.Time) (*GenerationResult, bool) {
+	texts := aiResponse.Alternatives
+	single := *aiRequest
+	single.Candidates = 0
+	for len(texts)+1 < aiRequest.Candidates {
+		extra, err := aiClient.Generate(ctx, &single)
 		if err != nil {
-			logging.Logger.Error("Failed to prepare runtime client",
-				"provider", options.Provider,
-				"error", err)
-			return nil, fmt.Errorf("runtime client creation failed for provider %s: %w",
-				options.Provider, err)
+			logging.Logger.Warn("Failed to generate additional SQL candidate", "error", err)
+			break
 		}
+		texts = append(texts, extra.Text)
+	}
 
-		aiClient = runtimeClient
-		if reused {
-			logging.Logger.Debug("Reusing cached runtime AI client",
-				"provider", options.Provider,
-				"endpoint", options.Endpoint)
-		} else {
-			logging.Logger.Info("Runtime AI client created and cached",
-				"provider", options.Provider,
-				"endpoint", options.Endpoint)
+	results := []*GenerationResult{first}
+	for _, text := range texts {
+		text, err := sanitizeResponseText(text)
+		if err != nil {
+			continue
 		}
+		candidate := &interfaces.GenerateResponse{Text: text, Model: aiResponse.Model}
+		results = append(results, g.parseAIResponse(candidate, options, dialect, requestID, start))
 	}
 
-	// Call AI service
-	aiResponse, err := aiClient.Generate(ctx, aiRequest)
+	maxBytes := g.currentConfig().MaxSQLBytes
+	best := 0
+	for i, result := range results {
+		if betterCandidate(result, results[best], maxBytes) {
+			best = i
+		}
+	}
+	selected := results[best]
+	dropped, oversized := 0, 0
+	for i, result := range results {
+		if i == best {
+			continue
+		}
+		if exceedsMaxSQLBytes(result, maxBytes) {
+			oversized++
+			continue
+		}
+		// Statements rejected by safety checks must not reach the caller as alternatives either
+		if result.Blocked {
+			dropped++
+			continue
+		}
+		if options.ValidateAgainstDSN != "" {
+			result.ValidationResults = append(result.ValidationResults,
+				g.explainValidation(ctx, options.DatabaseType, options.ValidateAgainstDSN, result.statement)...)
+		}
+		g.maskExplanation(result)
+		if kind, write := writeStatementKind(result.SQL); options.RequireConfirmForWrites && write {
+			if err := g.holdForConfirmation(result, kind); err != nil {
+				logging.Logger.Warn("Dropping SQL candidate that could not be held for confirmation", "error", err)
+				dropped++
+				continue
+			}
+		}
+		selected.Alternatives = append(selected.Alternatives, CandidateSQL{
+			SQL:               result.SQL,
+			Explanation:       result.Explanation,
+			ConfidenceScore:   result.ConfidenceScore,
+			Warnings:          result.Warnings,
+			ValidationResults: result.ValidationResults,
+			NeedsConfirmation: result.NeedsConfirmation,
+			ConfirmationToken: result.ConfirmationToken,
+		})
+	}
+	if dropped > 0 {
+		selected.Metadata.DebugInfo = append(selected.Metadata.DebugInfo, fmt.Sprintf("%d blocked candidate(s) dropped", dropped))
+	}
+	if oversized > 0 {
+		selected.Metadata.DebugInfo = append(selected.Metadata.DebugInfo, fmt.Sprintf("%d oversized candidate(s) dropped", oversized))
+	}
+	return selected, best == 0
+}
+
+// betterCandidate reports whether candidate should replace current as the primary result
+func betterCandidate(candidate, current *GenerationResult, maxBytes int) bool {
+	if tooLarge := exceedsMaxSQLBytes(candidate, maxBytes); tooLarge != exceedsMaxSQLBytes(current, maxBytes) {
+		return !tooLarge
+	}
+	if candidate.Blocked != current.Blocked {
+		return !candidate.Blocked
+	}
+	return candidate.ConfidenceScore > current.ConfidenceScore
+}
+
+// exceedsMaxSQLBytes reports whether result's SQL is over the ai.max_sql_bytes limit, when set
+func exceedsMaxSQLBytes(result *GenerationResult, maxBytes int) bool {
+	return maxBytes > 0 && len(result.SQL) > maxBytes
+}
+
+// secretPatterns match credentials that may be pasted into prompts, context or custom prompts
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/\-]+=*`),
+	regexp.MustCompile(`(?i)\b(api[_-]?key|access[_-]?token|secret|password)(\s*[:=]\s*)[^\s,;]+`),
+}
+
+// renderPrompt captures the prompts sent for aiRequest with known and pattern-matched secrets redacted
+func (g *SQLGenerator) renderPrompt(aiRequest *interfaces.GenerateRequest, options *GenerateOptions) *RenderedPrompt {
+	secrets := g.knownSecrets(options)
+	return &RenderedPrompt{
+		SystemPrompt: redactSecrets(aiRequest.SystemPrompt, secrets),
+		Prompt:       redactSecrets(aiRequest.Prompt, secrets),
+	}
+}
+
+// knownSecrets returns the request API key and every configured service API key
+func (g *SQLGenerator) knownSecrets(options *GenerateOptions) []string {
+	secrets := []string{options.APIKey}
+	for _, service := range g.currentConfig().Services {
+		secrets = append(secrets, service.APIKey)
+	}
+	return secrets
+}
+
+// redactSecrets replaces the given secret values and anything matching secretPatterns
+func redactSecrets(text string, secrets []string) string {
+	for _, secret := range secrets {
+		if strings.TrimSpace(secret) != "" {
+			text = strings.ReplaceAll(text, secret, pii.Redacted)
+		}
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if groups := pattern.FindStringSubmatch(match); len(groups) == 3 {
+				return groups[1] + groups[2] + pii.Redacted
+			}
+			return pii.Redacted
+		})
+	}
+	return text
+}
+
+// auditStream is the storage stream that receives audit records
+const auditStream = "audit"
+
+// auditRecord is the audit entry written for each generated statement
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Model     string    `json:"model"`
+	Dialect   string    `json:"dialect"`
+	QueryType string    `json:"query_type"`
+	SQL       string    `json:"sql"`
+}
+
+// auditGeneration writes the audit record for a generated statement when auditing is enabled.
+// The record is logged and, when ai.storage.backend is set, also appended to the backend.
+func (g *SQLGenerator) auditGeneration(result *GenerationResult) {
+	cfg := g.currentConfig()
+	if !cfg.Audit.Enabled {
+		return
+	}
+	sql := result.SQL
+	if cfg.Audit.Anonymize {
+		sql = sqlutil.Anonymize(sql)
+	}
+	logging.Logger.Info("SQL generation audit",
+		"request_id", result.Metadata.RequestID,
+		"model", result.Metadata.ModelUsed,
+		"dialect", result.Metadata.DatabaseDialect,
+		"query_type", result.Metadata.QueryType,
+		"sql", sql)
+
+	if cfg.Storage.Backend == "" {
+		return
+	}
+	g.configMu.RLock()
+	backend := g.storage
+	g.configMu.RUnlock()
+	record, err := json.Marshal(auditRecord{
+		Time:      time.Now(),
+		RequestID: result.Metadata.RequestID,
+		Model:     result.Metadata.ModelUsed,
+		Dialect:   result.Metadata.DatabaseDialect,
+		QueryType: result.Metadata.QueryType,
+		SQL:       sql,
+	})
+	if err == nil {
+		err = backend.Append(auditStream, record)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("AI generation failed: %w", err)
+		logging.Logger.Warn("Failed to persist SQL generation audit record", "request_id", result.Metadata.RequestID, "error", err)
 	}
+}
 
-	// Parse and validate the response
-	result := g.parseAIResponse(aiResponse, options, dialect, requestID, start)
-	return result, nil
+// maskExplanation redacts PII echoed in the explanation; the SQL is left untouched
+func (g *SQLGenerator) maskExplanation(result *GenerationResult) {
+	g.configMu.RLock()
+	detector := g.piiDetector
+	g.configMu.RUnlock()
+	if detector == nil || result.Explanation == "" {
+		return
+	}
+	masked, changed := detector.Mask(result.Explanation)
+	if changed {
+		result.Explanation = masked
+		result.Metadata.DebugInfo = append(result.Metadata.DebugInfo, "personal data masked in explanation")
+	}
 }
 
-// initializeDialects initializes SQL dialect support
-func (g *SQLGenerator) initializeDialects() {
-	// Initialize MySQL dialect
-	g.sqlDialects["mysql"] = &MySQLDialect{}
+// isWriteQueryType reports whether the query type modifies data or schema
+func isWriteQueryType(queryType string) bool {
+	switch queryType {
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT", "CREATE", "DROP", "ALTER", "TRUNCATE", "RENAME", "GRANT", "REVOKE":
+		return true
+	}
+	return false
+}
 
-	// Initialize PostgreSQL dialect
-	g.sqlDialects["postgresql"] = &PostgreSQLDialect{}
-	g.sqlDialects["postgres"] = &PostgreSQLDialect{}
+// writeStatementKind returns the type of the first statement or CTE in sql that modifies data or schema
+func writeStatementKind(sql string) (string, bool) {
+	for _, statementType := range statementTypes(sql) {
+		if isWriteQueryType(statementType.Kind) || len(statementType.Actions) > 0 {
+			return statementType.Kind, true
+		}
+	}
+	return "", false
+}
+
+// newConfirmationToken returns a random token for a held statement
+func newConfirmationToken() (string, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to create confirmation token: %w", err)
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
 
-	// Initialize SQLite dialect
-	g.sqlDialects["sqlite"] = &SQLiteDialect{}
+// holdForConfirmation marks a write statement of the given kind as unconfirmed and stores it under a new token
+func (g *SQLGenerator) holdForConfirmation(result *GenerationResult, kind string) error {
+	token, err := newConfirmationToken()
+	if err != nil {
+		return err
+	}
 
+	result.NeedsConfirmation = true
+	result.ConfirmationToken = token
+	result.Warnings = append(result.Warnings,
+		fmt.Sprintf("%s statement requires confirmation before it is released", kind))
+	g.storeConfirmation(token, result)
+	return nil
+}
+
+// storeConfirmation stores a copy of result under token, so changes the caller makes to the
+// returned result never alter what Confirm releases
+func (g *SQLGenerator) storeConfirmation(token string, result *GenerationResult) {
+	g.confirmMu.Lock()
+	defer g.confirmMu.Unlock()
+
+	if g.pendingConfirmations == nil {
+		g.pendingConfirmations = make(map[string]*pendingConfirmation)
+	}
+	now := time.Now()
+	for key, pending := range g.pendingConfirmations {
+		if now.After(pending.expiresAt) {
+			delete(g.pendingConfirmations, key)
+		}
+	}
+	g.pendingConfirmations[token] = &pendingConfirmation{
+		result:    cloneGenerationResult(result),
+		expiresAt: now.Add(confirmationTTL),
+	}
+}
+
+// reissueConfirmations gives a result shared by coalesced callers tokens of its own, so each
+// caller confirms its copy independently of the others
+func (g *SQLGenerator) reissueConfirmations(result *GenerationResult) error {
+	reissue := func(token string) (string, error) {
+		g.confirmMu.Lock()
+		pending, ok := g.pendingConfirmations[token]
+		g.confirmMu.Unlock()
+		if !ok {
+			return token, nil
+		}
+		reissued, err := newConfirmationToken()
+		if err != nil {
+			return "", err
+		}
+		g.storeConfirmation(reissued, pending.result)
+		return reissued, nil
+	}
+
+	if result.NeedsConfirmation {
+		token, err := reissue(result.ConfirmationToken)
+		if err != nil {
+			return err
+		}
+		result.ConfirmationToken = token
+	}
+	for i := range result.Alternatives {
+		if !result.Alternatives[i].NeedsConfirmation {
+			continue
+		}
+		token, err := reissue(result.Alternatives[i].ConfirmationToken)
+		if err != nil {
+			return err
+		}
+		result.Alternatives[i].ConfirmationToken = token
+	}
+	return nil
+}
+
+// Confirm releases a write statement held by RequireConfirmForWrites.
+// Each token can be confirmed once.
+func (g *SQLGenerator) Confirm(token string) (*GenerationResult, error) {
+	g.confirmMu.Lock()
+	defer g.confirmMu.Unlock()
+
+	pending, ok := g.pendingConfirmations[token]
+	if !ok || time.Now().After(pending.expiresAt) {
+		delete(g.pendingConfirmations, token)
+		return nil, ErrConfirmationNotFound
+	}
+	delete(g.pendingConfirmations, token)
+
+	released := cloneGenerationResult(pending.result)
+	released.NeedsConfirmation = false
+	released.ConfirmationToken = ""
+	return released, nil
+}
+
+// maxAutoContinuations caps how many times a truncated response is continued
+const maxAutoContinuations = 3
+
+// isTruncatedResponse reports whether the provider stopped because it hit the token limit.
+// OpenAI-compatible providers report finish_reason "length"; Ollama reports done_reason
+// "length" or leaves done unset when generation was cut short.
+func isTruncatedResponse(resp *interfaces.GenerateResponse) bool {
+	if resp == nil || resp.Metadata == nil {
+		return false
+	}
+	if reason, ok := resp.Metadata["finish_reason"].(string); ok {
+		switch strings.ToLower(reason) {
+		case "length", "max_tokens":
+			return true
+		}
+	}
+	if done, ok := resp.Metadata["done"].(bool); ok && !done {
+		return true
+	}
+	return false
+}
+
+// buildContinuationRequest asks the model to resume exactly where the partial output stopped
+func buildContinuationRequest(original *interfaces.GenerateRequest, partial string) *interfaces.GenerateRequest {
+	continuation := *original
+	continuation.Prompt = original.Prompt +
+		"\n\nYour previous response was cut off at the token limit. It ended with:\n" + partial +
+		"\n\nContinue exactly where it stopped. Output only the remaining text without repeating anything."
+	return &continuation
+}
+
+// builtinDialects returns the built-in SQL dialects keyed by database type
+func builtinDialects() map[string]SQLDialect {
+	return map[string]SQLDialect{
+		"mysql":      &MySQLDialect{},
+		"postgresql": &PostgreSQLDialect{},
+		"postgres":   &PostgreSQLDialect{},
+		"sqlite":     &SQLiteDialect{},
+		"snowflake":  &SnowflakeDialect{},
+	}
 }
 
 // buildPrompt constructs the AI prompt for SQL generation
 func (g *SQLGenerator) buildPrompt(naturalLanguage string, options *GenerateOptions, dialect SQLDialect) string {
 	var promptBuilder strings.Builder
 
+	// Prompt templates from ai.templates_dir override sections, most specific dialect first
+	promptTemplate, _ := g.templates.PromptFor(options.DatabaseType)
+
 	// Add custom prompt if provided
 	if customPrompt, exists := options.CustomPrompts["sql_generation"]; exists {
 		promptBuilder.WriteString(customPrompt + "\n\n")
+	} else if system := promptTemplate.Section(templates.SectionSystem); system != "" {
+		promptBuilder.WriteString(system + "\n\n")
 	} else {
 		// Default SQL generation prompt
 		promptBuilder.WriteString("Generate a SQL query based on the following natural language description.\n\n")
@@ -299,25 +1243,13 @@ func (g *SQLGenerator) buildPrompt(naturalLanguage string, options *GenerateOpti
 	// Add database-specific context
 	promptBuilder.WriteString(fmt.Sprintf("Database Type: %s\n", options.DatabaseType))
 	promptBuilder.WriteString(fmt.Sprintf("SQL Dialect: %s\n\n", dialect.Name()))
+	if instructions := promptTemplate.Section(templates.SectionInstructions); instructions != "" {
+		promptBuilder.WriteString("Dialect Instructions:\n" + instructions + "\n\n")
+	}
 
-	// Add schema information if provided
-	if len(options.Schema) > 0 {
-		promptBuilder.WriteString("Database Schema:\n")
-		for tableName, table := range options.Schema {
-			promptBuilder.WriteString(fmt.Sprintf("Table: %s\n", tableName))
-			for _, column := range table.Columns {
-				nullable := "NOT NULL"
-				if column.Nullable {
-					nullable = "NULL"
-				}
-				promptBuilder.WriteString(fmt.Sprintf("  - %s %s %s", column.Name, column.Type, nullable))
-				if column.Comment != "" {
-					promptBuilder.WriteString(fmt.Sprintf(" -- %s", column.Comment))
-				}
-				promptBuilder.WriteString("\n")
-			}
-			promptBuilder.WriteString("\n")
-		}
+	// Add schema information if provided; with prompt caching it lives in the system prompt instead
+	if !g.currentConfig().PromptCache.Enabled {
+		promptBuilder.WriteString(schemaText(options))
 	}
 
 	// Add context information
@@ -330,7 +1262,9 @@ func (g *SQLGenerator) buildPrompt(naturalLanguage string, options *GenerateOpti
 	}
 
 	// Add safety constraints if enabled
-	if options.SafetyMode {
+	if safety := promptTemplate.Section(templates.SectionSafety); options.SafetyMode && safety != "" {
+		promptBuilder.WriteString("Safety Requirements:\n" + safety + "\n\n")
+	} else if options.SafetyMode {
 		promptBuilder.WriteString("Safety Requirements:\n")
 		promptBuilder.WriteString("- Do not generate DROP, DELETE, or TRUNCATE statements unless explicitly requested\n")
 		promptBuilder.WriteString("- Include appropriate WHERE clauses to prevent accidental data modification\n")
@@ -338,6 +1272,17 @@ func (g *SQLGenerator) buildPrompt(naturalLanguage string, options *GenerateOpti
 		promptBuilder.WriteString("- Validate that the query follows security best practices\n\n")
 	}
 
+	// Add similar past generations for this schema as few-shot examples
+	if _, memory := g.memories(); memory != nil {
+		if examples := memory.similar(naturalLanguage, options); len(examples) > 0 {
+			promptBuilder.WriteString("Examples of previous queries for this schema:\n")
+			for _, example := range examples {
+				promptBuilder.WriteString(fmt.Sprintf("Query: %s\nsql:%s\n", example.prompt, example.sql))
+			}
+			promptBuilder.WriteString("\n")
+		}
+	}
+
 	// Add the natural language query
 	promptBuilder.WriteString("Natural Language Query:\n")
 	promptBuilder.WriteString(naturalLanguage)
@@ -359,6 +1304,53 @@ func (g *SQLGenerator) buildPrompt(naturalLanguage string, options *GenerateOpti
 	return promptBuilder.String()
 }
 
+// schemaText returns the rendered schema of options, reusing a session's prepared rendering
+func schemaText(options *GenerateOptions) string {
+	if options.renderedSchema != "" {
+		return options.renderedSchema
+	}
+	return renderSchema(options.Schema)
+}
+
+// renderSchema formats tables in name order so identical schemas render identically
+func renderSchema(schema map[string]Table) string {
+	if len(schema) == 0 {
+		return ""
+	}
+
+	tableNames := make([]string, 0, len(schema))
+	for tableName := range schema {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	var b strings.Builder
+	b.WriteString("Database Schema:\n")
+	for _, tableName := range tableNames {
+		b.WriteString(renderTable(tableName, schema[tableName]))
+	}
+	return b.String()
+}
+
+// renderTable formats one table and its columns for the schema section
+func renderTable(tableName string, table Table) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Table: %s\n", tableName))
+	for _, column := range table.Columns {
+		nullable := "NOT NULL"
+		if column.Nullable {
+			nullable = "NULL"
+		}
+		b.WriteString(fmt.Sprintf("  - %s %s %s", column.Name, column.Type, nullable))
+		if column.Comment != "" {
+			b.WriteString(fmt.Sprintf(" -- %s", column.Comment))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
 // getSystemPrompt returns the system prompt for SQL generation
 func (g *SQLGenerator) getSystemPrompt(databaseType string) string {
 	return fmt.Sprintf(`You are an expert SQL database assistant specializing in %s.
@@ -377,16 +1369,20 @@ Always respond in the exact format requested: sql:<query> explanation:<explanati
 
 // parseAIResponse parses and validates the AI response
 func (g *SQLGenerator) parseAIResponse(aiResponse *interfaces.GenerateResponse, options *GenerateOptions, dialect SQLDialect, requestID string, startTime time.Time) *GenerationResult {
+	g.logProviderResponse(aiResponse, options)
+
+	// Reasoning is kept out of extraction so SQL drafted while thinking is never picked up
+	reasoning, answer := splitReasoning(aiResponse)
+
 	// Try to extract JSON from the response
-	sqlResult := g.extractSQLFromResponse(aiResponse.Text)
+	sqlResult := g.extractSQLFromResponse(answer, g.responseStyle(options))
 
 	// Create generation result
 	result := &GenerationResult{
-		SQL:             sqlResult.SQL,
-		Explanation:     sqlResult.Explanation,
-		ConfidenceScore: sqlResult.Confidence,
-		Warnings:        sqlResult.Warnings,
-		Suggestions:     sqlResult.Suggestions,
+		SQL:         sqlResult.SQL,
+		Explanation: sqlResult.Explanation,
+		Warnings:    sqlResult.Warnings,
+		Suggestions: sqlResult.Suggestions,
 		Metadata: GenerationMetadata{
 			RequestID:       requestID,
 			ProcessingTime:  time.Since(startTime),
@@ -397,6 +1393,12 @@ func (g *SQLGenerator) parseAIResponse(aiResponse *interfaces.GenerateResponse,
 			Complexity:      g.assessComplexity(sqlResult.SQL),
 		},
 	}
+	if debug := g.currentConfig().Debug; reasoning != "" && debug.IncludeReasoning {
+		if debug.MaxResponseLogLength > 0 {
+			reasoning = truncateString(reasoning, debug.MaxResponseLogLength)
+		}
+		result.Metadata.DebugInfo = append(result.Metadata.DebugInfo, "model reasoning: "+reasoning)
+	}
 
 	// Validate SQL if requested
 	if options.ValidateSQL {
@@ -406,6 +1408,7 @@ func (g *SQLGenerator) parseAIResponse(aiResponse *interfaces.GenerateResponse,
 		} else {
 			result.ValidationResults = validationResults
 		}
+		result.ValidationResults = append(result.ValidationResults, ambiguousColumnResults(sqlResult.SQL, options.Schema)...)
 	}
 
 	// Optimize query if requested
@@ -419,9 +1422,208 @@ func (g *SQLGenerator) parseAIResponse(aiResponse *interfaces.GenerateResponse,
 		}
 	}
 
+	// Apply configured post-processors; a failing processor leaves the SQL as it was
+	g.configMu.RLock()
+	postProcessors := g.postProcessors
+	readOnly := g.config.ReadOnly
+	allowed, denied := g.config.AllowedStatementTypes, g.config.DeniedStatementTypes
+	weights := newConfidenceWeights(g.config.Confidence)
+	affix := newAffixProcessor(g.config.SQLPrefix, g.config.SQLSuffix)
+	validateAffixes := g.config.ValidateSQLAffixes
+	g.configMu.RUnlock()
+	for _, processor := range postProcessors {
+		previousSQL := result.SQL
+		if err := processor.Process(result, dialect); err != nil {
+			result.SQL = previousSQL
+			result.Warnings = append(result.Warnings, fmt.Sprintf("post-processor %s failed: %v", processor.Name(), err))
+		}
+	}
+	result.statement = result.SQL
+	if affix != nil && validateAffixes {
+		_ = affix.Process(result, dialect)
+		if options.ValidateSQL {
+			if validationResults, err := dialect.ValidateSQL(result.SQL); err == nil {
+				result.ValidationResults = append(validationResults, ambiguousColumnResults(result.SQL, options.Schema)...)
+			}
+		}
+	}
+
+	// Safety checks below describe policy rather than quality, so they do not affect confidence
+	result.ConfidenceScore = computeConfidence(weights, result, aiResponse, options)
+
+	if readOnly {
+		checkReadOnly(result)
+	}
+	allowed, restricted := narrowAllowedStatements(allowed, options.AllowedStatements)
+	g.checkStatementTypes(result, allowed, restricted, denied)
+	checkFullTableWrite(result, options)
+
+	// Unless configured otherwise, the prefix and suffix are added after every check has run
+	if affix != nil && !validateAffixes {
+		_ = affix.Process(result, dialect)
+	}
 	return result
 }
 
+// NormalizeNaturalLanguage trims surrounding whitespace and control characters from a query and
+// rejects it when nothing is left or when it exceeds maxBytes (0 disables the length check).
+func NormalizeNaturalLanguage(naturalLanguage string, maxBytes int) (string, error) {
+	trimmed := strings.TrimFunc(naturalLanguage, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	})
+	if trimmed == "" {
+		return "", ErrEmptyQuery
+	}
+	if maxBytes > 0 && len(trimmed) > maxBytes {
+		return "", fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrQueryTooLong, len(trimmed), maxBytes)
+	}
+	return trimmed, nil
+}
+
+// enforceReadOnly forces SafetyMode in read-only mode, overriding per-request options
+func (g *SQLGenerator) enforceReadOnly(options *GenerateOptions) *GenerateOptions {
+	if !g.currentConfig().ReadOnly {
+		return options
+	}
+	enforced := *options
+	enforced.SafetyMode = true
+	enforced.AcknowledgeFullTableWrite = false
+	return &enforced
+}
+
+// checkReadOnly blocks any statement other than a query
+func checkReadOnly(result *GenerationResult) {
+	kind, ok := nonReadStatement(result.SQL)
+	if !ok {
+		return
+	}
+
+	result.Blocked = true
+	result.ValidationResults = append(result.ValidationResults, ValidationResult{
+		Type:       "read_only",
+		Level:      "error",
+		Message:    fmt.Sprintf("%s statements are not allowed in read-only mode", kind),
+		Suggestion: "Rephrase the request as a SELECT query",
+	})
+}
+
+// checkStatementTypes blocks statements whose type is denied or, when allowed is set, not allowed
+func (g *SQLGenerator) checkStatementTypes(result *GenerationResult, allowed []string, restricted bool, denied []string) {
+	if !restricted && len(denied) == 0 {
+		return
+	}
+	for _, statementType := range statementTypes(result.SQL) {
+		var message string
+		deniedAction := ""
+		for _, action := range statementType.Actions {
+			if containsStatementType(denied, action) {
+				deniedAction = action
+				break
+			}
+		}
+		switch {
+		case containsStatementType(denied, statementType.Kind):
+			message = fmt.Sprintf("%s statements are denied by configuration", statementType.Kind)
+		case deniedAction != "":
+			message = fmt.Sprintf("%s statements that %s rows are denied by configuration", statementType.Kind, deniedAction)
+		case restricted && !containsStatementType(allowed, statementType.Kind):
+			allowedTypes := strings.Join(allowed, ", ")
+			if allowedTypes == "" {
+				allowedTypes = "none"
+			}
+			message = fmt.Sprintf("%s statements are not allowed; allowed types: %s", statementType.Kind, allowedTypes)
+		default:
+			continue
+		}
+		result.Blocked = true
+		result.ValidationResults = append(result.ValidationResults, ValidationResult{
+			Type:       "statement_type",
+			Level:      "error",
+			Message:    message,
+			Suggestion: "Rephrase the request so it only needs allowed statement types",
+		})
+		return
+	}
+}
+
+// narrowAllowedStatements combines the configured allowlist with a request's list, which can only
+// narrow it: the result holds the requested types that are also configured, or all requested types
+// when nothing is configured. An empty request list leaves the configured one in place. The flag
+// reports whether any allowlist applies; an empty intersection then allows nothing.
+func narrowAllowedStatements(configured, requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return configured, len(configured) > 0
+	}
+	if len(configured) == 0 {
+		return requested, true
+	}
+	narrowed := make([]string, 0, len(requested))
+	for _, statementType := range requested {
+		if containsStatementType(configured, strings.TrimSpace(statementType)) {
+			narrowed = append(narrowed, statementType)
+		}
+	}
+	return narrowed, true
+}
+
+// containsStatementType reports whether statementType is listed, ignoring case and surrounding whitespace
+func containsStatementType(list []string, statementType string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), statementType) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFullTableWrite suggests a WHERE clause for UPDATE/DELETE statements that touch every row
+// and, in SafetyMode, blocks them unless the caller acknowledged the full-table write.
+func checkFullTableWrite(result *GenerationResult, options *GenerateOptions) {
+	kind, ok := unboundedWriteStatement(result.SQL)
+	if !ok {
+		return
+	}
+
+	suggestion := fmt.Sprintf("Add a WHERE clause to limit the rows affected by this %s", kind)
+	result.Suggestions = append(result.Suggestions, suggestion)
+	if !options.SafetyMode || options.AcknowledgeFullTableWrite {
+		return
+	}
+
+	result.Blocked = true
+	result.ValidationResults = append(result.ValidationResults, ValidationResult{
+		Type:       "safety",
+		Level:      "error",
+		Message:    fmt.Sprintf("%s without a WHERE clause affects every row in the table", kind),
+		Suggestion: suggestion + ", or set acknowledge_full_table_write to allow it",
+	})
+}
+
+// responseLogKeys are the provider metadata fields describing why generation stopped and token usage
+var responseLogKeys = []string{
+	"finish_reason", "done_reason", "prompt_tokens", "completion_tokens", "total_tokens", "prompt_eval_count", "eval_count",
+}
+
+// logProviderResponse logs finish reason and usage at debug level, plus the redacted raw text when enabled
+func (g *SQLGenerator) logProviderResponse(aiResponse *interfaces.GenerateResponse, options *GenerateOptions) {
+	attrs := []any{"model", aiResponse.Model, "response_length", len(aiResponse.Text)}
+	for _, key := range responseLogKeys {
+		if value, ok := aiResponse.Metadata[key]; ok {
+			attrs = append(attrs, key, value)
+		}
+	}
+
+	debug := g.currentConfig().Debug
+	if debug.LogFullResponse {
+		text := redactSecrets(aiResponse.Text, g.knownSecrets(options))
+		if debug.MaxResponseLogLength > 0 {
+			text = truncateString(text, debug.MaxResponseLogLength)
+		}
+		attrs = append(attrs, "response", text)
+	}
+	logging.Logger.Debug("AI provider response", attrs...)
+}
+
 // SQLResponse represents the structured response from AI
 type SQLResponse struct {
 	SQL            string   `json:"sql"`
@@ -433,93 +1635,29 @@ type SQLResponse struct {
 	Suggestions    []string `json:"suggestions"`
 }
 
-// extractSQLFromResponse extracts structured SQL information from AI response
-func (g *SQLGenerator) extractSQLFromResponse(responseText string) *SQLResponse {
+// extractSQLFromResponse extracts structured SQL information from AI response.
+// A known style picks the matching parser first; otherwise the default parser chain is tried in order.
+func (g *SQLGenerator) extractSQLFromResponse(responseText string, style string) *SQLResponse {
 	responseText = strings.TrimSpace(responseText)
 
 	// DEBUG: Log the raw AI response to understand what we're getting
-	logging.Logger.Debug("AI response received", "response_length", len(responseText), "response_preview", truncateString(responseText, 100))
-
-	// First try to parse the new simple format: "sql:...\nexplanation:..."
-	if strings.HasPrefix(responseText, "sql:") {
-		// Try with newline separator first
-		parts := strings.SplitN(responseText, "\nexplanation:", 2)
-		if len(parts) == 1 {
-			// Fallback to space separator for backward compatibility
-			parts = strings.SplitN(responseText, " explanation:", 2)
-		}
-
-		sql := strings.TrimSpace(strings.TrimPrefix(parts[0], "sql:"))
-
-		explanation := "Generated SQL query based on natural language input"
-		if len(parts) > 1 {
-			explanation = strings.TrimSpace(parts[1])
-		}
-
-		return &SQLResponse{
-			SQL:            sql,
-			Explanation:    explanation,
-			Confidence:     0.8,
-			QueryType:      g.detectQueryType(sql),
-			TablesInvolved: g.extractTableNames(sql),
-			Warnings:       []string{},
-			Suggestions:    []string{},
-		}
-	}
-
-	// Fallback: Check if it looks like JSON (for backward compatibility)
-	if strings.HasPrefix(responseText, "{") && strings.HasSuffix(responseText, "}") {
-		var jsonResponse SQLResponse
-		if err := json.Unmarshal([]byte(responseText), &jsonResponse); err == nil {
-			// Successfully parsed JSON
-			if jsonResponse.SQL != "" {
-				// Clean up the SQL
-				sql := strings.TrimSpace(jsonResponse.SQL)
-				sql = strings.TrimPrefix(sql, "```sql")
-				sql = strings.TrimPrefix(sql, "```json")
-				sql = strings.TrimPrefix(sql, "```")
-				sql = strings.TrimSuffix(sql, "```")
-				sql = strings.TrimSpace(sql)
-
-				// Extract explanation
-				explanation := strings.TrimSpace(jsonResponse.Explanation)
-				if explanation == "" {
-					explanation = "Generated SQL query based on natural language input"
-				}
-
-				// Return a simplified SQLResponse with only SQL and explanation
-				return &SQLResponse{
-					SQL:            sql,
-					Explanation:    explanation,
-					Confidence:     0.8,
-					QueryType:      g.detectQueryType(sql),
-					TablesInvolved: g.extractTableNames(sql),
-					Warnings:       []string{},
-					Suggestions:    []string{},
-				}
-			}
-		}
+	preview := responseText
+	if g.currentConfig().Audit.Anonymize {
+		preview = sqlutil.Anonymize(preview)
 	}
+	logging.Logger.Debug("AI response received", "response_length", len(responseText), "response_preview", truncateString(preview, 100))
 
-	// If neither format worked, try to extract SQL from plain text
-	sql := strings.TrimSpace(responseText)
-
-	// Remove common prefixes and suffixes
-	sql = strings.TrimPrefix(sql, "```sql")
-	sql = strings.TrimPrefix(sql, "```json")
-	sql = strings.TrimPrefix(sql, "```")
-	sql = strings.TrimSuffix(sql, "```")
-	sql = strings.TrimSpace(sql)
-
-	// If it's still empty, provide a default
-	if sql == "" {
-		sql = "SELECT 1 as placeholder;"
+	sql, explanation, ok := parseResponse(responseParserChain(style), responseText)
+	if !ok {
+		sql = placeholderSQL
+	}
+	if explanation == "" {
+		explanation = "Generated SQL query based on natural language input"
 	}
 
 	return &SQLResponse{
 		SQL:            sql,
-		Explanation:    "Generated SQL query based on natural language input",
-		Confidence:     0.8,
+		Explanation:    explanation,
 		QueryType:      g.detectQueryType(sql),
 		TablesInvolved: g.extractTableNames(sql),
 		Warnings:       []string{},
@@ -527,28 +1665,166 @@ func (g *SQLGenerator) extractSQLFromResponse(responseText string) *SQLResponse
 	}
 }
 
-// detectQueryType determines the type of SQL query
+// sqlFenceLanguages are code fence language tags treated as SQL
+var sqlFenceLanguages = map[string]bool{
+	"": true, "sql": true, "mysql": true, "postgresql": true, "postgres": true, "psql": true,
+	"pgsql": true, "sqlite": true, "snowflake": true, "plsql": true, "tsql": true,
+}
+
+// cleanSQLText unwraps a fenced code block and drops commentary after the final statement
+func cleanSQLText(text string) string {
+	sql := strings.TrimSpace(text)
+	if block, ok := extractFencedSQL(sql); ok {
+		sql = block
+	} else if strings.HasPrefix(sql, "```") {
+		// Unterminated fence: drop the opening line with its language tag
+		if newline := strings.IndexByte(sql, '\n'); newline >= 0 {
+			sql = sql[newline+1:]
+		} else {
+			sql = strings.TrimSpace(strings.Trim(sql, "`"))
+			if len(sql) > 4 && strings.EqualFold(sql[:4], "sql ") {
+				sql = sql[4:]
+			}
+		}
+	}
+	sql = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sql), "```"))
+	return trimTrailingCommentary(sql)
+}
+
+// extractFencedSQL returns the first fenced code block tagged as SQL (or untagged), case-insensitively.
+// When no block carries a SQL tag, the first fenced block is used.
+func extractFencedSQL(text string) (string, bool) {
+	var first string
+	found := false
+	rest := text
+	for {
+		open := strings.Index(rest, "```")
+		if open < 0 {
+			break
+		}
+		afterOpen := rest[open+3:]
+		newline := strings.IndexByte(afterOpen, '\n')
+		if newline < 0 {
+			break
+		}
+		language := strings.ToLower(strings.TrimSpace(afterOpen[:newline]))
+		body := afterOpen[newline+1:]
+		closing := strings.Index(body, "```")
+		if closing < 0 {
+			break
+		}
+		block := strings.TrimSpace(body[:closing])
+		if sqlFenceLanguages[language] {
+			return block, true
+		}
+		if !found {
+			first, found = block, true
+		}
+		rest = body[closing+3:]
+	}
+	return first, found
+}
+
+// trimTrailingCommentary drops text after the last semicolon outside of quotes unless it starts
+// with a known statement keyword, so a final statement missing its semicolon is kept
+func trimTrailingCommentary(sql string) string {
+	lastSemicolon := -1
+	var quote rune
+	for i, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			lastSemicolon = i
+		}
+	}
+	if lastSemicolon < 0 || strings.TrimSpace(sql[lastSemicolon+1:]) == "" {
+		return sql
+	}
+	for _, statement := range splitStatements(tokenizeSQL(sql[lastSemicolon+1:])) {
+		if statementKind(statement) != unknownStatementType {
+			return sql
+		}
+	}
+	return sql[:lastSemicolon+1]
+}
+
+// detectQueryType returns the statementKind of the first statement in sql. A WITH statement has
+// the type of the statement after its CTEs.
 func (g *SQLGenerator) detectQueryType(sql string) string {
-	upper := strings.ToUpper(strings.TrimSpace(sql))
+	for _, statement := range splitStatements(tokenizeSQL(sql)) {
+		clauses := statementClauses(statement)
+		return statementKind(clauses[len(clauses)-1])
+	}
+	return unknownStatementType
+}
 
-	switch {
-	case strings.HasPrefix(upper, "SELECT"):
-		return "SELECT"
-	case strings.HasPrefix(upper, "INSERT"):
-		return "INSERT"
-	case strings.HasPrefix(upper, "UPDATE"):
-		return "UPDATE"
-	case strings.HasPrefix(upper, "DELETE"):
-		return "DELETE"
-	case strings.HasPrefix(upper, "CREATE"):
-		return "CREATE"
-	case strings.HasPrefix(upper, "DROP"):
-		return "DROP"
-	case strings.HasPrefix(upper, "ALTER"):
-		return "ALTER"
+// statementType is the type of a statement or data-modifying CTE and the write actions it performs
+// beyond that type: the WHEN actions of MERGE, the delete of REPLACE and the update of an upsert
+type statementType struct {
+	Kind    string
+	Actions []string
+}
+
+// statementTypes classifies every statement in sql. Each data-modifying CTE body of a WITH
+// statement is classified on its own, followed by the statement after the CTEs.
+func statementTypes(sql string) []statementType {
+	var types []statementType
+	for _, statement := range splitStatements(tokenizeSQL(sql)) {
+		types = append(types, clauseTypes(statement)...)
+	}
+	return types
+}
+
+// clauseTypes classifies the data-modifying CTE bodies and the main clause of one statement
+func clauseTypes(statement []sqlToken) []statementType {
+	clauses := statementClauses(statement)
+	var types []statementType
+	for i, clause := range clauses {
+		kind := statementKind(clause)
+		if i < len(clauses)-1 && !dataModifyingKeywords[kind] {
+			continue
+		}
+		types = append(types, statementType{Kind: kind, Actions: statementActions(kind, clause)})
 	}
+	return types
+}
 
-	return "UNKNOWN"
+// statementActions returns the write actions a clause of the given kind performs beyond its kind
+func statementActions(kind string, clause []sqlToken) []string {
+	var actions []string
+	switch kind {
+	case "MERGE":
+		for i := 1; i < len(clause); i++ {
+			if action := clause[i].Text; clause[i-1].Text == "THEN" && (action == "INSERT" || action == "UPDATE" || action == "DELETE") && !slices.Contains(actions, action) {
+				actions = append(actions, action)
+			}
+		}
+	case "REPLACE":
+		actions = []string{"INSERT", "DELETE"}
+	case "UPSERT":
+		actions = []string{"INSERT", "UPDATE"}
+	case "INSERT":
+		// ON CONFLICT ... DO UPDATE and ON DUPLICATE KEY UPDATE
+		for i := 1; i < len(clause); i++ {
+			if clause[i].Text == "UPDATE" && (clause[i-1].Text == "DO" || clause[i-1].Text == "KEY") {
+				return []string{"UPDATE"}
+			}
+		}
+	case "EXPLAIN":
+		// EXPLAIN ANALYZE runs the explained statement
+		for _, explained := range clauseTypes(explainedStatement(clause)) {
+			if isWriteQueryType(explained.Kind) || len(explained.Actions) > 0 {
+				actions = append(actions, explained.Kind)
+				actions = append(actions, explained.Actions...)
+			}
+		}
+	}
+	return actions
 }
 
 // extractTableNames extracts table names from SQL query
@@ -618,6 +1894,8 @@ func (g *SQLGenerator) assessComplexity(sql string) string {
 
 // GetCapabilities returns the SQL generation capabilities
 func (g *SQLGenerator) GetCapabilities() *SQLCapabilities {
+	g.configMu.RLock()
+	defer g.configMu.RUnlock()
 	return g.capabilities
 }
 
@@ -722,32 +2000,45 @@ func (g *SQLGenerator) getOrCreateRuntimeClient(options *GenerateOptions) (inter
 	return client, false, nil
 }
 
-// Close releases all cached runtime clients held by the generator.
+// Close releases all cached runtime clients held by the generator. Repeated calls are no-ops.
 func (g *SQLGenerator) Close() {
-	g.runtimeMu.Lock()
-	defer g.runtimeMu.Unlock()
-	for key, entry := range g.runtimeClients {
-		if entry == nil || entry.client == nil {
+	g.closeOnce.Do(func() {
+		g.runtimeMu.Lock()
+		defer g.runtimeMu.Unlock()
+		for key, entry := range g.runtimeClients {
+			if entry == nil || entry.client == nil {
+				delete(g.runtimeClients, key)
+				continue
+			}
+			if err := entry.client.Close(); err != nil {
+				logging.Logger.Warn("Failed to close runtime client during generator shutdown",
+					"key", key,
+					"error", err)
+			}
 			delete(g.runtimeClients, key)
-			continue
-		}
-		if err := entry.client.Close(); err != nil {
-			logging.Logger.Warn("Failed to close runtime client during generator shutdown",
-				"key", key,
-				"error", err)
 		}
-		delete(g.runtimeClients, key)
+	})
+}
+
+// runtimeProviders lists the providers a request can select at runtime
+var runtimeProviders = []string{"openai", "deepseek", "custom", "ollama"}
+
+// validateRuntimeProvider rejects runtime provider overrides that cannot produce a client,
+// before any client is looked up or created
+func validateRuntimeProvider(options *GenerateOptions) error {
+	provider := providers.Normalize(options.Provider)
+	if !slices.Contains(runtimeProviders, provider) {
+		return fmt.Errorf("%w: %s (supported: %s)",
+			ErrProviderNotSupported, options.Provider, strings.Join(runtimeProviders, ", "))
 	}
+	if provider == "custom" && strings.TrimSpace(options.Endpoint) == "" {
+		return fmt.Errorf("%w: endpoint is required for custom provider", ErrInvalidConfig)
+	}
+	return nil
 }
 
 // createRuntimeClient creates an AI client from runtime configuration
 func createRuntimeClient(provider string, runtimeConfig map[string]any) (interfaces.AIClient, error) {
-	// Normalize provider name (local -> ollama)
-	provider = strings.ToLower(strings.TrimSpace(provider))
-	if provider == "local" {
-		provider = "ollama"
-	}
-
 	// Extract common configuration values
 	apiKey := ""
 	if val, ok := runtimeConfig["api_key"].(string); ok {
@@ -777,7 +2068,7 @@ func createRuntimeClient(provider string, runtimeConfig map[string]any) (interfa
 	}
 
 	// Create client based on provider type
-	normalizedProvider := normalizeProviderName(provider)
+	normalizedProvider := providers.Normalize(provider)
 
 	switch normalizedProvider {
 	case "openai", "deepseek", "custom":
@@ -787,6 +2078,7 @@ func createRuntimeClient(provider string, runtimeConfig map[string]any) (interfa
 			APIKey:    apiKey,
 			Model:     model,
 			MaxTokens: maxTokens,
+			Stop:      constants.DefaultStopSequences,
 		}
 
 		if config.Endpoint == "" {
@@ -806,6 +2098,7 @@ func createRuntimeClient(provider string, runtimeConfig map[string]any) (interfa
 			Endpoint:  normalizeProviderEndpoint("ollama", baseURL),
 			Model:     model,
 			MaxTokens: maxTokens,
+			Stop:      constants.DefaultStopSequences,
 		}
 
 		// Default endpoint for Ollama
@@ -822,8 +2115,42 @@ func createRuntimeClient(provider string, runtimeConfig map[string]any) (interfa
 
 // truncateString truncates a string to the specified length, adding "..." if truncated
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	if utf8.RuneCountInString(s) <= maxLen {
 		return s
 	}
-	return s[:maxLen] + "..."
+	runes := 0
+	for i := range s {
+		if runes == maxLen {
+			return s[:i] + "..."
+		}
+		runes++
+	}
+	return s
+}
+
+// maxInvalidUTF8Ratio is the share of invalid bytes above which a response is treated as binary
+const maxInvalidUTF8Ratio = 0.1
+
+// sanitizeResponseText replaces stray invalid UTF-8 sequences in a provider response and rejects
+// responses that look binary (NUL bytes or mostly invalid encoding) with ErrInvalidResponseEncoding.
+func sanitizeResponseText(text string) (string, error) {
+	if strings.ContainsRune(text, 0) {
+		return "", fmt.Errorf("%w: response contains NUL bytes", ErrInvalidResponseEncoding)
+	}
+	if utf8.ValidString(text) {
+		return text, nil
+	}
+
+	invalid := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		}
+		i += size
+	}
+	if float64(invalid) > maxInvalidUTF8Ratio*float64(len(text)) {
+		return "", fmt.Errorf("%w: %d of %d bytes are not valid UTF-8", ErrInvalidResponseEncoding, invalid, len(text))
+	}
+	return strings.ToValidUTF8(text, string(utf8.RuneError)), nil
 }
@@ -1,8 +1,26 @@
 package ai
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/templates"
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"github.com/linuxsuren/atest-ext-ai/pkg/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,3 +52,2248 @@ func TestRuntimeClientReuseAndClose(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, reused3)
 }
+
+func TestGenerateRejectsInvalidRuntimeProvider(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		t.Fatal("the default client must not be used for a runtime provider override")
+		return nil, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	t.Run("unknown provider", func(t *testing.T) {
+		options := defaultGenerateOptions()
+		options.Provider, options.APIKey = "gemini", "key"
+		_, err := generator.Generate(context.Background(), "count users", options)
+		require.ErrorIs(t, err, ErrProviderNotSupported)
+		require.Contains(t, err.Error(), "supported: openai, deepseek, custom, ollama")
+	})
+
+	t.Run("custom provider without endpoint", func(t *testing.T) {
+		options := defaultGenerateOptions()
+		options.Provider, options.APIKey = "custom", "key"
+		_, err := generator.Generate(context.Background(), "count users", options)
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		require.Contains(t, err.Error(), "endpoint is required")
+	})
+
+	require.Empty(t, generator.runtimeClients)
+}
+
+func TestRegenerateIncludesPreviousSQLAndFeedback(t *testing.T) {
+	var captured *interfaces.GenerateRequest
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		captured = req
+		return &interfaces.GenerateResponse{Text: "sql:SELECT status, COUNT(*) FROM orders GROUP BY status;", Model: "stub"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	previous := &GenerationResult{
+		SQL:      "SELECT status, COUNT(*) FROM orders;",
+		Metadata: GenerationMetadata{RequestID: "sql_1"},
+	}
+	result, err := generator.Regenerate(context.Background(), previous, "that's missing a GROUP BY", nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, captured)
+	require.Contains(t, captured.Prompt, "SELECT status, COUNT(*) FROM orders;")
+	require.Contains(t, captured.Prompt, "that's missing a GROUP BY")
+	require.Equal(t, "SELECT status, COUNT(*) FROM orders GROUP BY status;", result.SQL)
+	require.Contains(t, result.Metadata.DebugInfo, "regeneration of sql_1 with user feedback")
+
+	_, err = generator.Regenerate(context.Background(), previous, "  ", nil)
+	require.Error(t, err)
+}
+
+func TestGenerateFromTemplate(t *testing.T) {
+	var captured string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		captured = req.Prompt
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+	require.NoError(t, generator.Templates().Register(&templates.Template{
+		Name:   "top_customers",
+		Prompt: "top {{n}} customers by {{metric}}",
+	}))
+
+	_, err = generator.GenerateFromTemplate(context.Background(), "top_customers",
+		map[string]string{"n": "5", "metric": "revenue"}, nil)
+	require.NoError(t, err)
+	require.Contains(t, captured, "top 5 customers by revenue")
+
+	_, err = generator.GenerateFromTemplate(context.Background(), "top_customers", map[string]string{"n": "5"}, nil)
+	require.True(t, errors.Is(err, templates.ErrMissingParameters))
+
+	_, err = generator.GenerateFromTemplate(context.Background(), "unknown", nil, nil)
+	require.True(t, errors.Is(err, templates.ErrTemplateNotFound))
+}
+
+func TestGenerateDetectsTruncationAndContinues(t *testing.T) {
+	newClient := func(calls *int) *stubAIClient {
+		return &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			*calls++
+			if *calls == 1 {
+				return &interfaces.GenerateResponse{
+					Text:     "sql:SELECT id, name FROM users WHERE",
+					Metadata: map[string]any{"finish_reason": "length"},
+				}, nil
+			}
+			require.Contains(t, req.Prompt, "SELECT id, name FROM users WHERE")
+			return &interfaces.GenerateResponse{
+				Text:     " active = 1;",
+				Metadata: map[string]any{"finish_reason": "stop"},
+			}, nil
+		}}
+	}
+
+	t.Run("detects truncation", func(t *testing.T) {
+		calls := 0
+		generator, err := NewSQLGenerator(newClient(&calls), config.AIConfig{})
+		require.NoError(t, err)
+
+		result, err := generator.Generate(context.Background(), "active users", nil)
+		require.NoError(t, err)
+		require.True(t, result.Truncated)
+		require.Equal(t, 1, calls)
+		require.NotEmpty(t, result.Warnings)
+	})
+
+	t.Run("auto continues", func(t *testing.T) {
+		calls := 0
+		generator, err := NewSQLGenerator(newClient(&calls), config.AIConfig{})
+		require.NoError(t, err)
+
+		options := defaultGenerateOptions()
+		options.AutoContinue = true
+		result, err := generator.Generate(context.Background(), "active users", options)
+		require.NoError(t, err)
+		require.False(t, result.Truncated)
+		require.Equal(t, 2, calls)
+		require.Equal(t, "SELECT id, name FROM users WHERE active = 1;", result.SQL)
+	})
+}
+
+func TestRequireConfirmForWrites(t *testing.T) {
+	responses := map[string]string{
+		"remove inactive users": "sql:DELETE FROM users WHERE active = 0;",
+		"list users":            "sql:SELECT * FROM users;",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, text := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: text}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.RequireConfirmForWrites = true
+
+	deleteResult, err := generator.Generate(context.Background(), "remove inactive users", options)
+	require.NoError(t, err)
+	require.True(t, deleteResult.NeedsConfirmation)
+	require.NotEmpty(t, deleteResult.ConfirmationToken)
+	require.Equal(t, "DELETE FROM users WHERE active = 0;", deleteResult.SQL)
+
+	released, err := generator.Confirm(deleteResult.ConfirmationToken)
+	require.NoError(t, err)
+	require.False(t, released.NeedsConfirmation)
+	require.Equal(t, deleteResult.SQL, released.SQL)
+
+	_, err = generator.Confirm(deleteResult.ConfirmationToken)
+	require.ErrorIs(t, err, ErrConfirmationNotFound, "tokens are single use")
+
+	selectResult, err := generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.False(t, selectResult.NeedsConfirmation)
+	require.Empty(t, selectResult.ConfirmationToken)
+}
+
+func TestRequireConfirmForWritesClassifiesEveryStatement(t *testing.T) {
+	writes := []string{
+		"SELECT 1; DELETE FROM users WHERE id = 1;",
+		"WITH gone AS (DELETE FROM users WHERE id = 1 RETURNING *) SELECT * FROM gone;",
+		"/* cleanup */ DELETE FROM users WHERE id = 1;",
+		"-- note\nDELETE FROM users WHERE id = 1;",
+		"MERGE INTO users u USING staging s ON u.id = s.id WHEN MATCHED THEN UPDATE SET name = s.name;",
+		"REPLACE INTO users (id, name) VALUES (1, 'ada');",
+	}
+	var sql string
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:" + sql}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.RequireConfirmForWrites = true
+	for i, statement := range writes {
+		sql = statement
+		result, err := generator.Generate(context.Background(), fmt.Sprintf("write %d", i), options)
+		require.NoError(t, err)
+		require.True(t, result.NeedsConfirmation, statement)
+		require.NotEmpty(t, result.ConfirmationToken, statement)
+	}
+}
+
+func TestHeldResultIsNotChangedByTheCaller(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:DELETE FROM users WHERE id = 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.RequireConfirmForWrites = true
+	held, err := generator.Generate(context.Background(), "remove user one", options)
+	require.NoError(t, err)
+	require.True(t, held.NeedsConfirmation)
+
+	held.SQL = "DROP TABLE users;"
+	held.Warnings[0] = "changed"
+	released, err := generator.Confirm(held.ConfirmationToken)
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM users WHERE id = 1;", released.SQL)
+	require.NotContains(t, released.Warnings, "changed")
+}
+
+func TestGenerateCacheNormalizesPrompt(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		normalize bool
+		calls     int
+	}{
+		{name: "normalize on", normalize: true, calls: 1},
+		{name: "normalize off", normalize: false, calls: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var prompts []string
+			client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+				prompts = append(prompts, req.Prompt)
+				return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;"}, nil
+			}}
+			generator, err := NewSQLGenerator(client, config.AIConfig{Cache: config.CacheConfig{
+				Enabled:    true,
+				TTL:        config.Duration{Duration: time.Minute},
+				MaxEntries: 10,
+				Normalize:  tc.normalize,
+			}})
+			require.NoError(t, err)
+
+			first, err := generator.Generate(context.Background(), "Show users.", defaultGenerateOptions())
+			require.NoError(t, err)
+			second, err := generator.Generate(context.Background(), "  show   USERS", defaultGenerateOptions())
+			require.NoError(t, err)
+
+			require.Equal(t, first.SQL, second.SQL)
+			require.Len(t, prompts, tc.calls)
+			require.Equal(t, tc.calls, generator.cache.len())
+			require.Contains(t, prompts[0], "Show users.", "the raw prompt is sent to the model")
+		})
+	}
+}
+
+func TestGenerateReportsCacheHitAndServedBy(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;", Model: "llama3"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		DefaultService: "local",
+		Cache:          config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	})
+	require.NoError(t, err)
+
+	fresh, err := generator.Generate(context.Background(), "show users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.False(t, fresh.Metadata.CacheHit)
+	require.Equal(t, "ollama", fresh.Metadata.ServedBy)
+
+	cached, err := generator.Generate(context.Background(), "show users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, cached.Metadata.CacheHit)
+	require.Equal(t, ServedByCache, cached.Metadata.ServedBy)
+	require.Equal(t, "llama3", cached.Metadata.ModelUsed, "the model that originally generated the result is kept")
+
+	generator.setDefaultClient("openai", client)
+	switched, err := generator.Generate(context.Background(), "count users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "openai", switched.Metadata.ServedBy)
+}
+
+func TestGenerateCacheKeyIncludesSchemaVersion(t *testing.T) {
+	calls := 0
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Cache: config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	})
+	require.NoError(t, err)
+
+	generate := func(version string) *GenerationResult {
+		options := defaultGenerateOptions()
+		options.Schema = map[string]Table{"users": {Name: "users", Columns: []Column{{Name: "id", Type: "INT"}}}}
+		options.SchemaVersion = version
+		result, err := generator.Generate(context.Background(), "show users", options)
+		require.NoError(t, err)
+		return result
+	}
+
+	require.False(t, generate("v1").Metadata.CacheHit)
+	require.True(t, generate("v1").Metadata.CacheHit)
+	require.False(t, generate("v2").Metadata.CacheHit, "a new schema version misses even with identical schema content")
+	require.True(t, generate("v1").Metadata.CacheHit, "entries for other versions are kept")
+	require.Equal(t, 2, calls)
+	require.Equal(t, 2, generator.cache.len())
+}
+
+func TestGenerateCacheKeySeparatesConfirmationAndCredentials(t *testing.T) {
+	calls := 0
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:UPDATE users SET active = 0 WHERE id = 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Cache: config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	})
+	require.NoError(t, err)
+
+	generate := func(confirm bool) *GenerationResult {
+		options := defaultGenerateOptions()
+		options.RequireConfirmForWrites = confirm
+		result, err := generator.Generate(context.Background(), "deactivate user 1", options)
+		require.NoError(t, err)
+		return result
+	}
+
+	require.False(t, generate(false).NeedsConfirmation)
+	require.True(t, generate(false).Metadata.CacheHit)
+	held := generate(true)
+	require.False(t, held.Metadata.CacheHit, "a cached unheld write is not served to a caller requiring confirmation")
+	require.True(t, held.NeedsConfirmation)
+	require.NotEmpty(t, held.ConfirmationToken)
+	require.Equal(t, 2, calls)
+
+	options := defaultGenerateOptions()
+	options.Provider = "openai"
+	options.APIKey = "sk-caller-one"
+	first := generationKey("show users", "openai", options)
+	require.Equal(t, first, generationKey("show users", "openai", options))
+	options.APIKey = "sk-caller-two"
+	require.NotEqual(t, first, generationKey("show users", "openai", options), "callers with different keys do not share results")
+	require.NotContains(t, first, "sk-caller")
+}
+
+func TestCachedFullTableWriteIsNotServedWithoutAcknowledgement(t *testing.T) {
+	calls := 0
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:DELETE FROM sessions;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Cache: config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	})
+	require.NoError(t, err)
+
+	generate := func(acknowledge bool) *GenerationResult {
+		options := defaultGenerateOptions()
+		options.AcknowledgeFullTableWrite = acknowledge
+		result, err := generator.Generate(context.Background(), "clear all sessions", options)
+		require.NoError(t, err)
+		return result
+	}
+
+	require.False(t, generate(true).Blocked)
+	require.True(t, generate(true).Metadata.CacheHit)
+	blocked := generate(false)
+	require.False(t, blocked.Metadata.CacheHit)
+	require.True(t, blocked.Blocked, "safety mode still blocks the write without the acknowledgement")
+	require.Equal(t, 2, calls)
+}
+
+func TestGenerateCacheKeyFollowsDefaultProvider(t *testing.T) {
+	answer := func(sql string, calls *int) *stubAIClient {
+		return &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			*calls++
+			return &interfaces.GenerateResponse{Text: "sql:" + sql}, nil
+		}}
+	}
+	var firstCalls, secondCalls int
+	generator, err := NewSQLGenerator(answer("SELECT * FROM users;", &firstCalls), config.AIConfig{
+		DefaultService: "ollama",
+		Cache:          config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	})
+	require.NoError(t, err)
+
+	generate := func() *GenerationResult {
+		result, err := generator.Generate(context.Background(), "show users", defaultGenerateOptions())
+		require.NoError(t, err)
+		return result
+	}
+
+	require.Equal(t, "SELECT * FROM users;", generate().SQL)
+	require.True(t, generate().Metadata.CacheHit)
+
+	generator.setDefaultClient("openai", answer("SELECT id FROM users;", &secondCalls))
+	result := generate()
+	require.False(t, result.Metadata.CacheHit, "results of the previous default provider are not served")
+	require.Equal(t, "SELECT id FROM users;", result.SQL)
+	require.Equal(t, 1, firstCalls)
+	require.Equal(t, 1, secondCalls)
+}
+
+// testResultCacheConformance checks the result cache behaves the same on every storage backend
+func testResultCacheConformance(t *testing.T, backend storage.Backend) {
+	t.Helper()
+	now := time.Now()
+	cacheConfig := config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 2}
+	cache := newResultCache(cacheConfig, backend, "v1")
+	cache.now = func() time.Time { return now }
+
+	_, ok := cache.get("missing")
+	require.False(t, ok)
+
+	stored := &GenerationResult{SQL: "SELECT 1;", Warnings: []string{"w"}, Metadata: GenerationMetadata{ModelUsed: "llama3"}}
+	cache.put("a", stored)
+	stored.Warnings[0] = "mutated"
+	cached, ok := cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, "SELECT 1;", cached.SQL)
+	require.Equal(t, []string{"w"}, cached.Warnings, "the cache keeps its own copy")
+	require.Equal(t, "llama3", cached.Metadata.ModelUsed)
+
+	cache.put("b", &GenerationResult{SQL: "SELECT 2;"})
+	_, _ = cache.get("a")
+	cache.put("c", &GenerationResult{SQL: "SELECT 3;"})
+	_, ok = cache.get("b")
+	require.False(t, ok, "the least recently used entry is evicted")
+	require.Equal(t, 2, cache.len())
+	_, stillStored, err := backend.Get(cache.prefix + "b")
+	require.NoError(t, err)
+	require.False(t, stillStored, "evicted entries are deleted from the backend")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("a")
+	require.False(t, ok, "expired entries miss")
+	require.Equal(t, 1, cache.len())
+
+	now = time.Now()
+	cache.put("d", &GenerationResult{SQL: "SELECT 4;"})
+	cacheConfig.MaxEntries = 1
+	reopened := newResultCache(cacheConfig, backend, "v1")
+	require.Equal(t, 1, reopened.len(), "entries already in the backend count towards max_entries")
+	keys, err := backend.Keys(resultCacheKeyPrefix)
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "entries beyond max_entries are deleted from the backend")
+	_, ok = reopened.get("d")
+	require.True(t, ok, "the most recently written entry is kept")
+
+	newResultCache(cacheConfig, backend, "v2")
+	keys, err = backend.Keys(resultCacheKeyPrefix)
+	require.NoError(t, err)
+	require.Empty(t, keys, "entries of another configuration are deleted")
+}
+
+func TestResultCacheConformance(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testResultCacheConformance(t, storage.NewMemoryBackend())
+	})
+	t.Run("file", func(t *testing.T) {
+		backend, err := storage.NewFileBackend(t.TempDir())
+		require.NoError(t, err)
+		testResultCacheConformance(t, backend)
+	})
+}
+
+func TestFileStoragePersistsCacheAndAudit(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users WHERE id = 42;"}, nil
+	}}
+	cfg := config.AIConfig{
+		Cache:   config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+		Audit:   config.AuditConfig{Enabled: true, Anonymize: true},
+		Storage: config.StorageConfig{Backend: "file", Path: dir},
+	}
+
+	first, err := NewSQLGenerator(client, cfg)
+	require.NoError(t, err)
+	_, err = first.Generate(context.Background(), "show user 42", defaultGenerateOptions())
+	require.NoError(t, err)
+
+	restarted, err := NewSQLGenerator(client, cfg)
+	require.NoError(t, err)
+	cached, err := restarted.Generate(context.Background(), "show user 42", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, cached.Metadata.CacheHit, "a new generator on the same directory reuses persisted results")
+	require.Equal(t, 1, calls)
+
+	audit, err := os.ReadFile(filepath.Join(dir, "audit.jsonl"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(audit)), "\n")
+	require.Len(t, lines, 1, "cache hits are not audited again")
+	var record auditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.NotContains(t, record.SQL, "42", "audit records are anonymized like the log")
+	require.NotEmpty(t, record.RequestID)
+
+	cfg.Cache.Normalize = true
+	require.NoError(t, restarted.UpdateConfig(cfg))
+	regenerated, err := restarted.Generate(context.Background(), "show user 42", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.False(t, regenerated.Metadata.CacheHit, "a config change resets the persisted cache")
+	require.Equal(t, 2, calls)
+	keys, err := restarted.storage.Keys(resultCacheKeyPrefix)
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "entries of the previous configuration are deleted")
+
+	cfg.Storage.Backend = "redis"
+	require.Error(t, restarted.UpdateConfig(cfg))
+}
+
+func TestHooksRewritePromptAndEditSQL(t *testing.T) {
+	var prompts []string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		prompts = append(prompts, req.Prompt)
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;"}, nil
+	}}
+	callerOptions := defaultGenerateOptions()
+	generator, err := NewSQLGenerator(client, config.AIConfig{}, WithHooks(Hooks{
+		BeforeGenerate: func(_ context.Context, options *GenerateOptions, prompt *string) (*GenerationResult, error) {
+			require.NotSame(t, callerOptions, options, "hooks edit a copy of the options")
+			options.MaxTokens = 321
+			*prompt += "\nAlways qualify tables with the tenant schema."
+			return nil, nil
+		},
+		AfterGenerate: func(_ context.Context, result *GenerationResult) error {
+			result.SQL = strings.Replace(result.SQL, "FROM users", "FROM tenant.users", 1)
+			return nil
+		},
+	}))
+	require.NoError(t, err)
+
+	result, err := generator.Generate(context.Background(), "show users", callerOptions)
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	require.Contains(t, prompts[0], "Always qualify tables with the tenant schema.")
+	require.Equal(t, "SELECT * FROM tenant.users;", result.SQL)
+	require.Equal(t, 2000, callerOptions.MaxTokens)
+
+	regenerated, err := generator.Regenerate(context.Background(), result, "only active users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Len(t, prompts, 2)
+	require.Contains(t, prompts[1], "Always qualify tables with the tenant schema.", "hooks also run for regenerations")
+	require.Equal(t, "SELECT * FROM tenant.users;", regenerated.SQL)
+}
+
+func TestHooksShortCircuitAndVeto(t *testing.T) {
+	calls := 0
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:DELETE FROM users WHERE id = 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Cache: config.CacheConfig{Enabled: true, TTL: config.Duration{Duration: time.Minute}, MaxEntries: 10},
+	}, WithHooks(Hooks{
+		BeforeGenerate: func(_ context.Context, _ *GenerateOptions, prompt *string) (*GenerationResult, error) {
+			switch {
+			case strings.Contains(*prompt, "canned"):
+				return &GenerationResult{SQL: "SELECT 1;"}, nil
+			case strings.Contains(*prompt, "forbidden"):
+				return nil, errors.New("topic not allowed")
+			}
+			return nil, nil
+		},
+		AfterGenerate: func(_ context.Context, result *GenerationResult) error {
+			if strings.HasPrefix(result.SQL, "DELETE") {
+				return errors.New("deletes are not allowed")
+			}
+			return nil
+		},
+	}))
+	require.NoError(t, err)
+
+	canned, err := generator.Generate(context.Background(), "canned answer", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 1;", canned.SQL)
+	require.Equal(t, ServedByHook, canned.Metadata.ServedBy)
+	require.NotEmpty(t, canned.Metadata.RequestID)
+	require.Zero(t, calls, "a short-circuited generation never reaches the model")
+	require.Zero(t, generator.cache.len(), "short-circuited results are not cached")
+
+	_, err = generator.Generate(context.Background(), "forbidden topic", defaultGenerateOptions())
+	require.ErrorIs(t, err, ErrGenerationRejected)
+	require.ErrorContains(t, err, "topic not allowed")
+	require.Zero(t, calls)
+
+	_, err = generator.Generate(context.Background(), "remove user 1", defaultGenerateOptions())
+	require.ErrorIs(t, err, ErrGenerationRejected)
+	require.ErrorContains(t, err, "deletes are not allowed")
+	require.Equal(t, 1, calls)
+	require.Zero(t, generator.cache.len(), "vetoed results are not cached")
+}
+
+func TestNormalizePrompt(t *testing.T) {
+	require.Equal(t, "show all users", normalizePrompt("  Show\tALL\n users?! "))
+	require.Equal(t, "", normalizePrompt("..."))
+}
+
+func TestCleanSQLTextHandlesFencesAndCommentary(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "lowercase fence", input: "```sql\nSELECT * FROM users;\n```", expected: "SELECT * FROM users;"},
+		{name: "uppercase fence", input: "```SQL\nSELECT * FROM users;\n```", expected: "SELECT * FROM users;"},
+		{name: "dialect fence", input: "```PostgreSQL\nSELECT 1;\n```", expected: "SELECT 1;"},
+		{name: "no language fence with prose", input: "Here is the query:\n```\nSELECT id FROM orders;\n```\nThis returns all order ids.", expected: "SELECT id FROM orders;"},
+		{name: "prefers sql block", input: "```json\n{\"a\":1}\n```\n```sql\nSELECT 2;\n```", expected: "SELECT 2;"},
+		{name: "trailing prose after semicolon", input: "SELECT name FROM users WHERE note = 'a;b'; This query lists names.", expected: "SELECT name FROM users WHERE note = 'a;b';"},
+		{name: "trailing statement without semicolon", input: "SET search_path = app; SELECT * FROM users", expected: "SET search_path = app; SELECT * FROM users"},
+		{name: "trailing commit", input: "BEGIN; UPDATE users SET active = 1 WHERE id = 2; COMMIT", expected: "BEGIN; UPDATE users SET active = 1 WHERE id = 2; COMMIT"},
+		{name: "trailing comment", input: "SELECT 1; -- lists nothing", expected: "SELECT 1;"},
+		{name: "single line fence", input: "```sql SELECT 1```", expected: "SELECT 1"},
+		{name: "unterminated fence", input: "```sql\nSELECT 1;", expected: "SELECT 1;"},
+		{name: "plain statement", input: "SELECT 1", expected: "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, cleanSQLText(tt.input))
+		})
+	}
+}
+
+func TestExtractSQLFromResponseStripsMixedCaseFence(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+
+	result := generator.extractSQLFromResponse("sql:```SQL\nSELECT * FROM users;\n```\nexplanation: lists users", "")
+	require.Equal(t, "SELECT * FROM users;", result.SQL)
+	require.Equal(t, "lists users", result.Explanation)
+}
+
+func TestExtractSQLFromResponseStyles(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		style       string
+		response    string
+		sql         string
+		explanation string
+	}{
+		{
+			name:        "markdown",
+			style:       ResponseStyleMarkdown,
+			response:    "Here's your query:\n```sql\nSELECT id FROM users;\n```\nThis lists every user id.",
+			sql:         "SELECT id FROM users;",
+			explanation: "This lists every user id.",
+		},
+		{
+			name:        "prefixed inline",
+			style:       ResponseStylePrefixed,
+			response:    "Here's your query: SELECT name FROM users WHERE active = 1; It returns active users.",
+			sql:         "SELECT name FROM users WHERE active = 1;",
+			explanation: "It returns active users.",
+		},
+		{
+			name:        "prefixed on its own line",
+			style:       ResponseStylePrefixed,
+			response:    "Sure! Below is the query you asked for\n\nWITH recent AS (SELECT * FROM orders) SELECT COUNT(*) FROM recent;",
+			sql:         "WITH recent AS (SELECT * FROM orders) SELECT COUNT(*) FROM recent;",
+			explanation: "Generated SQL query based on natural language input",
+		},
+		{
+			name:        "plain",
+			style:       ResponseStylePlain,
+			response:    "SELECT COUNT(*) FROM orders;\nCounts orders.",
+			sql:         "SELECT COUNT(*) FROM orders;",
+			explanation: "Counts orders.",
+		},
+		{
+			name:        "style mismatch falls back to best effort",
+			style:       ResponseStyleMarkdown,
+			response:    "sql:SELECT 1;\nexplanation: a constant",
+			sql:         "SELECT 1;",
+			explanation: "a constant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := generator.extractSQLFromResponse(tt.response, tt.style)
+			require.Equal(t, tt.sql, result.SQL)
+			require.Equal(t, tt.explanation, result.Explanation)
+		})
+	}
+}
+
+func TestResponseParsers(t *testing.T) {
+	tests := []struct {
+		name        string
+		parser      ResponseParser
+		response    string
+		sql         string
+		explanation string
+		ok          bool
+	}{
+		{name: "simple", parser: SimpleFormatParser{}, response: "sql:SELECT 1;\nexplanation: a constant", sql: "SELECT 1;", explanation: "a constant", ok: true},
+		{name: "simple with space separator", parser: SimpleFormatParser{}, response: "sql: SELECT 1; explanation: a constant", sql: "SELECT 1;", explanation: "a constant", ok: true},
+		{name: "simple rejects other formats", parser: SimpleFormatParser{}, response: "SELECT 1;"},
+		{name: "json", parser: JSONParser{}, response: `{"sql": "SELECT id FROM users;", "explanation": "user ids"}`, sql: "SELECT id FROM users;", explanation: "user ids", ok: true},
+		{name: "json without sql", parser: JSONParser{}, response: `{"explanation": "nothing"}`},
+		{name: "json rejects malformed objects", parser: JSONParser{}, response: `{"sql": }`},
+		{name: "fenced code", parser: FencedCodeParser{}, response: "Here you go:\n```sql\nSELECT id FROM users;\n```\nLists users.", sql: "SELECT id FROM users;", explanation: "Lists users.", ok: true},
+		{name: "fenced code without a fence", parser: FencedCodeParser{}, response: "SELECT id FROM users;"},
+		{name: "prefixed", parser: PrefixedParser{}, response: "Here's your query: SELECT 1; Done.", sql: "SELECT 1;", explanation: "Done.", ok: true},
+		{name: "prefixed without a statement", parser: PrefixedParser{}, response: "I cannot answer that."},
+		{name: "plain text", parser: PlainTextParser{}, response: "SELECT COUNT(*) FROM orders;\nCounts orders.", sql: "SELECT COUNT(*) FROM orders;", explanation: "Counts orders.", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, explanation, ok := tt.parser.Parse(tt.response)
+			require.Equal(t, tt.ok, ok)
+			if !tt.ok {
+				return
+			}
+			require.Equal(t, tt.sql, sql)
+			require.Equal(t, tt.explanation, explanation)
+		})
+	}
+
+	for style, parser := range responseParsers {
+		require.Equal(t, style, parser.Name())
+	}
+}
+
+func TestResponseParserChain(t *testing.T) {
+	require.Equal(t, DefaultResponseParsers, responseParserChain(""))
+
+	chain := responseParserChain(ResponseStylePrefixed)
+	require.Len(t, chain, len(DefaultResponseParsers)+1)
+	require.Equal(t, ResponseStylePrefixed, chain[0].Name())
+
+	// The simple format wins over the JSON-looking explanation, JSON over fenced code, and so on
+	sql, explanation, ok := parseResponse(DefaultResponseParsers, "sql:SELECT 1;\nexplanation: {\"sql\": \"SELECT 2\"}")
+	require.True(t, ok)
+	require.Equal(t, "SELECT 1;", sql)
+	require.Equal(t, `{"sql": "SELECT 2"}`, explanation)
+
+	sql, _, ok = parseResponse(DefaultResponseParsers, `{"sql": "SELECT 2;"}`)
+	require.True(t, ok)
+	require.Equal(t, "SELECT 2;", sql)
+
+	sql, _, ok = parseResponse(DefaultResponseParsers, "```sql\nSELECT 3;\n```")
+	require.True(t, ok)
+	require.Equal(t, "SELECT 3;", sql)
+
+	_, _, ok = parseResponse(DefaultResponseParsers, "   ")
+	require.False(t, ok)
+
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+	require.Equal(t, placeholderSQL, generator.extractSQLFromResponse("", "").SQL)
+}
+
+func TestGenerateUsesServiceResponseStyle(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "Here's your query: SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		DefaultService: "openai",
+		Services:       map[string]config.AIService{"openai": {ResponseStyle: "prefixed"}},
+	})
+	require.NoError(t, err)
+
+	result, err := generator.Generate(context.Background(), "list user ids", nil)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM users;", result.SQL)
+}
+
+func TestGenerateLogsFullResponseWhenEnabled(t *testing.T) {
+	var logs bytes.Buffer
+	previous := logging.Logger
+	logging.Logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	t.Cleanup(func() { logging.Logger = previous })
+
+	explanation := strings.Repeat("Selects every active user ordered by signup date. ", 4)
+	response := "sql:SELECT * FROM users WHERE active = 1;\nexplanation:" + explanation + "token sk-abcdefghijklmnopqrstuvwx"
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: response, Metadata: map[string]any{"finish_reason": "stop", "total_tokens": 42}}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Debug: config.DebugConfig{LogFullResponse: true, MaxResponseLogLength: 4096},
+	})
+	require.NoError(t, err)
+
+	_, err = generator.Generate(context.Background(), "list active users", defaultGenerateOptions())
+	require.NoError(t, err)
+
+	output := logs.String()
+	require.Contains(t, output, explanation)
+	require.Contains(t, output, `"finish_reason":"stop"`)
+	require.Contains(t, output, `"total_tokens":42`)
+	require.NotContains(t, output, "sk-abcdefghijklmnopqrstuvwx")
+}
+
+func TestGenerateMasksPIIInExplanation(t *testing.T) {
+	explanations := map[string]string{
+		"orders for jane": "Filters orders placed by jane.doe@example.com",
+		"count orders":    "Counts all orders",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, explanation := range explanations {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM orders WHERE email = 'jane.doe@example.com';\nexplanation:" + explanation}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{PII: config.PIIConfig{Mask: true}})
+	require.NoError(t, err)
+
+	masked, err := generator.Generate(context.Background(), "orders for jane", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "Filters orders placed by [REDACTED]", masked.Explanation)
+	require.Contains(t, masked.SQL, "jane.doe@example.com", "SQL is never masked")
+
+	clean, err := generator.Generate(context.Background(), "count orders", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "Counts all orders", clean.Explanation)
+}
+
+func TestFullTableWriteBlockedInSafetyMode(t *testing.T) {
+	responses := map[string]string{
+		"deactivate everyone":   "sql:UPDATE users SET active = 0;",
+		"deactivate stale":      "sql:UPDATE users SET active = 0 WHERE last_login < (SELECT NOW() - INTERVAL 1 YEAR);",
+		"delete from subselect": "sql:DELETE FROM users WHERE id IN (SELECT user_id FROM bans);",
+		"purge through cte":     "sql:WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone;",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, text := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: text}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	blocked, err := generator.Generate(context.Background(), "deactivate everyone", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, blocked.Blocked)
+	require.Contains(t, blocked.Suggestions, "Add a WHERE clause to limit the rows affected by this UPDATE")
+	require.NotEmpty(t, blocked.ValidationResults)
+	require.Equal(t, "safety", blocked.ValidationResults[len(blocked.ValidationResults)-1].Type)
+	require.ErrorIs(t, blockedGenerationError(blocked), ErrFullTableWrite)
+
+	blocked, err = generator.Generate(context.Background(), "purge through cte", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, blocked.Blocked, "a data-modifying CTE is checked like a top-level write")
+	require.ErrorIs(t, blockedGenerationError(blocked), ErrFullTableWrite)
+
+	options := defaultGenerateOptions()
+	options.AcknowledgeFullTableWrite = true
+	acknowledged, err := generator.Generate(context.Background(), "deactivate everyone", options)
+	require.NoError(t, err)
+	require.False(t, acknowledged.Blocked)
+	require.NotEmpty(t, acknowledged.Suggestions, "the suggestion is kept when acknowledged")
+
+	for _, nl := range []string{"deactivate stale", "delete from subselect"} {
+		bounded, err := generator.Generate(context.Background(), nl, defaultGenerateOptions())
+		require.NoError(t, err)
+		require.False(t, bounded.Blocked, nl)
+	}
+}
+
+func TestUnboundedWriteStatement(t *testing.T) {
+	kind, ok := unboundedWriteStatement("DELETE FROM users")
+	require.True(t, ok)
+	require.Equal(t, "DELETE", kind)
+
+	_, ok = unboundedWriteStatement("UPDATE users SET name = (SELECT name FROM archive WHERE archive.id = users.id)")
+	require.True(t, ok, "a WHERE inside a subquery does not bound the update")
+
+	_, ok = unboundedWriteStatement("SELECT * FROM users; DELETE FROM sessions WHERE expired = 1")
+	require.False(t, ok)
+
+	kind, ok = unboundedWriteStatement("WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone")
+	require.True(t, ok, "a full-table write inside a CTE is unbounded")
+	require.Equal(t, "DELETE", kind)
+
+	kind, ok = unboundedWriteStatement("WITH ids AS (SELECT id FROM archive WHERE old) UPDATE users SET active = false")
+	require.True(t, ok, "a WHERE in another CTE does not bound the main statement")
+	require.Equal(t, "UPDATE", kind)
+
+	_, ok = unboundedWriteStatement("WITH gone AS (DELETE FROM users WHERE id = 1 RETURNING *) SELECT * FROM gone WHERE id > 0")
+	require.False(t, ok)
+}
+
+func TestReadOnlyModeBlocksWrites(t *testing.T) {
+	responses := map[string]string{
+		"archive orders": "sql:INSERT INTO archive SELECT * FROM orders WHERE created_at < '2020-01-01';",
+		"purge sessions": "sql:WITH stale AS (DELETE FROM sessions WHERE expired = 1 RETURNING id) SELECT count(*) FROM stale;",
+		"count orders":   "sql:WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent;",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, text := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: text}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{ReadOnly: true})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.SafetyMode = false
+	options.AcknowledgeFullTableWrite = true
+	for _, nl := range []string{"archive orders", "purge sessions"} {
+		blocked, err := generator.Generate(context.Background(), nl, options)
+		require.NoError(t, err)
+		require.True(t, blocked.Blocked, "per-request options cannot lift read-only mode: %s", nl)
+		require.ErrorIs(t, blockedGenerationError(blocked), ErrReadOnlyViolation)
+	}
+
+	allowed, err := generator.Generate(context.Background(), "count orders", options)
+	require.NoError(t, err)
+	require.False(t, allowed.Blocked)
+}
+
+func TestNonReadStatement(t *testing.T) {
+	for _, sql := range []string{
+		"WITH t AS (SELECT REPLACE(name, 'a', 'b') AS n FROM users) SELECT * FROM t",
+		"(SELECT 1) UNION (SELECT 2)",
+		"EXPLAIN SELECT * FROM users",
+		"EXPLAIN (FORMAT JSON) SELECT * FROM users",
+		"EXPLAIN QUERY PLAN SELECT * FROM users",
+		"WITH RECURSIVE t(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < 5) SELECT * FROM t",
+		"SELECT * FROM users WHERE name = 'DELETE'",
+	} {
+		kind, ok := nonReadStatement(sql)
+		require.False(t, ok, "%s was rejected as %s", sql, kind)
+	}
+
+	for sql, want := range map[string]string{
+		"WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone":       "DELETE",
+		"WITH t AS MATERIALIZED (UPDATE users SET a = 1 RETURNING id) SELECT 1": "UPDATE",
+		"WITH t AS (SELECT id FROM users) DELETE FROM sessions WHERE id IN t":   "DELETE",
+		"EXPLAIN ANALYZE DELETE FROM users":                                     "DELETE",
+		"SELECT 1; DROP TABLE users":                                            "DROP",
+		"(INSERT INTO users VALUES (1))":                                        "INSERT",
+	} {
+		kind, ok := nonReadStatement(sql)
+		require.True(t, ok, sql)
+		require.Equal(t, want, kind, sql)
+	}
+}
+
+func TestUnsupportedDialectError(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.DatabaseType = "postgre"
+	_, err = generator.Generate(context.Background(), "list users", options)
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+
+	var dialectErr *UnsupportedDialectError
+	require.ErrorAs(t, err, &dialectErr)
+	require.Equal(t, "postgre", dialectErr.Requested)
+	require.Equal(t, []string{"mysql", "postgresql", "sqlite", "snowflake"}, dialectErr.Supported)
+	require.Equal(t, "postgresql", dialectErr.Suggestion)
+	require.Contains(t, err.Error(), `did you mean "postgresql"?`)
+
+	for requested, want := range map[string]string{
+		"pg":         "postgresql",
+		"MariaDB":    "mysql",
+		"mysl":       "mysql",
+		"sqlite3":    "sqlite",
+		"clickhouse": "",
+	} {
+		require.Equal(t, want, suggestDialect(requested), requested)
+	}
+}
+
+func TestPromptCacheMovesSchemaIntoSystemPrompt(t *testing.T) {
+	var requests []*interfaces.GenerateRequest
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		requests = append(requests, req)
+		return &interfaces.GenerateResponse{Text: "sql:SELECT COUNT(*) FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{PromptCache: config.PromptCacheConfig{Enabled: true}})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.Schema = map[string]Table{
+		"users":  {Columns: []Column{{Name: "id", Type: "INT"}}},
+		"orders": {Columns: []Column{{Name: "user_id", Type: "INT"}}},
+	}
+	for _, nl := range []string{"count users", "count orders"} {
+		_, err := generator.Generate(context.Background(), nl, options)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, requests, 2)
+	for _, req := range requests {
+		require.True(t, req.CacheSystemPrompt)
+		require.Contains(t, req.SystemPrompt, "Table: orders\n  - user_id INT NOT NULL\n\nTable: users")
+		require.NotContains(t, req.Prompt, "Database Schema:")
+	}
+	require.Equal(t, requests[0].SystemPrompt, requests[1].SystemPrompt, "repeated schemas share the same prefix")
+}
+
+func TestGenerateResolvesModelAliases(t *testing.T) {
+	var requested string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		requested = req.Model
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;", Model: req.Model}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		ModelAliases: map[string]string{"Fast": "llama3.2:1b", "gpt4": "gpt-4-turbo"},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"claude":        "claude-3-sonnet-20240229",
+		"fast":          "llama3.2:1b",
+		"gpt4":          "gpt-4-turbo",
+		"qwen2.5-coder": "qwen2.5-coder",
+	}
+	for model, expected := range tests {
+		options := defaultGenerateOptions()
+		options.Model = model
+		_, err := generator.Generate(context.Background(), "list users for "+model, options)
+		require.NoError(t, err)
+		require.Equal(t, expected, requested, model)
+		require.Equal(t, model, options.Model, "caller options are not modified")
+	}
+}
+
+func TestGenerateEnforcesServiceModelAllowlist(t *testing.T) {
+	var requested []string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		requested = append(requested, req.Model)
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;", Model: req.Model}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		DefaultService: "openai",
+		Services: map[string]config.AIService{
+			"openai": {Model: "gpt-4o", Models: []string{"gpt-4o-mini", "gpt-4"}},
+			"ollama": {Model: "llama3.2"},
+		},
+	})
+	require.NoError(t, err)
+
+	generate := func(provider, model string) error {
+		options := defaultGenerateOptions()
+		options.Provider = provider
+		options.Model = model
+		_, err := generator.Generate(context.Background(), "list users with "+provider+" "+model, options)
+		return err
+	}
+
+	err = generate("", "gpt-4.5-preview")
+	require.ErrorIs(t, err, ErrModelNotAllowed)
+	require.Contains(t, err.Error(), `"gpt-4.5-preview"`)
+	require.Empty(t, requested, "an off-list model never reaches the provider")
+
+	// Aliases resolve before the check, so an alias cannot smuggle in an off-list model
+	require.ErrorIs(t, generate("openai", "gpt35"), ErrModelNotAllowed)
+
+	require.NoError(t, generate("", "gpt-4o-mini"))
+	require.NoError(t, generate("openai", "gpt4"), "alias resolving to a listed model")
+	require.NoError(t, generate("openai", "gpt-4o"), "the service's own model")
+	require.NoError(t, generate("", ""), "the default model")
+	require.ErrorIs(t, generate("ollama", "qwen2.5-coder"), ErrModelNotAllowed, "a provider without an API key still runs on the default service")
+	require.Equal(t, []string{"gpt-4o-mini", "gpt-4", "gpt-4o", ""}, requested)
+
+	_, err = generator.Regenerate(context.Background(), &GenerationResult{SQL: "SELECT 1;"}, "use users", &GenerateOptions{DatabaseType: "mysql", Model: "o1"})
+	require.ErrorIs(t, err, ErrModelNotAllowed)
+
+	// An API key without a provider still runs on the configured default client
+	options := defaultGenerateOptions()
+	options.APIKey = "sk-caller"
+	options.Model = "o1"
+	_, err = generator.Generate(context.Background(), "list users with a caller key", options)
+	require.ErrorIs(t, err, ErrModelNotAllowed)
+
+	// A runtime client built from the caller's provider and key is not governed by the allowlist
+	options.Provider = "openai"
+	require.NoError(t, generator.checkModelAllowed(options))
+
+	// A confidence fallback is checked against the fallback service's own list
+	fallback := &GenerateOptions{Provider: "ollama", Model: "o1", client: client}
+	require.NoError(t, generator.checkModelAllowed(fallback), "empty allowlist")
+	require.NoError(t, generator.UpdateConfig(config.AIConfig{
+		DefaultService: "openai",
+		Services:       map[string]config.AIService{"ollama": {Model: "llama3.2", Models: []string{"qwen2.5-coder"}}},
+	}))
+	require.ErrorIs(t, generator.checkModelAllowed(fallback), ErrModelNotAllowed)
+	fallback.Model = "qwen2.5-coder"
+	require.NoError(t, generator.checkModelAllowed(fallback))
+}
+
+func TestGenerateRejectsEmptyAndOverlongQueries(t *testing.T) {
+	calls := 0
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls++
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{Limits: config.InputLimits{MaxPromptBytes: 16}})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		query    string
+		expected error
+	}{
+		"empty":           {query: "", expected: ErrEmptyQuery},
+		"whitespace":      {query: "   ", expected: ErrEmptyQuery},
+		"control":         {query: "\t\x00\n", expected: ErrEmptyQuery},
+		"over max length": {query: strings.Repeat("list users ", 4), expected: ErrQueryTooLong},
+	}
+	for name, tt := range tests {
+		_, err := generator.Generate(context.Background(), tt.query, defaultGenerateOptions())
+		require.ErrorIs(t, err, tt.expected, name)
+	}
+	require.Zero(t, calls, "rejected queries never reach the model")
+
+	_, err = generator.Generate(context.Background(), "  list users \n", defaultGenerateOptions())
+	require.NoError(t, err, "surrounding whitespace does not count towards the limit")
+}
+
+func TestCustomDialectInheritsBaseDialect(t *testing.T) {
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT region, `order` FROM users LIMIT 10"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{CustomDialects: map[string]config.CustomDialectConfig{
+		"TiDB": {Base: "mysql", Keywords: []string{"region"}, DataTypes: []string{"vector"}, Functions: []string{"tidb_version"}},
+	}})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.DatabaseType = "tidb"
+	result, err := generator.Generate(context.Background(), "list user regions", options)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, validation := range result.ValidationResults {
+		messages = append(messages, validation.Message)
+	}
+	require.Contains(t, messages, "'region' is a reserved keyword in TiDB and is used as an identifier")
+	require.Contains(t, messages, "SQL statement should end with semicolon", "base MySQL checks still run")
+
+	dialect := generator.sqlDialects["tidb"]
+	require.Contains(t, dialect.GetKeywords(), "SELECT")
+	require.Equal(t, "VECTOR", dialect.GetDataTypes()[len(dialect.GetDataTypes())-1].Name)
+	transformed, err := dialect.TransformSQL("SELECT `name` FROM users LIMIT 5, 10", "postgresql")
+	require.NoError(t, err)
+	require.Contains(t, transformed, "LIMIT 10 OFFSET 5")
+
+	_, err = NewSQLGenerator(client, config.AIConfig{CustomDialects: map[string]config.CustomDialectConfig{
+		"duck": {Base: "duckdb"},
+	}})
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+}
+
+func TestAmbiguousColumnResults(t *testing.T) {
+	schema := map[string]Table{
+		"users":  {Name: "users", Columns: []Column{{Name: "id"}, {Name: "name"}, {Name: "created_at"}}},
+		"orders": {Name: "orders", Columns: []Column{{Name: "id"}, {Name: "user_id"}, {Name: "total"}, {Name: "created_at"}}},
+	}
+
+	results := ambiguousColumnResults("SELECT id, name, total FROM users JOIN orders ON users.id = orders.user_id", schema)
+	require.Len(t, results, 1)
+	require.Equal(t, "semantic", results[0].Type)
+	require.Equal(t, "warning", results[0].Level)
+	require.Equal(t, "column 'id' is ambiguous: it exists in users, orders", results[0].Message)
+	require.Equal(t, "Qualify the column with its table, e.g. users.id", results[0].Suggestion)
+
+	require.Empty(t, ambiguousColumnResults("SELECT users.id, total FROM users JOIN orders ON users.id = orders.user_id", schema))
+	require.Empty(t, ambiguousColumnResults("SELECT id FROM users", schema), "a single table is never ambiguous")
+
+	require.Empty(t, ambiguousColumnResults("SELECT id, name, total FROM users JOIN orders USING (id)", schema),
+		"a USING column is merged into one output column")
+	results = ambiguousColumnResults("SELECT id, created_at FROM users JOIN orders USING (id)", schema)
+	require.Len(t, results, 1)
+	require.Equal(t, "column 'created_at' is ambiguous: it exists in users, orders", results[0].Message)
+}
+
+func TestInvalidUTF8Responses(t *testing.T) {
+	require.Equal(t, "héll...", truncateString("héllo wörld", 4))
+	require.True(t, utf8.ValidString(truncateString(strings.Repeat("数据", 80), 101)))
+
+	responses := map[string]string{
+		"stray byte": "sql:SELECT name FROM users WHERE city = 'K\xf6ln';",
+		"binary":     "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"garbage":    "\xff\xfe\xfd\xfcsql\xfa\xfb",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, text := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: text}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	result, err := generator.Generate(context.Background(), "stray byte", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, utf8.ValidString(result.SQL))
+	require.Contains(t, result.SQL, "K�ln")
+
+	for _, nl := range []string{"binary", "garbage"} {
+		_, err := generator.Generate(context.Background(), nl, defaultGenerateOptions())
+		require.ErrorIs(t, err, ErrInvalidResponseEncoding, nl)
+	}
+}
+
+func TestExampleMemoryInjectsSimilarExamples(t *testing.T) {
+	var prompts []string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		prompts = append(prompts, req.Prompt)
+		if strings.Contains(req.Prompt, "active customers") {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM customers WHERE active = 1;"}, nil
+		}
+		return &interfaces.GenerateResponse{Text: "sql:SELECT name FROM customers;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		ExampleMemory: config.ExampleMemoryConfig{Enabled: true, MinConfidence: 0.5},
+	})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.Schema = map[string]Table{"customers": {Columns: []Column{{Name: "id", Type: "INT"}}}}
+
+	_, err = generator.Generate(context.Background(), "count active customers", options)
+	require.NoError(t, err)
+	require.NotContains(t, prompts[0], "Examples of previous queries")
+
+	_, err = generator.Generate(context.Background(), "names of customers", options)
+	require.NoError(t, err)
+	require.Contains(t, prompts[1], "Query: count active customers\nsql:SELECT id FROM customers WHERE active = 1;")
+
+	otherSchema := defaultGenerateOptions()
+	_, err = generator.Generate(context.Background(), "names of customers again", otherSchema)
+	require.NoError(t, err)
+	require.NotContains(t, prompts[2], "Examples of previous queries", "examples are scoped to their schema")
+}
+
+func TestExampleMemoryRespectsSizeCapAndTTL(t *testing.T) {
+	memory := newExampleMemory(config.ExampleMemoryConfig{
+		Enabled:       true,
+		MaxEntries:    2,
+		MaxExamples:   5,
+		MinConfidence: 0.5,
+		TTL:           config.Duration{Duration: time.Minute},
+	})
+	now := time.Now()
+	memory.now = func() time.Time { return now }
+
+	options := defaultGenerateOptions()
+	for _, prompt := range []string{"orders by customer", "orders by region", "orders by month"} {
+		memory.remember(prompt, options, &GenerationResult{SQL: "SELECT 1", ConfidenceScore: 0.9})
+	}
+	memory.remember("orders by week", options, &GenerationResult{SQL: "SELECT 1", ConfidenceScore: 0.1})
+	require.Equal(t, 2, memory.len())
+
+	examples := memory.similar("orders", options)
+	require.Len(t, examples, 2)
+	require.Equal(t, "orders by month", examples[0].prompt)
+	require.Equal(t, "orders by region", examples[1].prompt)
+
+	now = now.Add(2 * time.Minute)
+	require.Empty(t, memory.similar("orders", options))
+	require.Equal(t, 0, memory.len())
+}
+
+func TestGenerateIncludesRenderedPromptOnlyWhenRequested(t *testing.T) {
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		Cache:    config.CacheConfig{Enabled: true, MaxEntries: 10, TTL: config.Duration{Duration: time.Minute}},
+		Services: map[string]config.AIService{"openai": {APIKey: "configured-secret-value"}},
+	})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.Context = []string{"token: configured-secret-value", "api_key=abc123", "Authorization: Bearer eyJhbGciOi.payload"}
+
+	result, err := generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.Nil(t, result.RenderedPrompt)
+
+	options.IncludePrompt = true
+	result, err = generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.NotNil(t, result.RenderedPrompt, "prompt debugging bypasses the cache")
+	require.Contains(t, result.RenderedPrompt.Prompt, "Natural Language Query:\nlist users")
+	require.NotEmpty(t, result.RenderedPrompt.SystemPrompt)
+	require.Contains(t, result.RenderedPrompt.Prompt, "api_key=[REDACTED]")
+	require.NotContains(t, result.RenderedPrompt.Prompt, "configured-secret-value")
+	require.NotContains(t, result.RenderedPrompt.Prompt, "abc123")
+	require.NotContains(t, result.RenderedPrompt.Prompt, "eyJhbGciOi")
+
+	options.IncludePrompt = false
+	result, err = generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.Nil(t, result.RenderedPrompt, "cached results never carry the prompt")
+}
+
+func TestSchemaBudgetKeepsImportantTables(t *testing.T) {
+	var prompt string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		prompt = req.Prompt
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	columns := []Column{{Name: "id", Type: "INT"}, {Name: "created_at", Type: "TIMESTAMP"}}
+	schema := map[string]Table{
+		"users":     {Columns: columns},
+		"orders":    {Columns: columns, Importance: 10},
+		"audit_log": {Columns: columns, Importance: 1},
+	}
+	options := defaultGenerateOptions()
+	options.Schema = schema
+	options.SchemaTokenBudget = estimateTokens("Database Schema:\n") +
+		estimateTokens(renderTable("users", schema["users"])) +
+		estimateTokens(renderTable("orders", schema["orders"]))
+
+	_, err = generator.Generate(context.Background(), "list the newest users", options)
+	require.NoError(t, err)
+	require.Contains(t, prompt, "Table: users", "tables named in the query are kept first")
+	require.Contains(t, prompt, "Table: orders", "the high-importance table is kept")
+	require.NotContains(t, prompt, "Table: audit_log")
+	require.Len(t, options.Schema, 3, "caller options are not modified")
+
+	options.SchemaTokenBudget = 0
+	_, err = generator.Generate(context.Background(), "list the newest users", options)
+	require.NoError(t, err)
+	require.Contains(t, prompt, "Table: audit_log", "no budget sends the whole schema")
+}
+
+func TestUpdateConfigConcurrentWithGenerate(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+
+	configs := []config.AIConfig{
+		{ReadOnly: true, PromptCache: config.PromptCacheConfig{Enabled: true}},
+		{Cache: config.CacheConfig{Enabled: true, MaxEntries: 10, TTL: config.Duration{Duration: time.Minute}}},
+		{CustomDialects: map[string]config.CustomDialectConfig{"tidb": {Base: "mysql"}}},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := generator.Generate(context.Background(), "list users", nil); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if capabilities := generator.GetCapabilities(); capabilities == nil {
+					errs <- errors.New("capabilities missing")
+				}
+			}
+		}()
+		go func(offset int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := generator.UpdateConfig(configs[(offset+j)%len(configs)]); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, generator.UpdateConfig(config.AIConfig{ReadOnly: true}))
+	err = generator.UpdateConfig(config.AIConfig{CustomDialects: map[string]config.CustomDialectConfig{"duck": {Base: "duckdb"}}})
+	require.ErrorIs(t, err, ErrUnsupportedDialect)
+	require.True(t, generator.currentConfig().ReadOnly, "a rejected configuration keeps the previous one")
+}
+
+func TestExplainValidationRequiresAllowlistedDSN(t *testing.T) {
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		DBValidation: config.DBValidationConfig{AllowedDSNs: []string{"file:allowed.db"}, Drivers: map[string]string{"mysql": "unregistered"}},
+	})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.ValidateAgainstDSN = "file:other.db"
+	result, err := generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	dbResults := validationResultsOfType(result, "db")
+	require.Len(t, dbResults, 1)
+	require.Equal(t, "warning", dbResults[0].Level)
+	require.Contains(t, dbResults[0].Message, "allowed_dsns")
+	require.NotContains(t, dbResults[0].Message, "other.db", "the DSN may carry credentials")
+
+	options.Candidates = 2
+	client.generate = func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;", Alternatives: []string{"sql:SELECT name FROM users;"}}, nil
+	}
+	result, err = generator.Generate(context.Background(), "list user names", options)
+	require.NoError(t, err)
+	require.Len(t, result.Alternatives, 1)
+	require.Len(t, validationResultsOfType(result, "db"), 1)
+	var alternativeDBResults []ValidationResult
+	for _, validation := range result.Alternatives[0].ValidationResults {
+		if validation.Type == "db" {
+			alternativeDBResults = append(alternativeDBResults, validation)
+		}
+	}
+	require.Len(t, alternativeDBResults, 1, "every kept candidate is explained")
+	require.Contains(t, alternativeDBResults[0].Message, "allowed_dsns")
+
+	require.Equal(t, "info", generator.explainValidation(context.Background(), "mysql", "file:allowed.db", "DELETE FROM users")[0].Level)
+	require.Equal(t, "info", generator.explainValidation(context.Background(), "mysql", "file:allowed.db", "SELECT 1; DROP TABLE users")[0].Level)
+
+	unknownDriver := generator.explainValidation(context.Background(), "mysql", "file:allowed.db", "SELECT id FROM users;")
+	require.Len(t, unknownDriver, 1)
+	require.Equal(t, "warning", unknownDriver[0].Level)
+	require.Contains(t, unknownDriver[0].Message, "unregistered")
+}
+
+func validationResultsOfType(result *GenerationResult, resultType string) []ValidationResult {
+	var matched []ValidationResult
+	for _, validation := range result.ValidationResults {
+		if validation.Type == resultType {
+			matched = append(matched, validation)
+		}
+	}
+	return matched
+}
+
+func TestGenerateAppliesTimeoutPrecedence(t *testing.T) {
+	var remaining time.Duration
+	client := &stubAIClient{generate: func(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		remaining = 0
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{
+		DefaultService: "openai",
+		Timeout:        config.Duration{Duration: time.Minute},
+		Services:       map[string]config.AIService{"ollama": {Timeout: config.Duration{Duration: 2 * time.Minute}}},
+	})
+	require.NoError(t, err)
+
+	_, err = generator.Generate(context.Background(), "list users", nil)
+	require.NoError(t, err)
+	require.InDelta(t, time.Minute, remaining, float64(time.Second), "ai.timeout applies without a service timeout or deadline")
+
+	options := defaultGenerateOptions()
+	options.Provider = "ollama"
+	_, err = generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.InDelta(t, 2*time.Minute, remaining, float64(time.Second), "the service timeout takes precedence")
+
+	options.Timeout = 10 * time.Second
+	_, err = generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.InDelta(t, 10*time.Second, remaining, float64(time.Second), "the request timeout takes precedence")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = generator.Generate(ctx, "list users", nil)
+	require.NoError(t, err)
+	require.InDelta(t, 30*time.Second, remaining, float64(time.Second), "an existing deadline is kept")
+}
+
+func TestGenerateReturnsCandidateAlternatives(t *testing.T) {
+	t.Run("multiple choices in one response", func(t *testing.T) {
+		calls := 0
+		client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			calls++
+			require.Equal(t, 3, req.Candidates)
+			return &interfaces.GenerateResponse{
+				Text:         "sql:SELECT id FROM users;",
+				Alternatives: []string{"sql:SELECT name FROM users", "sql:SELECT email FROM users;"},
+			}, nil
+		}}
+		generator, err := NewSQLGenerator(client, config.AIConfig{})
+		require.NoError(t, err)
+
+		options := defaultGenerateOptions()
+		options.Candidates = 3
+		result, err := generator.Generate(context.Background(), "list users", options)
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+		require.Equal(t, "SELECT id FROM users;", result.SQL)
+		require.Len(t, result.Alternatives, 2)
+		require.Equal(t, "SELECT name FROM users", result.Alternatives[0].SQL)
+		require.NotEmpty(t, result.Alternatives[0].ValidationResults, "each candidate is validated")
+		require.Empty(t, result.Alternatives[1].ValidationResults)
+	})
+
+	t.Run("separate calls with the best candidate promoted", func(t *testing.T) {
+		responses := []string{"sql:DELETE FROM users;", "sql:SELECT id FROM users;"}
+		calls := 0
+		client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			text := responses[calls%len(responses)]
+			calls++
+			return &interfaces.GenerateResponse{Text: text}, nil
+		}}
+		generator, err := NewSQLGenerator(client, config.AIConfig{ReadOnly: true})
+		require.NoError(t, err)
+
+		options := defaultGenerateOptions()
+		options.Candidates = 50
+		result, err := generator.Generate(context.Background(), "list users", options)
+		require.NoError(t, err)
+		require.Equal(t, maxCandidates, calls, "candidates are capped")
+		require.Equal(t, "SELECT id FROM users;", result.SQL, "an unblocked candidate wins over a blocked one")
+		require.False(t, result.Blocked)
+		require.Len(t, result.Alternatives, 1, "blocked candidates are dropped")
+		require.Equal(t, "SELECT id FROM users;", result.Alternatives[0].SQL)
+		require.Contains(t, result.Metadata.DebugInfo, "3 blocked candidate(s) dropped")
+	})
+
+	t.Run("oversized candidates are dropped", func(t *testing.T) {
+		client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{
+				Text:         "sql:SELECT id, name, email, created_at FROM users;",
+				Alternatives: []string{"sql:SELECT id FROM users;", "sql:SELECT id, name, email FROM users;"},
+			}, nil
+		}}
+		generator, err := NewSQLGenerator(client, config.AIConfig{MaxSQLBytes: 24})
+		require.NoError(t, err)
+
+		options := defaultGenerateOptions()
+		options.Candidates = 3
+		result, err := generator.Generate(context.Background(), "list users", options)
+		require.NoError(t, err, "a candidate within the limit is promoted over an oversized first response")
+		require.Equal(t, "SELECT id FROM users;", result.SQL)
+		require.Empty(t, result.Alternatives, "oversized candidates are dropped")
+		require.Contains(t, result.Metadata.DebugInfo, "2 oversized candidate(s) dropped")
+	})
+
+	t.Run("write candidates are held for confirmation", func(t *testing.T) {
+		client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{
+				Text:         "sql:SELECT id FROM users WHERE active = 0;",
+				Alternatives: []string{"sql:UPDATE users SET active = 1 WHERE active = 0;"},
+			}, nil
+		}}
+		generator, err := NewSQLGenerator(client, config.AIConfig{})
+		require.NoError(t, err)
+
+		options := defaultGenerateOptions()
+		options.Candidates = 2
+		options.RequireConfirmForWrites = true
+		result, err := generator.Generate(context.Background(), "inactive users", options)
+		require.NoError(t, err)
+		require.False(t, result.NeedsConfirmation)
+		require.Len(t, result.Alternatives, 1)
+		alternative := result.Alternatives[0]
+		require.True(t, alternative.NeedsConfirmation)
+		require.NotEmpty(t, alternative.ConfirmationToken)
+
+		released, err := generator.Confirm(alternative.ConfirmationToken)
+		require.NoError(t, err)
+		require.Equal(t, "UPDATE users SET active = 1 WHERE active = 0;", released.SQL)
+	})
+}
+
+func TestBuildPromptMergesInheritedTemplateSections(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sql_generation.yaml"), []byte(`sections:
+  system: You translate questions into a single SQL statement.
+  instructions: Qualify every column with its table alias.
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "postgresql.yaml"), []byte(`extends: sql_generation
+dialect: postgresql
+sections:
+  system: You translate questions into a single PostgreSQL statement.
+`), 0o600))
+
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{TemplatesDir: dir})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.DatabaseType = "postgresql"
+	prompt := generator.buildPrompt("list users", options, &PostgreSQLDialect{})
+	require.True(t, strings.HasPrefix(prompt, "You translate questions into a single PostgreSQL statement.\n\n"))
+	require.Contains(t, prompt, "Dialect Instructions:\nQualify every column with its table alias.\n\n")
+	require.Contains(t, prompt, "- Do not generate DROP", "sections the templates leave out keep their defaults")
+
+	options.DatabaseType = "mysql"
+	prompt = generator.buildPrompt("list users", options, &MySQLDialect{})
+	require.True(t, strings.HasPrefix(prompt, "You translate questions into a single SQL statement.\n\n"))
+}
+
+func TestGenerateCoalescesIdenticalConcurrentRequests(t *testing.T) {
+	const callers = 5
+	var (
+		mu      sync.Mutex
+		calls   int
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		mu.Lock()
+		calls++
+		if calls == 1 {
+			close(started)
+		}
+		mu.Unlock()
+		<-release
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;\nexplanation:lists users"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make(chan *GenerationResult, callers)
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := generator.Generate(context.Background(), "list users", defaultGenerateOptions())
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- result
+		}()
+	}
+	<-started
+	// Give the remaining callers time to join the in-flight generation
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	close(results)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	seen := map[*GenerationResult]bool{}
+	for result := range results {
+		require.Equal(t, "SELECT * FROM users;", result.SQL)
+		require.False(t, seen[result], "each caller receives its own copy")
+		seen[result] = true
+	}
+	require.Len(t, seen, callers)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, calls)
+}
+
+func TestCoalescedFollowerCanLeaveWhileLeaderIsInFlight(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return &interfaces.GenerateResponse{Text: "sql:SELECT * FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	leader := make(chan error, 1)
+	go func() {
+		_, err := generator.Generate(context.Background(), "list users", defaultGenerateOptions())
+		leader <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	follower := make(chan error, 1)
+	go func() {
+		_, err := generator.Generate(ctx, "list users", defaultGenerateOptions())
+		follower <- err
+	}()
+	// Give the follower time to join the in-flight generation
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-follower:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("follower kept waiting for the leader after its context was cancelled")
+	}
+
+	close(release)
+	require.NoError(t, <-leader)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestCoalescedCallersReceiveTheirOwnConfirmationTokens(t *testing.T) {
+	const callers = 3
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		once.Do(func() { close(started) })
+		<-release
+		return &interfaces.GenerateResponse{Text: "sql:DELETE FROM sessions WHERE expired = 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.RequireConfirmForWrites = true
+	var wg sync.WaitGroup
+	results := make(chan *GenerationResult, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := generator.Generate(context.Background(), "purge expired sessions", options)
+			if err == nil {
+				results <- result
+			}
+		}()
+	}
+	<-started
+	// Give the remaining callers time to join the in-flight generation
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	tokens := map[string]bool{}
+	for result := range results {
+		require.True(t, result.NeedsConfirmation)
+		require.False(t, tokens[result.ConfirmationToken], "each caller receives its own token")
+		tokens[result.ConfirmationToken] = true
+	}
+	require.Len(t, tokens, callers)
+	for token := range tokens {
+		released, err := generator.Confirm(token)
+		require.NoError(t, err)
+		require.Equal(t, "DELETE FROM sessions WHERE expired = 1;", released.SQL)
+	}
+}
+
+func TestGenerateDoesNotCoalesceRequestsWithDifferentSafetyOrDebugOptions(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		calls   int
+		release = make(chan struct{})
+	)
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return &interfaces.GenerateResponse{Text: "sql:UPDATE users SET active = 0 WHERE id = 1;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	plain := defaultGenerateOptions()
+	confirm := defaultGenerateOptions()
+	confirm.RequireConfirmForWrites = true
+	debug := defaultGenerateOptions()
+	debug.IncludePrompt = true
+	runtime := defaultGenerateOptions()
+	runtime.APIKey = "sk-other-caller"
+
+	var wg sync.WaitGroup
+	results := make([]*GenerationResult, 4)
+	for i, options := range []*GenerateOptions{plain, confirm, debug, runtime} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := generator.Generate(context.Background(), "deactivate user 1", options)
+			if err == nil {
+				results[i] = result
+			}
+		}()
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 4
+	}, time.Second, 5*time.Millisecond, "each request makes its own provider call")
+	close(release)
+	wg.Wait()
+
+	require.NotNil(t, results[0])
+	require.False(t, results[0].NeedsConfirmation)
+	require.NotNil(t, results[1])
+	require.True(t, results[1].NeedsConfirmation)
+	require.NotNil(t, results[2])
+	require.NotNil(t, results[2].RenderedPrompt)
+	require.NotNil(t, results[3])
+}
+
+func TestGenerateComputesConfidenceFromValidation(t *testing.T) {
+	responses := map[string]string{
+		"list user ids":    "sql:SELECT id FROM users;\nexplanation:lists user ids",
+		"list order ids":   "sql:SELECT id FROM orders LIMIT ALL;\nexplanation:lists order ids",
+		"list invoice ids": "sql:SELECT id FROM invoices;\nexplanation:lists invoice ids",
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, response := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return &interfaces.GenerateResponse{Text: response}, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.Schema = map[string]Table{
+		"users":  {Name: "users", Columns: []Column{{Name: "id", Type: "INT"}}},
+		"orders": {Name: "orders", Columns: []Column{{Name: "id", Type: "INT"}}},
+	}
+
+	grounded, err := generator.Generate(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.Greater(t, grounded.ConfidenceScore, 0.8, "clean, schema-grounded queries rise above the base score")
+
+	invalid, err := generator.Generate(context.Background(), "list order ids", options)
+	require.NoError(t, err)
+	require.Less(t, invalid.ConfidenceScore, grounded.ConfidenceScore, "validation errors lower the score")
+
+	ungrounded, err := generator.Generate(context.Background(), "list invoice ids", options)
+	require.NoError(t, err)
+	require.Less(t, ungrounded.ConfidenceScore, 0.8, "tables missing from the schema lower the score")
+}
+
+func TestGenerateRetriesBelowMinConfidence(t *testing.T) {
+	var prompts []string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		prompts = append(prompts, req.Prompt)
+		if len(prompts) == 1 {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM invoices;\nexplanation:ungrounded"}, nil
+		}
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;\nexplanation:grounded"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.Schema = map[string]Table{"users": {Name: "users", Columns: []Column{{Name: "id", Type: "INT"}}}}
+	options.MinConfidence = 0.85
+
+	result, err := generator.Generate(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.Len(t, prompts, 2, "the retry stops once the minimum is reached")
+	require.Contains(t, prompts[1], "SELECT id FROM invoices;", "the retry prompt quotes the low-confidence answer")
+	require.Equal(t, "SELECT id FROM users;", result.SQL)
+	require.GreaterOrEqual(t, result.ConfidenceScore, 0.85)
+	require.Contains(t, strings.Join(result.Metadata.DebugInfo, "\n"), "2 attempt(s)")
+	require.Empty(t, result.Warnings)
+}
+
+func TestGenerateKeepsBestResultAndUsesConfidenceFallback(t *testing.T) {
+	primaryCalls := 0
+	primary := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		primaryCalls++
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;\nexplanation:grounded"}, nil
+	}}
+	fallbackCalls := 0
+	fallback := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		fallbackCalls++
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM invoices;\nexplanation:ungrounded"}, nil
+	}}
+	generator, err := NewSQLGenerator(primary, config.AIConfig{})
+	require.NoError(t, err)
+	generator.setDefaultClient("openai", primary)
+	generator.setClientLookup(func(name string) (interfaces.AIClient, error) {
+		if name == "ollama" {
+			return fallback, nil
+		}
+		return nil, fmt.Errorf("unknown client %s", name)
+	})
+
+	options := defaultGenerateOptions()
+	options.Schema = map[string]Table{"users": {Name: "users", Columns: []Column{{Name: "id", Type: "INT"}}}}
+	options.MinConfidence = 0.99
+	options.ConfidenceFallbackProvider = "local"
+
+	result, err := generator.Generate(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.Equal(t, 1, primaryCalls)
+	require.Equal(t, maxConfidenceRetries, fallbackCalls)
+	require.Equal(t, "SELECT id FROM users;", result.SQL, "the highest-scoring attempt wins")
+	require.Equal(t, "openai", result.Metadata.ServedBy)
+	require.Contains(t, strings.Join(result.Metadata.DebugInfo, "\n"), "via ollama: 3 attempt(s)")
+	require.Contains(t, strings.Join(result.Warnings, "\n"), "below the requested minimum 0.99")
+}
+
+func TestComputeConfidence(t *testing.T) {
+	weights := newConfidenceWeights(config.ConfidenceConfig{})
+	options := defaultGenerateOptions()
+	clean := &GenerationResult{SQL: "SELECT 1;", Metadata: GenerationMetadata{Complexity: "simple"}}
+
+	require.InDelta(t, 0.8, computeConfidence(weights, clean, &interfaces.GenerateResponse{}, options), 1e-9)
+	require.InDelta(t, 0.5, computeConfidence(weights, clean, &interfaces.GenerateResponse{
+		Metadata: map[string]any{"finish_reason": "length"},
+	}, options), 1e-9, "truncated responses are penalized")
+	require.Zero(t, computeConfidence(weights, &GenerationResult{SQL: placeholderSQL}, &interfaces.GenerateResponse{}, options))
+
+	failing := &GenerationResult{SQL: "SELECT 1;", ValidationResults: []ValidationResult{
+		{Level: "error"}, {Level: "error"}, {Level: "error"}, {Level: "error"}, {Level: "error"},
+	}}
+	require.Zero(t, computeConfidence(weights, failing, &interfaces.GenerateResponse{}, options), "the score is clamped at zero")
+
+	zero, base := 0.0, 0.9
+	weights = newConfidenceWeights(config.ConfidenceConfig{Base: &base, TruncatedPenalty: &zero})
+	require.InDelta(t, 0.9, computeConfidence(weights, clean, &interfaces.GenerateResponse{
+		Metadata: map[string]any{"finish_reason": "length"},
+	}, options), 1e-9, "an explicit zero weight disables its term")
+}
+
+func TestGenerateStripsReasoningFromExtraction(t *testing.T) {
+	responses := map[string]*interfaces.GenerateResponse{
+		"list users": {
+			Text:     "sql:SELECT * FROM users;\nexplanation:lists users",
+			Metadata: map[string]any{"reasoning_content": "sql:SELECT * FROM accounts; is wrong, users holds the people"},
+		},
+		"count users": {
+			Text: "<think>\nsql:SELECT * FROM people; no, count them\n</think>\nsql:SELECT COUNT(*) FROM users;\nexplanation:counts users",
+		},
+	}
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		for nl, response := range responses {
+			if strings.Contains(req.Prompt, nl) {
+				return response, nil
+			}
+		}
+		return nil, errors.New("unexpected prompt")
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{Debug: config.DebugConfig{IncludeReasoning: true}})
+	require.NoError(t, err)
+
+	separate, err := generator.Generate(context.Background(), "list users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users;", separate.SQL)
+	require.Equal(t, "lists users", separate.Explanation)
+	require.Contains(t, separate.Metadata.DebugInfo, "model reasoning: sql:SELECT * FROM accounts; is wrong, users holds the people")
+
+	inline, err := generator.Generate(context.Background(), "count users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "SELECT COUNT(*) FROM users;", inline.SQL)
+	require.Contains(t, inline.Metadata.DebugInfo, "model reasoning: sql:SELECT * FROM people; no, count them")
+}
+
+func TestGenerationContextExtendsReasoningModelTimeout(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{
+		DefaultService: "deepseek",
+		Timeout:        config.Duration{Duration: time.Minute},
+		Services:       map[string]config.AIService{"deepseek": {Model: "deepseek-reasoner"}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := generator.generationContext(context.Background(), defaultGenerateOptions())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.Greater(t, time.Until(deadline), 4*time.Minute)
+
+	options := defaultGenerateOptions()
+	options.Model = "deepseek-chat"
+	ctx, cancel = generator.generationContext(context.Background(), options)
+	defer cancel()
+	deadline, ok = ctx.Deadline()
+	require.True(t, ok)
+	require.LessOrEqual(t, time.Until(deadline), time.Minute)
+}
+
+func TestGenerateRejectsOversizedSQL(t *testing.T) {
+	oversized := "SELECT " + strings.Repeat("id, ", 100) + "id FROM users;"
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		if strings.Contains(req.Prompt, "every id") {
+			return &interfaces.GenerateResponse{Text: "sql:" + oversized}, nil
+		}
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{MaxSQLBytes: 256})
+	require.NoError(t, err)
+
+	_, err = generator.Generate(context.Background(), "every id", defaultGenerateOptions())
+	require.ErrorIs(t, err, ErrGeneratedSQLTooLarge)
+
+	result, err := generator.Generate(context.Background(), "one id", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM users;", result.SQL)
+}
+
+func TestGenerateEnforcesStatementTypeLists(t *testing.T) {
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		switch {
+		case strings.Contains(req.Prompt, "remove"):
+			return &interfaces.GenerateResponse{Text: "sql:DELETE FROM users WHERE id = 1;"}, nil
+		case strings.Contains(req.Prompt, "add"):
+			return &interfaces.GenerateResponse{Text: "sql:INSERT INTO users (name) VALUES ('ada');"}, nil
+		}
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{AllowedStatementTypes: []string{"SELECT", "INSERT"}})
+	require.NoError(t, err)
+
+	for _, request := range []string{"list users", "add a user"} {
+		result, err := generator.Generate(context.Background(), request, defaultGenerateOptions())
+		require.NoError(t, err)
+		require.False(t, result.Blocked, request)
+	}
+
+	result, err := generator.Generate(context.Background(), "remove a user", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, result.Blocked)
+	err = blockedGenerationError(result)
+	require.ErrorIs(t, err, ErrStatementTypeNotAllowed)
+	require.Contains(t, err.Error(), "DELETE statements are not allowed")
+
+	options := defaultGenerateOptions()
+	options.AllowedStatements = []string{"select", "delete"}
+	result, err = generator.Generate(context.Background(), "remove a user", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "the request allowlist cannot widen the configured one")
+	require.Contains(t, blockedGenerationError(result).Error(), "allowed types: select")
+
+	options.AllowedStatements = []string{"select"}
+	result, err = generator.Generate(context.Background(), "add a user", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "the request allowlist narrows the configured one")
+
+	options.AllowedStatements = []string{}
+	result, err = generator.Generate(context.Background(), "add a user", options)
+	require.NoError(t, err)
+	require.False(t, result.Blocked, "an empty request allowlist leaves the configured one in place")
+	result, err = generator.Generate(context.Background(), "remove a user", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "an empty request allowlist does not lift the configured one")
+
+	options.AllowedStatements = []string{"drop"}
+	result, err = generator.Generate(context.Background(), "list users", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "no configured type left allows nothing")
+	require.Contains(t, blockedGenerationError(result).Error(), "allowed types: none")
+
+	unrestricted, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+	options.AllowedStatements = []string{"select"}
+	result, err = unrestricted.Generate(context.Background(), "remove a user", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "without a configured allowlist the request one applies as is")
+
+	options.AllowedStatements = []string{"select", "delete"}
+
+	cfg := generator.currentConfig()
+	cfg.DeniedStatementTypes = []string{"DELETE"}
+	require.NoError(t, generator.UpdateConfig(cfg))
+	result, err = generator.Generate(context.Background(), "remove a user", options)
+	require.NoError(t, err)
+	require.True(t, result.Blocked, "the denylist applies even when the request allows the type")
+	require.Contains(t, blockedGenerationError(result).Error(), "DELETE statements are denied")
+}
+
+func TestStatementTypes(t *testing.T) {
+	tests := map[string][]statementType{
+		"SELECT 1; DELETE FROM users WHERE id = 1":                        {{Kind: "SELECT"}, {Kind: "DELETE"}},
+		"/* cleanup */ DELETE FROM users":                                 {{Kind: "DELETE"}},
+		"(SELECT 1) UNION (SELECT 2)":                                     {{Kind: "SELECT"}},
+		"WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone": {{Kind: "DELETE"}, {Kind: "SELECT"}},
+		"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE WHEN NOT MATCHED THEN INSERT VALUES (s.id)": {{Kind: "MERGE", Actions: []string{"DELETE", "INSERT"}}},
+		"REPLACE INTO users (id) VALUES (1)":                                      {{Kind: "REPLACE", Actions: []string{"INSERT", "DELETE"}}},
+		"INSERT INTO users (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 1": {{Kind: "INSERT", Actions: []string{"UPDATE"}}},
+		"INSERT INTO users (id) VALUES (1) ON DUPLICATE KEY UPDATE id = 1":        {{Kind: "INSERT", Actions: []string{"UPDATE"}}},
+		"EXPLAIN ANALYZE DELETE FROM users WHERE id = 1":                          {{Kind: "EXPLAIN", Actions: []string{"DELETE"}}},
+		"SHOW TABLES": {{Kind: "SHOW"}},
+		"DESC users":  {{Kind: "DESCRIBE"}},
+		"VACUUM":      {{Kind: "UNKNOWN"}},
+	}
+	for sql, want := range tests {
+		require.Equal(t, want, statementTypes(sql), sql)
+	}
+}
+
+func TestDeniedStatementTypesCoverMergeActions(t *testing.T) {
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		if strings.Contains(req.Prompt, "sync") {
+			return &interfaces.GenerateResponse{Text: "sql:MERGE INTO users u USING staging s ON u.id = s.id WHEN MATCHED THEN DELETE;"}, nil
+		}
+		return &interfaces.GenerateResponse{Text: "sql:MERGE INTO users u USING staging s ON u.id = s.id WHEN MATCHED THEN UPDATE SET name = s.name;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{DeniedStatementTypes: []string{"DELETE"}})
+	require.NoError(t, err)
+
+	result, err := generator.Generate(context.Background(), "sync users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.True(t, result.Blocked)
+	require.Contains(t, blockedGenerationError(result).Error(), "MERGE statements that DELETE rows are denied")
+
+	result, err = generator.Generate(context.Background(), "update users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.False(t, result.Blocked)
+	require.Equal(t, "MERGE", result.Metadata.QueryType)
+
+	generator, err = NewSQLGenerator(client, config.AIConfig{AllowedStatementTypes: []string{"SELECT", "MERGE"}})
+	require.NoError(t, err)
+	result, err = generator.Generate(context.Background(), "update users", defaultGenerateOptions())
+	require.NoError(t, err)
+	require.False(t, result.Blocked, "MERGE can be named in the allowlist")
+}
+
+func TestGenerateReproducibleReturnsStableHash(t *testing.T) {
+	seeded := &stubAIClient{
+		generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			if req.Seed == nil {
+				return nil, errors.New("seed was not forwarded")
+			}
+			return &interfaces.GenerateResponse{Text: fmt.Sprintf("sql:SELECT id FROM users LIMIT %d;", *req.Seed)}, nil
+		},
+		capabilities: &interfaces.Capabilities{Provider: "ollama", Features: []interfaces.Feature{{Name: "seed", Enabled: true}}},
+	}
+	generator, err := NewSQLGenerator(seeded, config.AIConfig{})
+	require.NoError(t, err)
+
+	seed := int64(7)
+	options := defaultGenerateOptions()
+	options.Seed = &seed
+	first, firstHash, err := generator.GenerateReproducible(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM users LIMIT 7;", first.SQL)
+	_, secondHash, err := generator.GenerateReproducible(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.Equal(t, firstHash, secondHash)
+	require.Len(t, firstHash, 64)
+
+	otherSeed := int64(8)
+	options.Seed = &otherSeed
+	_, otherHash, err := generator.GenerateReproducible(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	require.NotEqual(t, firstHash, otherHash)
+
+	_, _, err = generator.GenerateReproducible(context.Background(), "list user ids", defaultGenerateOptions())
+	require.ErrorIs(t, err, ErrNotReproducible, "a seed is required")
+
+	unseeded, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+	_, _, err = unseeded.GenerateReproducible(context.Background(), "list user ids", options)
+	require.ErrorIs(t, err, ErrNotReproducible, "providers without seed support fail fast")
+}
+
+func TestGenerateInSessionReusesPreparedSchema(t *testing.T) {
+	var prompts []string
+	client := &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		prompts = append(prompts, req.Prompt)
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{})
+	require.NoError(t, err)
+
+	schema := map[string]Table{"users": {Columns: []Column{{Name: "id", Type: "INT"}}}}
+	sessionID, err := generator.OpenSession(schema)
+	require.NoError(t, err)
+	require.NotEmpty(t, sessionID)
+
+	// Changes to the caller's map after opening do not reach the session
+	schema["orders"] = Table{Name: "orders"}
+	session := generator.sessions.sessions[sessionID]
+	require.Equal(t, "users", session.schema["users"].Name)
+	require.NotContains(t, session.rendered, "orders")
+
+	// Generations use the rendering prepared at open time instead of rendering the schema again
+	session.rendered = "Database Schema:\nTable: prepared_once\n\n"
+	for _, query := range []string{"list user ids", "count users"} {
+		result, err := generator.GenerateInSession(context.Background(), sessionID, query, nil)
+		require.NoError(t, err)
+		require.Equal(t, "SELECT id FROM users;", result.SQL)
+	}
+	require.Len(t, prompts, 2)
+	for _, prompt := range prompts {
+		require.Contains(t, prompt, "Table: prepared_once")
+	}
+
+	require.NoError(t, generator.CloseSession(sessionID))
+	_, err = generator.GenerateInSession(context.Background(), sessionID, "list user ids", nil)
+	require.ErrorIs(t, err, ErrSessionNotFound)
+	require.ErrorIs(t, generator.CloseSession(sessionID), ErrSessionNotFound)
+}
+
+func TestSessionsExpireAndAreCapped(t *testing.T) {
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{
+		Sessions: config.SessionConfig{TTL: config.Duration{Duration: time.Minute}, MaxSessions: 1},
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	generator.sessions.now = func() time.Time { return now }
+
+	schema := map[string]Table{"users": {Name: "users"}}
+	first, err := generator.OpenSession(schema)
+	require.NoError(t, err)
+	_, err = generator.OpenSession(schema)
+	require.ErrorIs(t, err, ErrTooManySessions)
+
+	// Using a session extends its lifetime
+	now = now.Add(50 * time.Second)
+	_, err = generator.sessionOptions(first, nil)
+	require.NoError(t, err)
+	now = now.Add(50 * time.Second)
+	_, err = generator.sessionOptions(first, nil)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = generator.sessionOptions(first, nil)
+	require.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = generator.OpenSession(schema)
+	require.NoError(t, err, "expired sessions no longer count against the cap")
+
+	_, err = generator.OpenSession(nil)
+	require.Error(t, err)
+}
+
+func TestParseSchema(t *testing.T) {
+	keyed, err := ParseSchema([]byte(`{"users": {"name": "users", "columns": [{"name": "id", "type": "INT"}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, "id", keyed["users"].Columns[0].Name)
+
+	listed, err := ParseSchema([]byte(`[{"name": "orders", "columns": []}]`))
+	require.NoError(t, err)
+	require.Contains(t, listed, "orders")
+
+	_, err = ParseSchema([]byte(`[{"columns": []}]`))
+	require.Error(t, err)
+	_, err = ParseSchema([]byte(`"users"`))
+	require.Error(t, err)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// groupByNote describes how a dialect treats selected columns missing from GROUP BY.
+// Message and Suggestion are formatted with the column name.
+type groupByNote struct {
+	Level      string
+	Message    string
+	Suggestion string
+}
+
+var (
+	postgresGroupBy = groupByNote{
+		Level:      "error",
+		Message:    "column '%s' must appear in GROUP BY or be used in an aggregate function",
+		Suggestion: "Add %[1]s to GROUP BY or aggregate it, e.g. MAX(%[1]s)",
+	}
+	mysqlGroupBy = groupByNote{
+		Level:      "warning",
+		Message:    "column '%s' is not in GROUP BY and is rejected when ONLY_FULL_GROUP_BY is enabled",
+		Suggestion: "Add %[1]s to GROUP BY or wrap it in ANY_VALUE(%[1]s)",
+	}
+)
+
+// aggregateFunctions collapse the rows of a group into one value
+var aggregateFunctions = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+	"GROUP_CONCAT": true, "STRING_AGG": true, "ARRAY_AGG": true, "LISTAGG": true,
+	"JSON_AGG": true, "JSONB_AGG": true, "JSON_ARRAYAGG": true, "JSON_OBJECTAGG": true,
+	"BOOL_AND": true, "BOOL_OR": true, "BIT_AND": true, "BIT_OR": true, "BIT_XOR": true,
+	"STDDEV": true, "STDDEV_POP": true, "STDDEV_SAMP": true, "VARIANCE": true, "VAR_POP": true, "VAR_SAMP": true,
+	"ANY_VALUE": true,
+}
+
+// groupByClauseEnds are the keywords that end a GROUP BY list
+var groupByClauseEnds = map[string]bool{
+	"HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true,
+	"WINDOW": true, "QUALIFY": true, "WITH": true,
+}
+
+// valueKeywords are bare words that are values rather than column references
+var valueKeywords = map[string]bool{
+	"NULL": true, "TRUE": true, "FALSE": true, "CURRENT_DATE": true, "CURRENT_TIME": true,
+	"CURRENT_TIMESTAMP": true, "LOCALTIME": true, "LOCALTIMESTAMP": true,
+}
+
+// groupByResults flags plain columns selected alongside aggregates that GROUP BY does not list.
+// Each SELECT, including subqueries, is checked on its own; expressions are not inspected.
+func groupByResults(sql string, note groupByNote) []ValidationResult {
+	runes := []rune(sql)
+	tokens := tokenizeSQL(sql)
+
+	// depths[i] is the parenthesis depth of token i; a bracket has the depth outside it
+	depths := make([]int, len(tokens))
+	depth := 0
+	for i, token := range tokens {
+		if token.Text == ")" && depth > 0 {
+			depth--
+		}
+		depths[i] = depth
+		if token.Text == "(" {
+			depth++
+		}
+	}
+
+	var results []ValidationResult
+	for start, token := range tokens {
+		if token.Kind != tokenWord || token.Text != "SELECT" {
+			continue
+		}
+		d := depths[start]
+		end := start + 1
+		for end < len(tokens) && depths[end] >= d && !(depths[end] == d && (tokens[end].Text == ";" || setOperators[tokens[end].Text])) {
+			end++
+		}
+		listEnd := start + 1
+		for listEnd < end && !(depths[listEnd] == d && tokens[listEnd].Text == "FROM") {
+			listEnd++
+		}
+
+		items := splitTopLevel(tokens, depths, start+1, listEnd, d)
+		if len(items) > 0 {
+			items[0] = skipSelectModifiers(tokens, depths, items[0])
+		}
+		aggregated := false
+		for _, item := range items {
+			if containsAggregate(tokens, depths, item) {
+				aggregated = true
+				break
+			}
+		}
+		if !aggregated {
+			continue
+		}
+
+		grouped := groupedNames(tokens, depths, listEnd, end, d)
+		for position, item := range items {
+			column, alias, ok := plainColumn(tokens[item[0]:item[1]])
+			if !ok {
+				continue
+			}
+			last := column[strings.LastIndex(column, ".")+1:]
+			if grouped[column] || grouped[last] || (alias != "" && grouped[alias]) || grouped["#"+strconv.Itoa(position+1)] {
+				continue
+			}
+			line, col := runePosition(runes, tokens[item[0]].Pos)
+			results = append(results, ValidationResult{
+				Type:       "semantic",
+				Level:      note.Level,
+				Message:    fmt.Sprintf(note.Message, column),
+				Line:       line,
+				Column:     col,
+				Suggestion: fmt.Sprintf(note.Suggestion, column),
+			})
+		}
+	}
+	return results
+}
+
+// splitTopLevel splits tokens[from:to] at commas of depth d into [start, end) ranges
+func splitTopLevel(tokens []sqlToken, depths []int, from, to, d int) [][2]int {
+	var items [][2]int
+	itemStart := from
+	for i := from; i <= to; i++ {
+		if i == to || (depths[i] == d && tokens[i].Text == ",") {
+			if i > itemStart {
+				items = append(items, [2]int{itemStart, i})
+			}
+			itemStart = i + 1
+		}
+	}
+	return items
+}
+
+// skipSelectModifiers drops a leading DISTINCT, DISTINCT ON (...) or ALL from the first select item
+func skipSelectModifiers(tokens []sqlToken, depths []int, item [2]int) [2]int {
+	if item[0] < item[1] && (tokens[item[0]].Text == "DISTINCT" || tokens[item[0]].Text == "ALL") {
+		item[0]++
+		if item[0]+1 < item[1] && tokens[item[0]].Text == "ON" && tokens[item[0]+1].Text == "(" {
+			open := item[0] + 1
+			item[0] = open + 1
+			for item[0] < item[1] && !(tokens[item[0]].Text == ")" && depths[item[0]] == depths[open]) {
+				item[0]++
+			}
+			item[0]++
+		}
+	}
+	return item
+}
+
+// containsAggregate reports whether an item calls an aggregate function outside a window or subquery
+func containsAggregate(tokens []sqlToken, depths []int, item [2]int) bool {
+	for i := item[0]; i < item[1]; i++ {
+		if tokens[i].Text == "SELECT" {
+			return false
+		}
+	}
+	for i := item[0]; i+1 < item[1]; i++ {
+		if tokens[i].Kind != tokenWord || !aggregateFunctions[tokens[i].Text] || tokens[i+1].Text != "(" {
+			continue
+		}
+		closing := i + 2
+		for closing < item[1] && !(tokens[closing].Text == ")" && depths[closing] == depths[i+1]) {
+			closing++
+		}
+		if closing+1 < item[1] && tokens[closing+1].Text == "OVER" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// groupedNames collects the GROUP BY entries of the query between from and end at depth d.
+// Columns are keyed by their lower-cased name and qualified name, and positions by "#n".
+// Every column inside ROLLUP, CUBE or GROUPING SETS counts as grouped.
+func groupedNames(tokens []sqlToken, depths []int, from, end, d int) map[string]bool {
+	grouped := make(map[string]bool)
+	groupStart := -1
+	for i := from; i+1 < end; i++ {
+		if depths[i] == d && tokens[i].Text == "GROUP" && tokens[i+1].Text == "BY" {
+			groupStart = i + 2
+			break
+		}
+	}
+	if groupStart < 0 {
+		return grouped
+	}
+	groupEnd := groupStart
+	for groupEnd < end && !(depths[groupEnd] == d && groupByClauseEnds[tokens[groupEnd].Text]) {
+		groupEnd++
+	}
+
+	for _, item := range splitTopLevel(tokens, depths, groupStart, groupEnd, d) {
+		entry := tokens[item[0]:item[1]]
+		if len(entry) == 1 && entry[0].Kind == tokenNumber {
+			grouped["#"+entry[0].Text] = true
+			continue
+		}
+		if column, _, ok := plainColumn(entry); ok {
+			grouped[column] = true
+			grouped[column[strings.LastIndex(column, ".")+1:]] = true
+			continue
+		}
+		switch entry[0].Text {
+		case "ROLLUP", "CUBE", "GROUPING":
+			for _, token := range entry {
+				if token.Kind == tokenWord || token.Kind == tokenQuotedIdentifier {
+					grouped[identifierName(token)] = true
+				}
+			}
+		}
+	}
+	return grouped
+}
+
+// plainColumn recognizes a possibly qualified column reference with an optional alias and
+// returns its lower-cased dotted name and alias
+func plainColumn(item []sqlToken) (column, alias string, ok bool) {
+	isName := func(token sqlToken) bool {
+		return token.Kind == tokenQuotedIdentifier || (token.Kind == tokenWord && !valueKeywords[token.Text])
+	}
+	if len(item) == 0 || !isName(item[0]) {
+		return "", "", false
+	}
+	parts := []string{identifierName(item[0])}
+	i := 1
+	for i+1 < len(item) && item[i].Text == "." && isName(item[i+1]) {
+		parts = append(parts, identifierName(item[i+1]))
+		i += 2
+	}
+	if i < len(item) && item[i].Text == "AS" {
+		i++
+	}
+	if i+1 == len(item) && isName(item[i]) {
+		alias = identifierName(item[i])
+		i++
+	}
+	if i != len(item) {
+		return "", "", false
+	}
+	return strings.Join(parts, "."), alias, true
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"golang.org/x/sync/singleflight"
+)
+
+// healthCache remembers recent health results per provider so frequent callers such as
+// dashboards do not probe every provider on each request. Concurrent callers that find a
+// stale entry share a single probe. The zero value is ready to use.
+type healthCache struct {
+	mu       sync.Mutex
+	entries  map[string]healthEntry
+	inflight singleflight.Group
+}
+
+type healthEntry struct {
+	status    *interfaces.HealthStatus
+	checkedAt time.Time
+}
+
+// get returns the cached status of a provider when it was checked within ttl
+func (c *healthCache) get(name string, ttl time.Duration, now time.Time) (*interfaces.HealthStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[name]
+	if !ok || ttl <= 0 || now.Sub(entry.checkedAt) >= ttl {
+		return nil, false
+	}
+	return entry.status, true
+}
+
+// put records the status of a provider
+func (c *healthCache) put(name string, status *interfaces.HealthStatus, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]healthEntry)
+	}
+	c.entries[name] = healthEntry{status: status, checkedAt: now}
+}
+
+// remove forgets a provider, e.g. when its client is replaced or removed
+func (c *healthCache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+}
+
+// check probes a client, sharing the probe with concurrent callers, and caches the result.
+// Errors are reported as an unhealthy status so they are cached like any other result.
+func (c *healthCache) check(ctx context.Context, name string, client interfaces.AIClient) *interfaces.HealthStatus {
+	value, _, _ := c.inflight.Do(name, func() (interface{}, error) {
+		status, err := client.HealthCheck(ctx)
+		if err != nil {
+			status = &interfaces.HealthStatus{
+				Healthy: false,
+				Status:  err.Error(),
+			}
+		}
+		c.put(name, status, time.Now())
+		return status, nil
+	})
+	return value.(*interfaces.HealthStatus)
+}
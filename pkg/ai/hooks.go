@@ -0,0 +1,85 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrGenerationRejected is returned when a hook vetoes a generation
+var ErrGenerationRejected = errors.New("generation rejected by hook")
+
+// ServedByHook is the GenerationMetadata.ServedBy value of results returned by a BeforeGenerate hook
+const ServedByHook = "hook"
+
+// Hooks intercept Generate and Regenerate around the model call. Both callbacks are optional.
+type Hooks struct {
+	// BeforeGenerate may rewrite the options and the rendered prompt before the model is called.
+	// Returning a result skips the model and returns it uncached; returning an error vetoes the generation.
+	BeforeGenerate func(ctx context.Context, options *GenerateOptions, prompt *string) (*GenerationResult, error)
+	// AfterGenerate may edit the result before it is cached and returned; returning an error vetoes it.
+	AfterGenerate func(ctx context.Context, result *GenerationResult) error
+}
+
+// GeneratorOption configures a SQLGenerator in NewSQLGenerator
+type GeneratorOption func(*SQLGenerator)
+
+// WithHooks registers hooks invoked around every model call
+func WithHooks(hooks Hooks) GeneratorOption {
+	return func(g *SQLGenerator) {
+		g.hooks = hooks
+	}
+}
+
+// generateWithHooks runs the hooks around generate, which sends the prompt to the model.
+// Results short-circuited by BeforeGenerate are reported as not cacheable.
+func (g *SQLGenerator) generateWithHooks(ctx context.Context, prompt string, options *GenerateOptions, requestID string, generate func(prompt string, options *GenerateOptions) (*GenerationResult, error)) (result *GenerationResult, cacheable bool, err error) {
+	if before := g.hooks.BeforeGenerate; before != nil {
+		// The hook edits its own copy so the caller's options are never changed
+		hookOptions := *options
+		options = &hookOptions
+		result, err := before(ctx, options, &prompt)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %w", ErrGenerationRejected, err)
+		}
+		if result != nil {
+			if result.Metadata.RequestID == "" {
+				result.Metadata.RequestID = requestID
+			}
+			result.Metadata.ServedBy = ServedByHook
+			result.Metadata.DebugInfo = append(result.Metadata.DebugInfo, "returned by BeforeGenerate hook")
+			return result, false, nil
+		}
+	}
+
+	result, err = generate(prompt, options)
+	if err != nil {
+		return nil, false, err
+	}
+	if after := g.hooks.AfterGenerate; after != nil {
+		if err := after(ctx, result); err != nil {
+			return nil, false, fmt.Errorf("%w: %w", ErrGenerationRejected, err)
+		}
+		if result.NeedsConfirmation {
+			// Confirm releases the statement as edited by the hook
+			g.storeConfirmation(result.ConfirmationToken, result)
+		}
+	}
+	return result, true, nil
+}
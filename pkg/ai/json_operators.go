@@ -0,0 +1,178 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jsonOperators are the JSON and JSONB operators, longest first so "->>" wins over "->"
+var jsonOperators = []string{"#>>", "->>", "#>", "->", "@>", "<@", "?|", "?&", "#-"}
+
+// postgresOnlyJSONOperators have no MySQL equivalent operator
+var postgresOnlyJSONOperators = map[string]string{
+	"#>>": "JSON_UNQUOTE(JSON_EXTRACT(doc, '$.a.b'))",
+	"#>":  "JSON_EXTRACT(doc, '$.a.b')",
+	"@>":  "JSON_CONTAINS(doc, candidate)",
+	"<@":  "JSON_CONTAINS(candidate, doc)",
+	"?|":  "JSON_CONTAINS_PATH(doc, 'one', '$.a', '$.b')",
+	"?&":  "JSON_CONTAINS_PATH(doc, 'all', '$.a', '$.b')",
+	"#-":  "JSON_REMOVE(doc, '$.a')",
+}
+
+// jsonOperator is an operator found in a statement; Index is the position of its first token
+type jsonOperator struct {
+	Text  string
+	Index int
+	Pos   int
+}
+
+// findJSONOperators returns the JSON operators in tokens. Operators are lexed as adjacent
+// punctuation, so "a - > b" is not one.
+func findJSONOperators(tokens []sqlToken) []jsonOperator {
+	var operators []jsonOperator
+	for i := 0; i < len(tokens); i++ {
+		for _, operator := range jsonOperators {
+			if punctuationAt(tokens, i, operator) {
+				operators = append(operators, jsonOperator{Text: operator, Index: i, Pos: tokens[i].Pos})
+				i += len(operator) - 1
+				break
+			}
+		}
+	}
+	return operators
+}
+
+// punctuationAt reports whether the punctuation tokens starting at i spell operator without gaps
+func punctuationAt(tokens []sqlToken, i int, operator string) bool {
+	if i+len(operator) > len(tokens) {
+		return false
+	}
+	for offset, r := range operator {
+		token := tokens[i+offset]
+		if token.Kind != tokenPunct || token.Text != string(r) || token.Pos != tokens[i].Pos+offset {
+			return false
+		}
+	}
+	return true
+}
+
+// filtersOnJSON reports whether a JSON value is extracted inside the WHERE clause of tokens
+func filtersOnJSON(tokens []sqlToken, operators []jsonOperator, functions map[string]bool) bool {
+	inWhere := false
+	extractAt := make(map[int]bool, len(operators))
+	for _, operator := range operators {
+		if operator.Text == "->" || operator.Text == "->>" || operator.Text == "#>" || operator.Text == "#>>" {
+			extractAt[operator.Index] = true
+		}
+	}
+	for i, token := range tokens {
+		switch {
+		case token.Kind == tokenWord && token.Text == "WHERE":
+			inWhere = true
+		case token.Kind == tokenWord && (token.Text == "GROUP" || token.Text == "ORDER" || token.Text == "LIMIT" || token.Text == "HAVING"),
+			token.Text == ";":
+			inWhere = false
+		case inWhere && (extractAt[i] || (token.Kind == tokenWord && functions[token.Text] && i+1 < len(tokens) && tokens[i+1].Text == "(")):
+			return true
+		}
+	}
+	return false
+}
+
+// postgresJSONResults suggests indexable JSONB predicates when a query filters on extracted values
+func postgresJSONResults(sql string) []ValidationResult {
+	tokens := tokenizeSQL(sql)
+	operators := findJSONOperators(tokens)
+
+	var results []ValidationResult
+	for i, token := range tokens {
+		if token.Kind == tokenWord && token.Text == "JSON_EXTRACT" && i+1 < len(tokens) && tokens[i+1].Text == "(" {
+			line, column := runePosition([]rune(sql), token.Pos)
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    fmt.Sprintf("PostgreSQL has no JSON_EXTRACT function (line %d, column %d)", line, column),
+				Line:       line,
+				Column:     column,
+				Suggestion: "Use data->'name' for JSON, data->>'name' for text, or data #>> '{a,b}' for nested paths",
+			})
+			break
+		}
+	}
+	if filtersOnJSON(tokens, operators, nil) {
+		results = append(results, ValidationResult{
+			Type:       "performance",
+			Level:      "info",
+			Message:    "Filtering on values extracted with -> or ->> cannot use a GIN index on the JSONB column",
+			Suggestion: "Use containment such as data @> '{\"name\": \"x\"}' with a GIN index, or create an expression index on (data->>'name')",
+		})
+	}
+	return results
+}
+
+// mysqlJSONFunctions extract values from a JSON document
+var mysqlJSONFunctions = map[string]bool{"JSON_EXTRACT": true, "JSON_UNQUOTE": true, "JSON_VALUE": true}
+
+// mysqlJSONResults flags PostgreSQL-only JSON operators and JSON paths without the leading $,
+// and suggests indexable access when a query filters on extracted values
+func mysqlJSONResults(sql string) []ValidationResult {
+	runes := []rune(sql)
+	tokens := tokenizeSQL(sql)
+	operators := findJSONOperators(tokens)
+
+	var results []ValidationResult
+	for _, operator := range operators {
+		next := operator.Index + len(operator.Text)
+		if operator.Text == "<@" && next < len(tokens) && tokens[next].Kind == tokenWord && tokens[next].Pos == operator.Pos+2 {
+			// A comparison with a user variable, as in id<@max_id
+			continue
+		}
+		line, column := runePosition(runes, operator.Pos)
+		if alternative, ok := postgresOnlyJSONOperators[operator.Text]; ok {
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    fmt.Sprintf("MySQL does not support the %s JSON operator (line %d, column %d)", operator.Text, line, column),
+				Line:       line,
+				Column:     column,
+				Suggestion: "Use " + alternative,
+			})
+			continue
+		}
+		if next < len(tokens) && tokens[next].Kind == tokenString && !strings.HasPrefix(strings.Trim(tokens[next].Text, "'"), "$") {
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    fmt.Sprintf("MySQL JSON paths must start with $, got %s (line %d, column %d)", tokens[next].Text, line, column),
+				Line:       line,
+				Column:     column,
+				Suggestion: fmt.Sprintf("Write the path as '$.%s'", strings.Trim(tokens[next].Text, "'")),
+			})
+		}
+	}
+	if filtersOnJSON(tokens, operators, mysqlJSONFunctions) {
+		results = append(results, ValidationResult{
+			Type:       "performance",
+			Level:      "info",
+			Message:    "Filtering on JSON_EXTRACT or ->> results cannot use an index on the JSON column",
+			Suggestion: "Add an indexed generated column such as name VARCHAR(255) AS (data->>'$.name'), or use MEMBER OF / JSON_CONTAINS with a multi-valued index",
+		})
+	}
+	return results
+}
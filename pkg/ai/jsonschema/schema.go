@@ -0,0 +1,150 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonschema exports JSON Schema documents for the SQL generation request and
+// response types so that clients can stay in sync with the Go definitions.
+package jsonschema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai"
+)
+
+// draft is the JSON Schema dialect used by the exported documents
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema needed to describe the generation types.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false or *Schema
+}
+
+// RequestSchema describes ai.GenerateOptions.
+// No property is required because the generator applies defaults for omitted fields.
+func RequestSchema() *Schema {
+	schema := reflectType(reflect.TypeOf(ai.GenerateOptions{}), false)
+	schema.Schema = draft
+	schema.Title = "GenerateOptions"
+	schema.Description = "Options accepted by the SQL generator"
+	return schema
+}
+
+// ResponseSchema describes ai.GenerationResult.
+// Properties without omitempty are always present and therefore required.
+func ResponseSchema() *Schema {
+	schema := reflectType(reflect.TypeOf(ai.GenerationResult{}), true)
+	schema.Schema = draft
+	schema.Title = "GenerationResult"
+	schema.Description = "Result returned by the SQL generator"
+	return schema
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// reflectType builds a schema from a Go type using its json struct tags
+func reflectType(t reflect.Type, markRequired bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case durationType:
+		return &Schema{Type: "integer", Description: "duration in nanoseconds"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: reflectType(t.Elem(), markRequired)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reflectType(t.Elem(), markRequired)}
+	case reflect.Struct:
+		return reflectStruct(t, markRequired)
+	default:
+		return &Schema{}
+	}
+}
+
+func reflectStruct(t reflect.Type, markRequired bool) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := parseJSONTag(field)
+		if skip {
+			continue
+		}
+
+		schema.Properties[name] = reflectType(field.Type, markRequired)
+		if markRequired && !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func parseJSONTag(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
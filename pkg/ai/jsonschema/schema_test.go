@@ -0,0 +1,68 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSchemaValidatesSampleRequest(t *testing.T) {
+	schema := RequestSchema()
+	require.Equal(t, "object", schema.Type)
+	require.Contains(t, schema.Properties, "database_type")
+	require.NotContains(t, schema.Properties, "-")
+
+	sample := `{
+		"database_type": "postgresql",
+		"model": "llama3",
+		"max_tokens": 1024,
+		"validate_sql": true,
+		"context": ["orders are soft deleted"],
+		"schema": {"users": {"name": "users", "columns": [{"name": "id", "type": "int", "nullable": false}]}},
+		"custom_prompts": {"sql_generation": "Be concise"}
+	}`
+	require.NoError(t, Validate(schema, []byte(sample)))
+
+	assert.Error(t, Validate(schema, []byte(`{"max_tokens": "many"}`)))
+	assert.Error(t, Validate(schema, []byte(`{"unknown_field": 1}`)))
+	assert.Error(t, Validate(schema, []byte(`{"schema": {"users": {"columns": [{"nullable": "yes"}]}}}`)))
+}
+
+func TestResponseSchemaMatchesGenerationResult(t *testing.T) {
+	schema := ResponseSchema()
+	assert.Contains(t, schema.Required, "sql")
+	assert.NotContains(t, schema.Required, "validation_results")
+
+	result := ai.GenerationResult{
+		SQL:         "SELECT 1;",
+		Explanation: "constant",
+		Warnings:    []string{},
+		Suggestions: []string{},
+		Metadata:    ai.GenerationMetadata{RequestID: "sql_1", QueryType: "SELECT"},
+	}
+	payload, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.NoError(t, Validate(schema, payload))
+
+	_, err = json.Marshal(RequestSchema())
+	require.NoError(t, err)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Validate checks a JSON document against the schema.
+// Only the keywords produced by this package are supported.
+func Validate(schema *Schema, document []byte) error {
+	var value any
+	if err := json.Unmarshal(document, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return validateValue(schema, value, "$")
+}
+
+func validateValue(schema *Schema, value any, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return typeError(path, schema.Type, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(path, schema.Type, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return typeError(path, schema.Type, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return typeError(path, schema.Type, value)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return typeError(path, schema.Type, value)
+		}
+		var errs []error
+		for i, item := range items {
+			if err := validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return typeError(path, schema.Type, value)
+		}
+		return validateObject(schema, object, path)
+	}
+
+	return nil
+}
+
+func validateObject(schema *Schema, object map[string]any, path string) error {
+	var errs []error
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: missing required property %q", path, name))
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if property, ok := schema.Properties[key]; ok {
+			if err := validateValue(property, object[key], childPath); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, fmt.Errorf("%s: unknown property", childPath))
+			}
+		case *Schema:
+			if err := validateValue(additional, object[key], childPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func typeError(path, expected string, value any) error {
+	return fmt.Errorf("%s: expected %s, got %T", path, expected, value)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyEMAAlpha weights the newest sample in the exponential moving average
+	latencyEMAAlpha = 0.3
+	// latencyWindowSize is the number of recent samples kept per client for percentiles
+	latencyWindowSize = 256
+)
+
+// LatencyPercentiles summarizes the recent latency distribution of a client
+type LatencyPercentiles struct {
+	P50     time.Duration
+	P95     time.Duration
+	Samples int
+}
+
+// latencyTracker keeps an exponential moving average and a rolling window of generation latency per client.
+// The zero value is ready to use.
+type latencyTracker struct {
+	mu      sync.Mutex
+	ema     map[string]time.Duration
+	samples map[string]*latencyWindow
+	// probed is when each client last received a request, used to pace probes of demoted clients
+	probed map[string]time.Time
+}
+
+// latencyWindow is a fixed-size ring buffer of recent samples
+type latencyWindow struct {
+	values []time.Duration
+	next   int
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	if len(w.values) < latencyWindowSize {
+		w.values = append(w.values, latency)
+		return
+	}
+	w.values[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// record folds a new latency sample into the client's moving average
+func (t *latencyTracker) record(name string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ema == nil {
+		t.ema = make(map[string]time.Duration)
+		t.samples = make(map[string]*latencyWindow)
+	}
+	window, ok := t.samples[name]
+	if !ok {
+		window = &latencyWindow{}
+		t.samples[name] = window
+	}
+	window.add(latency)
+	if t.probed == nil {
+		t.probed = make(map[string]time.Time)
+	}
+	t.probed[name] = time.Now()
+
+	current, ok := t.ema[name]
+	if !ok {
+		t.ema[name] = latency
+		return
+	}
+	t.ema[name] = time.Duration(latencyEMAAlpha*float64(latency) + (1-latencyEMAAlpha)*float64(current))
+}
+
+// claimProbe reports whether a probe of the client is due and, if so, marks it as sent so
+// concurrent requests do not probe the same client again within interval
+func (t *latencyTracker) claimProbe(name string, now time.Time, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.probed[name]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if t.probed == nil {
+		t.probed = make(map[string]time.Time)
+	}
+	t.probed[name] = now
+	return true
+}
+
+// get returns the client's moving average, if any samples were recorded
+func (t *latencyTracker) get(name string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ema, ok := t.ema[name]
+	return ema, ok
+}
+
+// remove forgets the samples recorded for a client
+func (t *latencyTracker) remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.ema, name)
+	delete(t.samples, name)
+	delete(t.probed, name)
+}
+
+// snapshot returns a copy of all moving averages
+func (t *latencyTracker) snapshot() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[string]time.Duration, len(t.ema))
+	for name, ema := range t.ema {
+		result[name] = ema
+	}
+	return result
+}
+
+// percentiles returns p50/p95 over each client's rolling window of samples
+func (t *latencyTracker) percentiles() map[string]LatencyPercentiles {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[string]LatencyPercentiles, len(t.samples))
+	for name, window := range t.samples {
+		if len(window.values) == 0 {
+			continue
+		}
+		sorted := append([]time.Duration(nil), window.values...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		result[name] = LatencyPercentiles{
+			P50:     nearestRank(sorted, 0.50),
+			P95:     nearestRank(sorted, 0.95),
+			Samples: len(sorted),
+		}
+	}
+	return result
+}
+
+// nearestRank returns the nearest-rank percentile p (0-1] of sorted samples
+func nearestRank(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
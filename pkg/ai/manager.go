@@ -21,8 +21,11 @@ import (
 	cryptorand "crypto/rand"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -30,11 +33,13 @@ import (
 
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/discovery"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/models"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
 	"github.com/linuxsuren/atest-ext-ai/pkg/config"
 	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"github.com/linuxsuren/atest-ext-ai/pkg/metrics"
 )
 
 var (
@@ -49,6 +54,12 @@ var (
 
 	// ErrInvalidConfig is returned when the configuration is invalid
 	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// ErrProviderUnhealthy is returned when a provider fails the health check required to make it the default
+	ErrProviderUnhealthy = errors.New("provider is not healthy")
+
+	// ErrModelNotFound is returned when a provider does not offer the requested model
+	ErrModelNotFound = errors.New("model not found")
 )
 
 // ProviderConfigInfo captures metadata about a provider's requirements.
@@ -67,6 +78,10 @@ type ProviderInfo struct {
 	LastChecked time.Time                `json:"last_checked"`
 	Config      ProviderConfigInfo       `json:"config"`
 	Health      *interfaces.HealthStatus `json:"health,omitempty"`
+	// Configured is set when the manager holds a live client for the provider, so it is usable now
+	Configured bool `json:"configured"`
+	// Discovered is set when the provider was found running locally rather than listed by the catalog
+	Discovered bool `json:"discovered"`
 }
 
 // ConnectionTestResult represents the result of a connection test
@@ -92,6 +107,12 @@ type Manager struct {
 	config    config.AIConfig
 	discovery *discovery.OllamaDiscovery
 	mu        sync.RWMutex
+	latency   latencyTracker
+	circuits  circuitBreakers
+	health    healthCache
+	closeOnce sync.Once
+	// skipped records enabled services whose client could not be created, keyed by service name
+	skipped map[string]string
 }
 
 // NewAIManager creates a new unified AI manager.
@@ -118,43 +139,135 @@ func NewAIManager(cfg config.AIConfig) (*Manager, error) {
 	return manager, nil
 }
 
+// WaitForProviders polls provider health until every client is healthy or the startup timeout elapses.
+// It reports whether all providers became healthy; a timeout only logs, so startup always proceeds.
+// It is meant for process start only, configuration reloads must not block on it.
+func (m *Manager) WaitForProviders(ctx context.Context) bool {
+	startup := m.currentConfig().Startup
+	timeout := startup.Timeout.Value()
+	if timeout <= 0 {
+		timeout = constants.Startup.WaitTimeout
+	}
+	interval := startup.PollInterval.Value()
+	if interval <= 0 {
+		interval = constants.Startup.PollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for attempt := 1; ; attempt++ {
+		var pending []string
+		for name, status := range m.HealthCheckAll(ctx, true) {
+			if status == nil || !status.Healthy {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			logging.Logger.Info("All AI providers are ready", "attempts", attempt)
+			return true
+		}
+		sort.Strings(pending)
+		logging.Logger.Info("Waiting for AI providers to become ready", "pending", pending, "attempt", attempt)
+
+		select {
+		case <-ctx.Done():
+			logging.Logger.Warn("Timed out waiting for AI providers, continuing startup", "pending", pending, "timeout", timeout)
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
 // ===== Client Management (from ClientManager) =====
 
-// initializeClients creates clients for all enabled services
+// initializeClients creates clients for all enabled services. A service that fails is logged and
+// skipped; an error is only returned when services are enabled but none of them could be created.
 func (m *Manager) initializeClients() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	var errs []error
+	m.skipped = make(map[string]string)
 	for name, svc := range m.config.Services {
 		if !svc.Enabled {
 			continue
 		}
 
-		client, err := createClient(name, svc)
+		client, err := createClient(name, svc, m.config.Health)
 		if err != nil {
-			return fmt.Errorf("failed to create client %s: %w", name, err)
+			logging.Logger.Error("Skipping AI service that failed to initialize", "service", name, "error", err)
+			m.skipped[name] = err.Error()
+			errs = append(errs, fmt.Errorf("failed to create client %s: %w", name, err))
+			continue
 		}
 
 		m.clients[name] = client
 	}
 
+	if len(m.clients) == 0 && len(errs) > 0 {
+		return errors.Join(errs...)
+	}
 	return nil
 }
 
+// SkippedServices returns the enabled services that failed to initialize and the reason for each
+func (m *Manager) SkippedServices() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.skipped) == 0 {
+		return nil
+	}
+	skipped := make(map[string]string, len(m.skipped))
+	for name, reason := range m.skipped {
+		skipped[name] = reason
+	}
+	return skipped
+}
+
+// currentConfig returns a snapshot of the configuration; callers must not hold m.mu
+func (m *Manager) currentConfig() config.AIConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
+// SetRetryConfig replaces the retry policy used by subsequent generation attempts
+func (m *Manager) SetRetryConfig(retry config.RetryConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.Retry = retry
+}
+
 // Generate executes an AI generation request with inline retry logic
 func (m *Manager) Generate(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
 	var lastErr error
 	maxAttempts := 3
 
-	// Apply retry configuration if available
-	if m.config.Retry.MaxAttempts > 0 {
-		maxAttempts = m.config.Retry.MaxAttempts
+	// Apply retry configuration if available; the snapshot keeps one request on one policy
+	cfg := m.currentConfig()
+	if cfg.Retry.MaxAttempts > 0 {
+		maxAttempts = cfg.Retry.MaxAttempts
+	}
+	if req.MaxRetries != nil {
+		maxAttempts = max(*req.MaxRetries, 0) + 1
+	}
+
+	if err := m.checkServable(req); err != nil {
+		return nil, err
 	}
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		// Calculate backoff delay for retry attempts
 		if attempt > 0 {
-			delay := calculateBackoff(attempt, m.config.Retry)
+			delay := backoffDelay(attempt, cfg.Retry)
+			if suggested := suggestedRetryDelay(lastErr); suggested > 0 {
+				delay = suggested
+			}
 
 			select {
 			case <-time.After(delay):
@@ -165,47 +278,297 @@ func (m *Manager) Generate(ctx context.Context, req *interfaces.GenerateRequest)
 		}
 
 		// Select a healthy client
-		client := m.selectHealthyClient()
+		name, client := m.selectHealthyClient(req)
 		if client == nil {
 			lastErr = ErrNoHealthyClients
 			continue
 		}
 
 		// Execute the generation request
+		start := time.Now()
 		resp, err := client.Generate(ctx, req)
 		if err != nil {
 			// Check if error is retryable
 			if !isRetryableError(err) {
+				m.circuits.release(name)
 				return nil, err
 			}
+			m.circuits.recordFailure(name, time.Now())
 			lastErr = err
 			continue
 		}
 
+		m.circuits.recordSuccess(name)
+		m.recordLatency(name, time.Since(start))
+		if resp.Metadata == nil {
+			resp.Metadata = make(map[string]any)
+		}
+		resp.Metadata[MetadataServedBy] = name
+		if cfg.ABTest.Enabled {
+			resp.Metadata[MetadataABTestProvider] = name
+			metrics.RecordABSelection(name)
+		}
 		return resp, nil
 	}
 
 	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
 }
 
-// selectHealthyClient selects the best available client
-func (m *Manager) selectHealthyClient() interfaces.AIClient {
+// GenerateResponse.Metadata keys set by Manager.Generate
+const (
+	// MetadataServedBy names the client that answered the request
+	MetadataServedBy = "served_by"
+	// MetadataABTestProvider names the client picked while A/B testing is enabled
+	MetadataABTestProvider = "ab_test_provider"
+)
+
+// primaryClient returns the client first in selection order, or nil when there is none
+func (m *Manager) primaryClient() interfaces.AIClient {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Try default service first
-	if m.config.DefaultService != "" {
-		if client, ok := m.clients[m.config.DefaultService]; ok {
-			return client
+	names := m.orderedClientNames()
+	if len(names) == 0 {
+		return nil
+	}
+	return m.clients[names[0]]
+}
+
+// selectHealthyClient selects the best available client that may serve req
+func (m *Manager) selectHealthyClient(req *interfaces.GenerateRequest) (string, interfaces.AIClient) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name := m.abTestChoice(req); name != "" && m.circuits.acquire(name) {
+		return name, m.clients[name]
+	}
+
+	// A demoted client that would otherwise be preferred gets one probe request per interval,
+	// so its latency average can recover once the provider is fast again
+	threshold := m.config.LatencyThreshold.Duration
+	now := time.Now()
+	for _, name := range m.preferredClientNames() {
+		if !m.servesRequest(name, req) || !m.circuits.allow(name) {
+			continue
+		}
+		if !m.exceedsLatency(name, threshold) {
+			break
+		}
+		if m.latency.claimProbe(name, now, constants.Latency.ProbeInterval) && m.circuits.acquire(name) {
+			return name, m.clients[name]
 		}
 	}
 
-	// Return any available client
-	for _, client := range m.clients {
-		return client
+	for _, name := range m.orderedClientNames() {
+		if m.servesRequest(name, req) && m.circuits.acquire(name) {
+			return name, m.clients[name]
+		}
 	}
 
-	return nil
+	return "", nil
+}
+
+// servesRequest reports whether the named client may answer req: an explicitly requested model
+// must be allowed by the service, and a seeded request needs a provider that honours seeds, so
+// routing can never hand a reproducible request to a client that ignores the seed.
+// Callers must hold m.mu.
+func (m *Manager) servesRequest(name string, req *interfaces.GenerateRequest) bool {
+	if req == nil {
+		return true
+	}
+	return m.modelAllowed(name, req.Model) && (req.Seed == nil || m.supportsSeed(name))
+}
+
+// modelAllowed reports whether model is empty, the service's model or in its models allowlist,
+// when it has one. Callers must hold m.mu.
+func (m *Manager) modelAllowed(name, model string) bool {
+	model = strings.TrimSpace(model)
+	service := m.config.Services[name]
+	return model == "" || len(service.Models) == 0 || model == service.Model || slices.Contains(service.Models, model)
+}
+
+// supportsSeed reports whether the named client's provider honours GenerateRequest.Seed; clients
+// added without a configured service are identified by their name. Callers must hold m.mu.
+func (m *Manager) supportsSeed(name string) bool {
+	provider := m.config.Services[name].Provider
+	if provider == "" {
+		provider = name
+	}
+	return universal.SupportsSeed(providers.Normalize(provider))
+}
+
+// checkServable returns an error when no client may answer req
+func (m *Manager) checkServable(req *interfaces.GenerateRequest) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	modelAllowed := false
+	for name := range m.clients {
+		if m.servesRequest(name, req) {
+			return nil
+		}
+		modelAllowed = modelAllowed || m.modelAllowed(name, req.Model)
+	}
+	if len(m.clients) == 0 {
+		return nil
+	}
+	if !modelAllowed {
+		return fmt.Errorf("%w: %q is not in the models list of any available service", ErrModelNotAllowed, req.Model)
+	}
+	return fmt.Errorf("%w: no available service supports seeds", ErrNotReproducible)
+}
+
+// orderedClientNames returns client names in selection order: the preferred order of
+// preferredClientNames with clients slower than ai.latency_threshold moved behind the faster ones.
+// Callers must hold m.mu.
+func (m *Manager) orderedClientNames() []string {
+	names := m.preferredClientNames()
+	threshold := m.config.LatencyThreshold.Duration
+	if threshold <= 0 {
+		return names
+	}
+
+	// Stable partition: fast clients keep their relative order ahead of demoted ones
+	fast := make([]string, 0, len(names))
+	var slow []string
+	for _, name := range names {
+		if m.exceedsLatency(name, threshold) {
+			slow = append(slow, name)
+		} else {
+			fast = append(fast, name)
+		}
+	}
+	return append(fast, slow...)
+}
+
+// preferredClientNames returns client names in configured order: the default service,
+// the configured fallback order, then the remaining clients by priority and name.
+// Callers must hold m.mu.
+func (m *Manager) preferredClientNames() []string {
+	names := make([]string, 0, len(m.clients))
+	seen := make(map[string]struct{}, len(m.clients))
+	add := func(name string) {
+		if _, ok := m.clients[name]; !ok {
+			return
+		}
+		if _, ok := seen[name]; ok {
+			return
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	add(m.config.DefaultService)
+	for _, name := range m.config.Fallback {
+		add(name)
+	}
+
+	remaining := make([]string, 0, len(m.clients))
+	for name := range m.clients {
+		if _, ok := seen[name]; !ok {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		pi, pj := m.config.Services[remaining[i]].Priority, m.config.Services[remaining[j]].Priority
+		if pi != pj {
+			return pi < pj
+		}
+		return remaining[i] < remaining[j]
+	})
+	for _, name := range remaining {
+		add(name)
+	}
+	return names
+}
+
+// abTestChoice picks a weighted-random provider among the A/B candidates that may serve req, or ""
+// when A/B testing is off. Callers must hold m.mu.
+func (m *Manager) abTestChoice(req *interfaces.GenerateRequest) string {
+	if !m.config.ABTest.Enabled {
+		return ""
+	}
+
+	candidates := make([]string, 0, len(m.config.ABTest.Weights))
+	totalWeight := 0
+	for name, weight := range m.config.ABTest.Weights {
+		if weight <= 0 || m.clients[name] == nil || !m.servesRequest(name, req) || m.exceedsLatency(name, m.config.LatencyThreshold.Duration) || !m.circuits.allow(name) {
+			continue
+		}
+		candidates = append(candidates, name)
+		totalWeight += weight
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+
+	n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(totalWeight)))
+	if err != nil {
+		logging.Logger.Debug("failed to draw A/B selection, using first candidate", "error", err)
+		return candidates[0]
+	}
+	pick := int(n.Int64())
+	for _, name := range candidates {
+		pick -= m.config.ABTest.Weights[name]
+		if pick < 0 {
+			return name
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// recordLatency updates the client's latency moving average and logs demotion changes
+func (m *Manager) recordLatency(name string, latency time.Duration) {
+	threshold := m.currentConfig().LatencyThreshold.Duration
+	wasDemoted := m.exceedsLatency(name, threshold)
+	m.latency.record(name, latency)
+	if demoted := m.exceedsLatency(name, threshold); demoted != wasDemoted {
+		ema, _ := m.latency.get(name)
+		logging.Logger.Info("AI client latency demotion changed",
+			"client", name,
+			"demoted", demoted,
+			"latency_ema", ema,
+			"threshold", threshold)
+	}
+}
+
+// isDemoted reports whether the client's latency moving average exceeds ai.latency_threshold.
+// Callers must not hold m.mu.
+func (m *Manager) isDemoted(name string) bool {
+	return m.exceedsLatency(name, m.currentConfig().LatencyThreshold.Duration)
+}
+
+// exceedsLatency reports whether the client's latency moving average exceeds threshold
+func (m *Manager) exceedsLatency(name string, threshold time.Duration) bool {
+	if threshold <= 0 {
+		return false
+	}
+	ema, ok := m.latency.get(name)
+	return ok && ema > threshold
+}
+
+// CircuitStates returns the circuit breaker state of every client
+func (m *Manager) CircuitStates() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	states := make(map[string]string, len(m.clients))
+	for name := range m.clients {
+		states[name] = m.circuits.state(name, now)
+	}
+	return states
+}
+
+// LatencyEMA returns the generation latency moving average for each client with samples
+func (m *Manager) LatencyEMA() map[string]time.Duration {
+	return m.latency.snapshot()
+}
+
+// LatencyPercentiles returns p50/p95 generation latency per client over recent requests
+func (m *Manager) LatencyPercentiles() map[string]LatencyPercentiles {
+	return m.latency.percentiles()
 }
 
 // GetClient returns a specific client by name
@@ -235,6 +598,39 @@ func (m *Manager) GetAllClients() map[string]interfaces.AIClient {
 	return clients
 }
 
+// DefaultProvider returns the name of the client preferred by selection
+func (m *Manager) DefaultProvider() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.DefaultService
+}
+
+// SetDefaultProvider makes an existing, healthy client the preferred one for subsequent requests
+func (m *Manager) SetDefaultProvider(ctx context.Context, name string) error {
+	client, err := m.GetClient(name)
+	if err != nil {
+		return err
+	}
+
+	status, err := client.HealthCheck(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrProviderUnhealthy, name, err)
+	}
+	if status == nil || !status.Healthy {
+		return fmt.Errorf("%w: %s", ErrProviderUnhealthy, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrClientNotFound, name)
+	}
+	previous := m.config.DefaultService
+	m.config.DefaultService = name
+	logging.Logger.Info("Default AI provider changed", "from", previous, "to", name)
+	return nil
+}
+
 // GetPrimaryClient returns the primary (default) client
 func (m *Manager) GetPrimaryClient() interfaces.AIClient {
 	m.mu.RLock()
@@ -270,7 +666,7 @@ func (m *Manager) AddClient(ctx context.Context, name string, svc config.AIServi
 		opts.HealthCheckTimeout = 5 * time.Second
 	}
 
-	client, err := createClient(name, svc)
+	client, err := createClient(name, svc, m.currentConfig().Health)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
@@ -307,6 +703,8 @@ func (m *Manager) AddClient(ctx context.Context, name string, svc config.AIServi
 	}
 
 	m.clients[name] = client
+	m.health.remove(name)
+	delete(m.skipped, name)
 	logging.Logger.Info("AI client added successfully",
 		"client", name,
 		"skip_health_check", opts.SkipHealthCheck)
@@ -330,6 +728,9 @@ func (m *Manager) RemoveClient(name string) error {
 			"error", err)
 	}
 	delete(m.clients, name)
+	m.latency.remove(name)
+	m.circuits.remove(name)
+	m.health.remove(name)
 	return nil
 }
 
@@ -340,7 +741,7 @@ func (m *Manager) DiscoverProviders(ctx context.Context) ([]*ProviderInfo, error
 	var providers []*ProviderInfo
 
 	// Check for Ollama
-	if m.discovery.IsAvailable(ctx) {
+	if m.discovery != nil && m.discovery.IsAvailable(ctx) {
 		endpoint := m.discovery.GetBaseURL()
 
 		// Create temporary Ollama client for discovery
@@ -370,6 +771,7 @@ func (m *Manager) DiscoverProviders(ctx context.Context) ([]*ProviderInfo, error
 					ProviderType:   "local",
 					RequiresAPIKey: false,
 				},
+				Discovered: true,
 			}
 
 			providers = append(providers, provider)
@@ -384,13 +786,67 @@ func (m *Manager) DiscoverProviders(ctx context.Context) ([]*ProviderInfo, error
 	// Add online providers
 	providers = append(providers, m.getOnlineProviders()...)
 
-	return providers, nil
+	return m.reconcileConfigured(providers), nil
+}
+
+// reconcileConfigured marks providers backed by a live client and appends configured
+// providers that neither discovery nor the catalog reported
+func (m *Manager) reconcileConfigured(infos []*ProviderInfo) []*ProviderInfo {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.clients))
+	for name := range m.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	configured := make(map[string]config.AIService, len(names))
+	var order []string
+	for _, name := range names {
+		service := m.config.Services[name]
+		provider := providers.Normalize(service.Provider)
+		if provider == "" {
+			provider = providers.Normalize(name)
+		}
+		if _, seen := configured[provider]; !seen {
+			configured[provider] = service
+			order = append(order, provider)
+		}
+	}
+	m.mu.RUnlock()
+
+	listed := make(map[string]bool, len(infos))
+	for _, provider := range infos {
+		name := providers.Normalize(provider.Name)
+		listed[name] = true
+		_, provider.Configured = configured[name]
+	}
+	for _, name := range order {
+		if listed[name] {
+			continue
+		}
+		providerType := "cloud"
+		if name == "ollama" {
+			providerType = "local"
+		}
+		infos = append(infos, &ProviderInfo{
+			Name:        name,
+			Type:        providerType,
+			Available:   true,
+			Endpoint:    configured[name].Endpoint,
+			LastChecked: time.Now(),
+			Config: ProviderConfigInfo{
+				RequiresAPIKey: name != "ollama",
+				ProviderType:   providerType,
+			},
+			Configured: true,
+		})
+	}
+	return infos
 }
 
 // GetModels returns models for a specific provider
 func (m *Manager) GetModels(ctx context.Context, providerName string) ([]interfaces.ModelInfo, error) {
 	// Normalize provider name (local -> ollama)
-	providerName = normalizeProviderName(providerName)
+	providerName = providers.Normalize(providerName)
 
 	m.mu.RLock()
 	client, exists := m.clients[providerName]
@@ -467,9 +923,9 @@ func (m *Manager) TestConnection(ctx context.Context, cfg *universal.Config) (*C
 
 // ===== On-Demand Health Checking =====
 
-// HealthCheck checks health of a specific provider
+// HealthCheck probes a specific provider, bypassing and refreshing the health cache
 func (m *Manager) HealthCheck(ctx context.Context, provider string) (*interfaces.HealthStatus, error) {
-	provider = normalizeProviderName(provider)
+	provider = providers.Normalize(provider)
 
 	m.mu.RLock()
 	client, exists := m.clients[provider]
@@ -479,37 +935,49 @@ func (m *Manager) HealthCheck(ctx context.Context, provider string) (*interfaces
 		return nil, fmt.Errorf("provider not found: %s", provider)
 	}
 
-	return client.HealthCheck(ctx)
+	status, err := client.HealthCheck(ctx)
+	if err == nil {
+		m.health.put(provider, status, time.Now())
+	}
+	return status, err
 }
 
-// HealthCheckAll checks health of all providers
-func (m *Manager) HealthCheckAll(ctx context.Context) map[string]*interfaces.HealthStatus {
+// HealthCheckAll checks health of all providers concurrently. Results younger than
+// ai.health.cache_ttl are returned from the cache; force probes every provider regardless.
+func (m *Manager) HealthCheckAll(ctx context.Context, force bool) map[string]*interfaces.HealthStatus {
 	m.mu.RLock()
 	clients := make(map[string]interfaces.AIClient)
 	for name, client := range m.clients {
 		clients[name] = client
 	}
+	ttl := m.config.Health.CacheTTL.Value()
 	m.mu.RUnlock()
+	if ttl <= 0 {
+		ttl = constants.Health.CacheTTL
+	}
 
 	results := make(map[string]*interfaces.HealthStatus)
 
-	// Check each client concurrently
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
+	now := time.Now()
 	for name, client := range clients {
+		if !force {
+			if status, ok := m.health.get(name, ttl, now); ok {
+				mu.Lock()
+				results[name] = status
+				mu.Unlock()
+				continue
+			}
+		}
+
 		wg.Add(1)
 
 		go func(name string, client interfaces.AIClient) {
 			defer wg.Done()
 
-			status, err := client.HealthCheck(ctx)
-			if err != nil {
-				status = &interfaces.HealthStatus{
-					Healthy: false,
-					Status:  err.Error(),
-				}
-			}
+			status := m.health.check(ctx, name, client)
 
 			mu.Lock()
 			results[name] = status
@@ -521,38 +989,41 @@ func (m *Manager) HealthCheckAll(ctx context.Context) map[string]*interfaces.Hea
 	return results
 }
 
-// Close closes all clients
+// Close closes all clients.
+// Only the first call closes the clients; later calls are no-ops.
 func (m *Manager) Close() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	var closeErr error
+	m.closeOnce.Do(func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
 
-	var errors []error
-	for name, client := range m.clients {
-		if err := client.Close(); err != nil {
-			errors = append(errors, fmt.Errorf("failed to close client %s: %w", name, err))
+		var errors []error
+		for name, client := range m.clients {
+			if err := client.Close(); err != nil {
+				errors = append(errors, fmt.Errorf("failed to close client %s: %w", name, err))
+			}
 		}
-	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("errors occurred while closing clients: %v", errors)
-	}
 
-	return nil
+		if len(errors) > 0 {
+			closeErr = fmt.Errorf("errors occurred while closing clients: %v", errors)
+		}
+	})
+	return closeErr
 }
 
 // ===== Helper Functions =====
 
 // createClient creates a client based on provider name and configuration
-func createClient(provider string, cfg config.AIService) (interfaces.AIClient, error) {
+func createClient(provider string, cfg config.AIService, health config.HealthConfig) (interfaces.AIClient, error) {
 	// Normalize provider name
-	provider = normalizeProviderName(provider)
+	provider = providers.Normalize(provider)
 
 	switch provider {
 	case "openai", "deepseek", "custom":
-		return createOpenAICompatibleClient(provider, cfg)
+		return createOpenAICompatibleClient(provider, cfg, health)
 
 	case "ollama":
-		return createOllamaClient(cfg)
+		return createOllamaClient(cfg, health)
 
 	default:
 		return nil, fmt.Errorf("%w: %s", ErrProviderNotSupported, provider)
@@ -560,16 +1031,20 @@ func createClient(provider string, cfg config.AIService) (interfaces.AIClient, e
 }
 
 // createOpenAICompatibleClient creates an OpenAI-compatible client
-func createOpenAICompatibleClient(provider string, cfg config.AIService) (interfaces.AIClient, error) {
+func createOpenAICompatibleClient(provider string, cfg config.AIService, health config.HealthConfig) (interfaces.AIClient, error) {
 	normalized := strings.ToLower(provider)
 
 	uniCfg := &universal.Config{
-		Provider:  normalized,
-		Endpoint:  normalizeProviderEndpoint(normalized, cfg.Endpoint),
-		APIKey:    cfg.APIKey,
-		Model:     cfg.Model,
-		MaxTokens: cfg.MaxTokens,
-		Timeout:   cfg.Timeout.Value(),
+		Provider:        normalized,
+		Endpoint:        normalizeProviderEndpoint(normalized, cfg.Endpoint),
+		APIKey:          cfg.APIKey,
+		Model:           cfg.Model,
+		MaxTokens:       cfg.MaxTokens,
+		Timeout:         cfg.Timeout.Value(),
+		HealthPath:      cfg.HealthPath,
+		Stop:            stopSequences(cfg.Stop),
+		DeepHealthCheck: health.Deep,
+		CacheControl:    cfg.CacheControl,
 	}
 
 	if uniCfg.Endpoint == "" {
@@ -584,13 +1059,17 @@ func createOpenAICompatibleClient(provider string, cfg config.AIService) (interf
 }
 
 // createOllamaClient creates an Ollama client
-func createOllamaClient(cfg config.AIService) (interfaces.AIClient, error) {
+func createOllamaClient(cfg config.AIService, health config.HealthConfig) (interfaces.AIClient, error) {
 	config := &universal.Config{
-		Provider:  "ollama",
-		Endpoint:  cfg.Endpoint,
-		Model:     cfg.Model,
-		MaxTokens: cfg.MaxTokens,
-		Timeout:   cfg.Timeout.Value(),
+		Provider:             "ollama",
+		Endpoint:             cfg.Endpoint,
+		Model:                cfg.Model,
+		MaxTokens:            cfg.MaxTokens,
+		Timeout:              cfg.Timeout.Value(),
+		ModelRefreshInterval: cfg.ModelRefreshInterval.Value(),
+		HealthPath:           cfg.HealthPath,
+		Stop:                 stopSequences(cfg.Stop),
+		DeepHealthCheck:      health.Deep,
 	}
 
 	// Default endpoint
@@ -601,13 +1080,12 @@ func createOllamaClient(cfg config.AIService) (interfaces.AIClient, error) {
 	return universal.NewUniversalClient(config)
 }
 
-// normalizeProviderName normalizes provider name (local -> ollama)
-func normalizeProviderName(provider string) string {
-	provider = strings.ToLower(strings.TrimSpace(provider))
-	if provider == "local" {
-		return "ollama"
+// stopSequences returns the configured stop sequences, or the builtin markers when none are set
+func stopSequences(configured []string) []string {
+	if configured == nil {
+		return constants.DefaultStopSequences
 	}
-	return provider
+	return configured
 }
 
 // getOnlineProviders returns predefined online providers
@@ -652,16 +1130,45 @@ func (m *Manager) getOnlineProviders() []*ProviderInfo {
 
 // ===== Retry Logic =====
 
-// calculateBackoff calculates exponential backoff delay
-func calculateBackoff(attempt int, retryCfg config.RetryConfig) time.Duration {
-	if attempt == 0 {
+// Retry backoff defaults used when RetryConfig leaves a value unset
+const (
+	defaultBackoffBaseDelay  = 1 * time.Second
+	defaultBackoffMaxDelay   = 10 * time.Second
+	defaultBackoffMultiplier = 2.0
+	// maxSuggestedRetryDelay caps the delay a provider can ask for before the next attempt
+	maxSuggestedRetryDelay = time.Minute
+)
+
+// suggestedRetryDelay returns the delay the provider asked for in a rate-limit response, capped at
+// maxSuggestedRetryDelay, or 0 when err carries no suggestion
+func suggestedRetryDelay(err error) time.Duration {
+	var providerErr *interfaces.ProviderError
+	if !errors.As(err, &providerErr) {
+		return 0
+	}
+	return min(providerErr.RetryAfter, maxSuggestedRetryDelay)
+}
+
+// jitterSource returns a random value in [0, limit); tests replace it for deterministic delays
+var jitterSource = func(limit int64) (int64, error) {
+	n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(limit))
+	if err != nil {
+		return 0, err
+	}
+	return n.Int64(), nil
+}
+
+// backoffDelay returns the wait before retry number attempt (1 for the first retry).
+// The delay is base * multiplier^(attempt-1), capped at the max delay; with jitter
+// enabled up to a quarter of the capped delay is added on top. Attempt 0 waits nothing.
+func backoffDelay(attempt int, retryCfg config.RetryConfig) time.Duration {
+	if attempt <= 0 {
 		return 0
 	}
 
-	// Use configured values or defaults
-	baseDelay := 1 * time.Second
-	maxDelay := 10 * time.Second
-	multiplier := 2.0
+	baseDelay := defaultBackoffBaseDelay
+	maxDelay := defaultBackoffMaxDelay
+	multiplier := defaultBackoffMultiplier
 	if retryCfg.InitialDelay.Duration > 0 {
 		baseDelay = retryCfg.InitialDelay.Duration
 	}
@@ -671,34 +1178,26 @@ func calculateBackoff(attempt int, retryCfg config.RetryConfig) time.Duration {
 	if retryCfg.Multiplier > 0 {
 		multiplier = float64(retryCfg.Multiplier)
 	}
-	jitter := retryCfg.Jitter
 
-	// Calculate exponential backoff
-	delay := baseDelay
-	for i := 1; i < attempt; i++ {
-		delay = time.Duration(float64(delay) * multiplier)
-		if delay > maxDelay {
-			delay = maxDelay
-			break
-		}
+	// Compare in float64 so large attempts cannot overflow time.Duration
+	delay := maxDelay
+	if exponential := float64(baseDelay) * math.Pow(multiplier, float64(attempt-1)); exponential < float64(maxDelay) {
+		delay = time.Duration(exponential)
 	}
 
-	// Add jitter
-	if jitter {
-		jitterRange := delay / 4
-		if jitterRange > 0 {
-			rangeLimit := big.NewInt(int64(jitterRange))
-			n, err := cryptorand.Int(cryptorand.Reader, rangeLimit)
-			if err != nil {
-				logging.Logger.Debug("failed to generate crypto jitter, using deterministic midpoint", "error", err)
-				delay += jitterRange / 2
-			} else {
-				delay += time.Duration(n.Int64())
-			}
-		}
+	if !retryCfg.Jitter {
+		return delay
 	}
-
-	return delay
+	jitterRange := int64(delay / 4)
+	if jitterRange <= 0 {
+		return delay
+	}
+	n, err := jitterSource(jitterRange)
+	if err != nil {
+		logging.Logger.Debug("failed to generate crypto jitter, using deterministic midpoint", "error", err)
+		return delay + time.Duration(jitterRange/2)
+	}
+	return delay + time.Duration(n)
 }
 
 // isRetryableError determines if an error is retryable
@@ -772,3 +1271,37 @@ func isRetryableError(err error) bool {
 	// Default: not retryable
 	return false
 }
+
+// managerClient routes generation through Manager.Generate so every request takes part in
+// A/B selection, latency tracking, circuit breaking and retries
+type managerClient struct {
+	manager *Manager
+}
+
+// Generate implements interfaces.AIClient via Manager.Generate
+func (c *managerClient) Generate(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+	return c.manager.Generate(ctx, req)
+}
+
+// GetCapabilities implements interfaces.AIClient using the primary client
+func (c *managerClient) GetCapabilities(ctx context.Context) (*interfaces.Capabilities, error) {
+	client := c.manager.primaryClient()
+	if client == nil {
+		return nil, ErrNoHealthyClients
+	}
+	return client.GetCapabilities(ctx)
+}
+
+// HealthCheck implements interfaces.AIClient using the primary client
+func (c *managerClient) HealthCheck(ctx context.Context) (*interfaces.HealthStatus, error) {
+	client := c.manager.primaryClient()
+	if client == nil {
+		return nil, ErrNoHealthyClients
+	}
+	return client.HealthCheck(ctx)
+}
+
+// Close implements interfaces.AIClient; the manager owns and closes the underlying clients
+func (c *managerClient) Close() error {
+	return nil
+}
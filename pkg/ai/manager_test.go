@@ -0,0 +1,866 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/discovery"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyDemotionChangesSelectionOrder(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService:   "primary",
+		LatencyThreshold: config.Duration{Duration: 100 * time.Millisecond},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary":   &stubAIClient{},
+		"secondary": &stubAIClient{},
+	})
+
+	name, _ := manager.selectHealthyClient(nil)
+	require.Equal(t, "primary", name)
+
+	manager.recordLatency("primary", 500*time.Millisecond)
+	manager.recordLatency("secondary", 20*time.Millisecond)
+	assert.True(t, manager.isDemoted("primary"))
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "secondary", name, "slow default service is demoted")
+
+	for i := 0; i < 10; i++ {
+		manager.recordLatency("primary", 10*time.Millisecond)
+	}
+	assert.False(t, manager.isDemoted("primary"))
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "primary", name, "default service recovers once latency improves")
+
+	assert.Contains(t, manager.LatencyEMA(), "primary")
+}
+
+func TestOrderedClientNamesWithoutThreshold(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "b",
+		Fallback:       []string{"c"},
+		Services: map[string]config.AIService{
+			"a": {Priority: 2},
+			"d": {Priority: 1},
+		},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"a": &stubAIClient{}, "b": &stubAIClient{}, "c": &stubAIClient{}, "d": &stubAIClient{},
+	})
+	manager.recordLatency("b", time.Hour)
+
+	assert.Equal(t, []string{"b", "c", "d", "a"}, manager.orderedClientNames())
+}
+
+func TestNewAIManagerWaitsForProviders(t *testing.T) {
+	var readyAt atomic.Int64
+	readyAt.Store(time.Now().Add(150 * time.Millisecond).UnixNano())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if time.Now().UnixNano() < readyAt.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":[]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.AIConfig{
+		DefaultService: "ollama",
+		Services: map[string]config.AIService{
+			"ollama": {Enabled: true, Provider: "ollama", Endpoint: server.URL, Model: "llama3"},
+		},
+		Startup: config.StartupConfig{
+			WaitForProviders: true,
+			Timeout:          config.Duration{Duration: 5 * time.Second},
+			PollInterval:     config.Duration{Duration: 20 * time.Millisecond},
+		},
+	}
+
+	manager, err := NewAIManager(cfg)
+	require.NoError(t, err)
+	defer func() { _ = manager.Close() }()
+	assert.Less(t, time.Now().UnixNano(), readyAt.Load(), "building a manager, as reloads do, must not wait")
+
+	assert.True(t, manager.WaitForProviders(t.Context()))
+	assert.GreaterOrEqual(t, time.Now().UnixNano(), readyAt.Load(), "startup should wait until the provider is healthy")
+
+	// A provider that never becomes healthy only delays startup until the timeout
+	readyAt.Store(time.Now().Add(time.Hour).UnixNano())
+	manager.config.Startup.Timeout = config.Duration{Duration: 100 * time.Millisecond}
+	assert.False(t, manager.WaitForProviders(t.Context()))
+}
+
+func TestABTestSelectionFollowsWeights(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "control",
+		ABTest: config.ABTestConfig{
+			Enabled: true,
+			Weights: map[string]int{"control": 3, "candidate": 1, "disabled": 0},
+		},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"control":   &stubAIClient{},
+		"candidate": &stubAIClient{},
+		"disabled":  &stubAIClient{},
+	})
+
+	const calls = 4000
+	counts := map[string]int{}
+	for i := 0; i < calls; i++ {
+		name, client := manager.selectHealthyClient(nil)
+		require.NotNil(t, client)
+		counts[name]++
+	}
+	assert.Zero(t, counts["disabled"])
+	assert.InDelta(t, 0.75, float64(counts["control"])/calls, 0.05)
+	assert.InDelta(t, 0.25, float64(counts["candidate"])/calls, 0.05)
+
+	resp, err := manager.Generate(t.Context(), &interfaces.GenerateRequest{Prompt: "list users"})
+	require.NoError(t, err)
+	assert.Contains(t, []any{"control", "candidate"}, resp.Metadata["ab_test_provider"])
+
+	manager.config.ABTest.Enabled = false
+	for i := 0; i < 20; i++ {
+		name, _ := manager.selectHealthyClient(nil)
+		require.Equal(t, "control", name, "disabled A/B testing keeps the default ordering")
+	}
+}
+
+func TestEngineGenerationUsesABTestRouting(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "control",
+		ABTest: config.ABTestConfig{
+			Enabled: true,
+			Weights: map[string]int{"control": 1, "candidate": 1},
+		},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"control": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		}},
+		"candidate": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 2;"}, nil
+		}},
+	})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		resp, err := engine.GenerateSQL(context.Background(), &GenerateSQLRequest{
+			NaturalLanguage: fmt.Sprintf("count users %d", i),
+			DatabaseType:    "mysql",
+		})
+		require.NoError(t, err)
+		require.Equal(t, resp.ServedBy, resp.ABTestProvider)
+		counts[resp.ABTestProvider]++
+	}
+	assert.Positive(t, counts["control"])
+	assert.Positive(t, counts["candidate"], "generator requests take part in A/B routing")
+}
+
+func TestRoutedGenerationHonorsModelAllowlist(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "ollama",
+		Services: map[string]config.AIService{
+			"ollama": {Enabled: true, Provider: "ollama", Model: "llama3.2:1b"},
+			"openai": {Enabled: true, Provider: "openai", Model: "gpt-4o-mini", Models: []string{"gpt-4o-mini"}},
+		},
+		ABTest: config.ABTestConfig{Enabled: true, Weights: map[string]int{"openai": 1}},
+	}
+	var openaiModels []string
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		}},
+		"openai": &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			openaiModels = append(openaiModels, req.Model)
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 2;"}, nil
+		}},
+	})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		resp, err := engine.GenerateSQL(context.Background(), &GenerateSQLRequest{
+			NaturalLanguage: fmt.Sprintf("count users %d", i),
+			DatabaseType:    "mysql",
+			Context:         map[string]string{"preferred_model": "gpt-4-expensive"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ollama", resp.ServedBy, "A/B routing skips services whose allowlist excludes the model")
+	}
+	assert.Empty(t, openaiModels)
+
+	resp, err := engine.GenerateSQL(context.Background(), &GenerateSQLRequest{
+		NaturalLanguage: "count orders",
+		DatabaseType:    "mysql",
+		Context:         map[string]string{"preferred_model": "gpt-4o-mini"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "openai", resp.ServedBy)
+
+	delete(manager.clients, "ollama")
+	_, err = manager.Generate(t.Context(), &interfaces.GenerateRequest{Prompt: "count users", Model: "gpt-4-expensive"})
+	require.ErrorIs(t, err, ErrModelNotAllowed, "failover never reaches a service that excludes the model")
+	assert.Equal(t, []string{"gpt-4o-mini"}, openaiModels)
+}
+
+func TestSeededRequestsAreRoutedOnlyToSeedCapableClients(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "ollama",
+		Services: map[string]config.AIService{
+			"ollama":   {Enabled: true, Provider: "ollama", Model: "llama3.2:1b"},
+			"deepseek": {Enabled: true, Provider: "deepseek", Model: "deepseek-chat"},
+		},
+		ABTest: config.ABTestConfig{Enabled: true, Weights: map[string]int{"deepseek": 1}},
+	}
+	var deepseekSeeds int
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		}},
+		"deepseek": &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			if req.Seed != nil {
+				deepseekSeeds++
+			}
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 2;"}, nil
+		}},
+	})
+
+	seed := int64(7)
+	for i := 0; i < 20; i++ {
+		resp, err := manager.Generate(t.Context(), &interfaces.GenerateRequest{Prompt: fmt.Sprintf("count users %d", i), Seed: &seed})
+		require.NoError(t, err)
+		assert.Equal(t, "sql:SELECT 1;", resp.Text, "A/B routing skips providers that ignore seeds")
+	}
+	assert.Zero(t, deepseekSeeds)
+
+	delete(manager.clients, "ollama")
+	_, err := manager.Generate(t.Context(), &interfaces.GenerateRequest{Prompt: "count users", Seed: &seed})
+	require.ErrorIs(t, err, ErrNotReproducible, "failover never reaches a provider that ignores seeds")
+	assert.Zero(t, deepseekSeeds)
+
+	_, err = manager.Generate(t.Context(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	primary, secondary := &stubAIClient{}, &stubAIClient{}
+	manager := newTestManager(config.AIConfig{}, map[string]interfaces.AIClient{"primary": primary, "secondary": secondary})
+
+	require.NotPanics(t, func() {
+		require.NoError(t, manager.Close())
+		require.NoError(t, manager.Close())
+	})
+	assert.Equal(t, 1, primary.closed)
+	assert.Equal(t, 1, secondary.closed)
+
+	runtime := &stubAIClient{}
+	generator, err := NewSQLGenerator(&stubAIClient{}, config.AIConfig{})
+	require.NoError(t, err)
+	generator.runtimeClients["ollama"] = &runtimeClientEntry{client: runtime}
+
+	require.NotPanics(t, func() {
+		generator.Close()
+		generator.Close()
+	})
+	assert.Equal(t, 1, runtime.closed)
+}
+
+func TestLatencyPercentilesFromKnownDistribution(t *testing.T) {
+	manager := newTestManager(config.AIConfig{DefaultService: "primary"}, map[string]interfaces.AIClient{
+		"primary": &stubAIClient{},
+	})
+
+	for i := 100; i >= 1; i-- {
+		manager.recordLatency("primary", time.Duration(i)*time.Millisecond)
+	}
+
+	percentiles, ok := manager.LatencyPercentiles()["primary"]
+	require.True(t, ok)
+	assert.Equal(t, 100, percentiles.Samples)
+	assert.InDelta(t, 50, durationMillis(percentiles.P50), 1)
+	assert.InDelta(t, 95, durationMillis(percentiles.P95), 1)
+
+	for i := 0; i < latencyWindowSize; i++ {
+		manager.recordLatency("primary", 10*time.Millisecond)
+	}
+	percentiles = manager.LatencyPercentiles()["primary"]
+	assert.Equal(t, latencyWindowSize, percentiles.Samples)
+	assert.Equal(t, 10*time.Millisecond, percentiles.P95, "older samples roll out of the window")
+}
+
+func TestBenchmarkReportsPerProviderResults(t *testing.T) {
+	delayed := func(delay time.Duration, metadata map[string]any) *stubAIClient {
+		return &stubAIClient{generate: func(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			time.Sleep(delay)
+			return &interfaces.GenerateResponse{Text: "SELECT 1;", Metadata: metadata}, nil
+		}}
+	}
+	manager := newTestManager(config.AIConfig{DefaultService: "fast"}, map[string]interfaces.AIClient{
+		"fast": delayed(5*time.Millisecond, map[string]any{"prompt_tokens": 10, "completion_tokens": 4}),
+		"slow": delayed(40*time.Millisecond, map[string]any{"prompt_eval_count": float64(12), "eval_count": float64(6)}),
+		"down": &stubAIClient{health: &interfaces.HealthStatus{Healthy: false, Status: "down"}},
+	})
+
+	results, err := manager.Benchmark(context.Background(), []string{"list users", "count orders"}, BenchmarkOptions{Concurrency: 2})
+	require.NoError(t, err)
+	require.Len(t, results, 2, "unhealthy providers are skipped")
+
+	fast, slow := results["fast"], results["slow"]
+	assert.Equal(t, 2, fast.Requests)
+	assert.Equal(t, 0, fast.Errors)
+	assert.Equal(t, 1.0, fast.SuccessRate)
+	assert.Equal(t, 20, fast.PromptTokens)
+	assert.Equal(t, 8, fast.CompletionTokens)
+	assert.Equal(t, 24, slow.PromptTokens)
+	assert.Equal(t, 12, slow.CompletionTokens)
+	assert.Less(t, fast.AverageLatency, slow.AverageLatency)
+
+	_, err = manager.Benchmark(context.Background(), nil, BenchmarkOptions{})
+	assert.ErrorIs(t, err, ErrNoBenchmarkPrompts)
+}
+
+func TestBenchmarkEachCancelsOutstandingRequestsWhenEmitFails(t *testing.T) {
+	var (
+		cancelled atomic.Int32
+		started   sync.WaitGroup
+	)
+	started.Add(2)
+	manager := newTestManager(config.AIConfig{DefaultService: "fast"}, map[string]interfaces.AIClient{
+		// The fast provider completes only once both slow requests are in flight
+		"fast": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			started.Wait()
+			return &interfaces.GenerateResponse{Text: "SELECT 1;"}, nil
+		}},
+		"slow": &stubAIClient{generate: func(ctx context.Context, _ *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			started.Done()
+			<-ctx.Done()
+			cancelled.Add(1)
+			return nil, ctx.Err()
+		}},
+	})
+
+	stop := errors.New("client went away")
+	var emitted []string
+	err := manager.BenchmarkEach(context.Background(), []string{"list users", "count orders"}, BenchmarkOptions{},
+		func(result BenchmarkResult) error {
+			emitted = append(emitted, result.Provider)
+			return stop
+		})
+	require.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"fast"}, emitted)
+	assert.Equal(t, int32(2), cancelled.Load(), "outstanding requests are cancelled before returning")
+}
+
+func TestClientFactoriesNormalizeProviderAliases(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"models": [{"name": "llama3"}]}`))
+	}))
+	defer ollama.Close()
+
+	configured, err := createClient(" Local ", config.AIService{Endpoint: ollama.URL, Model: "llama3"}, config.HealthConfig{})
+	require.NoError(t, err)
+	defer func() { _ = configured.Close() }()
+	runtime, err := createRuntimeClient("LOCAL", map[string]any{"base_url": ollama.URL, "model": "llama3"})
+	require.NoError(t, err)
+	defer func() { _ = runtime.Close() }()
+
+	for _, client := range []interfaces.AIClient{configured, runtime} {
+		caps, err := client.GetCapabilities(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "ollama", caps.Provider)
+	}
+
+	_, err = createClient("mssql", config.AIService{}, config.HealthConfig{})
+	assert.ErrorIs(t, err, ErrProviderNotSupported)
+	_, err = createRuntimeClient("mssql", map[string]any{})
+	assert.ErrorIs(t, err, ErrProviderNotSupported)
+}
+
+func TestBackoffDelay(t *testing.T) {
+	original := jitterSource
+	t.Cleanup(func() { jitterSource = original })
+	// Deterministic jitter: always half of the allowed range
+	jitterSource = func(limit int64) (int64, error) { return limit / 2, nil }
+
+	retry := config.RetryConfig{
+		InitialDelay: config.Duration{Duration: 100 * time.Millisecond},
+		MaxDelay:     config.Duration{Duration: time.Second},
+		Multiplier:   2,
+	}
+	jittered := retry
+	jittered.Jitter = true
+
+	tests := []struct {
+		name     string
+		cfg      config.RetryConfig
+		expected []time.Duration
+	}{
+		{
+			name:     "exponential capped at max delay",
+			cfg:      retry,
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second},
+		},
+		{
+			name:     "jitter added after the cap",
+			cfg:      jittered,
+			expected: []time.Duration{112500 * time.Microsecond, 225 * time.Millisecond, 450 * time.Millisecond, 900 * time.Millisecond, 1125 * time.Millisecond},
+		},
+		{
+			name:     "defaults when unset",
+			cfg:      config.RetryConfig{},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, expected := range tt.expected {
+				assert.Equal(t, expected, backoffDelay(i+1, tt.cfg), "attempt %d", i+1)
+			}
+		})
+	}
+
+	assert.Zero(t, backoffDelay(0, retry))
+	assert.Equal(t, time.Second, backoffDelay(500, retry), "huge attempts do not overflow")
+}
+
+func TestGenerateMaxRetriesOverride(t *testing.T) {
+	var calls atomic.Int32
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls.Add(1)
+		return nil, &net.DNSError{Err: "no such host", Name: "ollama.local"}
+	}}
+	manager := newTestManager(config.AIConfig{
+		DefaultService: "primary",
+		Retry:          config.RetryConfig{MaxAttempts: 3, InitialDelay: config.Duration{Duration: time.Millisecond}},
+	}, map[string]interfaces.AIClient{"primary": client})
+
+	noRetries := 0
+	_, err := manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users", MaxRetries: &noRetries})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load(), "MaxRetries=0 makes a single attempt")
+
+	calls.Store(0)
+	_, err = manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users"})
+	require.Error(t, err)
+	assert.Equal(t, int32(3), calls.Load(), "nil keeps the configured attempts")
+}
+
+func TestGenerateHonorsProviderRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"SELECT 1;"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := universal.NewUniversalClient(&universal.Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", APIKey: "sk-test"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	// The computed backoff would wait 5s; the provider asks for 1s
+	manager := newTestManager(config.AIConfig{
+		DefaultService: "custom",
+		Retry:          config.RetryConfig{MaxAttempts: 2, InitialDelay: config.Duration{Duration: 5 * time.Second}},
+	}, map[string]interfaces.AIClient{"custom": client})
+
+	start := time.Now()
+	resp, err := manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	waited := time.Since(start)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", resp.Text)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, waited, time.Second)
+	assert.Less(t, waited, 3*time.Second)
+}
+
+func TestSuggestedRetryDelay(t *testing.T) {
+	assert.Zero(t, suggestedRetryDelay(errors.New("API returned status 429")))
+	assert.Equal(t, 2*time.Second, suggestedRetryDelay(fmt.Errorf("wrapped: %w", &interfaces.ProviderError{StatusCode: 429, RetryAfter: 2 * time.Second})))
+	assert.Equal(t, maxSuggestedRetryDelay, suggestedRetryDelay(&interfaces.ProviderError{StatusCode: 429, RetryAfter: time.Hour}))
+}
+
+func TestSetDefaultProviderSwitchesGenerations(t *testing.T) {
+	var served []string
+	provider := func(name string) *stubAIClient {
+		return &stubAIClient{generate: func(_ context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			served = append(served, name)
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		}}
+	}
+	cfg := config.AIConfig{DefaultService: "ollama"}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"ollama": provider("ollama"),
+		"openai": provider("openai"),
+		"broken": &stubAIClient{health: &interfaces.HealthStatus{Healthy: false, Status: "down"}},
+	})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+
+	request := &GenerateSQLRequest{NaturalLanguage: "count users", DatabaseType: "mysql"}
+	_, err = engine.GenerateSQL(context.Background(), request)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.SetDefaultProvider(context.Background(), "openai"))
+	assert.Equal(t, "openai", manager.DefaultProvider())
+	_, err = engine.GenerateSQL(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ollama", "openai"}, served)
+
+	assert.ErrorIs(t, engine.SetDefaultProvider(context.Background(), "claude"), ErrClientNotFound)
+	assert.ErrorIs(t, engine.SetDefaultProvider(context.Background(), "broken"), ErrProviderUnhealthy)
+	assert.Equal(t, "openai", manager.DefaultProvider(), "a rejected switch keeps the current default")
+}
+
+func TestEngineGenerationFeedsManagerLatency(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService:   "primary",
+		LatencyThreshold: config.Duration{Duration: 100 * time.Millisecond},
+	}
+	slow := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		time.Sleep(150 * time.Millisecond)
+		return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+	}}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary": slow,
+		"secondary": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 2;"}, nil
+		}},
+	})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+
+	request := &GenerateSQLRequest{NaturalLanguage: "count users", DatabaseType: "mysql"}
+	resp, err := engine.GenerateSQL(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.ServedBy)
+	assert.True(t, manager.isDemoted("primary"), "generator calls record provider latency")
+	assert.Contains(t, manager.LatencyPercentiles(), "primary")
+
+	resp, err = engine.GenerateSQL(context.Background(), &GenerateSQLRequest{NaturalLanguage: "count orders", DatabaseType: "mysql"})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", resp.ServedBy, "a demoted default is skipped")
+}
+
+func TestDemotedClientIsProbedAfterInterval(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService:   "primary",
+		LatencyThreshold: config.Duration{Duration: 100 * time.Millisecond},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary":   &stubAIClient{},
+		"secondary": &stubAIClient{},
+	})
+	manager.recordLatency("primary", 500*time.Millisecond)
+
+	name, _ := manager.selectHealthyClient(nil)
+	require.Equal(t, "secondary", name)
+
+	// Pretend the last request to the demoted client was sent a full interval ago
+	manager.latency.probed["primary"] = time.Now().Add(-constants.Latency.ProbeInterval)
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "primary", name, "a demoted client gets a probe request once the interval passes")
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "secondary", name, "only one probe is sent per interval")
+}
+
+func TestHalfOpenCircuitAllowsSingleTrial(t *testing.T) {
+	cfg := config.AIConfig{DefaultService: "primary"}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary":   &stubAIClient{},
+		"secondary": &stubAIClient{},
+	})
+	for i := 0; i < constants.CircuitBreaker.FailureThreshold; i++ {
+		manager.circuits.recordFailure("primary", time.Now().Add(-constants.CircuitBreaker.Cooldown))
+	}
+	require.Equal(t, CircuitHalfOpen, manager.CircuitStates()["primary"])
+
+	name, _ := manager.selectHealthyClient(nil)
+	require.Equal(t, "primary", name, "the first request is the half-open trial")
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "secondary", name, "requests during the trial skip the half-open client")
+
+	manager.circuits.release("primary")
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "primary", name, "a released trial can be claimed again")
+
+	manager.circuits.recordSuccess("primary")
+	assert.Equal(t, CircuitClosed, manager.CircuitStates()["primary"])
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "primary", name)
+	name, _ = manager.selectHealthyClient(nil)
+	assert.Equal(t, "primary", name, "a closed circuit admits every request")
+}
+
+func TestEngineGenerationFeedsCircuitBreaker(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "primary",
+		Retry:          config.RetryConfig{MaxAttempts: 1},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return nil, errors.New("503 service unavailable")
+		}},
+		"secondary": &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+			return &interfaces.GenerateResponse{Text: "sql:SELECT 1;"}, nil
+		}},
+	})
+	engine, err := newEngineFromManager(manager, cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < constants.CircuitBreaker.FailureThreshold; i++ {
+		_, err = engine.GenerateSQL(context.Background(), &GenerateSQLRequest{
+			NaturalLanguage: fmt.Sprintf("count users %d", i),
+			DatabaseType:    "mysql",
+		})
+		require.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, manager.CircuitStates()["primary"])
+
+	resp, err := engine.GenerateSQL(context.Background(), &GenerateSQLRequest{NaturalLanguage: "count orders", DatabaseType: "mysql"})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", resp.ServedBy)
+}
+
+func TestNewAIManagerSkipsFailingServices(t *testing.T) {
+	cfg := config.AIConfig{
+		DefaultService: "ollama",
+		Services: map[string]config.AIService{
+			"ollama": {Enabled: true, Provider: "ollama", Endpoint: "http://127.0.0.1:11434", Model: "llama3"},
+			"bogus":  {Enabled: true, Provider: "bogus", Model: "unknown"},
+		},
+	}
+
+	manager, err := NewAIManager(cfg)
+	require.NoError(t, err)
+	defer func() { _ = manager.Close() }()
+
+	_, err = manager.GetClient("ollama")
+	require.NoError(t, err)
+	_, err = manager.GetClient("bogus")
+	require.ErrorIs(t, err, ErrClientNotFound)
+	skipped := manager.SkippedServices()
+	require.Len(t, skipped, 1)
+	assert.Contains(t, skipped["bogus"], "provider not supported")
+
+	detector := NewCapabilityDetector(cfg, manager)
+	capabilities, err := detector.GetCapabilities(t.Context(), &CapabilitiesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, skipped, capabilities.Metadata.SkippedServices)
+	assert.True(t, capabilities.Metadata.Degraded)
+
+	// Failing every enabled service still prevents the manager from starting
+	delete(cfg.Services, "ollama")
+	_, err = NewAIManager(cfg)
+	require.ErrorIs(t, err, ErrProviderNotSupported)
+}
+
+func TestStopSequencesDefaultUnlessConfigured(t *testing.T) {
+	assert.Equal(t, constants.DefaultStopSequences, stopSequences(nil))
+	assert.Equal(t, []string{"\n\n"}, stopSequences([]string{"\n\n"}))
+	assert.Empty(t, stopSequences([]string{}), "an explicit empty list disables stop sequences")
+}
+
+func TestSetRetryConfigConcurrentWithGenerate(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		calls.Add(1)
+		if failing.Load() {
+			return nil, &net.DNSError{Err: "no such host", Name: "ollama.local"}
+		}
+		return &interfaces.GenerateResponse{Text: "SELECT 1;"}, nil
+	}}
+	cfg := config.AIConfig{
+		DefaultService:   "ollama",
+		LatencyThreshold: config.Duration{Duration: time.Second},
+		Retry:            config.RetryConfig{MaxAttempts: 3, InitialDelay: config.Duration{Duration: time.Millisecond}},
+	}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{"ollama": client})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users"})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		manager.SetRetryConfig(config.RetryConfig{MaxAttempts: 1 + i%3, InitialDelay: config.Duration{Duration: time.Millisecond}})
+	}
+	wg.Wait()
+
+	manager.SetRetryConfig(config.RetryConfig{MaxAttempts: 1})
+	failing.Store(true)
+	before := calls.Load()
+	_, err := manager.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users"})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load()-before, "the updated policy allows a single attempt")
+}
+
+func TestDiscoverProvidersFlagsConfiguredAndDiscovered(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"models":[]}`))
+	}))
+	defer ollama.Close()
+
+	cfg := config.AIConfig{Services: map[string]config.AIService{
+		"openai": {Enabled: true, Provider: "openai"},
+		"ops":    {Enabled: true, Provider: "custom", Endpoint: "https://llm.internal"},
+	}}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"openai": &stubAIClient{},
+		"ops":    &stubAIClient{},
+	})
+	manager.discovery = discovery.NewOllamaDiscovery(ollama.URL)
+
+	providers, err := manager.DiscoverProviders(context.Background())
+	require.NoError(t, err)
+
+	byName := make(map[string]*ProviderInfo, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name] = provider
+	}
+	require.Contains(t, byName, "ollama")
+	assert.True(t, byName["ollama"].Discovered)
+	assert.False(t, byName["ollama"].Configured, "a running Ollama without a client is only discovered")
+
+	require.Contains(t, byName, "openai")
+	assert.True(t, byName["openai"].Configured)
+	assert.False(t, byName["openai"].Discovered)
+
+	require.Contains(t, byName, "deepseek")
+	assert.False(t, byName["deepseek"].Configured, "catalog providers are merely known")
+	assert.False(t, byName["deepseek"].Discovered)
+
+	require.Contains(t, byName, "custom", "configured providers missing from the catalog are still listed")
+	assert.True(t, byName["custom"].Configured)
+	assert.Equal(t, "https://llm.internal", byName["custom"].Endpoint)
+}
+
+func TestHealthCheckAllCachesResultsWithinTTL(t *testing.T) {
+	var primaryProbes, secondaryProbes atomic.Int32
+	counting := func(probes *atomic.Int32) func(context.Context) (*interfaces.HealthStatus, error) {
+		return func(context.Context) (*interfaces.HealthStatus, error) {
+			probes.Add(1)
+			return &interfaces.HealthStatus{Healthy: true, Status: "ok"}, nil
+		}
+	}
+	cfg := config.AIConfig{Health: config.HealthConfig{CacheTTL: config.NewDuration(time.Minute)}}
+	manager := newTestManager(cfg, map[string]interfaces.AIClient{
+		"primary":   &stubAIClient{healthCheck: counting(&primaryProbes)},
+		"secondary": &stubAIClient{healthCheck: counting(&secondaryProbes)},
+	})
+
+	first := manager.HealthCheckAll(context.Background(), false)
+	second := manager.HealthCheckAll(context.Background(), false)
+	require.Len(t, first, 2)
+	require.Len(t, second, 2)
+	assert.True(t, second["primary"].Healthy)
+	assert.Equal(t, int32(1), primaryProbes.Load(), "second call within the window is served from the cache")
+	assert.Equal(t, int32(1), secondaryProbes.Load())
+
+	manager.HealthCheckAll(context.Background(), true)
+	assert.Equal(t, int32(2), primaryProbes.Load(), "force bypasses the cache")
+	assert.Equal(t, int32(2), secondaryProbes.Load())
+
+	manager.health.put("primary", &interfaces.HealthStatus{Healthy: true}, time.Now().Add(-2*time.Minute))
+	manager.HealthCheckAll(context.Background(), false)
+	assert.Equal(t, int32(3), primaryProbes.Load(), "stale entries are probed again")
+	assert.Equal(t, int32(2), secondaryProbes.Load())
+}
+
+func TestHealthCheckAllCachesErrorsAndForgetsRemovedClients(t *testing.T) {
+	var probes atomic.Int32
+	client := &stubAIClient{healthCheck: func(context.Context) (*interfaces.HealthStatus, error) {
+		probes.Add(1)
+		return nil, errors.New("connection refused")
+	}}
+	manager := newTestManager(config.AIConfig{}, map[string]interfaces.AIClient{"flaky": client})
+
+	status := manager.HealthCheckAll(context.Background(), false)["flaky"]
+	require.NotNil(t, status)
+	assert.False(t, status.Healthy)
+	assert.Equal(t, "connection refused", status.Status)
+	manager.HealthCheckAll(context.Background(), false)
+	assert.Equal(t, int32(1), probes.Load(), "the default window applies when cache_ttl is unset")
+
+	require.NoError(t, manager.RemoveClient("flaky"))
+	_, ok := manager.health.get("flaky", time.Minute, time.Now())
+	assert.False(t, ok)
+}
+
+func TestHealthCheckUsesConfiguredHealthPath(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	manager, err := NewAIManager(config.AIConfig{
+		DefaultService: "ollama",
+		Services: map[string]config.AIService{
+			"ollama": {Enabled: true, Provider: "ollama", Endpoint: server.URL, Model: "llama3", HealthPath: "/healthz"},
+			"custom": {Enabled: true, Provider: "custom", Endpoint: server.URL, Model: "gpt-test", HealthPath: "/healthz"},
+		},
+	})
+	require.NoError(t, err)
+	defer func() { _ = manager.Close() }()
+
+	for name, status := range manager.HealthCheckAll(context.Background(), true) {
+		require.NotNil(t, status, name)
+		assert.True(t, status.Healthy, "%s: %s", name, status.Status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/healthz", "/healthz"}, paths, "the provider default models endpoint is not probed")
+}
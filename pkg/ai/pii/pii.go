@@ -0,0 +1,99 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pii detects and masks personally identifiable information in model output.
+package pii
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces every detected value
+const Redacted = "[REDACTED]"
+
+// ErrUnknownRule is returned when a rule names no built-in pattern and supplies none of its own
+var ErrUnknownRule = errors.New("unknown PII rule")
+
+// builtinPatterns are the rules used when none are configured
+var builtinPatterns = map[string]string{
+	"email":       `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	"phone":       `(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{2,4}\)[\s.\-]?|\b\d{3,4}[\s.\-])\d{3,4}[\s.\-]?\d{4}\b`,
+	"national_id": `\b(?:\d{3}-\d{2}-\d{4}|\d{17}[\dXx])\b`,
+}
+
+// Rule is a named pattern; an empty Pattern selects the built-in rule with the same name
+type Rule struct {
+	Name    string
+	Pattern string
+}
+
+// Detector masks every match of its rules
+type Detector struct {
+	patterns []*regexp.Regexp
+}
+
+// DefaultRules returns the built-in email, phone and national ID rules
+func DefaultRules() []Rule {
+	names := make([]string, 0, len(builtinPatterns))
+	for name := range builtinPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]Rule, 0, len(names))
+	for _, name := range names {
+		rules = append(rules, Rule{Name: name})
+	}
+	return rules
+}
+
+// NewDetector compiles rules, falling back to DefaultRules when none are given
+func NewDetector(rules []Rule) (*Detector, error) {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	detector := &Detector{}
+	for _, rule := range rules {
+		pattern := rule.Pattern
+		if pattern == "" {
+			builtin, ok := builtinPatterns[strings.ToLower(rule.Name)]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownRule, rule.Name)
+			}
+			pattern = builtin
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for PII rule %q: %w", rule.Name, err)
+		}
+		detector.patterns = append(detector.patterns, compiled)
+	}
+	return detector, nil
+}
+
+// Mask replaces detected values with Redacted and reports whether anything was replaced
+func (d *Detector) Mask(text string) (string, bool) {
+	masked := text
+	for _, pattern := range d.patterns {
+		masked = pattern.ReplaceAllLiteralString(masked, Redacted)
+	}
+	return masked, masked != text
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pii
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskDefaultRules(t *testing.T) {
+	detector, err := NewDetector(nil)
+	require.NoError(t, err)
+
+	masked, changed := detector.Mask("Filters orders placed by jane.doe@example.com or 555-123-4567 (SSN 123-45-6789).")
+	assert.True(t, changed)
+	assert.Equal(t, "Filters orders placed by [REDACTED] or [REDACTED] (SSN [REDACTED]).", masked)
+
+	clean := "Counts orders per customer for the last 30 days."
+	masked, changed = detector.Mask(clean)
+	assert.False(t, changed)
+	assert.Equal(t, clean, masked)
+}
+
+func TestNewDetectorCustomRules(t *testing.T) {
+	detector, err := NewDetector([]Rule{{Name: "email"}, {Name: "employee_id", Pattern: `EMP-\d{5}`}})
+	require.NoError(t, err)
+
+	masked, _ := detector.Mask("Looks up EMP-00042 and 555-123-4567")
+	assert.Equal(t, "Looks up [REDACTED] and 555-123-4567", masked, "only configured rules apply")
+
+	_, err = NewDetector([]Rule{{Name: "passport"}})
+	assert.True(t, errors.Is(err, ErrUnknownRule))
+
+	_, err = NewDetector([]Rule{{Name: "broken", Pattern: "("}})
+	assert.Error(t, err)
+}
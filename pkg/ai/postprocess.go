@@ -0,0 +1,242 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/sqlutil"
+)
+
+// defaultAppendLimit is the row cap used by append_limit when none is configured
+const defaultAppendLimit = 1000
+
+// PostProcessor deterministically rewrites generated SQL to enforce house conventions.
+// Processors may change result.SQL and append suggestions.
+type PostProcessor interface {
+	// Name returns the name used in the ai.post_process configuration
+	Name() string
+
+	// Process transforms the result in place
+	Process(result *GenerationResult, dialect SQLDialect) error
+}
+
+// NewPostProcessor creates the named built-in post-processor
+func NewPostProcessor(cfg config.PostProcessorConfig) (PostProcessor, error) {
+	switch cfg.Name {
+	case "append_limit":
+		limit := cfg.Limit
+		if limit <= 0 {
+			limit = defaultAppendLimit
+		}
+		return &appendLimitProcessor{limit: limit}, nil
+	case "format":
+		return formatProcessor{}, nil
+	case "uppercase_keywords":
+		return uppercaseKeywordsProcessor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown post-processor %q", cfg.Name)
+	}
+}
+
+// newPostProcessors builds the configured pipeline in order
+func newPostProcessors(configs []config.PostProcessorConfig) ([]PostProcessor, error) {
+	processors := make([]PostProcessor, 0, len(configs))
+	for _, cfg := range configs {
+		processor, err := NewPostProcessor(cfg)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+// appendLimitProcessor caps single SELECT statements that have no top-level LIMIT
+type appendLimitProcessor struct {
+	limit int
+}
+
+func (p *appendLimitProcessor) Name() string {
+	return "append_limit"
+}
+
+func (p *appendLimitProcessor) Process(result *GenerationResult, _ SQLDialect) error {
+	// Leave multi-statement SQL and already limited queries alone
+	if singleSelect, limited := selectRowLimit(tokenizeSQL(result.SQL)); !singleSelect || limited {
+		return nil
+	}
+
+	result.SQL = insertRowLimit(result.SQL, fmt.Sprintf("LIMIT %d", p.limit))
+	result.Suggestions = append(result.Suggestions,
+		fmt.Sprintf("LIMIT %d was appended; remove it if you need every row", p.limit))
+	return nil
+}
+
+// formatProcessor applies the dialect's formatting rules
+type formatProcessor struct{}
+
+func (formatProcessor) Name() string {
+	return "format"
+}
+
+func (formatProcessor) Process(result *GenerationResult, dialect SQLDialect) error {
+	formatted, err := dialect.FormatSQL(result.SQL)
+	if err != nil {
+		return err
+	}
+	result.SQL = formatted
+	return nil
+}
+
+// uppercaseKeywordsProcessor uppercases dialect keywords outside literals, quoted identifiers and comments
+type uppercaseKeywordsProcessor struct{}
+
+func (uppercaseKeywordsProcessor) Name() string {
+	return "uppercase_keywords"
+}
+
+func (uppercaseKeywordsProcessor) Process(result *GenerationResult, dialect SQLDialect) error {
+	keywords := make(map[string]bool)
+	for _, keyword := range dialect.GetKeywords() {
+		keywords[strings.ToUpper(keyword)] = true
+	}
+
+	runes := []rune(result.SQL)
+	var b strings.Builder
+	b.Grow(len(result.SQL))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := i + 2
+			for end < len(runes) && (runes[end] != '*' || end+1 >= len(runes) || runes[end+1] != '/') {
+				end++
+			}
+			end = min(end+2, len(runes))
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case r == '\'' || r == '"' || r == '`':
+			end, _ := sqlutil.ScanQuoted(runes, i, r, false)
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			if upper := strings.ToUpper(word); keywords[upper] {
+				word = upper
+			}
+			b.WriteString(word)
+			i = end
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+	result.SQL = b.String()
+	return nil
+}
+
+// affixProcessor wraps generated SQL in the configured ai.sql_prefix and ai.sql_suffix
+type affixProcessor struct {
+	prefix, suffix string
+}
+
+// newAffixProcessor returns nil when neither a prefix nor a suffix is configured
+func newAffixProcessor(prefix, suffix string) *affixProcessor {
+	prefix, suffix = strings.TrimSpace(prefix), strings.TrimSpace(suffix)
+	if prefix == "" && suffix == "" {
+		return nil
+	}
+	return &affixProcessor{prefix: prefix, suffix: suffix}
+}
+
+func (p *affixProcessor) Name() string {
+	return "sql_affix"
+}
+
+func (p *affixProcessor) Process(result *GenerationResult, _ SQLDialect) error {
+	result.SQL = p.wrap(result.SQL)
+	return nil
+}
+
+// wrap keeps every statement terminated: a statement prefix or suffix gets its own semicolon,
+// and the generated SQL is terminated before a statement suffix. Comments are added as they are.
+func (p *affixProcessor) wrap(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if p.prefix != "" {
+		prefix := p.prefix
+		if !isSQLComment(prefix) && !strings.HasSuffix(prefix, ";") {
+			prefix += ";"
+		}
+		sql = prefix + "\n" + sql
+	}
+	if p.suffix != "" {
+		if isSQLComment(p.suffix) {
+			return sql + " " + p.suffix
+		}
+		if !strings.HasSuffix(sql, ";") {
+			sql += ";"
+		}
+		suffix := p.suffix
+		if !strings.HasSuffix(suffix, ";") {
+			suffix += ";"
+		}
+		sql += "\n" + suffix
+	}
+	return sql
+}
+
+// unwrap removes a prefix and suffix added by wrap, leaving other SQL unchanged
+func (p *affixProcessor) unwrap(sql string) string {
+	core := strings.TrimSpace(sql)
+	if p.prefix != "" {
+		for _, prefix := range []string{p.prefix + ";", p.prefix} {
+			if strings.HasPrefix(core, prefix+"\n") {
+				core = strings.TrimSpace(strings.TrimPrefix(core, prefix+"\n"))
+				break
+			}
+		}
+	}
+	if p.suffix != "" {
+		for _, suffix := range []string{" " + p.suffix, "\n" + p.suffix + ";", "\n" + p.suffix} {
+			if strings.HasSuffix(core, suffix) {
+				core = strings.TrimSpace(strings.TrimSuffix(core, suffix))
+				break
+			}
+		}
+	}
+	return core
+}
+
+// isSQLComment reports whether text is a line or block comment
+func isSQLComment(text string) bool {
+	return strings.HasPrefix(text, "--") || (strings.HasPrefix(text, "/*") && strings.HasSuffix(text, "*/"))
+}
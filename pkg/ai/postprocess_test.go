@@ -0,0 +1,136 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendLimitProcessor(t *testing.T) {
+	processor, err := NewPostProcessor(config.PostProcessorConfig{Name: "append_limit"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "SELECT * FROM users;", want: "SELECT * FROM users LIMIT 1000;"},
+		{sql: "SELECT id FROM users WHERE id IN (SELECT user_id FROM orders LIMIT 5)", want: "SELECT id FROM users WHERE id IN (SELECT user_id FROM orders LIMIT 5) LIMIT 1000"},
+		{sql: "SELECT * FROM users LIMIT 10;", want: "SELECT * FROM users LIMIT 10;"},
+		{sql: "UPDATE users SET active = 1 WHERE id = 2;", want: "UPDATE users SET active = 1 WHERE id = 2;"},
+		{sql: "SELECT 1; SELECT 2;", want: "SELECT 1; SELECT 2;"},
+		{sql: "SELECT * FROM users -- all rows", want: "SELECT * FROM users LIMIT 1000 -- all rows"},
+		{sql: "SELECT * FROM users; /* every user */\n", want: "SELECT * FROM users LIMIT 1000; /* every user */"},
+		{sql: "SELECT * FROM jobs WHERE state = 'new' FOR UPDATE SKIP LOCKED;", want: "SELECT * FROM jobs WHERE state = 'new' LIMIT 1000 FOR UPDATE SKIP LOCKED;"},
+		{sql: "SELECT * FROM jobs FOR SHARE", want: "SELECT * FROM jobs LIMIT 1000 FOR SHARE"},
+		{sql: "SELECT * FROM jobs LOCK IN SHARE MODE", want: "SELECT * FROM jobs LIMIT 1000 LOCK IN SHARE MODE"},
+	}
+	for _, tt := range tests {
+		result := &GenerationResult{SQL: tt.sql}
+		require.NoError(t, processor.Process(result, &MySQLDialect{}))
+		assert.Equal(t, tt.want, result.SQL, tt.sql)
+		assert.Equal(t, tt.sql != tt.want, len(result.Suggestions) == 1, "a suggestion is added only when LIMIT is appended")
+	}
+}
+
+func TestUppercaseKeywordsSkipsComments(t *testing.T) {
+	processor, err := NewPostProcessor(config.PostProcessorConfig{Name: "uppercase_keywords"})
+	require.NoError(t, err)
+
+	result := &GenerationResult{SQL: "select id /* select from here */ from users -- where next\nwhere id = 1"}
+	require.NoError(t, processor.Process(result, &MySQLDialect{}))
+	assert.Equal(t, "SELECT id /* select from here */ FROM users -- where next\nWHERE id = 1", result.SQL)
+}
+
+func TestPostProcessPipelineRunsInOrder(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:select name from users where note = 'select me'"}, nil
+	}}
+	generator, err := NewSQLGenerator(client, config.AIConfig{PostProcess: []config.PostProcessorConfig{
+		{Name: "uppercase_keywords"},
+		{Name: "append_limit", Limit: 50},
+	}})
+	require.NoError(t, err)
+
+	result, err := generator.Generate(context.Background(), "list user names", defaultGenerateOptions())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name FROM users WHERE note = 'select me' LIMIT 50", result.SQL)
+
+	_, err = NewSQLGenerator(client, config.AIConfig{PostProcess: []config.PostProcessorConfig{{Name: "shout"}}})
+	assert.ErrorContains(t, err, `unknown post-processor "shout"`)
+}
+
+func TestAffixProcessorKeepsStatementsTerminated(t *testing.T) {
+	tests := []struct {
+		prefix, suffix, sql, want string
+	}{
+		{prefix: "SET statement_timeout = 5000", sql: "SELECT 1;", want: "SET statement_timeout = 5000;\nSELECT 1;"},
+		{suffix: "-- source: dashboard", sql: "SELECT 1;", want: "SELECT 1; -- source: dashboard"},
+		{suffix: "SELECT pg_sleep(0)", sql: "SELECT 1", want: "SELECT 1;\nSELECT pg_sleep(0);"},
+		{prefix: "/* app=atest */", suffix: "/* end */", sql: "SELECT 1; SELECT 2;", want: "/* app=atest */\nSELECT 1; SELECT 2; /* end */"},
+	}
+	for _, tt := range tests {
+		processor := newAffixProcessor(tt.prefix, tt.suffix)
+		require.NotNil(t, processor)
+		result := &GenerationResult{SQL: tt.sql}
+		require.NoError(t, processor.Process(result, &PostgreSQLDialect{}))
+		assert.Equal(t, tt.want, result.SQL)
+		// A statement suffix leaves the generated SQL terminated
+		assert.Equal(t, strings.TrimSuffix(tt.sql, ";"), strings.TrimSuffix(processor.unwrap(result.SQL), ";"))
+	}
+	assert.Nil(t, newAffixProcessor(" ", ""))
+}
+
+func TestSQLAffixesWrapGeneratedSQL(t *testing.T) {
+	client := &stubAIClient{generate: func(context.Context, *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
+		return &interfaces.GenerateResponse{Text: "sql:SELECT id FROM users;"}, nil
+	}}
+	cfg := config.AIConfig{
+		ReadOnly:  true,
+		SQLPrefix: "SET statement_timeout = 5000;",
+		SQLSuffix: "-- source: atest",
+		PostProcess: []config.PostProcessorConfig{
+			{Name: "append_limit", Limit: 10},
+		},
+	}
+	generator, err := NewSQLGenerator(client, cfg)
+	require.NoError(t, err)
+
+	options := defaultGenerateOptions()
+	options.DatabaseType = "postgresql"
+	result, err := generator.Generate(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	assert.Equal(t, "SET statement_timeout = 5000;\nSELECT id FROM users LIMIT 10; -- source: atest", result.SQL)
+	assert.False(t, result.Blocked, "read-only mode checks the generated statement, not the SET prefix")
+	for _, validation := range result.ValidationResults {
+		assert.NotEqual(t, "error", validation.Level, validation.Message)
+	}
+
+	cfg.ValidateSQLAffixes = true
+	require.NoError(t, generator.UpdateConfig(cfg))
+	result, err = generator.Generate(context.Background(), "list user ids", options)
+	require.NoError(t, err)
+	assert.True(t, result.Blocked, "validated affixes are subject to read-only mode")
+	assert.Equal(t, "SET statement_timeout = 5000;\nSELECT id FROM users LIMIT 10; -- source: atest", result.SQL)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package providers holds the naming rules shared by every AI provider and database dialect lookup.
+package providers
+
+import "strings"
+
+// aliases maps alternative provider and database type spellings to their canonical names
+var aliases = map[string]string{
+	"local":    "ollama",
+	"mssql":    "sqlserver",
+	"postgres": "postgresql",
+	"pg":       "postgresql",
+	"psql":     "postgresql",
+	"pgsql":    "postgresql",
+	"sqlite3":  "sqlite",
+	"mariadb":  "mysql",
+}
+
+// Normalize lowercases and trims a provider or database type name and resolves known aliases,
+// such as local -> ollama, mssql -> sqlserver and postgres -> postgresql
+func Normalize(name string) string {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := aliases[normalized]; ok {
+		return canonical
+	}
+	return normalized
+}
+
+// Aliases returns a copy of the alias table, keyed by alias
+func Aliases() map[string]string {
+	copied := make(map[string]string, len(aliases))
+	for alias, canonical := range aliases {
+		copied[alias] = canonical
+	}
+	return copied
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"local":      "ollama",
+		" Local ":    "ollama",
+		"ollama":     "ollama",
+		"OpenAI":     "openai",
+		"mssql":      "sqlserver",
+		"sqlserver":  "sqlserver",
+		"postgres":   "postgresql",
+		"PG":         "postgresql",
+		"psql":       "postgresql",
+		"pgsql":      "postgresql",
+		"postgresql": "postgresql",
+		"sqlite3":    "sqlite",
+		"mariadb":    "mysql",
+		"snowflake":  "snowflake",
+		"custom":     "custom",
+		"":           "",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestAliasesReturnsCopy(t *testing.T) {
+	copied := Aliases()
+	copied["local"] = "openai"
+	if got := Normalize("local"); got != "ollama" {
+		t.Fatalf("modifying the returned aliases changed Normalize: got %q", got)
+	}
+}
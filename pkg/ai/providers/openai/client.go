@@ -17,13 +17,16 @@ limitations under the License.
 package openai
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/httpx"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
 	"github.com/tmc/langchaingo/llms"
@@ -94,6 +97,7 @@ func NewClient(config *Config) (*Client, error) {
 	opts := []openai.Option{
 		openai.WithToken(config.APIKey),
 		openai.WithModel(config.Model),
+		openai.WithHTTPClient(errorBodyDoer{client: &http.Client{Transport: httpx.NewTransport()}}),
 	}
 
 	// Add optional configurations
@@ -118,6 +122,31 @@ func NewClient(config *Config) (*Client, error) {
 	return client, nil
 }
 
+// errorBodyDoer reports JSON responses that carry an error object despite a 200 status as a
+// ProviderError, instead of letting them parse as an empty completion
+type errorBodyDoer struct {
+	client *http.Client
+}
+
+// Do sends the request and checks successful JSON responses for an error body
+func (d errorBodyDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if providerErr := interfaces.ProviderErrorFromBody("openai", resp.StatusCode, data); providerErr != nil {
+		return nil, providerErr
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
 // Generate executes a generation request using langchaingo
 func (c *Client) Generate(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
 	start := time.Now()
@@ -267,7 +296,7 @@ func (c *Client) HealthCheck(ctx context.Context) (*interfaces.HealthStatus, err
 		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	}
 
-	client := &http.Client{}
+	client := &http.Client{Transport: httpx.NewTransport()}
 	if c.config.Timeout > 0 {
 		client.Timeout = c.config.Timeout
 	}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/stretchr/testify/require"
 )
 
@@ -51,3 +52,23 @@ func TestHealthCheckFailure(t *testing.T) {
 	require.NotNil(t, status)
 	require.False(t, status.Healthy)
 }
+
+func TestGenerateReturnsProviderErrorForErrorBodyWithSuccessStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"error":{"message":"upstream model overloaded","type":"server_error","code":null}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(&Config{APIKey: "test", BaseURL: server.URL, Model: "gpt-test", Timeout: time.Second})
+	require.NoError(t, err)
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.Nil(t, resp)
+	var providerErr *interfaces.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, http.StatusOK, providerErr.StatusCode)
+	require.Equal(t, "upstream model overloaded", providerErr.Message)
+	require.Equal(t, "server_error", providerErr.Code)
+}
@@ -18,17 +18,32 @@ package universal
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/linuxsuren/atest-ext-ai/pkg/httpx"
 	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"github.com/linuxsuren/atest-ext-ai/pkg/metrics"
+)
+
+// ErrOllamaUnavailable is returned when nothing is listening at the configured Ollama endpoint
+var ErrOllamaUnavailable = errors.New("ollama is not available")
+
+// Health check modes reported in HealthStatus.Metadata["health_mode"]
+const (
+	HealthModeLight = "light"
+	HealthModeDeep  = "deep"
 )
 
 // Global HTTP client pool for connection reuse across providers
@@ -86,29 +101,13 @@ func getOrCreateHTTPClient(provider string, timeout time.Duration) *pooledHTTPCl
 		return entry
 	}
 
-	// Create new HTTP client with optimized transport settings
-	// Configuration follows Go net/http best practices:
-	// - MaxIdleConns: Total maximum idle connections across all hosts
-	// - MaxIdleConnsPerHost: Maximum idle connections per host (important for AI APIs)
-	// - IdleConnTimeout: How long idle connections remain in the pool
-	// - DisableCompression: Disabled for better compatibility with AI APIs
-	transport := &http.Transport{
-		MaxIdleConns:        100,              // Total pool size across all hosts
-		MaxIdleConnsPerHost: 10,               // Per-host idle connection limit (AI APIs typically use 1 host)
-		IdleConnTimeout:     90 * time.Second, // Keep idle connections for 90s
-		DisableCompression:  false,            // Enable compression for better bandwidth utilization
-		// Additional recommended settings for production use:
-		MaxConnsPerHost:       0,                // No limit on active connections (0 = unlimited)
-		ResponseHeaderTimeout: 30 * time.Second, // Timeout for reading response headers
-		ExpectContinueTimeout: 1 * time.Second,  // Timeout for 100-Continue handshake
-		ForceAttemptHTTP2:     true,             // Enable HTTP/2 when available
-		DisableKeepAlives:     false,            // Enable keep-alives for connection reuse
-		TLSHandshakeTimeout:   10 * time.Second, // Timeout for TLS handshake
-	}
+	// The shared transport pools connections and trusts the process-wide CA bundle
+	transport := httpx.NewTransport()
 
 	client := &http.Client{
-		Timeout:   timeout,
-		Transport: transport,
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
 	}
 
 	entry := &pooledHTTPClient{
@@ -136,6 +135,10 @@ type Client struct {
 	httpClient *http.Client
 	poolEntry  *pooledHTTPClient
 	strategy   ProviderStrategy // Strategy pattern to handle provider-specific logic
+
+	modelMu     sync.RWMutex
+	activeModel string // Model picked by the watcher when the configured one is unavailable
+	watcher     *modelWatcher
 }
 
 // Config holds configuration for the universal client
@@ -152,6 +155,16 @@ type Config struct {
 	ModelsPath      string            `json:"models_path"`          // API path for models (default: /v1/models)
 	HealthPath      string            `json:"health_path"`          // API path for health check
 	StreamSupported bool              `json:"stream_supported"`     // Whether streaming is supported
+	Stop            []string          `json:"stop,omitempty"`       // Stop sequences that end generation
+
+	// ModelRefreshInterval polls the Ollama model list and switches away from unloaded models (0 disables)
+	ModelRefreshInterval time.Duration `json:"model_refresh_interval,omitempty"`
+
+	// DeepHealthCheck makes HealthCheck also issue a 1-token generation after listing models
+	DeepHealthCheck bool `json:"deep_health_check,omitempty"`
+
+	// CacheControl marks a cacheable system prompt with an ephemeral cache_control content block
+	CacheControl bool `json:"cache_control,omitempty"`
 }
 
 // NewUniversalClient creates a new universal OpenAI-compatible client
@@ -196,7 +209,7 @@ func NewUniversalClient(config *Config) (*Client, error) {
 	// Set other defaults
 	if config.Timeout == 0 {
 		// Increase timeout for reasoning/thinking models
-		if strings.Contains(strings.ToLower(config.Model), "think") || strings.Contains(strings.ToLower(config.Model), "reason") {
+		if IsReasoningModel(config.Model) {
 			config.Timeout = 300 * time.Second // 5 minutes for thinking models
 		} else {
 			config.Timeout = 120 * time.Second // 2 minutes for regular models
@@ -220,6 +233,10 @@ func NewUniversalClient(config *Config) (*Client, error) {
 		poolEntry:  pooledClient,
 	}
 
+	if config.Provider == "ollama" && config.ModelRefreshInterval > 0 {
+		client.watcher = startModelWatcher(client, config.ModelRefreshInterval)
+	}
+
 	logging.Logger.Debug("Universal client created",
 		"provider", config.Provider,
 		"endpoint", config.Endpoint,
@@ -233,8 +250,14 @@ func NewUniversalClient(config *Config) (*Client, error) {
 func (c *Client) Generate(ctx context.Context, req *interfaces.GenerateRequest) (*interfaces.GenerateResponse, error) {
 	start := time.Now()
 
-	// Build request using strategy pattern
-	requestBody, err := c.strategy.BuildRequest(req, c.config)
+	// Build request using strategy pattern, with the watcher's model if it replaced the configured one
+	cfg := c.config
+	if model := c.currentModel(); model != cfg.Model {
+		override := *c.config
+		override.Model = model
+		cfg = &override
+	}
+	requestBody, err := c.strategy.BuildRequest(req, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
@@ -253,6 +276,7 @@ func (c *Client) Generate(ctx context.Context, req *interfaces.GenerateRequest)
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept-Encoding", "gzip")
 	if c.config.APIKey != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	}
@@ -263,30 +287,68 @@ func (c *Client) Generate(ctx context.Context, req *interfaces.GenerateRequest)
 	// Execute request
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
+		if unavailable := c.ollamaUnavailable(err); unavailable != nil {
+			return nil, unavailable
+		}
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	// Check status
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return nil, &interfaces.ProviderError{
+			Provider:   c.config.Provider,
+			StatusCode: resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header, time.Now()),
+		}
+	}
+
+	body, err := decodeResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	defer func() { _ = body.Close() }()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	// Some gateways report failures with a success status and an error object in the body
+	if providerErr := interfaces.ProviderErrorFromBody(c.config.Provider, resp.StatusCode, data); providerErr != nil {
+		return nil, providerErr
 	}
 
 	// Parse response using strategy pattern
-	response, err := c.strategy.ParseResponse(resp.Body, req.Model)
+	response, err := c.strategy.ParseResponse(bytes.NewReader(data), req.Model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	response.ProcessingTime = time.Since(start)
+	if rate, ok := response.Metadata["tokens_per_second"].(float64); ok {
+		metrics.RecordTokensPerSecond(response.Model, rate)
+	}
 	return response, nil
 }
 
+// FeatureSeed is the capability feature advertised by providers that honour GenerateRequest.Seed
+const FeatureSeed = "seed"
+
+// SupportsSeed reports whether the provider accepts a sampling seed
+func SupportsSeed(provider string) bool {
+	switch strings.ToLower(provider) {
+	case "openai", "ollama":
+		return true
+	}
+	return false
+}
+
 // GetCapabilities returns the capabilities of this AI client
 func (c *Client) GetCapabilities(ctx context.Context) (*interfaces.Capabilities, error) {
 	caps := &interfaces.Capabilities{
-		Provider:  c.config.Provider,
-		MaxTokens: c.config.MaxTokens,
+		Provider:     c.config.Provider,
+		MaxTokens:    c.config.MaxTokens,
+		CurrentModel: c.currentModel(),
 		Features: []interfaces.Feature{
 			{
 				Name:        "text-generation",
@@ -306,6 +368,14 @@ func (c *Client) GetCapabilities(ctx context.Context) (*interfaces.Capabilities,
 		caps.Models = c.getDefaultModelsForProvider()
 	}
 
+	if SupportsSeed(c.config.Provider) {
+		caps.Features = append(caps.Features, interfaces.Feature{
+			Name:        FeatureSeed,
+			Enabled:     true,
+			Description: "Seeded sampling for reproducible output",
+		})
+	}
+
 	// Add streaming feature if supported
 	if c.config.StreamSupported {
 		caps.Features = append(caps.Features, interfaces.Feature{
@@ -345,9 +415,13 @@ func (c *Client) HealthCheck(ctx context.Context) (*interfaces.HealthStatus, err
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		status := "Service unreachable"
+		if unavailable := c.ollamaUnavailable(err); unavailable != nil {
+			status = unavailable.Error()
+		}
 		return &interfaces.HealthStatus{
 			Healthy:      false,
-			Status:       "Service unreachable",
+			Status:       status,
 			ResponseTime: time.Since(start),
 			LastChecked:  time.Now(),
 			Errors:       []string{err.Error()},
@@ -361,21 +435,40 @@ func (c *Client) HealthCheck(ctx context.Context) (*interfaces.HealthStatus, err
 		status = fmt.Sprintf("Unhealthy (status: %d)", resp.StatusCode)
 	}
 
+	mode := HealthModeLight
+	var errs []string
+	if c.config.DeepHealthCheck {
+		mode = HealthModeDeep
+		// Listing models can succeed for keys that may not generate, so only a real call proves it
+		if healthy {
+			if _, err := c.Generate(ctx, &interfaces.GenerateRequest{Prompt: "ping", MaxTokens: 1}); err != nil {
+				healthy = false
+				status = "Unhealthy (generation failed)"
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
 	return &interfaces.HealthStatus{
 		Healthy:      healthy,
 		Status:       status,
 		ResponseTime: time.Since(start),
 		LastChecked:  time.Now(),
+		Errors:       errs,
 		Metadata: map[string]any{
-			"provider": c.config.Provider,
-			"endpoint": c.config.Endpoint,
-			"model":    c.config.Model,
+			"provider":    c.config.Provider,
+			"endpoint":    c.config.Endpoint,
+			"model":       c.currentModel(),
+			"health_mode": mode,
 		},
 	}, nil
 }
 
 // Close releases any resources held by the client
 func (c *Client) Close() error {
+	if c.watcher != nil {
+		c.watcher.stop()
+	}
 	if c.poolEntry != nil {
 		c.poolEntry.release()
 		c.poolEntry = nil
@@ -390,12 +483,16 @@ func (c *Client) getModels(ctx context.Context) ([]interfaces.ModelInfo, error)
 		return nil, err
 	}
 
+	req.Header.Set("Accept-Encoding", "gzip")
 	if c.config.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		if unavailable := c.ollamaUnavailable(err); unavailable != nil {
+			return nil, unavailable
+		}
 		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
@@ -404,8 +501,35 @@ func (c *Client) getModels(ctx context.Context) ([]interfaces.ModelInfo, error)
 		return nil, fmt.Errorf("failed to get models: status %d", resp.StatusCode)
 	}
 
+	body, err := decodeResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
 	// Parse response using strategy pattern
-	return c.strategy.ParseModels(resp.Body, c.config.MaxTokens)
+	return c.strategy.ParseModels(body, c.config.MaxTokens)
+}
+
+// ollamaUnavailable wraps a failure to dial the Ollama endpoint in ErrOllamaUnavailable with a
+// hint to start the server; it returns nil for other providers and errors. The dial check is
+// used instead of matching ECONNREFUSED because the errno differs across platforms.
+func (c *Client) ollamaUnavailable(err error) error {
+	var opErr *net.OpError
+	if c.config.Provider != "ollama" || !errors.As(err, &opErr) || opErr.Op != "dial" {
+		return nil
+	}
+	return fmt.Errorf("%w: is ollama running at %s? try 'ollama serve': %w", ErrOllamaUnavailable, c.config.Endpoint, err)
+}
+
+// decodeResponseBody returns the response payload, decompressing gzip bodies.
+// net/http only decompresses transparently when it added Accept-Encoding itself,
+// which is not the case once the header is set explicitly.
+func decodeResponseBody(resp *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(resp.Body), nil
+	}
+	return gzip.NewReader(resp.Body)
 }
 
 // getDefaultModelsForProvider returns default models using strategy pattern
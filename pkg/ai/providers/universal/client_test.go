@@ -0,0 +1,551 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package universal
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGzip(t *testing.T, w http.ResponseWriter, body string) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	_, err := gz.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+}
+
+func TestGenerateDecodesGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			writeGzip(t, w, `{"id":"req-1","model":"gpt-test","choices":[{"message":{"content":"sql:SELECT 1;"},"finish_reason":"stop"}]}`)
+		case "/v1/models":
+			writeGzip(t, w, `{"data":[{"id":"gpt-test"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{
+		Provider: "custom",
+		Endpoint: server.URL,
+		Model:    "gpt-test",
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "select one"})
+	require.NoError(t, err)
+	assert.Equal(t, "sql:SELECT 1;", resp.Text)
+	assert.Equal(t, "req-1", resp.RequestID)
+
+	models, err := client.getModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "gpt-test", models[0].ID)
+}
+
+func TestModelWatcherPicksUpChangedModel(t *testing.T) {
+	var available atomic.Value
+	available.Store("llama3")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = fmt.Fprintf(w, `{"models":[{"name":%q}]}`, available.Load())
+		case "/api/chat":
+			var body struct {
+				Model string `json:"model"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			_, _ = fmt.Fprintf(w, `{"model":%q,"message":{"content":"SELECT 1;"},"done":true}`, body.Model)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{
+		Provider:             "ollama",
+		Endpoint:             server.URL,
+		Model:                "llama3",
+		ModelRefreshInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	available.Store("qwen2.5")
+	require.Eventually(t, func() bool { return client.currentModel() == "qwen2.5" }, time.Second, 5*time.Millisecond)
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "select one"})
+	require.NoError(t, err)
+	assert.Equal(t, "qwen2.5", resp.Model)
+
+	caps, err := client.GetCapabilities(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "qwen2.5", caps.CurrentModel)
+
+	available.Store("llama3")
+	require.Eventually(t, func() bool { return client.currentModel() == "llama3" }, time.Second, 5*time.Millisecond)
+}
+
+func TestOpenAIStrategyMarksCacheablePrefix(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"req","choices":[{"message":{"content":"SELECT 1;"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "claude-test", CacheControl: true})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	system := "You are an expert SQL assistant.\n\nDatabase Schema:\nTable: users"
+	for _, prompt := range []string{"count users", "list users"} {
+		_, err := client.Generate(context.Background(), &interfaces.GenerateRequest{
+			Prompt:            prompt,
+			SystemPrompt:      system,
+			CacheSystemPrompt: true,
+		})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, bodies, 2)
+	for _, body := range bodies {
+		messages := body["messages"].([]any)
+		content := messages[0].(map[string]any)["content"].([]any)
+		part := content[0].(map[string]any)
+		assert.Equal(t, system, part["text"])
+		assert.Equal(t, map[string]any{"type": "ephemeral"}, part["cache_control"])
+	}
+
+	request, err := (&OpenAIStrategy{provider: "openai"}).BuildRequest(&interfaces.GenerateRequest{
+		Prompt:            "count users",
+		SystemPrompt:      system,
+		CacheSystemPrompt: true,
+	}, &Config{Model: "gpt-4o"})
+	require.NoError(t, err)
+	body := request.(map[string]any)
+	assert.Equal(t, promptCacheKey(system), body["prompt_cache_key"])
+	assert.Equal(t, system, body["messages"].([]map[string]any)[0]["content"], "OpenAI keeps the plain system message")
+
+	request, err = (&OpenAIStrategy{provider: "custom"}).BuildRequest(&interfaces.GenerateRequest{
+		Prompt:       "count users",
+		SystemPrompt: system,
+	}, &Config{Model: "claude-test", CacheControl: true})
+	require.NoError(t, err)
+	assert.Equal(t, system, request.(map[string]any)["messages"].([]map[string]any)[0]["content"], "no marker unless requested")
+
+	request, err = (&OpenAIStrategy{provider: "custom"}).BuildRequest(&interfaces.GenerateRequest{
+		Prompt:            "count users",
+		SystemPrompt:      system,
+		CacheSystemPrompt: true,
+	}, &Config{Model: "llama3"})
+	require.NoError(t, err)
+	assert.Equal(t, system, request.(map[string]any)["messages"].([]map[string]any)[0]["content"],
+		"custom services without cache_control keep the plain system message")
+}
+
+func TestOpenAIStrategyRequestsMultipleChoices(t *testing.T) {
+	request, err := (&OpenAIStrategy{provider: "openai"}).BuildRequest(&interfaces.GenerateRequest{Prompt: "count users", Candidates: 3}, &Config{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, request.(map[string]any)["n"])
+
+	request, err = (&OpenAIStrategy{provider: "deepseek"}).BuildRequest(&interfaces.GenerateRequest{Prompt: "count users", Candidates: 3}, &Config{Model: "deepseek-chat"})
+	require.NoError(t, err)
+	assert.NotContains(t, request.(map[string]any), "n", "providers without n support get separate calls")
+
+	resp, err := (&OpenAIStrategy{provider: "openai"}).ParseResponse(strings.NewReader(
+		`{"model":"gpt-4o","choices":[{"message":{"content":"SELECT 1;"}},{"message":{"content":"SELECT 2;"}},{"message":{"content":"SELECT 3;"}}]}`), "gpt-4o")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", resp.Text)
+	assert.Equal(t, []string{"SELECT 2;", "SELECT 3;"}, resp.Alternatives)
+}
+
+func TestStopSequencesAreSentToProviders(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/chat" {
+			_, _ = w.Write([]byte(`{"model":"llama3","message":{"content":"SELECT 1;"},"done":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"req","choices":[{"message":{"content":"SELECT 1;"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	stop := []string{"\nsql:", "\nNatural Language Query:"}
+
+	openai, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", Stop: stop})
+	require.NoError(t, err)
+	defer func() { _ = openai.Close() }()
+	_, err = openai.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.Equal(t, []any{"\nsql:", "\nNatural Language Query:"}, body["stop"])
+
+	ollama, err := NewUniversalClient(&Config{Provider: "ollama", Endpoint: server.URL, Model: "llama3", Stop: stop})
+	require.NoError(t, err)
+	defer func() { _ = ollama.Close() }()
+	_, err = ollama.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.NotContains(t, body, "stop")
+	assert.Equal(t, []any{"\nsql:", "\nNatural Language Query:"}, body["options"].(map[string]any)["stop"])
+
+	unset, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test"})
+	require.NoError(t, err)
+	defer func() { _ = unset.Close() }()
+	_, err = unset.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.NotContains(t, body, "stop", "no stop sequences are sent when none are configured")
+}
+
+func TestSeedIsSentToProvidersThatSupportIt(t *testing.T) {
+	seed := int64(42)
+	request, err := (&OpenAIStrategy{provider: "openai"}).BuildRequest(&interfaces.GenerateRequest{Prompt: "count users", Seed: &seed}, &Config{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), request.(map[string]any)["seed"])
+
+	request, err = (&OpenAIStrategy{provider: "deepseek"}).BuildRequest(&interfaces.GenerateRequest{Prompt: "count users", Seed: &seed}, &Config{Model: "deepseek-chat"})
+	require.NoError(t, err)
+	assert.NotContains(t, request.(map[string]any), "seed")
+
+	request, err = (&OllamaStrategy{}).BuildRequest(&interfaces.GenerateRequest{Prompt: "count users", Seed: &seed}, &Config{Model: "llama3"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), request.(map[string]any)["options"].(map[string]any)["seed"])
+
+	assert.True(t, SupportsSeed("ollama"))
+	assert.False(t, SupportsSeed("deepseek"))
+}
+
+func TestSameHostRedirectKeepsHeaders(t *testing.T) {
+	var authorization, tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/chat/completions" {
+			http.Redirect(w, r, "/v2/chat/completions", http.StatusTemporaryRedirect)
+			return
+		}
+		authorization, tenant = r.Header.Get("Authorization"), r.Header.Get("X-Tenant")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"req","choices":[{"message":{"content":"SELECT 1;"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test",
+		APIKey: "sk-test", Headers: map[string]string{"X-Tenant": "qa"}})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", resp.Text)
+	assert.Equal(t, "Bearer sk-test", authorization)
+	assert.Equal(t, "qa", tenant)
+}
+
+func TestCrossHostRedirectIsRefused(t *testing.T) {
+	var leaked atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Store(true)
+	}))
+	defer other.Close()
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer gateway.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: gateway.URL, Model: "gpt-test", APIKey: "sk-test"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	_, err = client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.ErrorIs(t, err, ErrCrossHostRedirect)
+	assert.False(t, leaked.Load(), "the redirect target must never receive the request")
+}
+
+func TestSchemeDowngradeRedirectIsRefused(t *testing.T) {
+	original := httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil)
+	original.Header.Set("Authorization", "Bearer sk-test")
+
+	downgrade := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/chat/completions", nil)
+	require.ErrorIs(t, checkRedirect(downgrade, []*http.Request{original}), ErrInsecureRedirect)
+	assert.Empty(t, downgrade.Header.Get("Authorization"), "headers are not copied to a refused redirect")
+
+	upgrade := httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil)
+	plain := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/chat/completions", nil)
+	require.NoError(t, checkRedirect(upgrade, []*http.Request{plain}))
+
+	secure := httptest.NewRequest(http.MethodPost, "https://api.example.com/v2/chat/completions", nil)
+	require.NoError(t, checkRedirect(secure, []*http.Request{original}))
+	assert.Equal(t, "Bearer sk-test", secure.Header.Get("Authorization"))
+}
+
+func TestRateLimitedResponseCarriesRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", APIKey: "sk-test"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	_, err = client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	var providerErr *interfaces.ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+	assert.Equal(t, 2*time.Second, providerErr.RetryAfter)
+	assert.Contains(t, err.Error(), "API returned status 429")
+}
+
+func TestErrorBodyWithSuccessStatusIsProviderError(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		body     string
+		message  string
+		code     string
+	}{
+		{name: "openai error object", provider: "custom", body: `{"error":{"message":"invalid model","type":"invalid_request_error","code":"model_not_found"}}`, message: "invalid model", code: "model_not_found"},
+		{name: "numeric code", provider: "custom", body: `{"error":{"message":"quota exceeded","code":429}}`, message: "quota exceeded", code: "429"},
+		{name: "ollama error string", provider: "ollama", body: `{"error":"model 'llama9' not found"}`, message: "model 'llama9' not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client, err := NewUniversalClient(&Config{Provider: tt.provider, Endpoint: server.URL, Model: "test-model", APIKey: "sk-test"})
+			require.NoError(t, err)
+			defer func() { _ = client.Close() }()
+
+			resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+			require.Nil(t, resp, "an error body must not become an empty completion")
+			var providerErr *interfaces.ProviderError
+			require.ErrorAs(t, err, &providerErr)
+			assert.Equal(t, http.StatusOK, providerErr.StatusCode)
+			assert.Equal(t, tt.message, providerErr.Message)
+			assert.Equal(t, tt.code, providerErr.Code)
+			assert.Contains(t, err.Error(), "API returned status 200: "+tt.message)
+		})
+	}
+
+	// A null error member, as some gateways always include, is not an error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"error":null,"model":"gpt-test","choices":[{"message":{"content":"SELECT 1;"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+	client, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", APIKey: "sk-test"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", resp.Text)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+	}{
+		{name: "none", want: 0},
+		{name: "retry after seconds", headers: map[string]string{"Retry-After": "3"}, want: 3 * time.Second},
+		{name: "retry after date", headers: map[string]string{"Retry-After": now.Add(5 * time.Second).Format(http.TimeFormat)}, want: 5 * time.Second},
+		{name: "retry after wins", headers: map[string]string{"Retry-After": "1", "X-Ratelimit-Reset": "30"}, want: time.Second},
+		{name: "reset seconds", headers: map[string]string{"X-Ratelimit-Reset": "4"}, want: 4 * time.Second},
+		{name: "reset unix time", headers: map[string]string{"X-Ratelimit-Reset": fmt.Sprint(now.Add(7 * time.Second).Unix())}, want: 7 * time.Second},
+		{name: "longest openai reset", headers: map[string]string{"X-Ratelimit-Reset-Requests": "200ms", "X-Ratelimit-Reset-Tokens": "1m30s"}, want: 90 * time.Second},
+		{name: "past date", headers: map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, want: 0},
+		{name: "garbage", headers: map[string]string{"Retry-After": "soon", "X-Ratelimit-Reset": "later"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.headers {
+				header.Set(key, value)
+			}
+			assert.Equal(t, tt.want, retryAfter(header, now))
+		})
+	}
+}
+
+func TestReasoningModelResponseKeepsReasoningSeparate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"req","model":"deepseek-reasoner","choices":[{"message":{` +
+			`"reasoning_content":"Maybe SELECT * FROM accounts; no, users is the right table.",` +
+			`"content":"sql:SELECT * FROM users;\nexplanation:lists users"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "deepseek", Endpoint: server.URL, Model: "deepseek-reasoner"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	assert.Equal(t, 300*time.Second, client.config.Timeout, "reasoning models get a longer default timeout")
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "list users"})
+	require.NoError(t, err)
+	assert.Equal(t, "sql:SELECT * FROM users;\nexplanation:lists users", resp.Text)
+	assert.Equal(t, "Maybe SELECT * FROM accounts; no, users is the right table.", resp.Metadata[MetadataReasoningContent])
+}
+
+func TestIsReasoningModel(t *testing.T) {
+	for model, expected := range map[string]bool{
+		"deepseek-reasoner":  true,
+		"deepseek-r1:14b":    true,
+		"o3-mini":            true,
+		"qwen3-thinking":     true,
+		"openai/o1":          true,
+		"deepseek-chat":      false,
+		"gpt-4o":             false,
+		"llama3.2:3b":        false,
+		"orca-mini":          false,
+		"deepseek-r1-distil": true,
+	} {
+		assert.Equal(t, expected, IsReasoningModel(model), model)
+	}
+}
+
+func TestOllamaResponseReportsTokensPerSecond(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"llama3:8b-q4","message":{"content":"SELECT 1;"},"done":true,` +
+			`"prompt_eval_count":20,"eval_count":120,"eval_duration":4000000000}`))
+	}))
+	defer server.Close()
+
+	client, err := NewUniversalClient(&Config{Provider: "ollama", Endpoint: server.URL, Model: "llama3:8b-q4"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	resp, err := client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "count users"})
+	require.NoError(t, err)
+	assert.InDelta(t, 30.0, resp.Metadata["tokens_per_second"], 0.001)
+
+	_, ok := tokensPerSecond(120, 0)
+	assert.False(t, ok, "a missing eval duration yields no rate")
+}
+
+func TestOllamaConnectionRefusedIsActionable(t *testing.T) {
+	// Reserve a port and release it so connections to it are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	client, err := NewUniversalClient(&Config{Provider: "ollama", Endpoint: endpoint, Model: "llama3.2:1b"})
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	_, err = client.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "select one"})
+	require.ErrorIs(t, err, ErrOllamaUnavailable)
+	assert.Contains(t, err.Error(), "is ollama running at "+endpoint+"? try 'ollama serve'")
+
+	health, err := client.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+	assert.Contains(t, health.Status, "try 'ollama serve'")
+
+	other, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: endpoint, Model: "gpt-test"})
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+
+	_, err = other.Generate(context.Background(), &interfaces.GenerateRequest{Prompt: "select one"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrOllamaUnavailable, "only the Ollama provider gets the hint")
+}
+
+func TestOllamaUnavailableMatchesDialErrors(t *testing.T) {
+	client := &Client{config: &Config{Provider: "ollama", Endpoint: "http://localhost:11434"}}
+
+	dial := &url.Error{Op: "Post", URL: "http://localhost:11434", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connectex: No connection could be made")}}
+	assert.ErrorIs(t, client.ollamaUnavailable(dial), ErrOllamaUnavailable)
+
+	read := &url.Error{Op: "Post", URL: "http://localhost:11434", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}}
+	assert.NoError(t, client.ollamaUnavailable(read), "only dial failures mean ollama is not running")
+	assert.NoError(t, client.ollamaUnavailable(context.DeadlineExceeded))
+}
+
+func TestDeepHealthCheckCatchesForbiddenGeneration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"data":[{"id":"gpt-test"}]}`))
+		case "/v1/chat/completions":
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.EqualValues(t, 1, body["max_tokens"], "the deep check asks for a single token")
+			http.Error(w, `{"error":{"message":"key lacks generation permission"}}`, http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	light, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test"})
+	require.NoError(t, err)
+	defer func() { _ = light.Close() }()
+
+	health, err := light.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.True(t, health.Healthy, "listing models is enough for the light check")
+	assert.Equal(t, HealthModeLight, health.Metadata["health_mode"])
+
+	deep, err := NewUniversalClient(&Config{Provider: "custom", Endpoint: server.URL, Model: "gpt-test", DeepHealthCheck: true})
+	require.NoError(t, err)
+	defer func() { _ = deep.Close() }()
+
+	health, err = deep.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, HealthModeDeep, health.Metadata["health_mode"])
+	assert.Equal(t, "Unhealthy (generation failed)", health.Status)
+	require.NotEmpty(t, health.Errors)
+	assert.Contains(t, health.Errors[0], "403")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package universal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+)
+
+// modelWatcher periodically refreshes the model list so an unloaded model is replaced
+type modelWatcher struct {
+	stopCh   chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startModelWatcher polls the client's model list every interval until stopped
+func startModelWatcher(c *Client, interval time.Duration) *modelWatcher {
+	w := &modelWatcher{
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				c.refreshActiveModel(ctx)
+				cancel()
+			}
+		}
+	}()
+	return w
+}
+
+// stop terminates the polling goroutine and waits for it to exit
+func (w *modelWatcher) stop() {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	<-w.done
+}
+
+// currentModel returns the model requests are sent to when none is specified
+func (c *Client) currentModel() string {
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	if c.activeModel != "" {
+		return c.activeModel
+	}
+	return c.config.Model
+}
+
+// refreshActiveModel keeps the configured or current model while available, otherwise picks the first one listed
+func (c *Client) refreshActiveModel(ctx context.Context) {
+	models, err := c.getModels(ctx)
+	if err != nil {
+		logging.Logger.Debug("Model refresh failed, keeping current model", "provider", c.config.Provider, "error", err)
+		return
+	}
+	if len(models) == 0 {
+		return
+	}
+
+	available := make(map[string]bool, len(models))
+	for _, model := range models {
+		available[model.ID] = true
+	}
+
+	c.modelMu.Lock()
+	defer c.modelMu.Unlock()
+	// Prefer the configured model again as soon as it is back
+	if c.config.Model != "" && available[c.config.Model] {
+		c.activeModel = ""
+		return
+	}
+	current := c.activeModel
+	if current != "" && available[current] {
+		return
+	}
+	if current == "" {
+		current = c.config.Model
+	}
+	c.activeModel = models[0].ID
+	logging.Logger.Info("Configured model unavailable, switched to available model",
+		"provider", c.config.Provider,
+		"previous", current,
+		"model", models[0].ID)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package universal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochThreshold separates x-ratelimit-reset values given as a Unix time from ones given in seconds
+const epochThreshold = 1_000_000_000
+
+// retryAfter returns the delay a provider asked for in Retry-After or the x-ratelimit-reset
+// headers, or 0 when none is present. Retry-After wins; otherwise the longest reset is used.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := strings.TrimSpace(header.Get("Retry-After")); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return positive(time.Duration(seconds * float64(time.Second)))
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return positive(at.Sub(now))
+		}
+	}
+
+	var longest time.Duration
+	for _, name := range []string{"X-Ratelimit-Reset", "X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens"} {
+		if delay := resetDelay(strings.TrimSpace(header.Get(name)), now); delay > longest {
+			longest = delay
+		}
+	}
+	return longest
+}
+
+// resetDelay parses a rate-limit reset given in seconds, as a Unix time or as a duration such as "6m0s"
+func resetDelay(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		if number >= epochThreshold {
+			return positive(time.Unix(int64(number), 0).Sub(now))
+		}
+		return positive(time.Duration(number * float64(time.Second)))
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return positive(duration)
+	}
+	return 0
+}
+
+func positive(delay time.Duration) time.Duration {
+	return max(delay, 0)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package universal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects matches the limit of the default net/http redirect policy
+const maxRedirects = 10
+
+// ErrCrossHostRedirect is returned when a provider endpoint redirects to another host.
+// Following it would either leak the API key or fail authentication, so it is refused.
+var ErrCrossHostRedirect = errors.New("provider endpoint redirected to a different host")
+
+// ErrRedirectDropsBody is returned when a redirect would replay a request as a GET without its body
+var ErrRedirectDropsBody = errors.New("provider endpoint redirected a request that cannot be replayed")
+
+// ErrInsecureRedirect is returned when an https endpoint redirects to plain http.
+// Following it would send the API key in clear text, so it is refused.
+var ErrInsecureRedirect = errors.New("provider endpoint redirected from https to http")
+
+// checkRedirect follows same-host redirects with the original headers, including
+// Authorization and configured headers, and refuses cross-host and https to http redirects.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	original := via[0]
+	if !strings.EqualFold(req.URL.Host, original.URL.Host) {
+		return fmt.Errorf("%w: %s redirected to %s; configure the new endpoint explicitly",
+			ErrCrossHostRedirect, original.URL.Host, req.URL.Host)
+	}
+	if previous := via[len(via)-1]; strings.EqualFold(previous.URL.Scheme, "https") && !strings.EqualFold(req.URL.Scheme, "https") {
+		return fmt.Errorf("%w: %s was redirected to %s", ErrInsecureRedirect, previous.URL.Redacted(), req.URL.Redacted())
+	}
+	if original.Method != req.Method {
+		return fmt.Errorf("%w: %s %s was redirected to %s %s; configure the new endpoint path explicitly",
+			ErrRedirectDropsBody, original.Method, original.URL.Path, req.Method, req.URL.Path)
+	}
+	for key, values := range original.Header {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = append([]string(nil), values...)
+		}
+	}
+	return nil
+}
@@ -64,13 +64,21 @@ func (s *OllamaStrategy) BuildRequest(req *interfaces.GenerateRequest, config *C
 		"content": req.Prompt,
 	})
 
+	options := map[string]any{
+		"num_predict": maxTokens,
+	}
+	if len(config.Stop) > 0 {
+		options["stop"] = config.Stop
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+
 	return map[string]any{
 		"model":    model,
 		"messages": messages,
 		"stream":   req.Stream,
-		"options": map[string]any{
-			"num_predict": maxTokens,
-		},
+		"options":  options,
 	}, nil
 }
 
@@ -81,13 +89,14 @@ func (s *OllamaStrategy) ParseResponse(body io.Reader, requestedModel string) (*
 		Message struct {
 			Content string `json:"content"`
 		} `json:"message"`
-		Done               bool  `json:"done"`
-		TotalDuration      int64 `json:"total_duration"`
-		LoadDuration       int64 `json:"load_duration"`
-		PromptEvalCount    int   `json:"prompt_eval_count"`
-		PromptEvalDuration int64 `json:"prompt_eval_duration"`
-		EvalCount          int   `json:"eval_count"`
-		EvalDuration       int64 `json:"eval_duration"`
+		Done               bool   `json:"done"`
+		DoneReason         string `json:"done_reason"`
+		TotalDuration      int64  `json:"total_duration"`
+		LoadDuration       int64  `json:"load_duration"`
+		PromptEvalCount    int    `json:"prompt_eval_count"`
+		PromptEvalDuration int64  `json:"prompt_eval_duration"`
+		EvalCount          int    `json:"eval_count"`
+		EvalDuration       int64  `json:"eval_duration"`
 	}
 
 	if err := json.NewDecoder(body).Decode(&resp); err != nil {
@@ -98,22 +107,37 @@ func (s *OllamaStrategy) ParseResponse(body io.Reader, requestedModel string) (*
 		resp.Model = requestedModel
 	}
 
+	metadata := map[string]any{
+		"done":             resp.Done,
+		"finish_reason":    resp.DoneReason,
+		"total_duration":   resp.TotalDuration,
+		"load_duration":    resp.LoadDuration,
+		"prompt_eval_time": resp.PromptEvalDuration,
+		"eval_time":        resp.EvalDuration,
+		// Token usage information available in metadata if needed
+		"prompt_eval_count": resp.PromptEvalCount,
+		"eval_count":        resp.EvalCount,
+	}
+	if rate, ok := tokensPerSecond(resp.EvalCount, resp.EvalDuration); ok {
+		metadata["tokens_per_second"] = rate
+	}
+
 	return &interfaces.GenerateResponse{
 		Text:      resp.Message.Content,
 		Model:     resp.Model,
 		RequestID: fmt.Sprintf("ollama-%d", time.Now().Unix()),
-		Metadata: map[string]any{
-			"total_duration":   resp.TotalDuration,
-			"load_duration":    resp.LoadDuration,
-			"prompt_eval_time": resp.PromptEvalDuration,
-			"eval_time":        resp.EvalDuration,
-			// Token usage information available in metadata if needed
-			"prompt_eval_count": resp.PromptEvalCount,
-			"eval_count":        resp.EvalCount,
-		},
+		Metadata:  metadata,
 	}, nil
 }
 
+// tokensPerSecond converts Ollama's eval count and nanosecond eval duration into a generation rate
+func tokensPerSecond(evalCount int, evalDuration int64) (float64, bool) {
+	if evalCount <= 0 || evalDuration <= 0 {
+		return 0, false
+	}
+	return float64(evalCount) / time.Duration(evalDuration).Seconds(), true
+}
+
 // ParseModels parses Ollama's model list response
 func (s *OllamaStrategy) ParseModels(body io.Reader, maxTokens int) ([]interfaces.ModelInfo, error) {
 	var resp struct {
@@ -17,6 +17,8 @@ limitations under the License.
 package universal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -45,25 +47,22 @@ func (s *OpenAIStrategy) BuildRequest(req *interfaces.GenerateRequest, config *C
 	}
 
 	// Build messages
-	messages := []map[string]string{}
+	messages := []map[string]any{}
 
 	if req.SystemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": req.SystemPrompt,
-		})
+		messages = append(messages, systemMessage(req, config))
 	}
 
 	// Add context
 	for _, ctx := range req.Context {
-		messages = append(messages, map[string]string{
+		messages = append(messages, map[string]any{
 			"role":    "assistant",
 			"content": ctx,
 		})
 	}
 
 	// Add the main prompt
-	messages = append(messages, map[string]string{
+	messages = append(messages, map[string]any{
 		"role":    "user",
 		"content": req.Prompt,
 	})
@@ -75,6 +74,24 @@ func (s *OpenAIStrategy) BuildRequest(req *interfaces.GenerateRequest, config *C
 		"stream":     req.Stream,
 	}
 
+	// Only OpenAI is known to honour n; other providers get one call per candidate from the caller
+	if req.Candidates > 1 && s.provider == "openai" {
+		request["n"] = req.Candidates
+	}
+
+	// OpenAI caches long prefixes automatically; the key keeps requests sharing a prefix on the same cache
+	if req.CacheSystemPrompt && req.SystemPrompt != "" && s.provider == "openai" {
+		request["prompt_cache_key"] = promptCacheKey(req.SystemPrompt)
+	}
+
+	if len(config.Stop) > 0 {
+		request["stop"] = config.Stop
+	}
+
+	if req.Seed != nil && SupportsSeed(s.provider) {
+		request["seed"] = *req.Seed
+	}
+
 	// Add any additional parameters from config
 	for k, v := range config.Parameters {
 		if _, exists := request[k]; !exists {
@@ -85,6 +102,34 @@ func (s *OpenAIStrategy) BuildRequest(req *interfaces.GenerateRequest, config *C
 	return request, nil
 }
 
+// systemMessage builds the system message. Services that opt in with cache_control (typically
+// gateways in front of Anthropic models) get an ephemeral cache_control marker when the prompt
+// is cacheable; plain OpenAI-compatible servers may reject array content, so it is never the default.
+func systemMessage(req *interfaces.GenerateRequest, config *Config) map[string]any {
+	if !req.CacheSystemPrompt || !config.CacheControl {
+		return map[string]any{
+			"role":    "system",
+			"content": req.SystemPrompt,
+		}
+	}
+	return map[string]any{
+		"role": "system",
+		"content": []map[string]any{
+			{
+				"type":          "text",
+				"text":          req.SystemPrompt,
+				"cache_control": map[string]string{"type": "ephemeral"},
+			},
+		},
+	}
+}
+
+// promptCacheKey derives a stable cache routing key from the cacheable prefix
+func promptCacheKey(prefix string) string {
+	sum := sha256.Sum256([]byte(prefix))
+	return "atest-" + hex.EncodeToString(sum[:8])
+}
+
 // ParseResponse parses an OpenAI-compatible API response
 func (s *OpenAIStrategy) ParseResponse(body io.Reader, requestedModel string) (*interfaces.GenerateResponse, error) {
 	var resp struct {
@@ -92,7 +137,8 @@ func (s *OpenAIStrategy) ParseResponse(body io.Reader, requestedModel string) (*
 		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -115,20 +161,53 @@ func (s *OpenAIStrategy) ParseResponse(body io.Reader, requestedModel string) (*
 		resp.Model = requestedModel
 	}
 
+	var alternatives []string
+	for _, choice := range resp.Choices[1:] {
+		alternatives = append(alternatives, choice.Message.Content)
+	}
+
+	metadata := map[string]any{
+		"finish_reason": resp.Choices[0].FinishReason,
+		// Token usage information available in metadata if needed
+		"prompt_tokens":     resp.Usage.PromptTokens,
+		"completion_tokens": resp.Usage.CompletionTokens,
+		"total_tokens":      resp.Usage.TotalTokens,
+	}
+	// Reasoning models such as deepseek-reasoner return their chain of thought separately
+	if reasoning := resp.Choices[0].Message.ReasoningContent; reasoning != "" {
+		metadata[MetadataReasoningContent] = reasoning
+	}
+
 	return &interfaces.GenerateResponse{
-		Text:      resp.Choices[0].Message.Content,
-		Model:     resp.Model,
-		RequestID: resp.ID,
-		Metadata: map[string]any{
-			"finish_reason": resp.Choices[0].FinishReason,
-			// Token usage information available in metadata if needed
-			"prompt_tokens":     resp.Usage.PromptTokens,
-			"completion_tokens": resp.Usage.CompletionTokens,
-			"total_tokens":      resp.Usage.TotalTokens,
-		},
+		Text:         resp.Choices[0].Message.Content,
+		Alternatives: alternatives,
+		Model:        resp.Model,
+		RequestID:    resp.ID,
+		Metadata:     metadata,
 	}, nil
 }
 
+// MetadataReasoningContent is the response metadata key holding a reasoning model's chain of thought
+const MetadataReasoningContent = "reasoning_content"
+
+// reasoningModelPrefixes name model families that think before answering
+var reasoningModelPrefixes = []string{"deepseek-reasoner", "deepseek-r1", "o1", "o3", "o4", "qwq"}
+
+// IsReasoningModel reports whether model is a known reasoning model, which is slower and may
+// return its reasoning separately from the answer
+func IsReasoningModel(model string) bool {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	for _, prefix := range reasoningModelPrefixes {
+		if name == prefix || strings.HasPrefix(name, prefix+"-") || strings.HasPrefix(name, prefix+":") {
+			return true
+		}
+	}
+	return strings.Contains(name, "think") || strings.Contains(name, "reason")
+}
+
 // ParseModels parses OpenAI's model list response
 func (s *OpenAIStrategy) ParseModels(body io.Reader, maxTokens int) ([]interfaces.ModelInfo, error) {
 	var resp struct {
@@ -0,0 +1,43 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+)
+
+// thinkBlockPattern matches inline <think> blocks emitted by reasoning models served without a separate field
+var thinkBlockPattern = regexp.MustCompile(`(?is)<think>(.*?)(?:</think>|$)`)
+
+// splitReasoning separates a reasoning model's chain of thought from the answer used for SQL extraction
+func splitReasoning(aiResponse *interfaces.GenerateResponse) (reasoning, answer string) {
+	var parts []string
+	if content, ok := aiResponse.Metadata[universal.MetadataReasoningContent].(string); ok && strings.TrimSpace(content) != "" {
+		parts = append(parts, strings.TrimSpace(content))
+	}
+	answer = thinkBlockPattern.ReplaceAllStringFunc(aiResponse.Text, func(block string) string {
+		if thought := strings.TrimSpace(thinkBlockPattern.FindStringSubmatch(block)[1]); thought != "" {
+			parts = append(parts, thought)
+		}
+		return ""
+	})
+	return strings.Join(parts, "\n"), strings.TrimSpace(answer)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+)
+
+// ErrNotReproducible is returned by GenerateReproducible when the output cannot be pinned
+var ErrNotReproducible = errors.New("generation is not reproducible")
+
+// GenerateReproducible generates SQL with a fixed seed for golden comparisons in CI and returns
+// the result with a stable hash of the generated SQL. It fails fast when options carry no seed,
+// ask for several candidates or a confidence fallback provider, or the selected provider does not
+// advertise seed support. Requests routed by the Manager are only sent to clients that support
+// seeds, whichever client A/B testing, latency demotion or failover picks.
+func (g *SQLGenerator) GenerateReproducible(ctx context.Context, naturalLanguage string, options *GenerateOptions) (*GenerationResult, string, error) {
+	if options == nil || options.Seed == nil {
+		return nil, "", fmt.Errorf("%w: a seed is required", ErrNotReproducible)
+	}
+	if clampCandidates(options.Candidates) > 1 {
+		return nil, "", fmt.Errorf("%w: candidates cannot be combined with a seed", ErrNotReproducible)
+	}
+	if options.ConfidenceFallbackProvider != "" {
+		return nil, "", fmt.Errorf("%w: a confidence fallback provider cannot be combined with a seed", ErrNotReproducible)
+	}
+
+	client, err := g.clientFor(options)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, routed := client.(*managerClient); routed {
+		return g.generateReproducible(ctx, naturalLanguage, options)
+	}
+	capabilities, err := client.GetCapabilities(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: cannot read provider capabilities: %v", ErrNotReproducible, err)
+	}
+	if !hasFeature(capabilities.Features, universal.FeatureSeed) {
+		return nil, "", fmt.Errorf("%w: provider %q does not support seeds", ErrNotReproducible, capabilities.Provider)
+	}
+
+	return g.generateReproducible(ctx, naturalLanguage, options)
+}
+
+// generateReproducible generates SQL for checked options and returns it with its output hash
+func (g *SQLGenerator) generateReproducible(ctx context.Context, naturalLanguage string, options *GenerateOptions) (*GenerationResult, string, error) {
+	result, err := g.Generate(ctx, naturalLanguage, options)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, outputHash(result), nil
+}
+
+// hasFeature reports whether features contains an enabled feature called name
+func hasFeature(features []interfaces.Feature, name string) bool {
+	for _, feature := range features {
+		if feature.Name == name && feature.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// outputHash is the hex SHA-256 of the generated SQL, stable across runs with identical output
+func outputHash(result *GenerationResult) string {
+	sum := sha256.Sum256([]byte(result.SQL))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ResponseParser extracts SQL and an explanation from a model response. It reports false when the
+// response is not in its format, so the next parser in the chain runs.
+type ResponseParser interface {
+	// Name identifies the parser; services select a preferred parser by this name through response_style
+	Name() string
+	Parse(text string) (sql, explanation string, ok bool)
+}
+
+// DefaultResponseParsers is the best-effort chain tried when no preferred parser matches
+var DefaultResponseParsers = []ResponseParser{SimpleFormatParser{}, JSONParser{}, FencedCodeParser{}, PlainTextParser{}}
+
+// responseParsers maps each selectable response style to its parser
+var responseParsers = map[string]ResponseParser{
+	ResponseStyleSimple:   SimpleFormatParser{},
+	ResponseStyleJSON:     JSONParser{},
+	ResponseStyleMarkdown: FencedCodeParser{},
+	ResponseStylePrefixed: PrefixedParser{},
+	ResponseStylePlain:    PlainTextParser{},
+}
+
+// responseParserChain returns the preferred parser for style, if any, followed by the default chain
+func responseParserChain(style string) []ResponseParser {
+	preferred, ok := responseParsers[style]
+	if !ok {
+		return DefaultResponseParsers
+	}
+	return append([]ResponseParser{preferred}, DefaultResponseParsers...)
+}
+
+// parseResponse runs parsers in order and returns the first non-empty SQL
+func parseResponse(parsers []ResponseParser, text string) (string, string, bool) {
+	for _, parser := range parsers {
+		if sql, explanation, ok := parser.Parse(text); ok && sql != "" {
+			return sql, explanation, true
+		}
+	}
+	return "", "", false
+}
+
+// SimpleFormatParser reads the "sql:...\nexplanation:..." format the prompts ask for
+type SimpleFormatParser struct{}
+
+// Name implements ResponseParser
+func (SimpleFormatParser) Name() string { return ResponseStyleSimple }
+
+// Parse implements ResponseParser
+func (SimpleFormatParser) Parse(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "sql:") {
+		return "", "", false
+	}
+	parts := strings.SplitN(text, "\nexplanation:", 2)
+	if len(parts) == 1 {
+		// Fallback to space separator for backward compatibility
+		parts = strings.SplitN(text, " explanation:", 2)
+	}
+	explanation := ""
+	if len(parts) > 1 {
+		explanation = strings.TrimSpace(parts[1])
+	}
+	return cleanSQLText(strings.TrimPrefix(parts[0], "sql:")), explanation, true
+}
+
+// JSONParser reads a JSON object carrying "sql" and "explanation" fields
+type JSONParser struct{}
+
+// Name implements ResponseParser
+func (JSONParser) Name() string { return ResponseStyleJSON }
+
+// Parse implements ResponseParser
+func (JSONParser) Parse(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return "", "", false
+	}
+	var response SQLResponse
+	if err := json.Unmarshal([]byte(text), &response); err != nil || response.SQL == "" {
+		return "", "", false
+	}
+	return cleanSQLText(response.SQL), strings.TrimSpace(response.Explanation), true
+}
+
+// FencedCodeParser takes the SQL from a fenced code block and the prose after the last fence
+type FencedCodeParser struct{}
+
+// Name implements ResponseParser
+func (FencedCodeParser) Name() string { return ResponseStyleMarkdown }
+
+// Parse implements ResponseParser
+func (FencedCodeParser) Parse(text string) (string, string, bool) {
+	body := strings.TrimPrefix(strings.TrimSpace(text), "sql:")
+	block, ok := extractFencedSQL(body)
+	if !ok {
+		return "", "", false
+	}
+	return trimTrailingCommentary(block), explanationText(body[strings.LastIndex(body, "```")+3:]), true
+}
+
+// PrefixedParser drops a lead-in such as "Here's your query:" before the first statement
+type PrefixedParser struct{}
+
+// Name implements ResponseParser
+func (PrefixedParser) Name() string { return ResponseStylePrefixed }
+
+// Parse implements ResponseParser
+func (PrefixedParser) Parse(text string) (string, string, bool) {
+	match := statementStart.FindStringSubmatchIndex(text)
+	if match == nil {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(text[match[2]:])
+	sql := cleanSQLText(rest)
+	if !strings.HasPrefix(rest, sql) {
+		return sql, "", true
+	}
+	return sql, explanationText(rest[len(sql):]), true
+}
+
+// PlainTextParser treats the whole response as SQL, keeping any commentary after it as the explanation
+type PlainTextParser struct{}
+
+// Name implements ResponseParser
+func (PlainTextParser) Name() string { return ResponseStylePlain }
+
+// Parse implements ResponseParser
+func (PlainTextParser) Parse(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	sql := cleanSQLText(text)
+	if !strings.HasPrefix(text, sql) {
+		return sql, "", true
+	}
+	return sql, explanationText(text[len(sql):]), true
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Response styles selectable per service through ai.services.<name>.response_style
+const (
+	ResponseStyleMarkdown = "markdown"
+	ResponseStylePrefixed = "prefixed"
+	ResponseStylePlain    = "plain"
+	ResponseStyleSimple   = "simple"
+	ResponseStyleJSON     = "json"
+)
+
+// statementStart finds the first statement keyword at the start of a line or right after a colon
+var statementStart = regexp.MustCompile(`(?im)(?:^|:)[ \t]*((?:SELECT|WITH|INSERT|UPDATE|DELETE|CREATE|ALTER|DROP|TRUNCATE|EXPLAIN|SHOW|DESCRIBE|MERGE)\b)`)
+
+// responseStyle returns the configured response style of the service answering the request
+func (g *SQLGenerator) responseStyle(options *GenerateOptions) string {
+	cfg := g.currentConfig()
+	service := options.Provider
+	if service == "" {
+		service = cfg.DefaultService
+	}
+	return strings.ToLower(strings.TrimSpace(cfg.Services[service].ResponseStyle))
+}
+
+// explanationText trims leftover fence markers and an explicit "explanation:" label
+func explanationText(text string) string {
+	text = strings.TrimSpace(strings.Trim(strings.TrimSpace(text), "`"))
+	if len(text) >= len("explanation:") && strings.EqualFold(text[:len("explanation:")], "explanation:") {
+		text = text[len("explanation:"):]
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema provides helpers for describing the database a query targets.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrUnknownDSN is returned when the dialect cannot be inferred from a DSN
+var ErrUnknownDSN = errors.New("unable to infer dialect from DSN")
+
+// DialectFromDSN infers the SQL dialect from a connection string.
+// It recognizes URL schemes (mysql://, postgres://, sqlite://, sqlserver://),
+// the go-sql-driver/mysql form (user:pass@tcp(host)/db), libpq key/value
+// strings (host=... dbname=...), and SQLite file paths (file:app.db, app.sqlite).
+func DialectFromDSN(dsn string) (string, error) {
+	trimmed := strings.TrimSpace(dsn)
+	if trimmed == "" {
+		return "", fmt.Errorf("%w: empty DSN", ErrUnknownDSN)
+	}
+	lower := strings.ToLower(trimmed)
+
+	if idx := strings.Index(lower, "://"); idx > 0 {
+		switch lower[:idx] {
+		case "mysql", "mariadb":
+			return "mysql", nil
+		case "postgres", "postgresql", "pgx":
+			return "postgresql", nil
+		case "sqlite", "sqlite3", "file":
+			return "sqlite", nil
+		case "sqlserver", "mssql":
+			return "sqlserver", nil
+		default:
+			return "", fmt.Errorf("%w: unsupported scheme %q", ErrUnknownDSN, lower[:idx])
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "file:"), hasSQLiteExtension(lower), lower == ":memory:":
+		return "sqlite", nil
+	case strings.Contains(lower, "@tcp(") || strings.Contains(lower, "@unix("):
+		return "mysql", nil
+	case strings.Contains(lower, "host=") || strings.Contains(lower, "dbname="):
+		return "postgresql", nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownDSN, redactDSN(trimmed))
+}
+
+func hasSQLiteExtension(dsn string) bool {
+	path := dsn
+	if idx := strings.Index(path, "?"); idx >= 0 {
+		path = path[:idx]
+	}
+	for _, ext := range []string{".db", ".sqlite", ".sqlite3"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordPairPattern matches password key/value pairs of libpq, ODBC/ADO and URL query strings;
+// quoted libpq values may contain spaces and escaped quotes
+var passwordPairPattern = regexp.MustCompile(`(?i)\b(password|passwd|pwd)(\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s;&]*)`)
+
+// redactDSN hides credentials so DSNs can be safely included in error messages: user info
+// before "@" and the values of password=... pairs are masked
+func redactDSN(dsn string) string {
+	dsn = passwordPairPattern.ReplaceAllString(dsn, "${1}${2}***")
+	if idx := strings.LastIndex(dsn, "@"); idx >= 0 {
+		return "***" + dsn[idx:]
+	}
+	return dsn
+}
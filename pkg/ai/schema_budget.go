@@ -0,0 +1,101 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+)
+
+// estimateTokens approximates a token count at four bytes per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// trimSchemaOptions returns options whose schema fits SchemaTokenBudget; the caller's options are not modified
+func trimSchemaOptions(naturalLanguage string, options *GenerateOptions) *GenerateOptions {
+	if options.SchemaTokenBudget <= 0 || len(options.Schema) == 0 {
+		return options
+	}
+	kept := trimSchema(options.Schema, naturalLanguage, options.SchemaTokenBudget)
+	if len(kept) == len(options.Schema) {
+		return options
+	}
+
+	logging.Logger.Debug("Schema trimmed to token budget",
+		"budget", options.SchemaTokenBudget,
+		"tables", len(options.Schema),
+		"kept", len(kept))
+	trimmed := *options
+	trimmed.Schema = kept
+	trimmed.renderedSchema = ""
+	return &trimmed
+}
+
+// trimSchema keeps the tables that fit within budget tokens, preferring tables the query
+// mentions, then higher Importance, then name order
+func trimSchema(schema map[string]Table, naturalLanguage string, budget int) map[string]Table {
+	type candidate struct {
+		name       string
+		mentioned  bool
+		importance int
+		tokens     int
+	}
+
+	keywords := promptKeywords(naturalLanguage)
+	candidates := make([]candidate, 0, len(schema))
+	for name, table := range schema {
+		candidates = append(candidates, candidate{
+			name:       name,
+			mentioned:  tableMentioned(name, keywords),
+			importance: table.Importance,
+			tokens:     estimateTokens(renderTable(name, table)),
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.mentioned != b.mentioned {
+			return a.mentioned
+		}
+		if a.importance != b.importance {
+			return a.importance > b.importance
+		}
+		return a.name < b.name
+	})
+
+	remaining := budget - estimateTokens("Database Schema:\n")
+	kept := make(map[string]Table)
+	for _, c := range candidates {
+		if c.tokens > remaining {
+			continue
+		}
+		kept[c.name] = schema[c.name]
+		remaining -= c.tokens
+	}
+	return kept
+}
+
+// tableMentioned reports whether the query names the table, allowing a plural or singular form
+func tableMentioned(name string, keywords map[string]bool) bool {
+	name = strings.ToLower(name)
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return keywords[name] || keywords[strings.TrimSuffix(name, "s")] || keywords[name+"s"]
+}
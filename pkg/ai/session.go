@@ -0,0 +1,189 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// ErrSessionNotFound is returned when a session id is unknown, closed or expired
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// ErrTooManySessions is returned when ai.sessions.max_sessions sessions are already open
+var ErrTooManySessions = errors.New("too many open sessions")
+
+// generationSession is a schema prepared once and reused by every generation in the session
+type generationSession struct {
+	schema    map[string]Table
+	rendered  string
+	expiresAt time.Time
+}
+
+// sessionStore holds open sessions; an expired session is dropped the next time the store is used
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*generationSession
+	now      func() time.Time
+}
+
+// newSessionStore creates an empty session store
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*generationSession), now: time.Now}
+}
+
+// ParseSchema decodes a schema document: a JSON object of tables keyed by name, or a JSON array of tables
+func ParseSchema(data []byte) (map[string]Table, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var tables []Table
+		if err := json.Unmarshal(data, &tables); err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		schema := make(map[string]Table, len(tables))
+		for _, table := range tables {
+			if table.Name == "" {
+				return nil, fmt.Errorf("invalid schema: table without a name")
+			}
+			schema[table.Name] = table
+		}
+		return schema, nil
+	}
+
+	var schema map[string]Table
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+// OpenSession prepares schema once and returns the id of a session that reuses it. The session
+// expires after ai.sessions.ttl without a generation.
+func (g *SQLGenerator) OpenSession(schema map[string]Table) (string, error) {
+	if len(schema) == 0 {
+		return "", fmt.Errorf("session schema cannot be empty")
+	}
+
+	// The session keeps its own copy so later changes to the caller's map do not leak in
+	prepared := make(map[string]Table, len(schema))
+	for name, table := range schema {
+		if table.Name == "" {
+			table.Name = name
+		}
+		prepared[name] = table
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", fmt.Errorf("failed to create session id: %w", err)
+	}
+	id := hex.EncodeToString(idBytes)
+
+	limits := g.currentConfig().Sessions
+	ttl := limits.TTL.Value()
+	if ttl <= 0 {
+		ttl = constants.Sessions.TTL
+	}
+	maxSessions := limits.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = constants.Sessions.MaxSessions
+	}
+
+	store := g.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.evictExpired()
+	if len(store.sessions) >= maxSessions {
+		return "", fmt.Errorf("%w: %d sessions are open", ErrTooManySessions, len(store.sessions))
+	}
+	store.sessions[id] = &generationSession{
+		schema:    prepared,
+		rendered:  renderSchema(prepared),
+		expiresAt: store.now().Add(ttl),
+	}
+	return id, nil
+}
+
+// CloseSession discards a session; closing an unknown or expired session returns ErrSessionNotFound
+func (g *SQLGenerator) CloseSession(id string) error {
+	store := g.sessions
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.evictExpired()
+	if _, ok := store.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(store.sessions, id)
+	return nil
+}
+
+// GenerateInSession generates SQL against the schema of an open session, ignoring options.Schema
+func (g *SQLGenerator) GenerateInSession(ctx context.Context, sessionID string, naturalLanguage string, options *GenerateOptions) (*GenerationResult, error) {
+	options, err := g.sessionOptions(sessionID, options)
+	if err != nil {
+		return nil, err
+	}
+	return g.Generate(ctx, naturalLanguage, options)
+}
+
+// sessionOptions returns a copy of options carrying the session's prepared schema and extends the
+// session's lifetime
+func (g *SQLGenerator) sessionOptions(sessionID string, options *GenerateOptions) (*GenerateOptions, error) {
+	ttl := g.currentConfig().Sessions.TTL.Value()
+	if ttl <= 0 {
+		ttl = constants.Sessions.TTL
+	}
+
+	store := g.sessions
+	store.mu.Lock()
+	store.evictExpired()
+	session, ok := store.sessions[sessionID]
+	if ok {
+		session.expiresAt = store.now().Add(ttl)
+	}
+	store.mu.Unlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	if options == nil {
+		options = defaultGenerateOptions()
+	}
+	sessionOptions := *options
+	sessionOptions.Schema = session.schema
+	sessionOptions.renderedSchema = session.rendered
+	return &sessionOptions, nil
+}
+
+// evictExpired drops expired sessions; the caller holds mu
+func (s *sessionStore) evictExpired() {
+	now := s.now()
+	for id, session := range s.sessions {
+		if now.After(session.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
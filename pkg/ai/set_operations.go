@@ -0,0 +1,144 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import "fmt"
+
+// setOperationNote explains why a dialect does not accept a set operation and what to use instead
+type setOperationNote struct {
+	Message    string
+	Suggestion string
+}
+
+// setOperators are the keywords that combine the results of two queries
+var setOperators = map[string]bool{"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true}
+
+// Unsupported set operations per dialect, keyed by operator with an optional " ALL"
+var (
+	mysqlSetOperations = map[string]setOperationNote{
+		"INTERSECT":     {"INTERSECT requires MySQL 8.0.31 or later", "On older servers use an INNER JOIN or WHERE EXISTS (...)"},
+		"INTERSECT ALL": {"INTERSECT ALL requires MySQL 8.0.31 or later", "On older servers use an INNER JOIN"},
+		"EXCEPT":        {"EXCEPT requires MySQL 8.0.31 or later", "On older servers use WHERE NOT EXISTS (...) or LEFT JOIN ... WHERE ... IS NULL"},
+		"EXCEPT ALL":    {"EXCEPT ALL requires MySQL 8.0.31 or later", "On older servers use LEFT JOIN ... WHERE ... IS NULL"},
+		"MINUS":         {"MySQL does not support MINUS", "Use EXCEPT on MySQL 8.0.31 or later, otherwise WHERE NOT EXISTS (...)"},
+		"MINUS ALL":     {"MySQL does not support MINUS", "Use EXCEPT ALL on MySQL 8.0.31 or later"},
+	}
+	postgresSetOperations = map[string]setOperationNote{
+		"MINUS":     {"PostgreSQL does not support MINUS", "Use EXCEPT"},
+		"MINUS ALL": {"PostgreSQL does not support MINUS", "Use EXCEPT ALL"},
+	}
+	sqliteSetOperations = map[string]setOperationNote{
+		"INTERSECT ALL": {"SQLite does not support INTERSECT ALL", "Use INTERSECT, which removes duplicates, or an INNER JOIN to keep them"},
+		"EXCEPT ALL":    {"SQLite does not support EXCEPT ALL", "Use EXCEPT, which removes duplicates, or WHERE NOT EXISTS (...) to keep them"},
+		"MINUS":         {"SQLite does not support MINUS", "Use EXCEPT"},
+		"MINUS ALL":     {"SQLite does not support MINUS", "Use EXCEPT"},
+	}
+	snowflakeSetOperations = map[string]setOperationNote{
+		"INTERSECT ALL": {"Snowflake does not support INTERSECT ALL", "Use INTERSECT, which removes duplicates"},
+		"EXCEPT ALL":    {"Snowflake does not support EXCEPT ALL", "Use EXCEPT or MINUS, which remove duplicates"},
+		"MINUS ALL":     {"Snowflake does not support MINUS ALL", "Use MINUS, which removes duplicates"},
+	}
+)
+
+// setOperationResults warns about every set operation in sql listed in unsupported
+func setOperationResults(sql string, unsupported map[string]setOperationNote) []ValidationResult {
+	runes := []rune(sql)
+	tokens := tokenizeSQL(sql)
+	var results []ValidationResult
+	for i, token := range tokens {
+		if token.Kind != tokenWord || !setOperators[token.Text] {
+			continue
+		}
+		operation := token.Text
+		if i+1 < len(tokens) && tokens[i+1].Kind == tokenWord && tokens[i+1].Text == "ALL" {
+			operation += " ALL"
+		}
+		note, ok := unsupported[operation]
+		if !ok {
+			continue
+		}
+		line, column := runePosition(runes, token.Pos)
+		results = append(results, ValidationResult{
+			Type:       "syntax",
+			Level:      "warning",
+			Message:    fmt.Sprintf("%s (line %d, column %d)", note.Message, line, column),
+			Line:       line,
+			Column:     column,
+			Suggestion: note.Suggestion,
+		})
+	}
+	return results
+}
+
+// parenthesizedCompoundResults warns when a member of a compound query is wrapped in parentheses,
+// which SQLite rejects, as in (SELECT a FROM t) UNION (SELECT a FROM u)
+func parenthesizedCompoundResults(sql string) []ValidationResult {
+	runes := []rune(sql)
+	tokens := tokenizeSQL(sql)
+	for i, token := range tokens {
+		if token.Kind != tokenWord || !setOperators[token.Text] {
+			continue
+		}
+		next := i + 1
+		if next < len(tokens) && tokens[next].Kind == tokenWord && (tokens[next].Text == "ALL" || tokens[next].Text == "DISTINCT") {
+			next++
+		}
+		before := i > 0 && tokens[i-1].Text == ")" && closesQuery(tokens[:i])
+		after := next+1 < len(tokens) && tokens[next].Text == "(" && tokens[next+1].Text == "SELECT"
+		if !before && !after {
+			continue
+		}
+		line, column := runePosition(runes, token.Pos)
+		return []ValidationResult{{
+			Type:       "syntax",
+			Level:      "warning",
+			Message:    fmt.Sprintf("SQLite does not allow parentheses around the queries of a %s (line %d, column %d)", token.Text, line, column),
+			Line:       line,
+			Column:     column,
+			Suggestion: "Remove the parentheses, or wrap the member in SELECT * FROM (...)",
+		}}
+	}
+	return nil
+}
+
+// closesQuery reports whether the ")" ending tokens closes a query that is itself a member of a
+// compound query: the "(" opens SELECT and starts the statement or follows another set operator
+func closesQuery(tokens []sqlToken) bool {
+	depth := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].Text {
+		case ")":
+			depth++
+		case "(":
+			if depth--; depth > 0 {
+				continue
+			}
+			if i+1 >= len(tokens) || tokens[i+1].Text != "SELECT" {
+				return false
+			}
+			if i == 0 {
+				return true
+			}
+			previous := i - 1
+			if (tokens[previous].Text == "ALL" || tokens[previous].Text == "DISTINCT") && previous > 0 {
+				previous--
+			}
+			return tokens[previous].Text == ";" || (tokens[previous].Kind == tokenWord && setOperators[tokens[previous].Text])
+		}
+	}
+	return false
+}
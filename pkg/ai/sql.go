@@ -17,9 +17,14 @@ limitations under the License.
 package ai
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 )
 
 // SQLDialect defines the interface for database-specific SQL handling
@@ -47,6 +52,144 @@ type SQLDialect interface {
 
 	// TransformSQL transforms SQL from one dialect to another
 	TransformSQL(sql string, targetDialect string) (string, error)
+
+	// ApplyPagination limits a single SELECT to limit rows starting at offset
+	ApplyPagination(sql string, limit, offset int) (string, error)
+}
+
+// supportedDialects lists the canonical database types accepted by the generator
+var supportedDialects = []string{"mysql", "postgresql", "sqlite", "snowflake"}
+
+// SupportedDialects returns the canonical database types with a built-in dialect
+func SupportedDialects() []string {
+	return append([]string(nil), supportedDialects...)
+}
+
+// dialectAliases maps alternative spellings to a supported database type
+var dialectAliases = supportedAliases()
+
+// supportedAliases returns the shared aliases that resolve to a supported database type
+func supportedAliases() map[string]string {
+	aliases := make(map[string]string)
+	for alias, canonical := range providers.Aliases() {
+		if slices.Contains(supportedDialects, canonical) {
+			aliases[alias] = canonical
+		}
+	}
+	return aliases
+}
+
+// ErrUnsupportedDialect is matched by errors.Is for every UnsupportedDialectError
+var ErrUnsupportedDialect = errors.New("unsupported database type")
+
+// ErrUnsupportedTransform is returned by TransformSQL when no dedicated transform exists for a target
+var ErrUnsupportedTransform = errors.New("unsupported target dialect")
+
+// ErrInvalidPagination is returned when pagination cannot be applied to a query
+var ErrInvalidPagination = errors.New("invalid pagination")
+
+// UnsupportedDialectError reports an unknown database type with the supported ones and a near match
+type UnsupportedDialectError struct {
+	Requested  string
+	Supported  []string
+	Suggestion string
+}
+
+func (e *UnsupportedDialectError) Error() string {
+	message := fmt.Sprintf("%s %q (supported: %s)", ErrUnsupportedDialect, e.Requested, strings.Join(e.Supported, ", "))
+	if e.Suggestion != "" {
+		message += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return message
+}
+
+func (e *UnsupportedDialectError) Unwrap() error {
+	return ErrUnsupportedDialect
+}
+
+// NewUnsupportedDialectError creates an UnsupportedDialectError with a near-match suggestion
+func NewUnsupportedDialectError(requested string) error {
+	return &UnsupportedDialectError{
+		Requested:  requested,
+		Supported:  append([]string(nil), supportedDialects...),
+		Suggestion: suggestDialect(requested),
+	}
+}
+
+// suggestDialect returns the supported database type closest to requested, or "" when none is close
+func suggestDialect(requested string) string {
+	normalized := strings.ToLower(strings.TrimSpace(requested))
+	if normalized == "" {
+		return ""
+	}
+	if alias, ok := dialectAliases[normalized]; ok {
+		return alias
+	}
+
+	best, bestDistance := "", 3
+	for _, candidate := range append(supportedDialects, mapKeys(dialectAliases)...) {
+		if len(normalized) >= 3 && strings.HasPrefix(candidate, normalized) {
+			return canonicalDialect(candidate)
+		}
+		if distance := editDistance(normalized, candidate); distance < bestDistance {
+			best, bestDistance = canonicalDialect(candidate), distance
+		}
+	}
+	return best
+}
+
+// canonicalDialect resolves an alias to its supported database type
+func canonicalDialect(name string) string {
+	if alias, ok := dialectAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// mapKeys returns the keys of m in sorted order
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// NewSQLDialect returns the built-in dialect for a database type such as "mysql" or "postgres"
+func NewSQLDialect(databaseType string) (SQLDialect, bool) {
+	switch providers.Normalize(databaseType) {
+	case "mysql":
+		return &MySQLDialect{}, true
+	case "postgresql":
+		return &PostgreSQLDialect{}, true
+	case "sqlite":
+		return &SQLiteDialect{}, true
+	case "snowflake":
+		return &SnowflakeDialect{}, true
+	}
+	return nil, false
 }
 
 // DataType represents a database data type
@@ -91,9 +234,6 @@ func (d *MySQLDialect) ValidateSQL(sql string) ([]ValidationResult, error) {
 		}}, nil
 	}
 
-	// Check for common MySQL syntax issues
-	upper := strings.ToUpper(sql)
-
 	// Check for proper statement termination
 	if !strings.HasSuffix(strings.TrimSpace(sql), ";") {
 		results = append(results, ValidationResult{
@@ -104,32 +244,26 @@ func (d *MySQLDialect) ValidateSQL(sql string) ([]ValidationResult, error) {
 		})
 	}
 
-	// Check for MySQL-specific issues
-	if strings.Contains(upper, "LIMIT") && !regexp.MustCompile(`LIMIT\s+\d+(\s*,\s*\d+)?`).MatchString(upper) {
-		results = append(results, ValidationResult{
-			Type:    "syntax",
-			Level:   "error",
-			Message: "Invalid LIMIT syntax for MySQL",
-		})
-	}
-
-	// Check for reserved keywords used as identifiers (not as SQL commands)
-	// We'll only check for keywords that might be used as table or column names
-	problematicKeywords := []string{"ORDER", "GROUP", "KEY", "INDEX", "TABLE", "DATABASE"}
-	for _, keyword := range problematicKeywords {
-		if strings.Contains(upper, keyword+" ") && !strings.Contains(upper, "`"+keyword+"`") {
-			// More sophisticated check to see if it's used as identifier
-			if !isKeywordUsedAsCommand(upper, keyword) {
-				results = append(results, ValidationResult{
-					Type:       "naming",
-					Level:      "warning",
-					Message:    fmt.Sprintf("'%s' might be a reserved keyword in MySQL", keyword),
-					Suggestion: fmt.Sprintf("Use backticks if using as identifier: `%s`", keyword),
-				})
-			}
+	// Check for MySQL-specific issues; LIMIT inside strings, such as a JSON path, is not a clause
+	for _, arguments := range limitArguments(tokenizeSQL(sql)) {
+		if len(arguments) == 0 || arguments[0].Kind != tokenNumber {
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    "Invalid LIMIT syntax for MySQL",
+				Suggestion: "Use LIMIT count or LIMIT offset, count",
+			})
+			break
 		}
 	}
 
+	// Check for reserved keywords used as unquoted identifiers
+	results = append(results, reservedIdentifierResults(sql, d, "`")...)
+	results = append(results, malformedClauseResults(sql)...)
+	results = append(results, setOperationResults(sql, mysqlSetOperations)...)
+	results = append(results, mysqlJSONResults(sql)...)
+	results = append(results, groupByResults(sql, mysqlGroupBy)...)
+
 	return results, nil
 }
 
@@ -226,12 +360,20 @@ func (d *MySQLDialect) GetKeywords() []string {
 func (d *MySQLDialect) TransformSQL(sql string, targetDialect string) (string, error) {
 	switch targetDialect {
 	case "postgresql":
-		return d.transformToPostgreSQL(sql)
+		return transformOutsideComments(sql, d.transformToPostgreSQL)
 	case "sqlite":
-		return d.transformToSQLite(sql)
+		return transformOutsideComments(sql, d.transformToSQLite)
 	default:
-		return sql, fmt.Errorf("unsupported target dialect: %s", targetDialect)
+		return sql, fmt.Errorf("%w: %s", ErrUnsupportedTransform, targetDialect)
+	}
+}
+
+// ApplyPagination appends MySQL's LIMIT offset, count form.
+func (d *MySQLDialect) ApplyPagination(sql string, limit, offset int) (string, error) {
+	if offset == 0 {
+		return appendPagination(sql, limit, offset, fmt.Sprintf("LIMIT %d", limit))
 	}
+	return appendPagination(sql, limit, offset, fmt.Sprintf("LIMIT %d, %d", offset, limit))
 }
 
 func (d *MySQLDialect) transformToPostgreSQL(sql string) (string, error) {
@@ -285,8 +427,6 @@ func (d *PostgreSQLDialect) ValidateSQL(sql string) ([]ValidationResult, error)
 		}}, nil
 	}
 
-	upper := strings.ToUpper(sql)
-
 	// Check for proper statement termination
 	if !strings.HasSuffix(strings.TrimSpace(sql), ";") {
 		results = append(results, ValidationResult{
@@ -297,14 +437,17 @@ func (d *PostgreSQLDialect) ValidateSQL(sql string) ([]ValidationResult, error)
 		})
 	}
 
-	// Check for PostgreSQL-specific issues
-	if strings.Contains(upper, "LIMIT") && strings.Contains(upper, ",") {
-		results = append(results, ValidationResult{
-			Type:       "syntax",
-			Level:      "error",
-			Message:    "PostgreSQL uses LIMIT x OFFSET y syntax, not LIMIT x, y",
-			Suggestion: "Use LIMIT count OFFSET start format",
-		})
+	// Check for PostgreSQL-specific issues; only a comma inside the LIMIT clause is the MySQL form
+	for _, arguments := range limitArguments(tokenizeSQL(sql)) {
+		if len(arguments) > 1 && arguments[1].Text == "," {
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    "PostgreSQL uses LIMIT x OFFSET y syntax, not LIMIT x, y",
+				Suggestion: "Use LIMIT count OFFSET start format",
+			})
+			break
+		}
 	}
 
 	// Check for identifier quoting
@@ -317,6 +460,13 @@ func (d *PostgreSQLDialect) ValidateSQL(sql string) ([]ValidationResult, error)
 		})
 	}
 
+	// Check for reserved keywords used as unquoted identifiers
+	results = append(results, reservedIdentifierResults(sql, d, "\"")...)
+	results = append(results, malformedClauseResults(sql)...)
+	results = append(results, setOperationResults(sql, postgresSetOperations)...)
+	results = append(results, postgresJSONResults(sql)...)
+	results = append(results, groupByResults(sql, postgresGroupBy)...)
+
 	return results, nil
 }
 
@@ -415,14 +565,21 @@ func (d *PostgreSQLDialect) GetKeywords() []string {
 func (d *PostgreSQLDialect) TransformSQL(sql string, targetDialect string) (string, error) {
 	switch targetDialect {
 	case "mysql":
-		return d.transformToMySQL(sql)
+		return transformOutsideComments(sql, d.transformToMySQL)
 	case "sqlite":
-		return d.transformToSQLite(sql)
+		return transformOutsideComments(sql, d.transformToSQLite)
+	case "snowflake":
+		return transformOutsideComments(sql, d.transformToSnowflake)
 	default:
-		return sql, fmt.Errorf("unsupported target dialect: %s", targetDialect)
+		return sql, fmt.Errorf("%w: %s", ErrUnsupportedTransform, targetDialect)
 	}
 }
 
+// ApplyPagination appends LIMIT with an OFFSET when one is requested.
+func (d *PostgreSQLDialect) ApplyPagination(sql string, limit, offset int) (string, error) {
+	return appendPagination(sql, limit, offset, limitOffsetClause(limit, offset))
+}
+
 func (d *PostgreSQLDialect) transformToMySQL(sql string) (string, error) {
 	transformed := sql
 
@@ -451,6 +608,20 @@ func (d *PostgreSQLDialect) transformToSQLite(sql string) (string, error) {
 	return transformed, nil
 }
 
+func (d *PostgreSQLDialect) transformToSnowflake(sql string) (string, error) {
+	transformed := sql
+
+	// Snowflake only accepts LIMIT before OFFSET, and spells LIMIT ALL as LIMIT NULL
+	offsetFirst := regexp.MustCompile(`(?i)\bOFFSET\s+(\d+)\s+LIMIT\s+(\d+)`)
+	transformed = offsetFirst.ReplaceAllString(transformed, "LIMIT $2 OFFSET $1")
+	transformed = regexp.MustCompile(`(?i)\bLIMIT\s+ALL\b`).ReplaceAllString(transformed, "LIMIT NULL")
+
+	// Replace PostgreSQL-specific functions
+	transformed = regexp.MustCompile(`(?i)\bNOW\s*\(\s*\)`).ReplaceAllString(transformed, "CURRENT_TIMESTAMP()")
+
+	return transformed, nil
+}
+
 // SQLiteDialect implements SQLDialect for SQLite
 type SQLiteDialect struct{}
 
@@ -494,6 +665,12 @@ func (d *SQLiteDialect) ValidateSQL(sql string) ([]ValidationResult, error) {
 		})
 	}
 
+	// Check for reserved keywords used as unquoted identifiers
+	results = append(results, reservedIdentifierResults(sql, d, "\"")...)
+	results = append(results, malformedClauseResults(sql)...)
+	results = append(results, setOperationResults(sql, sqliteSetOperations)...)
+	results = append(results, parenthesizedCompoundResults(sql)...)
+
 	return results, nil
 }
 
@@ -574,14 +751,19 @@ func (d *SQLiteDialect) GetKeywords() []string {
 func (d *SQLiteDialect) TransformSQL(sql string, targetDialect string) (string, error) {
 	switch targetDialect {
 	case "mysql":
-		return d.transformToMySQL(sql)
+		return transformOutsideComments(sql, d.transformToMySQL)
 	case "postgresql":
-		return d.transformToPostgreSQL(sql)
+		return transformOutsideComments(sql, d.transformToPostgreSQL)
 	default:
-		return sql, fmt.Errorf("unsupported target dialect: %s", targetDialect)
+		return sql, fmt.Errorf("%w: %s", ErrUnsupportedTransform, targetDialect)
 	}
 }
 
+// ApplyPagination appends LIMIT with an OFFSET when one is requested.
+func (d *SQLiteDialect) ApplyPagination(sql string, limit, offset int) (string, error) {
+	return appendPagination(sql, limit, offset, limitOffsetClause(limit, offset))
+}
+
 func (d *SQLiteDialect) transformToMySQL(sql string) (string, error) {
 	transformed := sql
 
@@ -610,14 +792,203 @@ func (d *SQLiteDialect) transformToPostgreSQL(sql string) (string, error) {
 	return transformed, nil
 }
 
-// isKeywordUsedAsCommand checks if a keyword is used as a SQL command rather than an identifier
-func isKeywordUsedAsCommand(sql, keyword string) bool {
-	// This is a simplified check - in practice you'd want more sophisticated parsing
-	commandPrefixes := []string{"SELECT ", "FROM ", "WHERE ", "GROUP BY", "ORDER BY", "HAVING ", "UNION ", "JOIN "}
-	for _, prefix := range commandPrefixes {
-		if strings.Contains(sql, prefix) && strings.Contains(sql, prefix+keyword) {
-			return true
-		}
+// SnowflakeDialect implements SQLDialect for Snowflake
+type SnowflakeDialect struct{}
+
+// snowflakeQuotedMixedCase matches quoted identifiers that keep lower-case letters
+var snowflakeQuotedMixedCase = regexp.MustCompile(`"([^"]*[a-z][^"]*)"`)
+
+// Name implements SQLDialect.Name for Snowflake.
+func (d *SnowflakeDialect) Name() string {
+	return "Snowflake"
+}
+
+// ValidateSQL implements SQLDialect.ValidateSQL with Snowflake-specific rules.
+func (d *SnowflakeDialect) ValidateSQL(sql string) ([]ValidationResult, error) {
+	var results []ValidationResult
+
+	sql = strings.TrimSpace(sql)
+	if sql == "" {
+		return []ValidationResult{{
+			Type:    "syntax",
+			Level:   "error",
+			Message: "Empty SQL statement",
+		}}, nil
+	}
+
+	upper := strings.ToUpper(sql)
+
+	// Check for proper statement termination
+	if !strings.HasSuffix(strings.TrimSpace(sql), ";") {
+		results = append(results, ValidationResult{
+			Type:       "syntax",
+			Level:      "warning",
+			Message:    "SQL statement should end with semicolon",
+			Suggestion: "Add ';' at the end of the statement",
+		})
+	}
+
+	if regexp.MustCompile(`(?i)\bLIMIT\s+\d+\s*,`).MatchString(sql) {
+		results = append(results, ValidationResult{
+			Type:       "syntax",
+			Level:      "error",
+			Message:    "Snowflake uses LIMIT x OFFSET y syntax, not LIMIT x, y",
+			Suggestion: "Use LIMIT count OFFSET start format",
+		})
+	}
+
+	if strings.Contains(sql, "`") {
+		results = append(results, ValidationResult{
+			Type:       "syntax",
+			Level:      "warning",
+			Message:    "Snowflake uses double quotes for identifiers, not backticks",
+			Suggestion: "Use double quotes (\") instead of backticks (`)",
+		})
+	}
+
+	// Unquoted identifiers fold to upper case, so quoted lower-case names must always be quoted
+	for _, match := range snowflakeQuotedMixedCase.FindAllStringSubmatch(sql, -1) {
+		results = append(results, ValidationResult{
+			Type:       "semantic",
+			Level:      "info",
+			Message:    fmt.Sprintf("Quoted identifier \"%s\" is case-sensitive; Snowflake folds unquoted identifiers to upper case", match[1]),
+			Suggestion: fmt.Sprintf("Always reference it as \"%s\", or use an unquoted upper-case name", match[1]),
+		})
+	}
+
+	// QUALIFY filters on window functions without wrapping the query in a subquery
+	hasQualify := regexp.MustCompile(`\bQUALIFY\b`).MatchString(upper)
+	hasWindow := regexp.MustCompile(`\bOVER\s*\(`).MatchString(upper)
+	switch {
+	case hasQualify && !hasWindow:
+		results = append(results, ValidationResult{
+			Type:       "syntax",
+			Level:      "error",
+			Message:    "QUALIFY requires a window function in the query",
+			Suggestion: "Filter with WHERE or HAVING, or add a window function such as ROW_NUMBER() OVER (...)",
+		})
+	case hasWindow && !hasQualify && strings.Count(upper, "SELECT") > 1:
+		results = append(results, ValidationResult{
+			Type:       "style",
+			Level:      "info",
+			Message:    "Snowflake supports QUALIFY for filtering window function results",
+			Suggestion: "Replace the subquery filter with QUALIFY, e.g. QUALIFY ROW_NUMBER() OVER (...) = 1",
+		})
+	}
+
+	// Check for reserved keywords used as unquoted identifiers
+	results = append(results, reservedIdentifierResults(sql, d, "\"")...)
+	results = append(results, malformedClauseResults(sql)...)
+	results = append(results, setOperationResults(sql, snowflakeSetOperations)...)
+
+	return results, nil
+}
+
+// OptimizeSQL provides tuning suggestions for Snowflake queries.
+func (d *SnowflakeDialect) OptimizeSQL(sql string) (string, []string, error) {
+	var suggestions []string
+	optimizedSQL := sql
+
+	upper := strings.ToUpper(sql)
+
+	if strings.Contains(upper, "SELECT *") {
+		suggestions = append(suggestions, "Select only the needed columns; Snowflake scans columnar storage per column")
+	}
+
+	if strings.Contains(upper, "SELECT") && !strings.Contains(upper, "LIMIT") {
+		suggestions = append(suggestions, "Consider adding a LIMIT clause to prevent large result sets")
+	}
+
+	if strings.Contains(upper, "WHERE") {
+		suggestions = append(suggestions, "Filter on clustering key columns so micro-partitions can be pruned")
+	}
+
+	return optimizedSQL, suggestions, nil
+}
+
+// FormatSQL formats SQL according to Snowflake conventions.
+func (d *SnowflakeDialect) FormatSQL(sql string) (string, error) {
+	formatted := strings.TrimSpace(sql)
+
+	keywords := []string{"SELECT", "FROM", "WHERE", "GROUP BY", "HAVING", "QUALIFY", "ORDER BY", "LIMIT", "OFFSET"}
+	for _, keyword := range keywords {
+		pattern := regexp.MustCompile(`(?i)\b` + keyword + `\b`)
+		formatted = pattern.ReplaceAllString(formatted, "\n"+keyword)
+	}
+
+	return strings.TrimSpace(formatted), nil
+}
+
+// GetDataTypes lists Snowflake data types.
+func (d *SnowflakeDialect) GetDataTypes() []DataType {
+	return []DataType{
+		{Name: "NUMBER", Category: "numeric", Aliases: []string{"DECIMAL", "NUMERIC", "INT", "INTEGER", "BIGINT", "SMALLINT"}, Precision: 38},
+		{Name: "FLOAT", Category: "numeric", Aliases: []string{"DOUBLE", "REAL", "FLOAT8"}},
+		{Name: "VARCHAR", Category: "string", Aliases: []string{"STRING", "TEXT"}, MaxLength: 16777216},
+		{Name: "CHAR", Category: "string", Aliases: []string{"CHARACTER"}},
+		{Name: "BINARY", Category: "binary", Aliases: []string{"VARBINARY"}},
+		{Name: "BOOLEAN", Category: "boolean"},
+		{Name: "DATE", Category: "date"},
+		{Name: "TIME", Category: "date"},
+		{Name: "TIMESTAMP_NTZ", Category: "date", Aliases: []string{"DATETIME", "TIMESTAMP"}},
+		{Name: "TIMESTAMP_LTZ", Category: "date"},
+		{Name: "TIMESTAMP_TZ", Category: "date"},
+		{Name: "VARIANT", Category: "semi-structured"},
+		{Name: "OBJECT", Category: "semi-structured"},
+		{Name: "ARRAY", Category: "semi-structured"},
+		{Name: "GEOGRAPHY", Category: "geospatial"},
+	}
+}
+
+// GetFunctions enumerates Snowflake functions used by the generator.
+func (d *SnowflakeDialect) GetFunctions() []Function {
+	return []Function{
+		{Name: "COUNT", Category: "aggregate", Description: "Count rows", Syntax: "COUNT(column)", Examples: []string{"COUNT(*)", "COUNT(id)"}},
+		{Name: "SUM", Category: "aggregate", Description: "Sum values", Syntax: "SUM(column)", Examples: []string{"SUM(amount)"}},
+		{Name: "AVG", Category: "aggregate", Description: "Average values", Syntax: "AVG(column)", Examples: []string{"AVG(price)"}},
+		{Name: "MAX", Category: "aggregate", Description: "Maximum value", Syntax: "MAX(column)", Examples: []string{"MAX(created_at)"}},
+		{Name: "MIN", Category: "aggregate", Description: "Minimum value", Syntax: "MIN(column)", Examples: []string{"MIN(price)"}},
+		{Name: "ARRAY_AGG", Category: "aggregate", Description: "Aggregate values into an array", Syntax: "ARRAY_AGG(expr) [WITHIN GROUP (ORDER BY ...)]", Examples: []string{"ARRAY_AGG(name) WITHIN GROUP (ORDER BY name)"}},
+		{Name: "IFF", Category: "conditional", Description: "Inline if", Syntax: "IFF(condition, then, else)", Examples: []string{"IFF(amount > 0, 'credit', 'debit')"}},
+		{Name: "DATE_TRUNC", Category: "date", Description: "Truncate a date or timestamp", Syntax: "DATE_TRUNC(part, expr)", Examples: []string{"DATE_TRUNC('MONTH', created_at)"}},
+		{Name: "DATEADD", Category: "date", Description: "Add to a date part", Syntax: "DATEADD(part, value, expr)", Examples: []string{"DATEADD(DAY, -7, CURRENT_DATE())"}},
+		{Name: "CURRENT_TIMESTAMP", Category: "date", Description: "Current timestamp", Syntax: "CURRENT_TIMESTAMP()", Examples: []string{"CURRENT_TIMESTAMP()"}},
+		{Name: "PARSE_JSON", Category: "semi-structured", Description: "Parse JSON text into a VARIANT", Syntax: "PARSE_JSON(str)", Examples: []string{"PARSE_JSON(payload):user.id"}},
 	}
-	return false
+}
+
+// GetKeywords returns Snowflake reserved words.
+func (d *SnowflakeDialect) GetKeywords() []string {
+	return []string{
+		"SELECT", "FROM", "WHERE", "INSERT", "UPDATE", "DELETE", "MERGE", "CREATE", "DROP", "ALTER",
+		"TABLE", "VIEW", "SCHEMA", "DATABASE", "FUNCTION", "TRIGGER", "NOT", "NULL", "DEFAULT",
+		"AND", "OR", "IN", "LIKE", "ILIKE", "RLIKE", "BETWEEN", "EXISTS", "IS", "CASE", "WHEN", "THEN", "ELSE",
+		"GROUP", "BY", "ORDER", "HAVING", "QUALIFY", "LIMIT", "OFFSET", "SAMPLE", "UNION", "MINUS",
+		"JOIN", "LEFT", "RIGHT", "INNER", "OUTER", "FULL", "LATERAL", "ON", "AS", "DISTINCT", "ALL", "ASC", "DESC",
+	}
+}
+
+// TransformSQL adapts Snowflake queries to other dialects when possible.
+func (d *SnowflakeDialect) TransformSQL(sql string, targetDialect string) (string, error) {
+	switch targetDialect {
+	case "postgresql":
+		return transformOutsideComments(sql, d.transformToPostgreSQL)
+	default:
+		return sql, fmt.Errorf("%w: %s", ErrUnsupportedTransform, targetDialect)
+	}
+}
+
+// ApplyPagination appends LIMIT with an OFFSET when one is requested.
+func (d *SnowflakeDialect) ApplyPagination(sql string, limit, offset int) (string, error) {
+	return appendPagination(sql, limit, offset, limitOffsetClause(limit, offset))
+}
+
+func (d *SnowflakeDialect) transformToPostgreSQL(sql string) (string, error) {
+	transformed := sql
+
+	// Replace Snowflake-specific functions
+	transformed = regexp.MustCompile(`(?i)\bCURRENT_TIMESTAMP\s*\(\s*\)`).ReplaceAllString(transformed, "NOW()")
+	transformed = regexp.MustCompile(`(?i)\bLIMIT\s+NULL\b`).ReplaceAllString(transformed, "LIMIT ALL")
+
+	return transformed, nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package ai
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -244,6 +245,13 @@ func TestMySQLDialect_TransformSQL(t *testing.T) {
 			expectedSQL:   "SELECT DATETIME('now') FROM USERS",
 			expectError:   false,
 		},
+		{
+			name:          "MySQL to SQLite - comments are preserved",
+			sql:           "-- default to `now()`\nSELECT NOW() /* keep NOW() */ FROM users",
+			targetDialect: "sqlite",
+			expectedSQL:   "-- default to `now()`\nSELECT DATETIME('now') /* keep NOW() */ FROM USERS",
+			expectError:   false,
+		},
 		{
 			name:          "unsupported target dialect",
 			sql:           "SELECT * FROM users",
@@ -384,6 +392,13 @@ func TestPostgreSQLDialect_TransformSQL(t *testing.T) {
 			expectedSQL:   "SELECT `name` FROM `users`",
 			expectError:   false,
 		},
+		{
+			name:          "PostgreSQL to MySQL - comments are preserved",
+			sql:           "SELECT \"name\" -- the \"display\" name\nFROM \"users\"",
+			targetDialect: "mysql",
+			expectedSQL:   "SELECT `name` -- the \"display\" name\nFROM `users`",
+			expectError:   false,
+		},
 		{
 			name:          "PostgreSQL to MySQL - LIMIT OFFSET",
 			sql:           "SELECT * FROM users LIMIT 20 OFFSET 10",
@@ -405,6 +420,20 @@ func TestPostgreSQLDialect_TransformSQL(t *testing.T) {
 			expectedSQL:   "SELECT DATE('now'), DATETIME('now') FROM users",
 			expectError:   false,
 		},
+		{
+			name:          "PostgreSQL to Snowflake - NOW and LIMIT OFFSET",
+			sql:           "SELECT id, now() FROM users ORDER BY id LIMIT 20 OFFSET 10",
+			targetDialect: "snowflake",
+			expectedSQL:   "SELECT id, CURRENT_TIMESTAMP() FROM users ORDER BY id LIMIT 20 OFFSET 10",
+			expectError:   false,
+		},
+		{
+			name:          "PostgreSQL to Snowflake - OFFSET before LIMIT and LIMIT ALL",
+			sql:           "SELECT * FROM (SELECT * FROM users LIMIT ALL) u OFFSET 5 LIMIT 10",
+			targetDialect: "snowflake",
+			expectedSQL:   "SELECT * FROM (SELECT * FROM users LIMIT NULL) u LIMIT 10 OFFSET 5",
+			expectError:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -608,6 +637,98 @@ func TestSQLiteDialect_TransformSQL(t *testing.T) {
 	}
 }
 
+func TestSnowflakeDialect_GetDataTypes(t *testing.T) {
+	dialect := &SnowflakeDialect{}
+	dataTypes := dialect.GetDataTypes()
+
+	expectedTypes := []string{"NUMBER", "VARCHAR", "TIMESTAMP_NTZ", "VARIANT", "ARRAY", "OBJECT"}
+	for _, expected := range expectedTypes {
+		found := false
+		for _, dataType := range dataTypes {
+			if dataType.Name == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected data type %s not found", expected)
+		}
+	}
+
+	functions := map[string]bool{}
+	for _, function := range dialect.GetFunctions() {
+		functions[function.Name] = true
+	}
+	for _, expected := range []string{"IFF", "ARRAY_AGG", "DATE_TRUNC"} {
+		if !functions[expected] {
+			t.Errorf("Expected function %s not found", expected)
+		}
+	}
+
+	if dialect, ok := NewSQLDialect("snowflake"); !ok || dialect.Name() != "Snowflake" {
+		t.Errorf("Expected snowflake to be a built-in dialect")
+	}
+}
+
+func TestSnowflakeDialect_ValidateSQL(t *testing.T) {
+	dialect := &SnowflakeDialect{}
+
+	tests := []struct {
+		name            string
+		sql             string
+		expectedMessage string
+		expectedLevel   string
+	}{
+		{
+			name:            "quoted lower-case identifier",
+			sql:             `SELECT "userId" FROM users;`,
+			expectedMessage: `Quoted identifier "userId" is case-sensitive; Snowflake folds unquoted identifiers to upper case`,
+			expectedLevel:   "info",
+		},
+		{
+			name:            "QUALIFY without window function",
+			sql:             "SELECT id FROM users QUALIFY id = 1;",
+			expectedMessage: "QUALIFY requires a window function in the query",
+			expectedLevel:   "error",
+		},
+		{
+			name:            "window function filtered in a subquery",
+			sql:             "SELECT * FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY team ORDER BY id) AS rn FROM users) WHERE rn = 1;",
+			expectedMessage: "Snowflake supports QUALIFY for filtering window function results",
+			expectedLevel:   "info",
+		},
+		{
+			name:            "MySQL style LIMIT",
+			sql:             "SELECT id FROM users LIMIT 10, 20;",
+			expectedMessage: "Snowflake uses LIMIT x OFFSET y syntax, not LIMIT x, y",
+			expectedLevel:   "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := dialect.ValidateSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, result := range results {
+				if result.Message == tt.expectedMessage {
+					if result.Level != tt.expectedLevel {
+						t.Errorf("Expected level %s, got %s", tt.expectedLevel, result.Level)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected validation message %q in %+v", tt.expectedMessage, results)
+		})
+	}
+
+	results, err := dialect.ValidateSQL("SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS rn FROM users QUALIFY rn = 1;")
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected valid QUALIFY query, got %+v (%v)", results, err)
+	}
+}
+
 func TestSQLDialect_FormatSQL(t *testing.T) {
 	dialects := []struct {
 		name    string
@@ -689,3 +810,454 @@ func TestSQLDialect_Integration(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformSQLWithFallback(t *testing.T) {
+	transformed, warnings, err := TransformSQLWithFallback(&MySQLDialect{}, "SELECT `name`, 'a\"b' FROM `users` -- `keep`\nLIMIT 20, 10", "snowflake")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "SELECT \"name\", 'a\"b' FROM \"users\" -- `keep`\nLIMIT 10 OFFSET 20"
+	if transformed != expected {
+		t.Errorf("Expected %q, got %q", expected, transformed)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "best-effort") {
+		t.Errorf("Expected a best-effort warning, got %v", warnings)
+	}
+
+	transformed, warnings, err = TransformSQLWithFallback(&SnowflakeDialect{}, `SELECT "name" FROM users LIMIT 10 OFFSET 20`, "mysql")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if transformed != "SELECT `name` FROM users LIMIT 20, 10" || len(warnings) != 1 {
+		t.Errorf("Unexpected best-effort result %q with warnings %v", transformed, warnings)
+	}
+
+	_, warnings, err = TransformSQLWithFallback(&MySQLDialect{}, "SELECT 1", "postgresql")
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("Expected a dedicated transform without warnings, got %v, %v", warnings, err)
+	}
+
+	_, _, err = TransformSQLWithFallback(&MySQLDialect{}, "SELECT 1", "oracle")
+	if !errors.Is(err, ErrUnsupportedTransform) {
+		t.Errorf("Expected ErrUnsupportedTransform for an unknown target, got %v", err)
+	}
+}
+
+func TestSQLDialect_ReservedIdentifiers(t *testing.T) {
+	dialects := map[string]struct {
+		dialect SQLDialect
+		quote   string
+	}{
+		"mysql":      {dialect: &MySQLDialect{}, quote: "`"},
+		"postgresql": {dialect: &PostgreSQLDialect{}, quote: `"`},
+		"sqlite":     {dialect: &SQLiteDialect{}, quote: `"`},
+	}
+
+	for name, tc := range dialects {
+		t.Run(name, func(t *testing.T) {
+			flagged, err := tc.dialect.ValidateSQL("SELECT order FROM table;")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := namingResults(flagged); len(got) != 2 {
+				t.Errorf("Expected order and table to be flagged, got %v", got)
+			}
+
+			quoted := strings.NewReplacer("`", tc.quote).Replace("SELECT `order` FROM `table`;")
+			clean, err := tc.dialect.ValidateSQL(quoted)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := namingResults(clean); len(got) != 0 {
+				t.Errorf("Expected quoted identifiers to be clean, got %v", got)
+			}
+
+			keywords, err := tc.dialect.ValidateSQL("SELECT DISTINCT o.id FROM orders o WHERE o.note = 'order by table' AND NOT o.deleted GROUP BY o.id ORDER BY o.id DESC;")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := namingResults(keywords); len(got) != 0 {
+				t.Errorf("Expected keywords used as commands to be clean, got %v", got)
+			}
+		})
+	}
+}
+
+func namingResults(results []ValidationResult) []string {
+	var messages []string
+	for _, result := range results {
+		if result.Type == "naming" {
+			messages = append(messages, result.Message)
+		}
+	}
+	return messages
+}
+
+func TestApplyPagination(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     SQLDialect
+		sql         string
+		limit       int
+		offset      int
+		expectedSQL string
+		expectError bool
+	}{
+		{
+			name:        "MySQL limit only",
+			dialect:     &MySQLDialect{},
+			sql:         "SELECT * FROM users;",
+			limit:       10,
+			expectedSQL: "SELECT * FROM users LIMIT 10;",
+		},
+		{
+			name:        "MySQL offset form",
+			dialect:     &MySQLDialect{},
+			sql:         "SELECT * FROM users",
+			limit:       10,
+			offset:      20,
+			expectedSQL: "SELECT * FROM users LIMIT 20, 10",
+		},
+		{
+			name:        "PostgreSQL limit offset",
+			dialect:     &PostgreSQLDialect{},
+			sql:         "SELECT * FROM users ORDER BY id ;",
+			limit:       10,
+			offset:      20,
+			expectedSQL: "SELECT * FROM users ORDER BY id LIMIT 10 OFFSET 20;",
+		},
+		{
+			name:        "SQLite limit offset",
+			dialect:     &SQLiteDialect{},
+			sql:         "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders LIMIT 5)",
+			limit:       10,
+			offset:      30,
+			expectedSQL: "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders LIMIT 5) LIMIT 10 OFFSET 30",
+		},
+		{
+			name:        "custom dialect uses its base",
+			dialect:     &ConfigDialect{name: "tidb", base: &MySQLDialect{}},
+			sql:         "SELECT * FROM users",
+			limit:       5,
+			offset:      5,
+			expectedSQL: "SELECT * FROM users LIMIT 5, 5",
+		},
+		{
+			name:        "trailing comment stays after the clause",
+			dialect:     &PostgreSQLDialect{},
+			sql:         "SELECT * FROM users ORDER BY id -- newest last",
+			limit:       10,
+			offset:      20,
+			expectedSQL: "SELECT * FROM users ORDER BY id LIMIT 10 OFFSET 20 -- newest last",
+		},
+		{
+			name:        "locking clause stays after the clause",
+			dialect:     &MySQLDialect{},
+			sql:         "SELECT * FROM jobs WHERE state = 'new' FOR UPDATE;",
+			limit:       10,
+			offset:      20,
+			expectedSQL: "SELECT * FROM jobs WHERE state = 'new' LIMIT 20, 10 FOR UPDATE;",
+		},
+		{
+			name:        "existing LIMIT is not duplicated",
+			dialect:     &PostgreSQLDialect{},
+			sql:         "SELECT * FROM users LIMIT 3",
+			limit:       10,
+			expectError: true,
+		},
+		{
+			name:        "existing FETCH is not duplicated",
+			dialect:     &PostgreSQLDialect{},
+			sql:         "SELECT * FROM users FETCH FIRST 3 ROWS ONLY",
+			limit:       10,
+			expectError: true,
+		},
+		{
+			name:        "non-SELECT statement",
+			dialect:     &MySQLDialect{},
+			sql:         "DELETE FROM users",
+			limit:       10,
+			expectError: true,
+		},
+		{
+			name:        "non-positive limit",
+			dialect:     &SQLiteDialect{},
+			sql:         "SELECT * FROM users",
+			limit:       0,
+			expectError: true,
+		},
+		{
+			name:        "negative offset",
+			dialect:     &SQLiteDialect{},
+			sql:         "SELECT * FROM users",
+			limit:       10,
+			offset:      -1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.dialect.ApplyPagination(tt.sql, tt.limit, tt.offset)
+
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidPagination) {
+					t.Errorf("Expected ErrInvalidPagination, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if result != tt.expectedSQL {
+				t.Errorf("Expected paginated SQL: %s, got: %s", tt.expectedSQL, result)
+			}
+		})
+	}
+}
+
+func TestSQLDialect_MalformedClauses(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		message string
+		line    int
+		column  int
+	}{
+		{name: "trailing comma before FROM", sql: "SELECT a, FROM t;", message: "Trailing comma before FROM", line: 1, column: 9},
+		{name: "trailing comma at end", sql: "SELECT a,\nb,", message: "Trailing comma before the end of the statement", line: 2, column: 2},
+		{name: "WHERE without condition", sql: "SELECT a FROM t\nWHERE;", message: "WHERE clause has no condition", line: 2, column: 1},
+		{name: "WHERE before ORDER BY", sql: "SELECT a FROM t WHERE ORDER BY a;", message: "WHERE clause has no condition", line: 1, column: 17},
+		{name: "dangling AND", sql: "SELECT a FROM t WHERE a = 1 AND;", message: "AND has no right-hand condition", line: 1, column: 29},
+	}
+
+	dialects := []SQLDialect{&MySQLDialect{}, &PostgreSQLDialect{}, &SQLiteDialect{}, &SnowflakeDialect{}}
+	for _, tt := range tests {
+		for _, dialect := range dialects {
+			t.Run(dialect.Name()+"_"+tt.name, func(t *testing.T) {
+				results, err := dialect.ValidateSQL(tt.sql)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				for _, result := range results {
+					if strings.HasPrefix(result.Message, tt.message) {
+						if result.Level != "error" || result.Line != tt.line || result.Column != tt.column {
+							t.Errorf("Expected error at %d:%d, got %s at %d:%d", tt.line, tt.column, result.Level, result.Line, result.Column)
+						}
+						return
+					}
+				}
+				t.Errorf("Expected %q in %v", tt.message, results)
+			})
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT a FROM t WHERE a BETWEEN 1 AND 2 ORDER BY a LIMIT 10 OFFSET 5;",
+		"INSERT INTO t (a, b) VALUES (1, 2);",
+		"SELECT a, COUNT(*) FROM t GROUP BY a HAVING COUNT(*) > 1;",
+		"SELECT 'a, FROM' FROM t;",
+	} {
+		results, err := (&PostgreSQLDialect{}).ValidateSQL(sql)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, result := range results {
+			if result.Level == "error" {
+				t.Errorf("Unexpected error for %q: %s", sql, result.Message)
+			}
+		}
+	}
+}
+
+func TestSQLDialect_SetOperations(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		sql     string
+		message string
+		line    int
+		column  int
+	}{
+		{name: "MySQL INTERSECT", dialect: &MySQLDialect{}, sql: "SELECT id FROM a\nINTERSECT SELECT id FROM b;", message: "INTERSECT requires MySQL 8.0.31 or later", line: 2, column: 1},
+		{name: "MySQL EXCEPT", dialect: &MySQLDialect{}, sql: "SELECT id FROM a EXCEPT SELECT id FROM b;", message: "EXCEPT requires MySQL 8.0.31 or later", line: 1, column: 18},
+		{name: "SQLite EXCEPT ALL", dialect: &SQLiteDialect{}, sql: "SELECT id FROM a EXCEPT ALL SELECT id FROM b;", message: "SQLite does not support EXCEPT ALL", line: 1, column: 18},
+		{name: "SQLite parenthesized member", dialect: &SQLiteDialect{}, sql: "(SELECT id FROM a) UNION (SELECT id FROM b);", message: "SQLite does not allow parentheses", line: 1, column: 20},
+		{name: "PostgreSQL MINUS", dialect: &PostgreSQLDialect{}, sql: "SELECT id FROM a MINUS SELECT id FROM b;", message: "PostgreSQL does not support MINUS", line: 1, column: 18},
+		{name: "Snowflake EXCEPT ALL", dialect: &SnowflakeDialect{}, sql: "SELECT id FROM a EXCEPT ALL SELECT id FROM b;", message: "Snowflake does not support EXCEPT ALL", line: 1, column: 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.dialect.ValidateSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, result := range results {
+				if strings.HasPrefix(result.Message, tt.message) {
+					if result.Level != "warning" || result.Line != tt.line || result.Column != tt.column {
+						t.Errorf("Expected warning at %d:%d, got %s at %d:%d", tt.line, tt.column, result.Level, result.Line, result.Column)
+					}
+					if result.Suggestion == "" {
+						t.Errorf("Expected a suggested alternative for %q", result.Message)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected %q in %v", tt.message, results)
+		})
+	}
+
+	clean := []struct {
+		dialect SQLDialect
+		sql     string
+	}{
+		{&SQLiteDialect{}, "SELECT id FROM a EXCEPT SELECT id FROM b;"},
+		{&SQLiteDialect{}, "SELECT id FROM a WHERE id IN (SELECT id FROM c) UNION SELECT id FROM b;"},
+		{&PostgreSQLDialect{}, "(SELECT id FROM a) INTERSECT ALL (SELECT id FROM b);"},
+		{&SnowflakeDialect{}, "SELECT id FROM a MINUS SELECT id FROM b;"},
+		{&MySQLDialect{}, "SELECT 'a INTERSECT b' FROM t UNION ALL SELECT name FROM u;"},
+	}
+	for _, tc := range clean {
+		results, err := tc.dialect.ValidateSQL(tc.sql)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, result := range results {
+			if strings.Contains(result.Message, "does not support") || strings.Contains(result.Message, "requires MySQL") || strings.Contains(result.Message, "parentheses") {
+				t.Errorf("Unexpected set operation warning for %s %q: %s", tc.dialect.Name(), tc.sql, result.Message)
+			}
+		}
+	}
+}
+
+func TestSQLDialect_JSONOperators(t *testing.T) {
+	clean := []struct {
+		dialect SQLDialect
+		sql     string
+	}{
+		{&PostgreSQLDialect{}, "SELECT data->>'name' FROM users;"},
+		{&PostgreSQLDialect{}, "SELECT id, data->'tags' FROM users WHERE data @> '{\"active\": true}' LIMIT 10;"},
+		{&PostgreSQLDialect{}, "SELECT data #>> '{address,city}' FROM users WHERE data ? 'email' AND data ?| array['a', 'b'];"},
+		{&MySQLDialect{}, "SELECT JSON_EXTRACT(data,'$.name') FROM users;"},
+		{&MySQLDialect{}, "SELECT id, data->>'$.limit' FROM users LIMIT 10;"},
+		{&MySQLDialect{}, "SELECT JSON_EXTRACT(data, '$.limit') FROM users WHERE id<@max_id;"},
+	}
+	for _, tc := range clean {
+		results, err := tc.dialect.ValidateSQL(tc.sql)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, result := range results {
+			if result.Level == "error" || result.Type == "naming" {
+				t.Errorf("Unexpected %s for %s %q: %s", result.Level, tc.dialect.Name(), tc.sql, result.Message)
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		sql     string
+		level   string
+		message string
+	}{
+		{name: "PostgreSQL filter on extracted text", dialect: &PostgreSQLDialect{}, sql: "SELECT id FROM users WHERE data->>'name' = 'alice';", level: "info", message: "cannot use a GIN index"},
+		{name: "PostgreSQL JSON_EXTRACT", dialect: &PostgreSQLDialect{}, sql: "SELECT JSON_EXTRACT(data, '$.name') FROM users;", level: "error", message: "PostgreSQL has no JSON_EXTRACT function"},
+		{name: "PostgreSQL LIMIT with comma", dialect: &PostgreSQLDialect{}, sql: "SELECT id FROM users LIMIT 10, 20;", level: "error", message: "PostgreSQL uses LIMIT x OFFSET y"},
+		{name: "MySQL filter on JSON_EXTRACT", dialect: &MySQLDialect{}, sql: "SELECT id FROM users WHERE JSON_EXTRACT(data, '$.name') = 'alice';", level: "info", message: "cannot use an index"},
+		{name: "MySQL containment operator", dialect: &MySQLDialect{}, sql: "SELECT id FROM users WHERE data @> '{\"a\": 1}';", level: "error", message: "MySQL does not support the @> JSON operator"},
+		{name: "MySQL path without $", dialect: &MySQLDialect{}, sql: "SELECT data->>'name' FROM users;", level: "error", message: "MySQL JSON paths must start with $"},
+		{name: "MySQL LIMIT without a count", dialect: &MySQLDialect{}, sql: "SELECT id FROM users LIMIT ALL;", level: "error", message: "Invalid LIMIT syntax for MySQL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.dialect.ValidateSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, result := range results {
+				if strings.Contains(result.Message, tt.message) {
+					if result.Level != tt.level {
+						t.Errorf("Expected %s level, got %s", tt.level, result.Level)
+					}
+					if result.Suggestion == "" {
+						t.Errorf("Expected a suggestion for %q", result.Message)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected %q in %v", tt.message, results)
+		})
+	}
+}
+
+func TestSQLDialect_MissingGroupByColumns(t *testing.T) {
+	clean := []string{
+		"SELECT dept, COUNT(*) FROM e GROUP BY dept;",
+		"SELECT e.dept, COUNT(*) AS total FROM e GROUP BY dept;",
+		"SELECT dept AS d, SUM(salary) FROM e GROUP BY d;",
+		"SELECT dept, COUNT(*) FROM e GROUP BY 1;",
+		"SELECT dept, region, COUNT(*) FROM e GROUP BY ROLLUP (dept, region);",
+		"SELECT DISTINCT dept, name FROM e;",
+		"SELECT dept, COUNT(*) OVER (PARTITION BY dept) FROM e;",
+		"SELECT COUNT(*), MAX(salary) FROM e;",
+		"SELECT UPPER(dept), COUNT(*) FROM e GROUP BY UPPER(dept);",
+		"SELECT name FROM e WHERE salary > (SELECT AVG(salary) FROM e);",
+	}
+	for _, dialect := range []SQLDialect{&PostgreSQLDialect{}, &MySQLDialect{}} {
+		for _, sql := range clean {
+			results, err := dialect.ValidateSQL(sql)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, result := range results {
+				if strings.Contains(result.Message, "GROUP BY") {
+					t.Errorf("Unexpected result for %s %q: %s", dialect.Name(), sql, result.Message)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name    string
+		dialect SQLDialect
+		sql     string
+		level   string
+		column  string
+	}{
+		{name: "PostgreSQL missing GROUP BY", dialect: &PostgreSQLDialect{}, sql: "SELECT dept, COUNT(*) FROM e;", level: "error", column: "dept"},
+		{name: "PostgreSQL column not grouped", dialect: &PostgreSQLDialect{}, sql: "SELECT dept, name, COUNT(*) FROM e GROUP BY dept;", level: "error", column: "name"},
+		{name: "MySQL missing GROUP BY", dialect: &MySQLDialect{}, sql: "SELECT dept, COUNT(*) FROM e;", level: "warning", column: "dept"},
+		{name: "subquery", dialect: &PostgreSQLDialect{}, sql: "SELECT * FROM (SELECT e.dept, AVG(salary) FROM e GROUP BY region) s;", level: "error", column: "e.dept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.dialect.ValidateSQL(tt.sql)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var flagged []ValidationResult
+			for _, result := range results {
+				if strings.Contains(result.Message, "GROUP BY") {
+					flagged = append(flagged, result)
+				}
+			}
+			if len(flagged) != 1 {
+				t.Fatalf("Expected one GROUP BY result, got %v", flagged)
+			}
+			if flagged[0].Level != tt.level {
+				t.Errorf("Expected %s level, got %s", tt.level, flagged[0].Level)
+			}
+			if !strings.Contains(flagged[0].Message, "'"+tt.column+"'") {
+				t.Errorf("Expected column %s in %q", tt.column, flagged[0].Message)
+			}
+			if flagged[0].Line != 1 || flagged[0].Column == 0 || flagged[0].Suggestion == "" {
+				t.Errorf("Expected a position and suggestion, got %+v", flagged[0])
+			}
+		})
+	}
+}
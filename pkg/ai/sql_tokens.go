@@ -0,0 +1,701 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/sqlutil"
+)
+
+// sqlTokenKind classifies tokens produced by tokenizeSQL
+type sqlTokenKind int
+
+const (
+	tokenWord sqlTokenKind = iota
+	tokenQuotedIdentifier
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+// sqlToken is a lexical token; Text is upper-cased for bare words and Pos is its rune offset
+type sqlToken struct {
+	Kind sqlTokenKind
+	Text string
+	Pos  int
+}
+
+// tokenizeSQL splits SQL into words, quoted identifiers, literals and punctuation.
+// Comments are dropped. It is a lexer only and does not validate syntax.
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(sql)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i += 2
+		case r == '\'':
+			end, _ := sqlutil.ScanQuoted(runes, i, '\'', false)
+			tokens = append(tokens, sqlToken{Kind: tokenString, Text: string(runes[i:end]), Pos: i})
+			i = end
+		case r == '"' || r == '`':
+			end, _ := sqlutil.ScanQuoted(runes, i, r, false)
+			tokens = append(tokens, sqlToken{Kind: tokenQuotedIdentifier, Text: string(runes[i:end]), Pos: i})
+			i = end
+		case r == '[':
+			end, _ := sqlutil.ScanQuoted(runes, i, ']', false)
+			tokens = append(tokens, sqlToken{Kind: tokenQuotedIdentifier, Text: string(runes[i:end]), Pos: i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{Kind: tokenWord, Text: strings.ToUpper(string(runes[start:i])), Pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{Kind: tokenNumber, Text: string(runes[start:i]), Pos: start})
+		default:
+			tokens = append(tokens, sqlToken{Kind: tokenPunct, Text: string(r), Pos: i})
+			i++
+		}
+	}
+	return tokens
+}
+
+// selectRowLimit reports whether tokens form a single SELECT statement and whether it
+// already limits its rows at the top level
+func selectRowLimit(tokens []sqlToken) (singleSelect, limited bool) {
+	if len(tokens) == 0 || tokens[0].Kind != tokenWord || (tokens[0].Text != "SELECT" && tokens[0].Text != "WITH") {
+		return false, false
+	}
+
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			depth--
+		case token.Text == ";" && i != len(tokens)-1:
+			return false, false
+		case depth == 0 && token.Kind == tokenWord && (token.Text == "LIMIT" || token.Text == "FETCH"):
+			limited = true
+		}
+	}
+	return true, limited
+}
+
+// limitArguments returns the tokens after each LIMIT keyword up to the end of its clause
+func limitArguments(tokens []sqlToken) [][]sqlToken {
+	var clauses [][]sqlToken
+	for i, token := range tokens {
+		if token.Kind != tokenWord || token.Text != "LIMIT" {
+			continue
+		}
+		end := i + 1
+		for end < len(tokens) && tokens[end].Text != ";" && tokens[end].Text != ")" &&
+			(tokens[end].Kind != tokenWord || tokens[end].Text == "ALL") {
+			end++
+		}
+		clauses = append(clauses, tokens[i+1:end])
+	}
+	return clauses
+}
+
+// splitTerminator trims trailing whitespace and returns sql without its final semicolon, if any
+func splitTerminator(sql string) (body, terminator string) {
+	body = strings.TrimRightFunc(sql, unicode.IsSpace)
+	if strings.HasSuffix(body, ";") {
+		return strings.TrimRightFunc(strings.TrimSuffix(body, ";"), unicode.IsSpace), ";"
+	}
+	return body, ""
+}
+
+// lockingClauses are the words that may follow FOR in a top-level row locking clause
+var lockingClauses = map[string]bool{"UPDATE": true, "SHARE": true, "NO": true, "KEY": true}
+
+// insertRowLimit adds clause to a single SELECT where a row limit belongs: before a top-level
+// locking clause (FOR UPDATE, FOR SHARE, LOCK IN SHARE MODE), otherwise after the last code
+// token, so trailing comments and the terminator stay after it
+func insertRowLimit(sql, clause string) string {
+	tokens := tokenizeSQL(sql)
+	runes := []rune(sql)
+
+	at, locking := -1, false
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			depth--
+		case depth == 0 && token.Kind == tokenWord && i+1 < len(tokens) && tokens[i+1].Kind == tokenWord &&
+			((token.Text == "FOR" && lockingClauses[tokens[i+1].Text]) || (token.Text == "LOCK" && tokens[i+1].Text == "IN")):
+			at, locking = token.Pos, true
+		}
+		if locking {
+			break
+		}
+		if token.Text != ";" {
+			// Upper-casing maps rune to rune, so the token text has the source's rune count
+			at = token.Pos + len([]rune(token.Text))
+		}
+	}
+	if at < 0 {
+		return sql
+	}
+
+	before := strings.TrimRightFunc(string(runes[:at]), unicode.IsSpace)
+	after := strings.TrimRightFunc(string(runes[at:]), unicode.IsSpace)
+	if locking {
+		return before + " " + clause + " " + after
+	}
+	if trimmed := strings.TrimLeftFunc(after, unicode.IsSpace); strings.HasPrefix(trimmed, ";") {
+		// The terminator follows the clause directly
+		after = trimmed
+	}
+	return before + " " + clause + after
+}
+
+// appendPagination inserts clause into a single unlimited SELECT after validating limit and offset
+func appendPagination(sql string, limit, offset int, clause string) (string, error) {
+	if limit <= 0 {
+		return "", fmt.Errorf("%w: limit must be positive, got %d", ErrInvalidPagination, limit)
+	}
+	if offset < 0 {
+		return "", fmt.Errorf("%w: offset cannot be negative, got %d", ErrInvalidPagination, offset)
+	}
+
+	singleSelect, limited := selectRowLimit(tokenizeSQL(sql))
+	if !singleSelect {
+		return "", fmt.Errorf("%w: only a single SELECT statement can be paginated", ErrInvalidPagination)
+	}
+	if limited {
+		return "", fmt.Errorf("%w: query already has a LIMIT or FETCH clause", ErrInvalidPagination)
+	}
+
+	return insertRowLimit(sql, clause), nil
+}
+
+// limitOffsetClause renders the LIMIT ... OFFSET ... form, omitting a zero offset
+func limitOffsetClause(limit, offset int) string {
+	if offset == 0 {
+		return fmt.Sprintf("LIMIT %d", limit)
+	}
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+// commentPlaceholder stands in for a comment while code is transformed; NUL survives case changes and regexes
+func commentPlaceholder(index int) string {
+	return fmt.Sprintf("\x00%d\x00", index)
+}
+
+// transformOutsideComments applies transform to the code of sql only, restoring
+// line and block comments verbatim afterwards. Quoted text is not scanned for comments.
+func transformOutsideComments(sql string, transform func(string) (string, error)) (string, error) {
+	var (
+		code     strings.Builder
+		comments []string
+	)
+	runes := []rune(sql)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			end, _ := sqlutil.ScanQuoted(runes, i, r, false)
+			code.WriteString(string(runes[i:end]))
+			i = end
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			start := i
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			code.WriteString(commentPlaceholder(len(comments)))
+			comments = append(comments, string(runes[start:i]))
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := i
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i = min(i+2, len(runes))
+			code.WriteString(commentPlaceholder(len(comments)))
+			comments = append(comments, string(runes[start:i]))
+		default:
+			code.WriteRune(r)
+			i++
+		}
+	}
+
+	if len(comments) == 0 {
+		return transform(sql)
+	}
+
+	transformed, err := transform(code.String())
+	if err != nil {
+		return sql, err
+	}
+	for index, comment := range comments {
+		transformed = strings.Replace(transformed, commentPlaceholder(index), comment, 1)
+	}
+	return transformed, nil
+}
+
+// identifierContexts are tokens after which a bare word names a table, column or alias
+var identifierContexts = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+	"WHERE": true, "AND": true, "OR": true, "ON": true, "BY": true, "SET": true, "AS": true, ",": true,
+}
+
+// expressionKeywords may legitimately follow an identifier context as part of the grammar
+var expressionKeywords = map[string]bool{
+	"DISTINCT": true, "ALL": true, "CASE": true, "NOT": true, "NULL": true, "EXISTS": true,
+	"PRIMARY": true, "FOREIGN": true, "UNIQUE": true, "SELECT": true,
+}
+
+// reservedIdentifierResults flags bare identifiers that collide with the dialect's reserved words
+func reservedIdentifierResults(sql string, dialect SQLDialect, quote string) []ValidationResult {
+	reserved := make(map[string]bool)
+	for _, keyword := range dialect.GetKeywords() {
+		reserved[strings.ToUpper(keyword)] = true
+	}
+
+	var results []ValidationResult
+	reported := make(map[string]bool)
+	tokens := tokenizeSQL(sql)
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind != tokenWord || !reserved[token.Text] || reported[token.Text] {
+			continue
+		}
+		previous := tokens[i-1].Text
+		if !identifierContexts[previous] || expressionKeywords[token.Text] {
+			continue
+		}
+		// ON UPDATE / ON DELETE are referential actions, not identifiers
+		if previous == "ON" && (token.Text == "UPDATE" || token.Text == "DELETE") {
+			continue
+		}
+
+		reported[token.Text] = true
+		results = append(results, ValidationResult{
+			Type:       "naming",
+			Level:      "warning",
+			Message:    fmt.Sprintf("'%s' is a reserved keyword in %s and is used as an identifier", strings.ToLower(token.Text), dialect.Name()),
+			Suggestion: fmt.Sprintf("Quote the identifier: %s%s%s", quote, strings.ToLower(token.Text), quote),
+		})
+	}
+	return results
+}
+
+// clauseKeywords start a new clause, so a comma or an incomplete clause must not directly precede them
+var clauseKeywords = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true, "LIMIT": true,
+	"OFFSET": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+}
+
+// danglingKeywords must be followed by an expression, table or column list
+var danglingKeywords = map[string]string{
+	"FROM": "FROM requires a table", "WHERE": "WHERE clause has no condition",
+	"HAVING": "HAVING clause has no condition", "ON": "ON clause has no join condition",
+	"AND": "AND has no right-hand condition", "OR": "OR has no right-hand condition",
+	"BY": "BY has no column list", "SET": "SET has no assignments",
+	"LIMIT": "LIMIT has no row count", "OFFSET": "OFFSET has no row count",
+}
+
+// malformedClauseResults flags generation glitches that no dialect accepts: a comma directly
+// before a clause keyword, a closing parenthesis or the end of a statement, and clause keywords
+// with nothing after them
+func malformedClauseResults(sql string) []ValidationResult {
+	runes := []rune(sql)
+	tokens := tokenizeSQL(sql)
+	// ends reports whether the token at index i ends the current clause or statement
+	ends := func(i int) bool {
+		if i >= len(tokens) {
+			return true
+		}
+		token := tokens[i]
+		return token.Text == ";" || token.Text == ")" || (token.Kind == tokenWord && clauseKeywords[token.Text])
+	}
+
+	var results []ValidationResult
+	for i, token := range tokens {
+		switch {
+		case token.Kind == tokenPunct && token.Text == "," && ends(i+1):
+			next := "the end of the statement"
+			if i+1 < len(tokens) && tokens[i+1].Text != ";" {
+				next = tokens[i+1].Text
+			}
+			line, column := runePosition(runes, token.Pos)
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    fmt.Sprintf("Trailing comma before %s at line %d, column %d", next, line, column),
+				Line:       line,
+				Column:     column,
+				Suggestion: "Remove the trailing comma or add the missing item",
+			})
+		case token.Kind == tokenWord && danglingKeywords[token.Text] != "" && ends(i+1):
+			line, column := runePosition(runes, token.Pos)
+			results = append(results, ValidationResult{
+				Type:       "syntax",
+				Level:      "error",
+				Message:    fmt.Sprintf("%s at line %d, column %d", danglingKeywords[token.Text], line, column),
+				Line:       line,
+				Column:     column,
+				Suggestion: fmt.Sprintf("Complete or remove the dangling %s", token.Text),
+			})
+		}
+	}
+	return results
+}
+
+// runePosition converts a rune offset in runes into a 1-based line and column
+func runePosition(runes []rune, offset int) (line, column int) {
+	line, column = 1, 1
+	for _, r := range runes[:min(offset, len(runes))] {
+		if r == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
+	}
+	return line, column
+}
+
+// unboundedWriteStatement returns the first UPDATE or DELETE statement kind in sql that has
+// no top-level WHERE clause, including data-modifying CTE bodies checked at their own depth.
+// WHERE clauses inside subqueries do not count.
+func unboundedWriteStatement(sql string) (string, bool) {
+	for _, statement := range splitStatements(tokenizeSQL(sql)) {
+		for _, clause := range statementClauses(statement) {
+			if len(clause) == 0 || clause[0].Kind != tokenWord {
+				continue
+			}
+			kind := clause[0].Text
+			if kind != "UPDATE" && kind != "DELETE" {
+				continue
+			}
+
+			depth := 0
+			hasWhere := false
+			for _, token := range clause {
+				switch {
+				case token.Text == "(":
+					depth++
+				case token.Text == ")":
+					depth--
+				case depth == 0 && token.Kind == tokenWord && token.Text == "WHERE":
+					hasWhere = true
+				}
+			}
+			if !hasWhere {
+				return kind, true
+			}
+		}
+	}
+	return "", false
+}
+
+// dataModifyingKeywords mark a WITH statement whose CTEs write data
+var dataModifyingKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true,
+}
+
+// explainableKeywords start the statement explained by an EXPLAIN statement
+var explainableKeywords = map[string]bool{
+	"SELECT": true, "WITH": true, "VALUES": true, "TABLE": true,
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true,
+}
+
+// splitStatements splits tokens at semicolons, dropping empty statements
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	for start := 0; start < len(tokens); {
+		end := start
+		for end < len(tokens) && tokens[end].Text != ";" {
+			end++
+		}
+		if end > start {
+			statements = append(statements, tokens[start:end])
+		}
+		start = end + 1
+	}
+	return statements
+}
+
+// skipOpenParens drops the opening parentheses of a parenthesized query such as (SELECT 1) UNION (SELECT 2)
+func skipOpenParens(statement []sqlToken) []sqlToken {
+	for len(statement) > 0 && statement[0].Text == "(" {
+		statement = statement[1:]
+	}
+	return statement
+}
+
+// withParts splits a WITH statement into the bodies of its common table expressions, the tokens
+// inside the parentheses after AS, and the statement that follows them.
+func withParts(statement []sqlToken) (bodies [][]sqlToken, main []sqlToken) {
+	depth := 0
+	bodyStart, bodyEnd := -1, -1
+	for i := 1; i < len(statement); i++ {
+		token := statement[i]
+		if depth == 0 && bodyEnd == i-1 && token.Text != "," {
+			return bodies, statement[i:]
+		}
+		switch token.Text {
+		case "(":
+			if previous := statement[i-1].Text; depth == 0 && (previous == "AS" || previous == "MATERIALIZED") {
+				bodyStart = i + 1
+			}
+			depth++
+		case ")":
+			depth--
+			if depth == 0 && bodyStart >= 0 {
+				bodies = append(bodies, statement[bodyStart:i])
+				bodyStart, bodyEnd = -1, i
+			}
+		}
+	}
+	return bodies, nil
+}
+
+// statementClauses returns the CTE bodies of a WITH statement followed by the statement after
+// them, or just the statement otherwise, each without leading parentheses
+func statementClauses(statement []sqlToken) [][]sqlToken {
+	statement = skipOpenParens(statement)
+	if len(statement) == 0 || statement[0].Kind != tokenWord || statement[0].Text != "WITH" {
+		return [][]sqlToken{statement}
+	}
+	bodies, main := withParts(statement)
+	var clauses [][]sqlToken
+	for _, body := range bodies {
+		clauses = append(clauses, statementClauses(body)...)
+	}
+	return append(clauses, statementClauses(main)...)
+}
+
+// statementKind returns the type of a clause from statementClauses: its leading keyword when that
+// is one of config.StatementTypes, with DESC counted as DESCRIBE, and UNKNOWN otherwise
+func statementKind(clause []sqlToken) string {
+	if len(clause) == 0 || clause[0].Kind != tokenWord {
+		return unknownStatementType
+	}
+	kind := clause[0].Text
+	if kind == "DESC" {
+		kind = "DESCRIBE"
+	}
+	if !slices.Contains(config.StatementTypes, kind) {
+		return unknownStatementType
+	}
+	return kind
+}
+
+// unknownStatementType is the type of statements without a recognized leading keyword
+const unknownStatementType = "UNKNOWN"
+
+// explainedStatement returns the statement explained by an EXPLAIN statement, skipping options
+// such as ANALYZE, QUERY PLAN or a parenthesized option list
+func explainedStatement(statement []sqlToken) []sqlToken {
+	depth := 0
+	for i := 1; i < len(statement); i++ {
+		switch token := statement[i]; {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			depth--
+		case depth == 0 && token.Kind == tokenWord && explainableKeywords[token.Text]:
+			return statement[i:]
+		}
+	}
+	return statement[1:]
+}
+
+// nonReadStatement returns the leading keyword of the first statement that is not a plain query.
+// SELECT passes, and so do EXPLAIN of a query and WITH unless one of its CTE bodies starts with
+// a data-modifying keyword.
+func nonReadStatement(sql string) (string, bool) {
+	for _, statement := range splitStatements(tokenizeSQL(sql)) {
+		if kind, ok := nonReadKind(statement); ok {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// nonReadKind returns the leading keyword of statement when it is not a plain query
+func nonReadKind(statement []sqlToken) (string, bool) {
+	statement = skipOpenParens(statement)
+	if len(statement) == 0 {
+		return "", false
+	}
+	if statement[0].Kind != tokenWord {
+		return statement[0].Text, true
+	}
+	switch kind := statement[0].Text; kind {
+	case "SELECT":
+		return "", false
+	case "WITH":
+		bodies, main := withParts(statement)
+		for _, body := range bodies {
+			if kind, ok := nonReadKind(body); ok && dataModifyingKeywords[kind] {
+				return kind, true
+			}
+		}
+		return nonReadKind(main)
+	case "EXPLAIN":
+		return nonReadKind(explainedStatement(statement))
+	default:
+		return kind, true
+	}
+}
+
+// tableContexts are keywords followed by a table reference
+var tableContexts = map[string]bool{"FROM": true, "JOIN": true}
+
+// identifierName returns the lower-cased name of a bare word or quoted identifier token
+func identifierName(token sqlToken) string {
+	text := token.Text
+	if token.Kind == tokenQuotedIdentifier && len(text) >= 2 {
+		text = text[1 : len(text)-1]
+	}
+	return strings.ToLower(text)
+}
+
+// ambiguousColumnResults flags unqualified columns that exist in more than one table joined by the
+// same statement, using the provided schema to know each table's columns. Columns joined with
+// USING (...) are merged by the database and never reported.
+func ambiguousColumnResults(sql string, schema map[string]Table) []ValidationResult {
+	if len(schema) < 2 {
+		return nil
+	}
+	columnsByTable := make(map[string]map[string]bool, len(schema))
+	for key, table := range schema {
+		name := table.Name
+		if name == "" {
+			name = key
+		}
+		columns := make(map[string]bool, len(table.Columns))
+		for _, column := range table.Columns {
+			columns[strings.ToLower(column.Name)] = true
+		}
+		columnsByTable[strings.ToLower(name)] = columns
+	}
+
+	var results []ValidationResult
+	reported := make(map[string]bool)
+	tokens := tokenizeSQL(sql)
+	for start := 0; start < len(tokens); {
+		end := start
+		for end < len(tokens) && tokens[end].Text != ";" {
+			end++
+		}
+		statement := tokens[start:end]
+		start = end + 1
+
+		// Tables referenced by the statement; a schema-qualified name counts by its last part
+		var joined []string
+		seen := make(map[string]bool)
+		for i := 1; i < len(statement); i++ {
+			if !tableContexts[statement[i-1].Text] || (statement[i].Kind != tokenWord && statement[i].Kind != tokenQuotedIdentifier) {
+				continue
+			}
+			j := i
+			for j+2 < len(statement) && statement[j+1].Text == "." {
+				j += 2
+			}
+			name := identifierName(statement[j])
+			if _, ok := columnsByTable[name]; ok && !seen[name] {
+				seen[name] = true
+				joined = append(joined, name)
+			}
+		}
+		if len(joined) < 2 {
+			continue
+		}
+
+		// Columns named in JOIN ... USING (...) are merged into a single output column
+		merged := make(map[string]bool)
+		for i := 1; i+1 < len(statement); i++ {
+			if statement[i-1].Text != "USING" || statement[i].Text != "(" {
+				continue
+			}
+			for j := i + 1; j < len(statement) && statement[j].Text != ")"; j++ {
+				if statement[j].Kind == tokenWord || statement[j].Kind == tokenQuotedIdentifier {
+					merged[identifierName(statement[j])] = true
+				}
+			}
+		}
+
+		for i, token := range statement {
+			if token.Kind != tokenWord && token.Kind != tokenQuotedIdentifier {
+				continue
+			}
+			if i > 0 && (statement[i-1].Text == "." || statement[i-1].Text == "AS" || tableContexts[statement[i-1].Text]) {
+				continue
+			}
+			if i+1 < len(statement) && (statement[i+1].Text == "." || statement[i+1].Text == "(") {
+				continue
+			}
+
+			column := identifierName(token)
+			if reported[column] || merged[column] {
+				continue
+			}
+			var owners []string
+			for _, table := range joined {
+				if columnsByTable[table][column] {
+					owners = append(owners, table)
+				}
+			}
+			if len(owners) < 2 {
+				continue
+			}
+
+			reported[column] = true
+			results = append(results, ValidationResult{
+				Type:       "semantic",
+				Level:      "warning",
+				Message:    fmt.Sprintf("column '%s' is ambiguous: it exists in %s", column, strings.Join(owners, ", ")),
+				Suggestion: fmt.Sprintf("Qualify the column with its table, e.g. %s.%s", owners[0], column),
+			})
+		}
+	}
+	return results
+}
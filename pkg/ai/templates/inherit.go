@@ -0,0 +1,141 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PromptTemplateName is the base prompt template used when no template targets a dialect
+const PromptTemplateName = "sql_generation"
+
+// Prompt sections the SQL generator understands; other section names are ignored
+const (
+	// SectionSystem replaces the opening instruction of the generation prompt
+	SectionSystem = "system"
+	// SectionInstructions adds dialect guidance after the database description
+	SectionInstructions = "instructions"
+	// SectionSafety replaces the safety requirements written in safety mode
+	SectionSafety = "safety"
+)
+
+// Section returns the trimmed text of a named section, or "" when it is not defined.
+func (t *Template) Section(name string) string {
+	if t == nil {
+		return ""
+	}
+	return strings.TrimSpace(t.Sections[name])
+}
+
+// Resolve returns the template registered under name with its base chain merged in.
+// A child inherits the description, prompt and every section it does not override.
+func (r *Registry) Resolve(name string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolve(name, make(map[string]bool))
+}
+
+// Validate checks that every base template exists and that no inheritance chain loops.
+func (r *Registry) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := r.resolve(name, make(map[string]bool)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PromptFor returns the resolved prompt template for a dialect, falling back to the
+// PromptTemplateName base. ok is false when neither is registered or resolvable.
+func (r *Registry) PromptFor(dialect string) (*Template, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Several templates may claim a dialect; pick by name so the choice is stable
+	name := ""
+	for candidate, tmpl := range r.templates {
+		if dialect != "" && strings.EqualFold(tmpl.Dialect, dialect) && (name == "" || candidate < name) {
+			name = candidate
+		}
+	}
+	if name == "" {
+		if _, ok := r.templates[PromptTemplateName]; !ok {
+			return nil, false
+		}
+		name = PromptTemplateName
+	}
+
+	resolved, err := r.resolve(name, make(map[string]bool))
+	if err != nil {
+		return nil, false
+	}
+	return resolved, true
+}
+
+// resolve merges name with its bases; callers must hold r.mu
+func (r *Registry) resolve(name string, visiting map[string]bool) (*Template, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	resolved := *tmpl
+	resolved.Sections = make(map[string]string, len(tmpl.Sections))
+	if tmpl.Extends == "" {
+		for key, value := range tmpl.Sections {
+			resolved.Sections[key] = value
+		}
+		return &resolved, nil
+	}
+
+	if visiting[name] {
+		return nil, fmt.Errorf("%w: inheritance cycle through %s", ErrInvalidTemplate, name)
+	}
+	visiting[name] = true
+
+	base, err := r.resolve(tmpl.Extends, visiting)
+	if err != nil {
+		return nil, fmt.Errorf("%s extends %s: %w", name, tmpl.Extends, err)
+	}
+	for key, value := range base.Sections {
+		resolved.Sections[key] = value
+	}
+	for key, value := range tmpl.Sections {
+		resolved.Sections[key] = value
+	}
+	if resolved.Description == "" {
+		resolved.Description = base.Description
+	}
+	if strings.TrimSpace(resolved.Prompt) == "" {
+		resolved.Prompt = base.Prompt
+	}
+	return &resolved, nil
+}
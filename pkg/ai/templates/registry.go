@@ -0,0 +1,182 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package templates provides reusable natural language query templates with named placeholders.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a template name is not registered
+	ErrTemplateNotFound = errors.New("template not found")
+
+	// ErrMissingParameters is returned when a template is rendered without all placeholders
+	ErrMissingParameters = errors.New("missing template parameters")
+
+	// ErrInvalidTemplate is returned when a template definition is malformed
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// placeholderPattern matches {{name}} slots, allowing surrounding whitespace
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Template is a named natural language query shape with {{placeholder}} slots.
+//
+// Prompt templates instead carry named Sections that shape the generation prompt for a Dialect,
+// and may extend a base template whose prompt and sections they inherit unless overridden.
+type Template struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description,omitempty"`
+	Prompt      string            `yaml:"prompt" json:"prompt"`
+	Extends     string            `yaml:"extends" json:"extends,omitempty"`
+	Dialect     string            `yaml:"dialect" json:"dialect,omitempty"`
+	Sections    map[string]string `yaml:"sections" json:"sections,omitempty"`
+}
+
+// Placeholders returns the unique placeholder names in order of first appearance.
+func (t *Template) Placeholders() []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, match := range placeholderPattern.FindAllStringSubmatch(t.Prompt, -1) {
+		if _, ok := seen[match[1]]; ok {
+			continue
+		}
+		seen[match[1]] = struct{}{}
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Render substitutes every placeholder with its parameter value.
+// All placeholders must be provided; extra parameters are ignored.
+func (t *Template) Render(params map[string]string) (string, error) {
+	var missing []string
+	for _, name := range t.Placeholders() {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w for %s: %s", ErrMissingParameters, t.Name, strings.Join(missing, ", "))
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(t.Prompt, func(slot string) string {
+		return params[placeholderPattern.FindStringSubmatch(slot)[1]]
+	}), nil
+}
+
+// Registry holds templates by name and is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	templates map[string]*Template
+}
+
+// NewRegistry creates an empty template registry.
+func NewRegistry() *Registry {
+	return &Registry{templates: make(map[string]*Template)}
+}
+
+// LoadDir creates a registry from every .yaml/.yml file in dir.
+// A file's base name is used when the template does not declare a name.
+func LoadDir(dir string) (*Registry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory %s: %w", dir, err)
+	}
+
+	registry := NewRegistry()
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+
+		var tmpl Template
+		if err := yaml.Unmarshal(data, &tmpl); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+		}
+		if tmpl.Name == "" {
+			tmpl.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+
+		if err := registry.Register(&tmpl); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := registry.Validate(); err != nil {
+		return nil, fmt.Errorf("templates directory %s: %w", dir, err)
+	}
+	return registry, nil
+}
+
+// Register adds or replaces a template.
+func (r *Registry) Register(tmpl *Template) error {
+	if tmpl == nil || strings.TrimSpace(tmpl.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	// Templates that extend a base or only define sections may leave the prompt empty
+	if strings.TrimSpace(tmpl.Prompt) == "" && tmpl.Extends == "" && len(tmpl.Sections) == 0 {
+		return fmt.Errorf("%w: prompt is required for %s", ErrInvalidTemplate, tmpl.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[tmpl.Name] = tmpl
+	return nil
+}
+
+// Get returns the template registered under name.
+func (r *Registry) Get(name string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return tmpl, nil
+}
+
+// List returns all templates sorted by name.
+func (r *Registry) List() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Template, 0, len(r.templates))
+	for _, tmpl := range r.templates {
+		list = append(list, tmpl)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := &Template{Name: "top_customers", Prompt: "top {{n}} customers by {{ metric }} in {{n}} regions"}
+
+	assert.Equal(t, []string{"n", "metric"}, tmpl.Placeholders())
+
+	rendered, err := tmpl.Render(map[string]string{"n": "10", "metric": "revenue", "unused": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "top 10 customers by revenue in 10 regions", rendered)
+
+	_, err = tmpl.Render(map[string]string{"n": "10"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrMissingParameters))
+	assert.Contains(t, err.Error(), "metric")
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "top_customers.yaml"),
+		[]byte("description: Top customers\nprompt: \"top {{n}} customers by {{metric}}\"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "named.yml"),
+		[]byte("name: recent_orders\nprompt: orders from the last {{days}} days\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o600))
+
+	registry, err := LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, registry.List(), 2)
+
+	tmpl, err := registry.Get("top_customers")
+	require.NoError(t, err)
+	assert.Equal(t, "Top customers", tmpl.Description)
+
+	_, err = registry.Get("recent_orders")
+	require.NoError(t, err)
+
+	_, err = registry.Get("missing")
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+}
+
+func TestLoadDirResolvesInheritance(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sql_generation.yaml"), []byte(`description: Base prompt
+sections:
+  system: Write one SQL query.
+  instructions: Prefer explicit column lists.
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "postgresql.yaml"), []byte(`extends: sql_generation
+dialect: postgresql
+sections:
+  system: Write one PostgreSQL query using ILIKE for case-insensitive matches.
+`), 0o600))
+
+	registry, err := LoadDir(dir)
+	require.NoError(t, err)
+
+	postgres, ok := registry.PromptFor("PostgreSQL")
+	require.True(t, ok)
+	assert.Equal(t, "postgresql", postgres.Name)
+	assert.Equal(t, "Base prompt", postgres.Description)
+	assert.Equal(t, "Write one PostgreSQL query using ILIKE for case-insensitive matches.", postgres.Section(SectionSystem))
+	assert.Equal(t, "Prefer explicit column lists.", postgres.Section(SectionInstructions), "unset sections come from the base")
+
+	mysql, ok := registry.PromptFor("mysql")
+	require.True(t, ok, "dialects without a child use the base")
+	assert.Equal(t, "Write one SQL query.", mysql.Section(SectionSystem))
+
+	base, err := registry.Get(PromptTemplateName)
+	require.NoError(t, err)
+	assert.Equal(t, "Write one SQL query.", base.Sections[SectionSystem], "resolving never mutates the base")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orphan.yaml"), []byte("extends: missing\n"), 0o600))
+	_, err = LoadDir(dir)
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+	assert.Contains(t, err.Error(), "orphan extends missing")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orphan.yaml"), []byte("extends: loop\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "loop.yaml"), []byte("extends: orphan\n"), 0o600))
+	_, err = LoadDir(dir)
+	assert.True(t, errors.Is(err, ErrInvalidTemplate))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
+	"github.com/linuxsuren/atest-ext-ai/pkg/sqlutil"
+)
+
+var (
+	// commaLimitPattern matches MySQL's LIMIT offset, count form
+	commaLimitPattern = regexp.MustCompile(`(?i)\bLIMIT\s+(\d+)\s*,\s*(\d+)`)
+	// offsetLimitPattern matches the LIMIT count OFFSET offset form
+	offsetLimitPattern = regexp.MustCompile(`(?i)\bLIMIT\s+(\d+)\s+OFFSET\s+(\d+)`)
+)
+
+// TransformSQLWithFallback transforms sql from source into target. When source has no
+// dedicated transform for a built-in target, only identifier quoting and LIMIT syntax are
+// converted and a warning marks the result as best-effort.
+func TransformSQLWithFallback(source SQLDialect, sql, target string) (string, []string, error) {
+	target = providers.Normalize(target)
+	transformed, err := source.TransformSQL(sql, target)
+	if err == nil {
+		return transformed, nil, nil
+	}
+	if !errors.Is(err, ErrUnsupportedTransform) || !isSupportedDialect(target) {
+		return sql, nil, err
+	}
+
+	sourceQuote, _ := sqlutil.IdentifierQuote(source.Name())
+	transformed, err = transformOutsideComments(sql, func(code string) (string, error) {
+		return bestEffortTransform(code, sourceQuote, target)
+	})
+	if err != nil {
+		return sql, nil, err
+	}
+	warning := fmt.Sprintf("no dedicated %s to %s transform; only identifier quoting and LIMIT syntax were converted (best-effort)",
+		source.Name(), target)
+	return transformed, []string{warning}, nil
+}
+
+// isSupportedDialect reports whether name is a canonical built-in dialect
+func isSupportedDialect(name string) bool {
+	for _, dialect := range supportedDialects {
+		if dialect == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bestEffortTransform re-quotes identifiers for target and rewrites LIMIT into target's form.
+// A zero sourceQuote treats both double quotes and backticks as identifier quotes.
+func bestEffortTransform(sql string, sourceQuote rune, target string) (string, error) {
+	var b strings.Builder
+	runes := []rune(sql)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			end, _ := sqlutil.ScanQuoted(runes, i, r, false)
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case r == '"' || r == '`':
+			end, _ := sqlutil.ScanQuoted(runes, i, r, false)
+			if sourceQuote != 0 && r != sourceQuote {
+				b.WriteString(string(runes[i:end]))
+				i = end
+				continue
+			}
+			quoted := string(runes[i:end])
+			name := strings.TrimSuffix(strings.TrimPrefix(quoted, string(r)), string(r))
+			name = strings.ReplaceAll(name, string(r)+string(r), string(r))
+			requoted, err := sqlutil.QuoteIdentifier(name, target)
+			if err != nil {
+				return sql, err
+			}
+			b.WriteString(requoted)
+			i = end
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+
+	if target == "mysql" {
+		return offsetLimitPattern.ReplaceAllString(b.String(), "LIMIT $2, $1"), nil
+	}
+	return commaLimitPattern.ReplaceAllString(b.String(), "LIMIT $2 OFFSET $1"), nil
+}
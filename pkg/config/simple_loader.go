@@ -54,10 +54,47 @@ func LoadConfig() (*Config, error) {
 	return cfg, nil
 }
 
-// loadConfigFile tries to find and load a config file from standard locations
-func loadConfigFile() (*Config, error) {
-	// Search paths in priority order
-	searchPaths := []string{
+// ReloadConfig loads the configuration of a running process again. Unlike LoadConfig it never
+// falls back to defaults: a missing, unparsable or invalid file is an error, so the caller can
+// keep its current configuration. path is the file loaded at startup; when empty, the first
+// existing file in the standard locations is used.
+func ReloadConfig(path string) (*Config, error) {
+	if path == "" {
+		path = findConfigFile()
+		if path == "" {
+			return nil, fmt.Errorf("no config file found (tried %d paths)", len(configSearchPaths()))
+		}
+	}
+
+	cfg, err := loadYAMLFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+	cfg.SourcePath = path
+
+	applyEnvOverrides(cfg)
+	applyDefaults(cfg)
+	if err := validateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadFile loads a single config file and applies defaults without validating it
+// or reading environment overrides, so the result reflects the file alone.
+func LoadFile(path string) (*Config, error) {
+	cfg, err := loadYAMLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SourcePath = path
+	applyDefaults(cfg)
+	return cfg, nil
+}
+
+// configSearchPaths returns the standard config file locations in priority order
+func configSearchPaths() []string {
+	return []string{
 		"config.yaml",
 		"config.yml",
 		"./config.yaml",
@@ -65,6 +102,21 @@ func loadConfigFile() (*Config, error) {
 		filepath.Join(os.Getenv("HOME"), ".config", "atest", "config.yaml"),
 		"/etc/atest/config.yaml",
 	}
+}
+
+// findConfigFile returns the first standard location that exists, or "" when there is none
+func findConfigFile() string {
+	for _, path := range configSearchPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfigFile tries to find and load a config file from standard locations
+func loadConfigFile() (*Config, error) {
+	searchPaths := configSearchPaths()
 
 	var lastErr error
 	var attemptedPaths []string
@@ -74,6 +126,7 @@ func loadConfigFile() (*Config, error) {
 		cfg, err := loadYAMLFile(path)
 		if err == nil {
 			fmt.Fprintf(os.Stderr, "Configuration loaded from: %s\n", path)
+			cfg.SourcePath = path
 			return cfg, nil
 		}
 		lastErr = err
@@ -154,6 +207,14 @@ func applyEnvOverrides(cfg *Config) {
 		}
 	}
 
+	if readOnly := os.Getenv("ATEST_EXT_AI_READ_ONLY"); readOnly != "" {
+		cfg.AI.ReadOnly = strings.ToLower(readOnly) == "true"
+	}
+
+	if templatesDir := os.Getenv("ATEST_EXT_AI_TEMPLATES_DIR"); templatesDir != "" {
+		cfg.AI.TemplatesDir = templatesDir
+	}
+
 	// Initialize services map if nil
 	if cfg.AI.Services == nil {
 		cfg.AI.Services = make(map[string]AIService)
@@ -268,6 +329,9 @@ func applyDefaults(cfg *Config) {
 	if cfg.Server.MaxConns == 0 {
 		cfg.Server.MaxConns = constants.ServerDefaults.MaxConnections
 	}
+	if cfg.Server.ShedRetryAfter.Duration == 0 {
+		cfg.Server.ShedRetryAfter = Duration{Duration: constants.ServerDefaults.ShedRetryAfter}
+	}
 
 	// Plugin defaults
 	if cfg.Plugin.Name == "" {
@@ -346,6 +410,54 @@ func applyDefaults(cfg *Config) {
 		cfg.AI.RateLimit.WindowSize = Duration{Duration: constants.RateLimit.WindowSize}
 	}
 
+	// Cache defaults
+	if cfg.AI.Cache.TTL.Duration == 0 {
+		cfg.AI.Cache.TTL = Duration{Duration: constants.Cache.TTL}
+	}
+	if cfg.AI.Cache.MaxEntries == 0 {
+		cfg.AI.Cache.MaxEntries = constants.Cache.MaxEntries
+	}
+
+	// Example memory defaults
+	if cfg.AI.ExampleMemory.TTL.Duration == 0 {
+		cfg.AI.ExampleMemory.TTL = Duration{Duration: constants.ExampleMemory.TTL}
+	}
+	if cfg.AI.ExampleMemory.MaxEntries == 0 {
+		cfg.AI.ExampleMemory.MaxEntries = constants.ExampleMemory.MaxEntries
+	}
+	if cfg.AI.ExampleMemory.MaxExamples == 0 {
+		cfg.AI.ExampleMemory.MaxExamples = constants.ExampleMemory.MaxExamples
+	}
+	if cfg.AI.ExampleMemory.MinConfidence == 0 {
+		cfg.AI.ExampleMemory.MinConfidence = constants.ExampleMemory.MinConfidence
+	}
+
+	// EXPLAIN validation defaults
+	if cfg.AI.DBValidation.Timeout.Duration == 0 {
+		cfg.AI.DBValidation.Timeout = Duration{Duration: constants.DBValidation.Timeout}
+	}
+
+	// Debug logging defaults
+	if cfg.AI.Debug.MaxResponseLogLength == 0 {
+		cfg.AI.Debug.MaxResponseLogLength = constants.Debug.MaxResponseLogLength
+	}
+
+	// Input limit defaults
+	if cfg.AI.Limits.MaxPromptBytes == 0 {
+		cfg.AI.Limits.MaxPromptBytes = constants.InputLimits.MaxPromptBytes
+	}
+	if cfg.AI.Limits.MaxContextBytes == 0 {
+		cfg.AI.Limits.MaxContextBytes = constants.InputLimits.MaxContextBytes
+	}
+
+	// Startup defaults
+	if cfg.AI.Startup.Timeout.Duration == 0 {
+		cfg.AI.Startup.Timeout = Duration{Duration: constants.Startup.WaitTimeout}
+	}
+	if cfg.AI.Startup.PollInterval.Duration == 0 {
+		cfg.AI.Startup.PollInterval = Duration{Duration: constants.Startup.PollInterval}
+	}
+
 	// Database defaults
 	if cfg.Database.Driver == "" {
 		cfg.Database.Driver = constants.DefaultDatabaseDriver
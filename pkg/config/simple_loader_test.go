@@ -46,6 +46,9 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.AI.DefaultService != "ollama" {
 		t.Errorf("Expected default service 'ollama', got '%s'", cfg.AI.DefaultService)
 	}
+	if cfg.Server.MaxConcurrentGenerations != 0 {
+		t.Errorf("Expected concurrent generations to be unlimited by default, got %d", cfg.Server.MaxConcurrentGenerations)
+	}
 }
 
 func TestLoadConfigFromYAML(t *testing.T) {
@@ -146,6 +149,67 @@ func TestLoadConfigWithEnvOverrides(t *testing.T) {
 	}
 }
 
+func TestReloadConfigIsStrict(t *testing.T) {
+	_ = os.Setenv("ATEST_EXT_AI_LOG_LEVEL", "debug")
+	defer func() { _ = os.Unsetenv("ATEST_EXT_AI_LOG_LEVEL") }()
+
+	tempDir := t.TempDir()
+	switchToDir(t, tempDir)
+
+	if _, err := ReloadConfig(""); err == nil {
+		t.Error("Expected an error when no config file exists")
+	}
+
+	path := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(path, []byte("ai: [not a mapping"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := ReloadConfig(path); err == nil {
+		t.Error("Expected a parse error instead of default configuration")
+	}
+
+	valid := `
+ai:
+  default_service: ollama
+  services:
+    ollama:
+      enabled: true
+      provider: ollama
+      endpoint: http://localhost:11434
+      model: llama3
+`
+	if err := os.WriteFile(path, []byte(valid), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := ReloadConfig("")
+	if err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+	if cfg.SourcePath != "config.yaml" {
+		t.Errorf("Expected source path 'config.yaml', got '%s'", cfg.SourcePath)
+	}
+	if cfg.Plugin.LogLevel != "debug" {
+		t.Errorf("Expected log level from env 'debug', got '%s'", cfg.Plugin.LogLevel)
+	}
+
+	invalid := `
+ai:
+  default_service: missing
+  services:
+    ollama:
+      enabled: true
+      provider: ollama
+      endpoint: http://localhost:11434
+      model: llama3
+`
+	if err := os.WriteFile(path, []byte(invalid), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := ReloadConfig(path); err == nil {
+		t.Error("Expected a validation error for an unknown default service")
+	}
+}
+
 func TestApplyDefaults(t *testing.T) {
 	cfg := &Config{}
 	applyDefaults(cfg)
@@ -25,6 +25,8 @@ type Config struct {
 	AI       AIConfig       `yaml:"ai" json:"ai" validate:"required"`
 	Database DatabaseConfig `yaml:"database" json:"database"`
 	Logging  LoggingConfig  `yaml:"logging" json:"logging"`
+	// SourcePath is the file the configuration was loaded from, empty when defaults were used
+	SourcePath string `yaml:"-" json:"-"`
 }
 
 // ServerConfig contains server-specific configuration
@@ -37,6 +39,10 @@ type ServerConfig struct {
 	ListenAddress string   `yaml:"listen_address" json:"listen_address"`
 	ReadTimeout   Duration `yaml:"read_timeout" json:"read_timeout"`
 	WriteTimeout  Duration `yaml:"write_timeout" json:"write_timeout"`
+	// MaxConcurrentGenerations sheds generations beyond this many with ResourceExhausted; zero, the default, means unlimited
+	MaxConcurrentGenerations int `yaml:"max_concurrent_generations" json:"max_concurrent_generations"`
+	// ShedRetryAfter is the backoff suggested to shed clients in the retry-after trailer
+	ShedRetryAfter Duration `yaml:"shed_retry_after" json:"shed_retry_after"`
 }
 
 // PluginConfig contains plugin-specific configuration
@@ -50,12 +56,68 @@ type PluginConfig struct {
 
 // AIConfig contains AI service configuration
 type AIConfig struct {
-	DefaultService string               `yaml:"default_service" json:"default_service"`
-	Services       map[string]AIService `yaml:"services" json:"services"`
-	Fallback       []string             `yaml:"fallback_order" json:"fallback_order"`
-	Timeout        Duration             `yaml:"timeout" json:"timeout"`
-	RateLimit      RateLimitConfig      `yaml:"rate_limit" json:"rate_limit"`
-	Retry          RetryConfig          `yaml:"retry" json:"retry"`
+	DefaultService string                   `yaml:"default_service" json:"default_service"`
+	Services       map[string]AIService     `yaml:"services" json:"services"`
+	Fallback       []string                 `yaml:"fallback_order" json:"fallback_order"`
+	Timeout        Duration                 `yaml:"timeout" json:"timeout"` // Default generation deadline; service and request timeouts take precedence
+	RateLimit      RateLimitConfig          `yaml:"rate_limit" json:"rate_limit"`
+	Retry          RetryConfig              `yaml:"retry" json:"retry"`
+	Models         map[string]ModelOverride `yaml:"models" json:"models,omitempty"`
+	TemplatesDir   string                   `yaml:"templates_dir" json:"templates_dir,omitempty"`
+	// LatencyThreshold demotes clients whose average generation latency exceeds it (0 disables)
+	LatencyThreshold Duration          `yaml:"latency_threshold" json:"latency_threshold,omitempty"`
+	Cache            CacheConfig       `yaml:"cache" json:"cache"`
+	Startup          StartupConfig     `yaml:"startup" json:"startup"`
+	Audit            AuditConfig       `yaml:"audit" json:"audit"`
+	Storage          StorageConfig     `yaml:"storage" json:"storage"`
+	ABTest           ABTestConfig      `yaml:"ab_test" json:"ab_test"`
+	Limits           InputLimits       `yaml:"limits" json:"limits"`
+	PII              PIIConfig         `yaml:"pii" json:"pii"`
+	PromptCache      PromptCacheConfig `yaml:"prompt_cache" json:"prompt_cache"`
+	// PostProcess lists processors applied in order to every generated statement
+	PostProcess []PostProcessorConfig `yaml:"post_process" json:"post_process,omitempty"`
+	// SQLPrefix and SQLSuffix wrap every generated statement, e.g. "SET statement_timeout = 5000;"
+	// or a trailing "-- analytics" comment; they are added after the other post-processors
+	SQLPrefix string `yaml:"sql_prefix" json:"sql_prefix,omitempty"`
+	SQLSuffix string `yaml:"sql_suffix" json:"sql_suffix,omitempty"`
+	// ValidateSQLAffixes also validates and safety-checks the prefix and suffix; by default only
+	// the generated statement is checked
+	ValidateSQLAffixes bool `yaml:"validate_sql_affixes" json:"validate_sql_affixes,omitempty"`
+	// ReadOnly forces SafetyMode and blocks every generated statement other than a query
+	ReadOnly bool `yaml:"read_only" json:"read_only"`
+	// MaxSQLBytes rejects generated statements larger than this many bytes (0 disables the cap)
+	MaxSQLBytes int `yaml:"max_sql_bytes" json:"max_sql_bytes,omitempty"`
+	// AllowedStatementTypes blocks generated statements of any other type, such as SELECT or INSERT (empty allows all);
+	// see StatementTypes for the accepted names
+	AllowedStatementTypes []string `yaml:"allowed_statement_types" json:"allowed_statement_types,omitempty"`
+	// DeniedStatementTypes blocks generated statements of these types, even when they are allowed. Denying INSERT,
+	// UPDATE or DELETE also blocks MERGE, REPLACE and upserts that perform that action.
+	DeniedStatementTypes []string `yaml:"denied_statement_types" json:"denied_statement_types,omitempty"`
+	// ModelAliases maps shorthand names such as "claude" to concrete model ids
+	ModelAliases map[string]string `yaml:"model_aliases" json:"model_aliases,omitempty"`
+	// CustomDialects registers additional dialects by name on top of a built-in base dialect
+	CustomDialects map[string]CustomDialectConfig `yaml:"custom_dialects" json:"custom_dialects,omitempty"`
+	// ExampleMemory reuses recent high-confidence generations as few-shot examples
+	ExampleMemory ExampleMemoryConfig `yaml:"example_memory" json:"example_memory"`
+	// DBValidation allows generated queries to be checked with EXPLAIN against read-only databases
+	DBValidation DBValidationConfig `yaml:"db_validation" json:"db_validation"`
+	// Health controls how provider health checks probe each service
+	Health HealthConfig `yaml:"health" json:"health"`
+	// Debug controls extra diagnostics logged at debug level
+	Debug DebugConfig `yaml:"debug" json:"debug"`
+	// Confidence tunes the weights of the generated statement confidence score
+	Confidence ConfidenceConfig `yaml:"confidence" json:"confidence"`
+	// Sessions bounds the schema sessions opened for interactive use
+	Sessions SessionConfig `yaml:"sessions" json:"sessions"`
+}
+
+// ModelOverride replaces auto-detected capability values for a specific model.
+// Zero values leave the detected value untouched.
+type ModelOverride struct {
+	MaxTokens       int     `yaml:"max_tokens" json:"max_tokens,omitempty"`
+	ContextSize     int     `yaml:"context_size" json:"context_size,omitempty"`
+	InputCostPer1K  float64 `yaml:"input_cost_per_1k" json:"input_cost_per_1k,omitempty"`
+	OutputCostPer1K float64 `yaml:"output_cost_per_1k" json:"output_cost_per_1k,omitempty"`
 }
 
 // AIService represents configuration for a specific AI service
@@ -71,6 +133,17 @@ type AIService struct {
 	Models    []string          `yaml:"models" json:"models"`
 	Priority  int               `yaml:"priority" json:"priority"`
 	Timeout   Duration          `yaml:"timeout" json:"timeout"`
+	// ModelRefreshInterval polls the Ollama model list so an unloaded model is replaced (0 disables)
+	ModelRefreshInterval Duration `yaml:"model_refresh_interval" json:"model_refresh_interval,omitempty"`
+	// ResponseStyle names the parser tried first: "markdown", "prefixed", "plain", "simple" or "json" (empty is best-effort)
+	ResponseStyle string `yaml:"response_style" json:"response_style,omitempty"`
+	// Stop sequences end generation early; unset uses the builtin markers and an empty list disables them
+	Stop []string `yaml:"stop" json:"stop,omitempty"`
+	// HealthPath replaces the provider's health check endpoint, e.g. a cheap /healthz on a gateway
+	HealthPath string `yaml:"health_path" json:"health_path,omitempty"`
+	// CacheControl sends the system prompt as a content block with an ephemeral cache_control
+	// marker when ai.prompt_cache is enabled; only set it for gateways that accept that format
+	CacheControl bool `yaml:"cache_control" json:"cache_control,omitempty"`
 
 	// Deprecated fields (kept for backward compatibility warning)
 	Temperature float32 `yaml:"temperature" json:"temperature,omitempty"`
@@ -103,6 +176,159 @@ type RetryConfig struct {
 	Jitter       bool     `yaml:"jitter" json:"jitter"`
 }
 
+// StartupConfig controls whether startup waits for AI providers to report healthy.
+// Startup proceeds once the timeout elapses, so a slow provider never blocks the plugin.
+type StartupConfig struct {
+	WaitForProviders bool     `yaml:"wait_for_providers" json:"wait_for_providers"`
+	Timeout          Duration `yaml:"timeout" json:"timeout"`
+	PollInterval     Duration `yaml:"poll_interval" json:"poll_interval"`
+}
+
+// InputLimits caps the size of generation input accepted from callers.
+// MaxContextBytes covers the runtime config payload, which carries schemas and other context.
+type InputLimits struct {
+	MaxPromptBytes  int `yaml:"max_prompt_bytes" json:"max_prompt_bytes"`
+	MaxContextBytes int `yaml:"max_context_bytes" json:"max_context_bytes"`
+}
+
+// ABTestConfig routes each request to one of the weighted providers at random.
+// Weights are relative; providers with a zero weight are never picked.
+type ABTestConfig struct {
+	Enabled bool           `yaml:"enabled" json:"enabled"`
+	Weights map[string]int `yaml:"weights" json:"weights,omitempty"`
+}
+
+// PostProcessorConfig selects a built-in post-processor: append_limit, format or uppercase_keywords.
+type PostProcessorConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Limit is the row cap for append_limit (defaults to 1000)
+	Limit int `yaml:"limit" json:"limit,omitempty"`
+}
+
+// CustomDialectConfig describes a dialect that inherits validation and transforms from Base
+// (mysql, postgresql or sqlite) and adds its own reserved keywords, data types and functions.
+type CustomDialectConfig struct {
+	Base      string   `yaml:"base" json:"base"`
+	Keywords  []string `yaml:"keywords" json:"keywords,omitempty"`
+	DataTypes []string `yaml:"data_types" json:"data_types,omitempty"`
+	Functions []string `yaml:"functions" json:"functions,omitempty"`
+}
+
+// PromptCacheConfig moves the schema into the system prompt and marks that stable prefix
+// as cacheable for providers that support prompt caching. OpenAI gets a prompt_cache_key;
+// other services only get a cache_control marker when their cache_control flag is set.
+type PromptCacheConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// PIIConfig controls masking of personal data echoed in generated explanations.
+// The generated SQL is never modified.
+type PIIConfig struct {
+	Mask bool `yaml:"mask" json:"mask"`
+	// Rules replaces the built-in email, phone and national_id rules when set
+	Rules []PIIRule `yaml:"rules" json:"rules,omitempty"`
+}
+
+// PIIRule is a named detection pattern; an empty pattern selects the built-in rule of that name
+type PIIRule struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern,omitempty"`
+}
+
+// AuditConfig controls the audit log record emitted for each generated statement.
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Anonymize replaces SQL literals with placeholders before they reach the logs
+	Anonymize bool `yaml:"anonymize" json:"anonymize"`
+}
+
+// StorageConfig selects where cached generation results and audit records are kept.
+// Backend is "memory" (the default) or "file", which persists data under Path across restarts.
+// There is no Redis backend yet; "redis" is rejected like any other unknown backend.
+// Audit records are only written to the backend when Backend is set explicitly.
+type StorageConfig struct {
+	Backend string `yaml:"backend" json:"backend,omitempty"`
+	Path    string `yaml:"path" json:"path,omitempty"`
+}
+
+// CacheConfig controls caching of generation results.
+//
+// Normalize lowercases the prompt, collapses whitespace and strips trailing
+// punctuation before building the cache key, so "Show users." and "show  users"
+// share an entry. The raw prompt is still sent to the model. The trade-off is that
+// prompts differing only in case or punctuation (e.g. quoted string literals) are
+// served the same cached SQL.
+type CacheConfig struct {
+	Enabled    bool     `yaml:"enabled" json:"enabled"`
+	TTL        Duration `yaml:"ttl" json:"ttl"`
+	MaxEntries int      `yaml:"max_entries" json:"max_entries"`
+	Normalize  bool     `yaml:"normalize" json:"normalize"`
+}
+
+// ExampleMemoryConfig controls the store of past generations injected as few-shot examples.
+//
+// Only results at or above MinConfidence are stored, and an example is only offered
+// for a later prompt against the same schema that shares keywords with it.
+type ExampleMemoryConfig struct {
+	Enabled       bool     `yaml:"enabled" json:"enabled"`
+	TTL           Duration `yaml:"ttl" json:"ttl"`
+	MaxEntries    int      `yaml:"max_entries" json:"max_entries"`
+	MaxExamples   int      `yaml:"max_examples" json:"max_examples"`
+	MinConfidence float64  `yaml:"min_confidence" json:"min_confidence"`
+}
+
+// SessionConfig controls schema sessions.
+//
+// A session keeps a prepared schema so repeated generations against it skip preparing it
+// again. It expires after TTL without use; MaxSessions caps how many may be open at once.
+type SessionConfig struct {
+	TTL         Duration `yaml:"ttl" json:"ttl"`
+	MaxSessions int      `yaml:"max_sessions" json:"max_sessions"`
+}
+
+// DBValidationConfig controls EXPLAIN-based validation of generated queries.
+//
+// GenerateOptions.ValidateAgainstDSN must name one of AllowedDSNs. Only single SELECT
+// statements are explained, inside a read-only transaction, and they are never executed.
+type DBValidationConfig struct {
+	AllowedDSNs []string `yaml:"allowed_dsns" json:"allowed_dsns,omitempty"`
+	Timeout     Duration `yaml:"timeout" json:"timeout"`
+	// Drivers overrides the database/sql driver name used for a database type
+	Drivers map[string]string `yaml:"drivers" json:"drivers,omitempty"`
+}
+
+// HealthConfig controls provider health checks.
+//
+// The default light check only lists models. Deep mode also issues a 1-token generation so
+// keys without generation permission or quota are reported unhealthy, at the cost of a request.
+// CacheTTL is how long a provider's result is reused by Manager.HealthCheckAll before it probes again.
+type HealthConfig struct {
+	Deep     bool     `yaml:"deep" json:"deep"`
+	CacheTTL Duration `yaml:"cache_ttl" json:"cache_ttl"`
+}
+
+// DebugConfig controls diagnostics for provider responses.
+// LogFullResponse logs the raw response (with secrets redacted) instead of a short preview,
+// truncated to MaxResponseLogLength characters. IncludeReasoning adds a reasoning model's
+// chain of thought to the result's debug info, truncated the same way.
+type DebugConfig struct {
+	LogFullResponse      bool `yaml:"log_full_response" json:"log_full_response"`
+	MaxResponseLogLength int  `yaml:"max_response_log_length" json:"max_response_log_length,omitempty"`
+	IncludeReasoning     bool `yaml:"include_reasoning" json:"include_reasoning"`
+}
+
+// ConfidenceConfig weighs the signals combined into GenerationResult.ConfidenceScore.
+// Unset weights use the built-in values; an explicit 0 turns a signal off.
+type ConfidenceConfig struct {
+	Base              *float64 `yaml:"base" json:"base,omitempty"`
+	ErrorPenalty      *float64 `yaml:"error_penalty" json:"error_penalty,omitempty"`
+	WarningPenalty    *float64 `yaml:"warning_penalty" json:"warning_penalty,omitempty"`
+	GroundedBonus     *float64 `yaml:"grounded_bonus" json:"grounded_bonus,omitempty"`
+	UngroundedPenalty *float64 `yaml:"ungrounded_penalty" json:"ungrounded_penalty,omitempty"`
+	ComplexityPenalty *float64 `yaml:"complexity_penalty" json:"complexity_penalty,omitempty"`
+	TruncatedPenalty  *float64 `yaml:"truncated_penalty" json:"truncated_penalty,omitempty"`
+}
+
 // DatabaseConfig contains database configuration (optional)
 type DatabaseConfig struct {
 	Enabled     bool     `yaml:"enabled" json:"enabled"`
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"regexp"
 	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 )
 
 // ValidationSeverity indicates whether an issue is an error or warning.
@@ -17,6 +21,14 @@ const (
 	SeverityWarning ValidationSeverity = "warning"
 )
 
+// StatementTypes are the names accepted by ai.allowed_statement_types and ai.denied_statement_types.
+// A statement's type is its leading keyword; statements starting with any other keyword are UNKNOWN.
+var StatementTypes = []string{
+	"SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT",
+	"CREATE", "DROP", "ALTER", "TRUNCATE", "RENAME", "GRANT", "REVOKE",
+	"EXPLAIN", "SHOW", "DESCRIBE", "VALUES", "CALL", "SET", "USE", "BEGIN", "COMMIT", "ROLLBACK", "UNKNOWN",
+}
+
 // ValidationIssue describes a single validation finding.
 type ValidationIssue struct {
 	Field    string
@@ -114,6 +126,14 @@ func (cfg *Config) validateServer(result *ValidationResult) {
 	if cfg.Server.MaxConns < 1 {
 		result.AddError("server.max_connections", "max_connections must be greater than zero", cfg.Server.MaxConns)
 	}
+
+	if cfg.Server.MaxConcurrentGenerations < 0 {
+		result.AddError("server.max_concurrent_generations", "max_concurrent_generations cannot be negative", cfg.Server.MaxConcurrentGenerations)
+	}
+
+	if cfg.Server.ShedRetryAfter.Duration < 0 {
+		result.AddError("server.shed_retry_after", "shed_retry_after cannot be negative", cfg.Server.ShedRetryAfter)
+	}
 }
 
 func (cfg *Config) validateAI(result *ValidationResult) {
@@ -124,6 +144,185 @@ func (cfg *Config) validateAI(result *ValidationResult) {
 	if len(cfg.AI.Services) == 0 {
 		result.AddError("ai.services", "at least one AI service must be configured", nil)
 	}
+
+	if cfg.AI.LatencyThreshold.Duration < 0 {
+		result.AddError("ai.latency_threshold", "latency_threshold cannot be negative", cfg.AI.LatencyThreshold)
+	}
+
+	if cfg.AI.Cache.Enabled {
+		if cfg.AI.Cache.TTL.Duration < 0 {
+			result.AddError("ai.cache.ttl", "ttl cannot be negative", cfg.AI.Cache.TTL)
+		}
+		if cfg.AI.Cache.MaxEntries < 0 {
+			result.AddError("ai.cache.max_entries", "max_entries cannot be negative", cfg.AI.Cache.MaxEntries)
+		}
+	}
+
+	switch storage := cfg.AI.Storage; strings.ToLower(strings.TrimSpace(storage.Backend)) {
+	case "", "memory":
+	case "file":
+		if strings.TrimSpace(storage.Path) == "" {
+			result.AddError("ai.storage.path", "path is required for the file backend", storage.Path)
+		}
+	case "redis":
+		result.AddError("ai.storage.backend", "the redis backend is not implemented yet; use memory or file", storage.Backend)
+	default:
+		result.AddError("ai.storage.backend", "backend must be one of memory, file", storage.Backend)
+	}
+
+	if cfg.AI.ExampleMemory.Enabled {
+		memory := cfg.AI.ExampleMemory
+		if memory.TTL.Duration < 0 {
+			result.AddError("ai.example_memory.ttl", "ttl cannot be negative", memory.TTL)
+		}
+		if memory.MaxEntries < 0 {
+			result.AddError("ai.example_memory.max_entries", "max_entries cannot be negative", memory.MaxEntries)
+		}
+		if memory.MaxExamples < 0 {
+			result.AddError("ai.example_memory.max_examples", "max_examples cannot be negative", memory.MaxExamples)
+		}
+		if memory.MinConfidence < 0 || memory.MinConfidence > 1 {
+			result.AddError("ai.example_memory.min_confidence", "min_confidence must be between 0 and 1", memory.MinConfidence)
+		}
+	}
+
+	if cfg.AI.DBValidation.Timeout.Duration < 0 {
+		result.AddError("ai.db_validation.timeout", "timeout cannot be negative", cfg.AI.DBValidation.Timeout)
+	}
+	for i, dsn := range cfg.AI.DBValidation.AllowedDSNs {
+		if strings.TrimSpace(dsn) == "" {
+			result.AddError(fmt.Sprintf("ai.db_validation.allowed_dsns[%d]", i), "dsn cannot be empty", dsn)
+		}
+	}
+
+	confidence := map[string]*float64{
+		"base":               cfg.AI.Confidence.Base,
+		"error_penalty":      cfg.AI.Confidence.ErrorPenalty,
+		"warning_penalty":    cfg.AI.Confidence.WarningPenalty,
+		"grounded_bonus":     cfg.AI.Confidence.GroundedBonus,
+		"ungrounded_penalty": cfg.AI.Confidence.UngroundedPenalty,
+		"complexity_penalty": cfg.AI.Confidence.ComplexityPenalty,
+		"truncated_penalty":  cfg.AI.Confidence.TruncatedPenalty,
+	}
+	for field, weight := range confidence {
+		if weight != nil && (*weight < 0 || *weight > 1) {
+			result.AddError("ai.confidence."+field, "weight must be between 0 and 1", *weight)
+		}
+	}
+
+	if cfg.AI.MaxSQLBytes < 0 {
+		result.AddError("ai.max_sql_bytes", "max_sql_bytes cannot be negative", cfg.AI.MaxSQLBytes)
+	}
+
+	for field, configured := range map[string][]string{
+		"ai.allowed_statement_types": cfg.AI.AllowedStatementTypes,
+		"ai.denied_statement_types":  cfg.AI.DeniedStatementTypes,
+	} {
+		for _, statementType := range configured {
+			if !containsFold(StatementTypes, strings.TrimSpace(statementType)) {
+				result.AddError(field, fmt.Sprintf("statement type must be one of %s", strings.Join(StatementTypes, ", ")), statementType)
+			}
+		}
+	}
+
+	if cfg.AI.Debug.MaxResponseLogLength < 0 {
+		result.AddError("ai.debug.max_response_log_length", "max_response_log_length cannot be negative", cfg.AI.Debug.MaxResponseLogLength)
+	}
+
+	if cfg.AI.ABTest.Enabled {
+		totalWeight := 0
+		for name, weight := range cfg.AI.ABTest.Weights {
+			if weight < 0 {
+				result.AddError("ai.ab_test.weights."+name, "weight cannot be negative", weight)
+				continue
+			}
+			totalWeight += weight
+			if _, ok := cfg.AI.Services[name]; !ok {
+				result.AddWarning("ai.ab_test.weights."+name, "weighted provider is not a configured service", name)
+			}
+		}
+		if totalWeight == 0 {
+			result.AddError("ai.ab_test.weights", "at least one provider needs a positive weight when A/B testing is enabled", nil)
+		}
+	}
+
+	if cfg.AI.PII.Mask {
+		for i, rule := range cfg.AI.PII.Rules {
+			field := fmt.Sprintf("ai.pii.rules[%d]", i)
+			if rule.Name == "" {
+				result.AddError(field+".name", "rule name is required", rule)
+				continue
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				result.AddError(field+".pattern", fmt.Sprintf("invalid pattern: %v", err), rule.Pattern)
+			}
+		}
+	}
+
+	for i, processor := range cfg.AI.PostProcess {
+		field := fmt.Sprintf("ai.post_process[%d]", i)
+		if processor.Name == "" {
+			result.AddError(field+".name", "post-processor name is required", processor)
+		}
+		if processor.Limit < 0 {
+			result.AddError(field+".limit", "limit cannot be negative", processor.Limit)
+		}
+	}
+
+	for alias, model := range cfg.AI.ModelAliases {
+		if strings.TrimSpace(alias) == "" || strings.TrimSpace(model) == "" {
+			result.AddError("ai.model_aliases", "aliases and their models must not be empty", map[string]string{alias: model})
+		}
+	}
+
+	for name, dialect := range cfg.AI.CustomDialects {
+		field := "ai.custom_dialects." + name
+		if strings.TrimSpace(name) == "" {
+			result.AddError("ai.custom_dialects", "dialect name must not be empty", dialect)
+		}
+		if strings.TrimSpace(dialect.Base) == "" {
+			result.AddError(field+".base", "base dialect is required", dialect)
+		}
+	}
+
+	if cfg.AI.Limits.MaxPromptBytes < 0 {
+		result.AddError("ai.limits.max_prompt_bytes", "max_prompt_bytes cannot be negative", cfg.AI.Limits.MaxPromptBytes)
+	}
+	if cfg.AI.Limits.MaxContextBytes < 0 {
+		result.AddError("ai.limits.max_context_bytes", "max_context_bytes cannot be negative", cfg.AI.Limits.MaxContextBytes)
+	}
+
+	if cfg.AI.Health.CacheTTL.Duration < 0 {
+		result.AddError("ai.health.cache_ttl", "cache_ttl cannot be negative", cfg.AI.Health.CacheTTL)
+	}
+	if cfg.AI.Startup.Timeout.Duration < 0 {
+		result.AddError("ai.startup.timeout", "timeout cannot be negative", cfg.AI.Startup.Timeout)
+	}
+	if cfg.AI.Startup.PollInterval.Duration < 0 {
+		result.AddError("ai.startup.poll_interval", "poll_interval cannot be negative", cfg.AI.Startup.PollInterval)
+	}
+
+	if cfg.AI.TemplatesDir != "" {
+		if info, err := os.Stat(cfg.AI.TemplatesDir); err != nil || !info.IsDir() {
+			result.AddWarning("ai.templates_dir", "templates directory does not exist; query templates are disabled", cfg.AI.TemplatesDir)
+		}
+	}
+
+	for id, override := range cfg.AI.Models {
+		fieldPrefix := fmt.Sprintf("ai.models.%s", id)
+		if override.MaxTokens < 0 {
+			result.AddError(fieldPrefix+".max_tokens", "max_tokens cannot be negative", override.MaxTokens)
+		}
+		if override.ContextSize < 0 {
+			result.AddError(fieldPrefix+".context_size", "context_size cannot be negative", override.ContextSize)
+		}
+		if override.InputCostPer1K < 0 || override.OutputCostPer1K < 0 {
+			result.AddError(fieldPrefix, "costs cannot be negative", nil)
+		}
+	}
 }
 
 func (cfg *Config) validateRateLimit(result *ValidationResult) {
@@ -198,6 +397,7 @@ func (cfg *Config) validateProviders(result *ValidationResult) {
 	}
 
 	knownProviders := []string{"ollama", "openai", "claude", "deepseek", "custom"}
+	validResponseStyles := []string{"markdown", "prefixed", "plain", "simple", "json"}
 	providerRules := map[string]struct {
 		requireAPIKey   bool
 		requireEndpoint bool
@@ -215,7 +415,7 @@ func (cfg *Config) validateProviders(result *ValidationResult) {
 		}
 
 		fieldPrefix := fmt.Sprintf("ai.services.%s", name)
-		provider := normalizeProviderName(svc.Provider)
+		provider := providers.Normalize(svc.Provider)
 		if provider == "" {
 			result.AddError(fieldPrefix+".provider", "provider must be specified", svc.Provider)
 			continue
@@ -252,6 +452,27 @@ func (cfg *Config) validateProviders(result *ValidationResult) {
 		if provider == "ollama" && strings.TrimSpace(svc.Model) == "" {
 			result.AddWarning(fieldPrefix+".model", "model not specified for ollama provider", nil)
 		}
+
+		if svc.ModelRefreshInterval.Duration < 0 {
+			result.AddError(fieldPrefix+".model_refresh_interval", "model_refresh_interval cannot be negative", svc.ModelRefreshInterval)
+		}
+
+		for i, stop := range svc.Stop {
+			if stop == "" {
+				result.AddError(fmt.Sprintf("%s.stop[%d]", fieldPrefix, i), "stop sequence cannot be empty", stop)
+			}
+		}
+		if len(svc.Stop) > 4 {
+			result.AddWarning(fieldPrefix+".stop", "OpenAI-compatible providers accept at most 4 stop sequences", len(svc.Stop))
+		}
+
+		if svc.HealthPath != "" && !strings.HasPrefix(svc.HealthPath, "/") {
+			result.AddError(fieldPrefix+".health_path", "health_path must start with /", svc.HealthPath)
+		}
+
+		if svc.ResponseStyle != "" && !containsFold(validResponseStyles, svc.ResponseStyle) {
+			result.AddError(fieldPrefix+".response_style", fmt.Sprintf("response_style must be one of %s", strings.Join(validResponseStyles, ", ")), svc.ResponseStyle)
+		}
 	}
 }
 
@@ -296,14 +517,6 @@ func (cfg *Config) validateLogging(result *ValidationResult) {
 	}
 }
 
-func normalizeProviderName(provider string) string {
-	p := strings.ToLower(strings.TrimSpace(provider))
-	if p == "local" {
-		return "ollama"
-	}
-	return p
-}
-
 func containsFold(haystack []string, needle string) bool {
 	for _, item := range haystack {
 		if strings.EqualFold(item, needle) {
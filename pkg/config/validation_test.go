@@ -57,6 +57,39 @@ func TestValidate_OllamaRequiresEndpoint(t *testing.T) {
 	}
 }
 
+func TestValidate_ResponseStyleMustBeKnown(t *testing.T) {
+	cfg := defaultConfig()
+	ollama := cfg.AI.Services["ollama"]
+	ollama.ResponseStyle = "Markdown"
+	cfg.AI.Services["ollama"] = ollama
+
+	if result := cfg.Validate(); hasErrorFor(result, "ai.services.ollama.response_style") {
+		t.Fatalf("expected response styles to match case-insensitively, got %v", result.Errors)
+	}
+
+	ollama.ResponseStyle = "xml"
+	cfg.AI.Services["ollama"] = ollama
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.services.ollama.response_style") {
+		t.Fatalf("expected response_style error for unknown style")
+	}
+}
+
+func TestValidate_HealthPathMustBeAbsolute(t *testing.T) {
+	cfg := defaultConfig()
+	ollama := cfg.AI.Services["ollama"]
+	ollama.HealthPath = "/healthz"
+	cfg.AI.Services["ollama"] = ollama
+	if result := cfg.Validate(); hasErrorFor(result, "ai.services.ollama.health_path") {
+		t.Fatalf("unexpected health_path error: %v", result.Errors)
+	}
+
+	ollama.HealthPath = "healthz"
+	cfg.AI.Services["ollama"] = ollama
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.services.ollama.health_path") {
+		t.Fatalf("expected health_path error for a relative path")
+	}
+}
+
 func TestValidate_FallbackMustExist(t *testing.T) {
 	cfg := defaultConfig()
 	cfg.AI.Fallback = []string{"missing-service"}
@@ -82,6 +115,48 @@ func TestValidate_DatabaseDriverRequiredWhenEnabled(t *testing.T) {
 	}
 }
 
+func TestValidate_StatementTypesMustBeKnown(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AI.AllowedStatementTypes = []string{"select", "INSERT"}
+	cfg.AI.DeniedStatementTypes = []string{"DELETE"}
+	if result := cfg.Validate(); hasErrorFor(result, "ai.allowed_statement_types") || hasErrorFor(result, "ai.denied_statement_types") {
+		t.Fatalf("unexpected statement type errors: %v", result.Errors)
+	}
+
+	cfg.AI.AllowedStatementTypes = []string{"SELECT", "explain", "Show", "MERGE"}
+	if result := cfg.Validate(); hasErrorFor(result, "ai.allowed_statement_types") {
+		t.Fatalf("unexpected statement type errors: %v", result.Errors)
+	}
+
+	cfg.AI.DeniedStatementTypes = []string{"DESTROY"}
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.denied_statement_types") {
+		t.Fatalf("expected error for unknown denied statement type")
+	}
+}
+
+func TestValidate_StorageBackend(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AI.Storage = StorageConfig{Backend: "File", Path: "/var/lib/atest-ext-ai"}
+	if result := cfg.Validate(); hasErrorFor(result, "ai.storage.backend") || hasErrorFor(result, "ai.storage.path") {
+		t.Fatalf("unexpected storage errors: %v", result.Errors)
+	}
+
+	cfg.AI.Storage.Path = ""
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.storage.path") {
+		t.Fatalf("expected error for a file backend without a path")
+	}
+
+	cfg.AI.Storage.Backend = "bolt"
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.storage.backend") {
+		t.Fatalf("expected error for an unknown storage backend")
+	}
+
+	cfg.AI.Storage.Backend = "redis"
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.storage.backend") {
+		t.Fatalf("expected error for the unimplemented redis backend")
+	}
+}
+
 func hasErrorFor(result *ValidationResult, field string) bool {
 	for _, issue := range result.Errors {
 		if issue.Field == field {
@@ -90,3 +165,17 @@ func hasErrorFor(result *ValidationResult, field string) bool {
 	}
 	return false
 }
+
+func TestValidate_ConfidenceWeights(t *testing.T) {
+	cfg := defaultConfig()
+	zero, tooLarge := 0.0, 1.5
+	cfg.AI.Confidence = ConfidenceConfig{TruncatedPenalty: &zero}
+	if result := cfg.Validate(); hasErrorFor(result, "ai.confidence.truncated_penalty") {
+		t.Fatalf("an explicit zero weight must be accepted: %v", result.Errors)
+	}
+
+	cfg.AI.Confidence.Base = &tooLarge
+	if result := cfg.Validate(); !hasErrorFor(result, "ai.confidence.base") {
+		t.Fatalf("expected error for a weight above 1")
+	}
+}
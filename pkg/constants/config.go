@@ -12,6 +12,8 @@ type TimeoutDefaults struct {
 	Ollama    time.Duration
 	Shutdown  time.Duration
 	Discovery time.Duration
+	// Reasoning is the minimum generation deadline for reasoning models without a service timeout
+	Reasoning time.Duration
 }
 
 // Timeouts contains the canonical timeout values for the plugin.
@@ -23,16 +25,57 @@ var Timeouts = TimeoutDefaults{
 	Ollama:    60 * time.Second,
 	Shutdown:  30 * time.Second,
 	Discovery: 5 * time.Second,
+	Reasoning: 5 * time.Minute,
 }
 
 // ServerConfigDefaults lists server-specific numeric defaults.
 type ServerConfigDefaults struct {
 	MaxConnections int
+	ShedRetryAfter time.Duration
 }
 
 // ServerDefaults centralizes limits applied to the embedded gRPC server.
 var ServerDefaults = ServerConfigDefaults{
 	MaxConnections: 100,
+	ShedRetryAfter: 2 * time.Second,
+}
+
+// KeepaliveDefaults describes the gRPC server keepalive parameters.
+type KeepaliveDefaults struct {
+	MaxConnectionIdle time.Duration
+	MaxConnectionAge  time.Duration
+	Time              time.Duration
+	Timeout           time.Duration
+}
+
+// Keepalive matches the keepalive settings used by the store plugin binaries.
+var Keepalive = KeepaliveDefaults{
+	MaxConnectionIdle: 5 * time.Minute,
+	MaxConnectionAge:  30 * time.Second,
+	Time:              30 * time.Second,
+	Timeout:           10 * time.Second,
+}
+
+// CircuitBreakerDefaults controls when a failing provider is temporarily skipped.
+type CircuitBreakerDefaults struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// CircuitBreaker opens a provider's circuit after consecutive failures and retries it after the cooldown.
+var CircuitBreaker = CircuitBreakerDefaults{
+	FailureThreshold: 5,
+	Cooldown:         30 * time.Second,
+}
+
+// LatencyDefaults controls how latency-demoted providers are re-evaluated.
+type LatencyDefaults struct {
+	ProbeInterval time.Duration
+}
+
+// Latency sends one probe request to a demoted provider per interval so it can recover.
+var Latency = LatencyDefaults{
+	ProbeInterval: 30 * time.Second,
 }
 
 // RetryPolicyDefaults captures retry strategy values for AI providers.
@@ -71,6 +114,132 @@ var RateLimit = RateLimitDefaults{
 	WindowSize:        1 * time.Minute,
 }
 
+// InputLimitDefaults bounds the size of user-supplied generation input.
+type InputLimitDefaults struct {
+	MaxPromptBytes  int
+	MaxContextBytes int
+}
+
+// InputLimits provides the builtin caps applied to prompts and schema/context payloads.
+var InputLimits = InputLimitDefaults{
+	MaxPromptBytes:  64 << 10,
+	MaxContextBytes: 1 << 20,
+}
+
+// StartupDefaults describes how long startup waits for AI providers to become healthy.
+type StartupDefaults struct {
+	WaitTimeout  time.Duration
+	PollInterval time.Duration
+}
+
+// Startup provides the builtin provider readiness wait settings.
+var Startup = StartupDefaults{
+	WaitTimeout:  60 * time.Second,
+	PollInterval: 2 * time.Second,
+}
+
+// HealthDefaults describes the provider health check defaults.
+type HealthDefaults struct {
+	CacheTTL time.Duration
+}
+
+// Health provides the builtin provider health check settings.
+var Health = HealthDefaults{
+	CacheTTL: 10 * time.Second,
+}
+
+// CacheDefaults describes the generation result cache defaults.
+type CacheDefaults struct {
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// Cache provides the builtin limits for the generation result cache.
+var Cache = CacheDefaults{
+	TTL:        10 * time.Minute,
+	MaxEntries: 256,
+}
+
+// ExampleMemoryDefaults describes the few-shot example store defaults.
+type ExampleMemoryDefaults struct {
+	TTL           time.Duration
+	MaxEntries    int
+	MaxExamples   int
+	MinConfidence float64
+}
+
+// ExampleMemory provides the builtin limits for the few-shot example store.
+var ExampleMemory = ExampleMemoryDefaults{
+	TTL:           24 * time.Hour,
+	MaxEntries:    100,
+	MaxExamples:   3,
+	MinConfidence: 0.8,
+}
+
+// DBValidationDefaults describes the EXPLAIN validation defaults.
+type DBValidationDefaults struct {
+	Timeout time.Duration
+}
+
+// DBValidation provides the builtin limits for EXPLAIN validation.
+var DBValidation = DBValidationDefaults{
+	Timeout: 5 * time.Second,
+}
+
+// SessionDefaults describes the schema session defaults.
+type SessionDefaults struct {
+	TTL         time.Duration
+	MaxSessions int
+}
+
+// Sessions provides the builtin limits for schema sessions.
+var Sessions = SessionDefaults{
+	TTL:         30 * time.Minute,
+	MaxSessions: 100,
+}
+
+// StorageDefaults describes the storage backend defaults.
+type StorageDefaults struct {
+	MaxMemoryRecords int
+}
+
+// Storage provides the builtin limits for storage backends.
+var Storage = StorageDefaults{
+	MaxMemoryRecords: 1000,
+}
+
+// DebugDefaults describes the diagnostic logging defaults.
+type DebugDefaults struct {
+	MaxResponseLogLength int
+}
+
+// Debug provides the builtin limits for diagnostic logging.
+var Debug = DebugDefaults{
+	MaxResponseLogLength: 4096,
+}
+
+// ConfidenceDefaults describes the weights of the confidence score.
+type ConfidenceDefaults struct {
+	Base              float64
+	ErrorPenalty      float64
+	WarningPenalty    float64
+	GroundedBonus     float64
+	UngroundedPenalty float64
+	ComplexityPenalty float64
+	TruncatedPenalty  float64
+}
+
+// Confidence provides the builtin weights of the confidence score.
+var Confidence = ConfidenceDefaults{
+	Base:              0.8,
+	ErrorPenalty:      0.2,
+	WarningPenalty:    0.05,
+	GroundedBonus:     0.1,
+	UngroundedPenalty: 0.2,
+	ComplexityPenalty: 0.05,
+	TruncatedPenalty:  0.3,
+}
+
 // DatabasePoolDefaults outlines default values for database connection pools.
 type DatabasePoolDefaults struct {
 	MaxConns    int
@@ -11,6 +11,9 @@ const (
 	DefaultServerHost = "0.0.0.0"
 	DefaultServerPort = 8080
 
+	// DefaultGRPCMaxMsgSize caps gRPC request and response messages (4MB)
+	DefaultGRPCMaxMsgSize = 4 << 20
+
 	// Plugin metadata defaults
 	DefaultPluginName        = "atest-ext-ai"
 	DefaultPluginVersion     = "1.0.0"
@@ -36,3 +39,6 @@ const (
 	DefaultLogFilePath   = "/var/log/atest-ext-ai.log"
 	DefaultLogFileSize   = "100MB"
 )
+
+// DefaultStopSequences end generation once a model starts a second answer or echoes the prompt
+var DefaultStopSequences = []string{"\nsql:", "\nNatural Language Query:"}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcx
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryInterceptor converts panics in unary handlers into codes.Internal errors.
+// The panic value and stack are logged but never returned to the caller.
+func RecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logging.Logger.Error("Recovered from panic in gRPC handler",
+					"method", info.FullMethod,
+					"panic", recovered,
+					"stack", string(debug.Stack()))
+				resp = nil
+				err = status.Error(codes.Internal, "internal error while handling "+info.FullMethod)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor converts panics in streaming handlers into codes.Internal errors.
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logging.Logger.Error("Recovered from panic in gRPC stream handler",
+					"method", info.FullMethod,
+					"panic", recovered,
+					"stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error while handling "+info.FullMethod)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
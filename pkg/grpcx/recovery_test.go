@@ -0,0 +1,65 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptorConvertsPanic(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Panic"}
+	handler := func(_ context.Context, _ any) (any, error) {
+		var values map[string]*string
+		return *values["missing"], nil
+	}
+
+	resp, err := RecoveryInterceptor()(context.Background(), nil, info, handler)
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, err.Error(), "nil pointer", "panic details must not leak to callers")
+}
+
+func TestRecoveryInterceptorPassesThrough(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Ok"}
+	handler := func(_ context.Context, req any) (any, error) {
+		return req, nil
+	}
+
+	resp, err := RecoveryInterceptor()(context.Background(), "ping", info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", resp)
+}
+
+func TestStreamRecoveryInterceptorConvertsPanic(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream", IsServerStream: true}
+	handler := func(_ any, _ grpc.ServerStream) error {
+		panic("boom")
+	}
+
+	err := StreamRecoveryInterceptor()(nil, nil, info, handler)
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, err.Error(), "boom")
+}
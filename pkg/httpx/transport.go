@@ -0,0 +1,91 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CABundleEnv names a PEM file whose certificates every provider transport trusts in addition to the system pool
+const CABundleEnv = "AI_PLUGIN_CA_BUNDLE"
+
+// ErrNoCertificates is returned when a CA bundle contains no PEM certificates
+var ErrNoCertificates = errors.New("no certificates found in CA bundle")
+
+var (
+	rootCAsMu sync.RWMutex
+	rootCAs   *x509.CertPool
+)
+
+// LoadCABundle trusts the PEM certificates in path, on top of the system pool, for transports created afterwards
+func LoadCABundle(path string) error {
+	pem, err := os.ReadFile(path) // #nosec G304 -- the bundle path is operator configuration
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle %s: %w", path, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("%w: %s", ErrNoCertificates, path)
+	}
+
+	rootCAsMu.Lock()
+	defer rootCAsMu.Unlock()
+	rootCAs = pool
+	return nil
+}
+
+// ResetCABundle goes back to trusting only the system pool
+func ResetCABundle() {
+	rootCAsMu.Lock()
+	defer rootCAsMu.Unlock()
+	rootCAs = nil
+}
+
+// NewTransport creates a provider transport with pooled connections that trusts the loaded CA bundle, if any.
+// Settings follow net/http recommendations; AI APIs typically talk to a single host.
+func NewTransport() *http.Transport {
+	transport := &http.Transport{
+		MaxIdleConns:          100,              // Total pool size across all hosts
+		MaxIdleConnsPerHost:   10,               // Per-host idle connection limit
+		IdleConnTimeout:       90 * time.Second, // Keep idle connections for 90s
+		ResponseHeaderTimeout: 30 * time.Second, // Timeout for reading response headers
+		ExpectContinueTimeout: 1 * time.Second,  // Timeout for 100-Continue handshake
+		ForceAttemptHTTP2:     true,             // Enable HTTP/2 when available
+		TLSHandshakeTimeout:   10 * time.Second, // Timeout for TLS handshake
+	}
+
+	rootCAsMu.RLock()
+	defer rootCAsMu.RUnlock()
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return transport
+}
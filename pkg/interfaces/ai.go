@@ -18,7 +18,11 @@ limitations under the License.
 package interfaces
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -44,6 +48,18 @@ type GenerateRequest struct {
 
 	// Stream indicates whether to stream the response
 	Stream bool `json:"stream,omitempty"`
+
+	// CacheSystemPrompt marks SystemPrompt as a stable prefix that providers may cache
+	CacheSystemPrompt bool `json:"cache_system_prompt,omitempty"`
+
+	// MaxRetries overrides the configured retry count for this request; nil keeps the default
+	MaxRetries *int `json:"max_retries,omitempty"`
+
+	// Candidates asks providers that support it for this many completions in one call
+	Candidates int `json:"candidates,omitempty"`
+
+	// Seed fixes sampling on providers that support it; nil leaves sampling random
+	Seed *int64 `json:"seed,omitempty"`
 }
 
 // GenerateResponse represents a unified AI generation response
@@ -65,6 +81,9 @@ type GenerateResponse struct {
 
 	// ConfidenceScore indicates the model's confidence in the response
 	ConfidenceScore float64 `json:"confidence_score,omitempty"`
+
+	// Alternatives holds the completions after Text when more than one candidate was requested
+	Alternatives []string `json:"alternatives,omitempty"`
 }
 
 // HealthStatus represents the health status of an AI service
@@ -102,6 +121,9 @@ type Capabilities struct {
 	// MaxTokens indicates the maximum token limit
 	MaxTokens int `json:"max_tokens"`
 
+	// CurrentModel is the model the client currently sends requests to
+	CurrentModel string `json:"current_model,omitempty"`
+
 	// SupportedLanguages lists supported programming/natural languages
 	SupportedLanguages []string `json:"supported_languages,omitempty"`
 
@@ -166,6 +188,74 @@ type RateLimits struct {
 	TokensPerDay int `json:"tokens_per_day,omitempty"`
 }
 
+// ProviderError is returned by clients when a provider answers a request with an error status,
+// or with a success status whose body is an error object, as some OpenAI-compatible gateways do
+type ProviderError struct {
+	// Provider is the name of the provider that returned the error
+	Provider string
+
+	// StatusCode is the HTTP status returned by the provider
+	StatusCode int
+
+	// RetryAfter is the delay the provider asked for before retrying, 0 when it suggested none
+	RetryAfter time.Duration
+
+	// Message is the error message from the response body, empty when the body had none
+	Message string
+
+	// Code is the error code or type from the response body, empty when the body had none
+	Code string
+}
+
+func (e *ProviderError) Error() string {
+	msg := fmt.Sprintf("API returned status %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+	}
+	return msg
+}
+
+// ProviderErrorFromBody returns the error carried by a JSON response body with an "error" member,
+// either an object such as {"error": {"message": "...", "code": "..."}} or a string, or nil when
+// the body holds no error
+func ProviderErrorFromBody(provider string, statusCode int, body []byte) *ProviderError {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	raw := bytes.TrimSpace(envelope.Error)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) || bytes.Equal(raw, []byte("false")) || bytes.Equal(raw, []byte(`""`)) {
+		return nil
+	}
+
+	providerErr := &ProviderError{Provider: provider, StatusCode: statusCode}
+	var message string
+	var detail struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+	}
+	switch {
+	case json.Unmarshal(raw, &message) == nil:
+		providerErr.Message = message
+	case json.Unmarshal(raw, &detail) == nil:
+		providerErr.Message = detail.Message
+		providerErr.Code = strings.Trim(string(bytes.TrimSpace(detail.Code)), `"`)
+		if providerErr.Code == "" || providerErr.Code == "null" {
+			providerErr.Code = detail.Type
+		}
+	}
+	if providerErr.Message == "" {
+		providerErr.Message = string(raw)
+	}
+	return providerErr
+}
+
 // AIClient defines the unified interface for AI service providers
 type AIClient interface {
 	// Generate executes an AI generation request
@@ -42,6 +42,25 @@ var (
 		[]string{"method", "provider"},
 	)
 
+	// 每个模型的生成速度（tokens/s）
+	aiTokensPerSecond = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "atest_ai_tokens_per_second",
+			Help:    "Generation speed in completion tokens per second",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"model"},
+	)
+
+	// A/B 测试的提供商选择次数
+	aiABSelections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "atest_ai_ab_selections_total",
+			Help: "Number of requests routed to each provider by A/B testing",
+		},
+		[]string{"provider"},
+	)
+
 	// AI服务健康状态
 	aiServiceHealth = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -62,6 +81,16 @@ func RecordDuration(method, provider string, duration float64) {
 	aiRequestDuration.WithLabelValues(method, provider).Observe(duration)
 }
 
+// RecordTokensPerSecond 记录模型的生成速度
+func RecordTokensPerSecond(model string, rate float64) {
+	aiTokensPerSecond.WithLabelValues(model).Observe(rate)
+}
+
+// RecordABSelection 记录 A/B 测试选中的提供商
+func RecordABSelection(provider string) {
+	aiABSelections.WithLabelValues(provider).Inc()
+}
+
 // SetHealthStatus 设置健康状态
 func SetHealthStatus(provider string, healthy bool) {
 	value := 0.0
@@ -0,0 +1,164 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"net/url"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+	"github.com/linuxsuren/atest-ext-ai/pkg/interfaces"
+)
+
+// DoctorReport is a one-shot snapshot of the plugin environment for support requests.
+// It never contains API keys; endpoint credentials and query values are redacted.
+type DoctorReport struct {
+	PluginVersion   string           `json:"plugin_version"`
+	APIVersion      string           `json:"api_version"`
+	GoVersion       string           `json:"go_version"`
+	Platform        string           `json:"platform"`
+	ConfigPath      string           `json:"config_path,omitempty"`
+	Listener        string           `json:"listener,omitempty"`
+	DefaultProvider string           `json:"default_provider,omitempty"`
+	AIAvailable     bool             `json:"ai_available"`
+	Providers       []DoctorProvider `json:"providers"`
+	OllamaModels    []string         `json:"ollama_models,omitempty"`
+	Errors          []string         `json:"errors,omitempty"`
+	GeneratedAt     time.Time        `json:"generated_at"`
+}
+
+// DoctorProvider describes one configured or runtime-added provider and its health
+type DoctorProvider struct {
+	Name         string        `json:"name"`
+	Provider     string        `json:"provider,omitempty"`
+	Endpoint     string        `json:"endpoint,omitempty"`
+	Model        string        `json:"model,omitempty"`
+	Enabled      bool          `json:"enabled"`
+	HasAPIKey    bool          `json:"has_api_key"`
+	Healthy      bool          `json:"healthy"`
+	Status       string        `json:"status,omitempty"`
+	ResponseTime time.Duration `json:"response_time,omitempty"`
+}
+
+// SetListenAddress records the resolved listener URI so it can be reported by Doctor
+func (s *AIPluginService) SetListenAddress(uri string) {
+	s.listenAddress = uri
+}
+
+// Doctor collects versions, configuration, provider health and detected Ollama models
+func (s *AIPluginService) Doctor(ctx context.Context) *DoctorReport {
+	report := &DoctorReport{
+		PluginVersion: PluginVersion,
+		APIVersion:    APIVersion,
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Listener:      s.listenAddress,
+		AIAvailable:   s.aiEngine != nil && s.aiManager != nil,
+		Providers:     []DoctorProvider{},
+		GeneratedAt:   time.Now(),
+	}
+
+	providers := make(map[string]*DoctorProvider)
+	if s.config != nil {
+		report.ConfigPath = s.config.SourcePath
+		report.DefaultProvider = s.config.AI.DefaultService
+		for name, svc := range s.config.AI.Services {
+			providers[name] = &DoctorProvider{
+				Name:      name,
+				Provider:  svc.Provider,
+				Endpoint:  redactEndpoint(svc.Endpoint),
+				Model:     svc.Model,
+				Enabled:   svc.Enabled,
+				HasAPIKey: svc.APIKey != "",
+				Status:    "not initialized",
+			}
+		}
+	}
+
+	if s.aiManager == nil {
+		report.Errors = append(report.Errors, "AI manager is not initialized")
+	} else {
+		report.DefaultProvider = s.aiManager.DefaultProvider()
+
+		healthCtx, cancel := context.WithTimeout(ctx, constants.Timeouts.Discovery)
+		defer cancel()
+		for name, health := range s.aiManager.HealthCheckAll(healthCtx, true) {
+			provider, ok := providers[name]
+			if !ok {
+				provider = &DoctorProvider{Name: name, Enabled: true}
+				providers[name] = provider
+			}
+			provider.Healthy = health.Healthy
+			provider.Status = health.Status
+			provider.ResponseTime = health.ResponseTime
+		}
+
+		if _, ok := s.aiManager.GetAllClients()["ollama"]; ok {
+			models, err := s.aiManager.GetModels(healthCtx, "ollama")
+			if err != nil {
+				report.Errors = append(report.Errors, "failed to list Ollama models: "+err.Error())
+			}
+			report.OllamaModels = modelNames(models)
+		}
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		report.Providers = append(report.Providers, *providers[name])
+	}
+	return report
+}
+
+// modelNames returns the sorted names of models
+func modelNames(models []interfaces.ModelInfo) []string {
+	names := make([]string, 0, len(models))
+	for _, model := range models {
+		name := model.ID
+		if name == "" {
+			name = model.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// redactEndpoint hides credentials embedded in an endpoint URL and the values of its query parameters
+func redactEndpoint(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return endpoint
+	}
+	if parsed.User != nil {
+		parsed.User = url.User("redacted")
+	}
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for key := range query {
+			query.Set(key, "redacted")
+		}
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
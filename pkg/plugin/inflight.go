@@ -0,0 +1,149 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apperrors "github.com/linuxsuren/atest-ext-ai/pkg/errors"
+)
+
+// drainCancelGrace bounds how long drain waits for generations to return after cancelling them
+const drainCancelGrace = 2 * time.Second
+
+// inflightGenerations tracks running generations so they can be cancelled by their caller-supplied
+// request id and drained during shutdown
+type inflightGenerations struct {
+	mu sync.Mutex
+	// cancels and active hold each generation's cancel func by pointer, which identifies
+	// the generation even after its request id is reused
+	cancels  map[string]*context.CancelFunc
+	active   map[*context.CancelFunc]struct{}
+	running  sync.WaitGroup
+	draining bool
+	// limit sheds generations beyond this many; zero means unlimited
+	limit      int
+	retryAfter time.Duration
+}
+
+// track derives a cancelable context for a generation; done must be called when it ends.
+// A non-empty requestID also makes the generation cancelable through cancel.
+func (g *inflightGenerations) track(ctx context.Context, requestID string) (context.Context, func(), error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.draining {
+		return nil, nil, fmt.Errorf("%w: the plugin is shutting down", apperrors.ErrProviderNotAvailable)
+	}
+	if _, exists := g.cancels[requestID]; requestID != "" && exists {
+		return nil, nil, fmt.Errorf("%w: request id %q is already in flight", apperrors.ErrInvalidRequest, requestID)
+	}
+	if g.limit > 0 && len(g.active) >= g.limit {
+		return nil, nil, fmt.Errorf("%w: %d generations already in flight, retry later", apperrors.ErrResourceExhausted, len(g.active))
+	}
+	if g.cancels == nil {
+		g.cancels = make(map[string]*context.CancelFunc)
+		g.active = make(map[*context.CancelFunc]struct{})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	key := &cancel
+	g.active[key] = struct{}{}
+	if requestID != "" {
+		g.cancels[requestID] = key
+	}
+	g.running.Add(1)
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			g.mu.Lock()
+			delete(g.active, key)
+			// A cancelled id may already belong to a newer generation; only remove our own entry
+			if g.cancels[requestID] == key {
+				delete(g.cancels, requestID)
+			}
+			g.mu.Unlock()
+			g.running.Done()
+		})
+	}, nil
+}
+
+// setLimit changes how many generations may run at once and the backoff suggested to shed callers
+func (g *inflightGenerations) setLimit(limit int, retryAfter time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.limit = limit
+	g.retryAfter = retryAfter
+}
+
+// retryAfterHint returns the backoff suggested to callers rejected by the concurrency limit
+func (g *inflightGenerations) retryAfterHint() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.retryAfter
+}
+
+// cancel cancels the generation registered under requestID and reports whether one was found
+func (g *inflightGenerations) cancel(requestID string) bool {
+	g.mu.Lock()
+	cancel, exists := g.cancels[requestID]
+	delete(g.cancels, requestID)
+	g.mu.Unlock()
+
+	if exists {
+		(*cancel)()
+	}
+	return exists
+}
+
+// drain rejects new generations and waits for running ones to finish. When ctx ends first the
+// remaining generations are cancelled and given a short grace period to return. It reports
+// whether every generation finished on its own.
+func (g *inflightGenerations) drain(ctx context.Context) bool {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+
+	idle := make(chan struct{})
+	go func() {
+		g.running.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return true
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	for cancel := range g.active {
+		(*cancel)()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-time.After(drainCancelGrace):
+	}
+	return false
+}
@@ -20,20 +20,31 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/linuxsuren/api-testing/pkg/server"
 	"github.com/linuxsuren/api-testing/pkg/testing/remote"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/jsonschema"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/models"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
 	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers/universal"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/schema"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/templates"
 	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
 	apperrors "github.com/linuxsuren/atest-ext-ai/pkg/errors"
 	"github.com/linuxsuren/atest-ext-ai/pkg/logging"
 	"github.com/linuxsuren/atest-ext-ai/pkg/metrics"
+	"github.com/linuxsuren/atest-ext-ai/pkg/sqlutil"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -209,19 +220,27 @@ func (s *AIPluginService) requireManagerAvailable(operation, baseMessage string)
 }
 
 func normalizeDatabaseType(value string) string {
-	dbType := strings.ToLower(strings.TrimSpace(value))
-	switch dbType {
-	case "mysql":
-		return "mysql"
-	case "postgres", "postgresql", "pg":
-		return "postgresql"
-	case "sqlite", "sqlite3":
-		return "sqlite"
+	switch dbType := providers.Normalize(value); dbType {
+	case "mysql", "postgresql", "sqlite", "snowflake":
+		return dbType
 	default:
 		return ""
 	}
 }
 
+// databaseTypeFromDSN infers a supported database type from a connection string
+func databaseTypeFromDSN(dsn string) (string, error) {
+	dialect, err := schema.DialectFromDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	normalized := normalizeDatabaseType(dialect)
+	if normalized == "" {
+		return "", fmt.Errorf("database type %q inferred from DSN is not supported", dialect)
+	}
+	return normalized, nil
+}
+
 func (s *AIPluginService) defaultDatabaseType() string {
 	if normalized := normalizeDatabaseType(s.config.Database.DefaultType); normalized != "" {
 		return normalized
@@ -233,6 +252,9 @@ func (s *AIPluginService) resolveDatabaseType(explicit string, overrides Generat
 	if normalized := normalizeDatabaseType(explicit); normalized != "" {
 		return normalized
 	}
+	if custom := s.customDialect(explicit); custom != "" {
+		return custom
+	}
 
 	if fromConfig := overrides.preferredDatabaseType(); fromConfig != "" {
 		return fromConfig
@@ -241,6 +263,20 @@ func (s *AIPluginService) resolveDatabaseType(explicit string, overrides Generat
 	return s.defaultDatabaseType()
 }
 
+// customDialect returns the normalized name of a dialect declared in ai.custom_dialects, or ""
+func (s *AIPluginService) customDialect(databaseType string) string {
+	name := strings.ToLower(strings.TrimSpace(databaseType))
+	if name == "" || s.config == nil {
+		return ""
+	}
+	for configured := range s.config.AI.CustomDialects {
+		if strings.ToLower(strings.TrimSpace(configured)) == name {
+			return name
+		}
+	}
+	return ""
+}
+
 func normalizeDurationField(payload map[string]any, key string) {
 	raw, ok := payload[key]
 	if !ok || raw == nil {
@@ -277,6 +313,9 @@ type AIPluginService struct {
 	config             *config.Config
 	capabilityDetector *ai.CapabilityDetector
 	aiManager          *ai.Manager
+	reloadMu           sync.Mutex
+	inflight           inflightGenerations
+	listenAddress      string
 }
 
 // NewAIPluginService creates a new AI plugin service instance
@@ -303,6 +342,7 @@ func NewAIPluginService() (*AIPluginService, error) {
 	service := &AIPluginService{
 		config: cfg,
 	}
+	service.inflight.setLimit(cfg.Server.MaxConcurrentGenerations, cfg.Server.ShedRetryAfter.Value())
 
 	// Try to initialize AI engine - but allow plugin to start if it fails
 	aiEngine, err := ai.NewEngine(cfg.AI)
@@ -364,6 +404,11 @@ func NewAIPluginService() (*AIPluginService, error) {
 		logging.Logger.Info("AI manager initialized successfully")
 		service.aiManager = aiManager
 
+		// Only process start waits for providers; reloads swap in new components immediately
+		if cfg.AI.Startup.WaitForProviders {
+			aiManager.WaitForProviders(context.Background())
+		}
+
 		// Initialize capability detector only if AI manager is available
 		capabilityDetector := ai.NewCapabilityDetector(cfg.AI, aiManager)
 		logging.Logger.Info("Capability detector initialized")
@@ -422,6 +467,38 @@ func (s *AIPluginService) Query(ctx context.Context, req *server.DataQuery) (*se
 			return nil, err
 		}
 		return s.handleAIGenerate(ctx, req)
+	case "regenerate":
+		if err := s.requireEngineAvailable(
+			"AI regeneration requested but AI engine is not available",
+			"AI generation service is currently unavailable.",
+			"Please check AI provider configuration and connectivity."); err != nil {
+			return nil, err
+		}
+		return s.handleAIRegenerate(ctx, req)
+	case "generate_template":
+		if err := s.requireEngineAvailable(
+			"Template generation requested but AI engine is not available",
+			"AI generation service is currently unavailable.",
+			"Please check AI provider configuration and connectivity."); err != nil {
+			return nil, err
+		}
+		return s.handleAIGenerateTemplate(ctx, req)
+	case "open_session":
+		if err := s.requireEngineAvailable(
+			"Session requested but AI engine is not available",
+			"AI generation service is currently unavailable.",
+			"Please check AI provider configuration and connectivity."); err != nil {
+			return nil, err
+		}
+		return s.handleOpenSession(ctx, req)
+	case "close_session":
+		if err := s.requireEngineAvailable(
+			"Session close requested but AI engine is not available",
+			"AI generation service is currently unavailable.",
+			"Please check AI provider configuration and connectivity."); err != nil {
+			return nil, err
+		}
+		return s.handleCloseSession(ctx, req)
 	case "capabilities":
 		return s.handleAICapabilities(ctx, req)
 	case "providers":
@@ -433,6 +510,42 @@ func (s *AIPluginService) Query(ctx context.Context, req *server.DataQuery) (*se
 		return s.handleGetProviders(ctx, req)
 	case "models_catalog":
 		return s.handleGetModelCatalog(ctx, req)
+	case "schema":
+		return s.handleGetSchema(ctx, req)
+	case "normalize_sql":
+		return s.handleNormalizeSQL(ctx, req)
+	case "dialect_info":
+		return s.handleDialectInfo(ctx, req)
+	case "paginate":
+		return s.handlePaginate(ctx, req)
+	case "dialect_preview":
+		return s.handleDialectPreview(ctx, req)
+	case "get_default_provider":
+		if err := s.requireManagerAvailable(
+			"Default provider requested but AI manager is not available",
+			"AI provider management is currently unavailable."); err != nil {
+			return nil, err
+		}
+		return s.handleGetDefaultProvider(ctx, req)
+	case "set_default_provider":
+		if err := s.requireEngineAvailable(
+			"Default provider change requested but AI engine is not available",
+			"AI provider management is currently unavailable.",
+			"Please check AI provider configuration and connectivity."); err != nil {
+			return nil, err
+		}
+		return s.handleSetDefaultProvider(ctx, req)
+	case "cancel":
+		return s.handleCancel(ctx, req)
+	case "doctor":
+		return s.handleDoctor(ctx, req)
+	case "benchmark":
+		if err := s.requireManagerAvailable(
+			"Provider benchmark requested but AI manager is not available",
+			"AI provider benchmarking is currently unavailable."); err != nil {
+			return nil, err
+		}
+		return s.handleBenchmark(ctx, req)
 	case "models":
 		if err := s.requireManagerAvailable(
 			"Model listing requested but AI manager is not available",
@@ -440,6 +553,13 @@ func (s *AIPluginService) Query(ctx context.Context, req *server.DataQuery) (*se
 			return nil, err
 		}
 		return s.handleGetModels(ctx, req)
+	case "model_details":
+		if err := s.requireManagerAvailable(
+			"Model details requested but AI manager is not available",
+			"AI model details are currently unavailable."); err != nil {
+			return nil, err
+		}
+		return s.handleModelDetails(ctx, req)
 	case "test_connection":
 		if err := s.requireManagerAvailable(
 			"Connection test requested but AI manager is not available",
@@ -495,6 +615,9 @@ func (s *AIPluginService) handleCapabilitiesQuery(ctx context.Context, req *serv
 			if checkHealth, ok := params["check_health"]; ok {
 				capReq.CheckHealth = checkHealth
 			}
+			if probeGeneration, ok := params["probe_generation"]; ok {
+				capReq.ProbeGeneration = probeGeneration
+			}
 		} else {
 			logging.Logger.Error("Failed to parse capability request parameters", "error", err)
 		}
@@ -626,6 +749,10 @@ func (s *AIPluginService) Verify(ctx context.Context, _ *server.Empty) (*server.
 		}
 	}
 
+	if isReady && s.config.AI.ReadOnly {
+		message += " (read-only mode: only SELECT statements are generated)"
+	}
+
 	// Include detailed version information for diagnostics
 	versionInfo := fmt.Sprintf("%s (API: %s, gRPC: %s, requires api-testing >= %s)",
 		PluginVersion, APIVersion, GRPCInterfaceVersion, MinCompatibleAPITestingVersion)
@@ -645,10 +772,30 @@ func (s *AIPluginService) Verify(ctx context.Context, _ *server.Empty) (*server.
 	return status, nil
 }
 
-// Shutdown gracefully stops the AI plugin service
+// Environment returns the configured plugin environment (e.g. production, development)
+func (s *AIPluginService) Environment() string {
+	if s.config == nil {
+		return constants.DefaultPluginEnvironment
+	}
+	return s.config.Plugin.Environment
+}
+
+// Shutdown gracefully stops the AI plugin service. New generations are rejected, running ones
+// get up to the shutdown timeout to finish, and only then are the AI clients closed.
 func (s *AIPluginService) Shutdown() {
+	s.shutdown(constants.Timeouts.Shutdown)
+}
+
+// shutdown drains in-flight generations for at most timeout and then closes the AI engine
+func (s *AIPluginService) shutdown(timeout time.Duration) {
 	logging.Logger.Info("Shutting down AI plugin service...")
 
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if !s.inflight.drain(ctx) {
+		logging.Logger.Warn("Cancelled in-flight generations that did not finish before the shutdown timeout", "timeout", timeout)
+	}
+
 	if s.aiEngine != nil {
 		logging.Logger.Info("Closing AI engine...")
 		s.aiEngine.Close()
@@ -658,6 +805,30 @@ func (s *AIPluginService) Shutdown() {
 	logging.Logger.Info("AI plugin service shutdown complete")
 }
 
+// retryAfterTrailer is the trailer key carrying the suggested backoff, in whole seconds
+const retryAfterTrailer = "retry-after"
+
+// trackGeneration registers a generation with the in-flight tracker. When the concurrency limit
+// is reached the call fails fast with ResourceExhausted and a retry-after trailer instead of queueing.
+func (s *AIPluginService) trackGeneration(ctx context.Context, requestID string) (context.Context, func(), error) {
+	tracked, done, err := s.inflight.track(ctx, requestID)
+	if err == nil {
+		return tracked, done, nil
+	}
+
+	if errors.Is(err, apperrors.ErrResourceExhausted) {
+		seconds := int(math.Ceil(s.inflight.retryAfterHint().Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		if trailerErr := grpc.SetTrailer(ctx, metadata.Pairs(retryAfterTrailer, strconv.Itoa(seconds))); trailerErr != nil {
+			logging.Logger.Debug("Failed to set retry-after trailer", "error", trailerErr)
+		}
+		logging.Logger.Warn("Shedding generation, too many in flight", "retry_after_seconds", seconds)
+	}
+	return nil, nil, apperrors.ToGRPCError(err)
+}
+
 // GetVersion returns the plugin version information
 func (s *AIPluginService) GetVersion(ctx context.Context, _ *server.Empty) (*server.Version, error) {
 	logging.Logger.Debug("GetVersion called")
@@ -690,6 +861,15 @@ type GenerationMetadata struct {
 	Confidence float32 `json:"confidence"`
 	Model      string  `json:"model,omitempty"`
 	Dialect    string  `json:"dialect"`
+	Truncated  bool    `json:"truncated,omitempty"`
+	CacheHit   bool    `json:"cache_hit,omitempty"`
+	ServedBy   string  `json:"served_by,omitempty"`
+	// ABTestProvider is present when A/B routing picked the provider
+	ABTestProvider string `json:"ab_test_provider,omitempty"`
+	// RenderedPrompt is present when the runtime config sets include_prompt
+	RenderedPrompt *ai.RenderedPrompt `json:"rendered_prompt,omitempty"`
+	// Alternatives are present when the runtime config asks for more than one candidate
+	Alternatives []ai.CandidateSQL `json:"alternatives,omitempty"`
 }
 
 // CapabilitySummary is returned when the capability detector is unavailable.
@@ -740,6 +920,9 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 		Prompt       string `json:"prompt"`
 		Config       string `json:"config"`
 		DatabaseType string `json:"database_type"`
+		DSN          string `json:"dsn"`
+		RequestID    string `json:"request_id"`
+		SessionID    string `json:"session_id"`
 	}
 
 	if req.Sql != "" {
@@ -748,8 +931,20 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 		}
 	}
 
-	if params.Prompt == "" {
-		return nil, apperrors.ToGRPCError(apperrors.ErrInvalidRequest)
+	if _, err := ai.NormalizeNaturalLanguage(params.Prompt, 0); err != nil {
+		return generationFailureResult(err), nil
+	}
+
+	if rejected := s.rejectOversizedInput(len(params.Prompt), len(params.Config)); rejected != nil {
+		return rejected, nil
+	}
+
+	if params.DatabaseType == "" && params.DSN != "" {
+		dialect, err := databaseTypeFromDSN(params.DSN)
+		if err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+		}
+		params.DatabaseType = dialect
 	}
 
 	// Parse optional config
@@ -768,24 +963,27 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 	apiKey := apiKeyFromContext(ctx)
 
 	// Generate using AI engine
-	context := map[string]string{}
-	if params.Model != "" {
-		context["preferred_model"] = params.Model
-		logging.Logger.Debug("Setting preferred model", "model", params.Model)
-	}
-	if params.Config != "" {
-		context["config"] = params.Config
-	}
+	context := generationContext(params.Model, params.Config)
 
 	// Get database type from configuration, fallback to mysql if not configured
+	if rejected := s.rejectUnsupportedDatabaseType(params.DatabaseType); rejected != nil {
+		return rejected, nil
+	}
 	databaseType := s.resolveDatabaseType(params.DatabaseType, generationOverrides)
 	context["database_type"] = databaseType
 
+	ctx, done, err := s.trackGeneration(ctx, params.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	sqlResult, err := s.aiEngine.GenerateSQL(ctx, &ai.GenerateSQLRequest{
 		NaturalLanguage: params.Prompt,
 		DatabaseType:    databaseType,
 		Context:         context,
 		RuntimeAPIKey:   apiKey,
+		SessionID:       params.SessionID,
 	})
 	if err != nil {
 		metrics.RecordRequest("generate", provider, "error")
@@ -797,24 +995,294 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 
 		// Business logic error: return error in response data, not as gRPC error
 		// This allows the main project to handle it gracefully
+		return generationFailureResult(err), nil
+	}
+
+	metrics.RecordRequest("generate", provider, "success")
+
+	return generationSuccessResult(sqlResult, databaseType), nil
+}
+
+// handleAIRegenerate handles ai.regenerate calls that correct previously generated SQL
+func (s *AIPluginService) handleAIRegenerate(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	start := time.Now()
+	provider := s.config.AI.DefaultService
+
+	defer func() {
+		metrics.RecordDuration("regenerate", provider, time.Since(start).Seconds())
+	}()
+
+	var params struct {
+		PreviousSQL  string `json:"previous_sql"`
+		Feedback     string `json:"feedback"`
+		Model        string `json:"model"`
+		Config       string `json:"config"`
+		DatabaseType string `json:"database_type"`
+		RequestID    string `json:"request_id"`
+	}
+
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "failed to parse regenerate parameters: %v", err)
+		}
+	}
+
+	if strings.TrimSpace(params.PreviousSQL) == "" || strings.TrimSpace(params.Feedback) == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "previous_sql and feedback are required")
+	}
+
+	if rejected := s.rejectOversizedInput(len(params.PreviousSQL)+len(params.Feedback), len(params.Config)); rejected != nil {
+		return rejected, nil
+	}
+
+	var generationOverrides GenerationConfigOverrides
+	if params.Config != "" {
+		if err := json.Unmarshal([]byte(params.Config), &generationOverrides); err != nil {
+			logging.Logger.Warn("Failed to parse config JSON", "error", err)
+		}
+	}
+
+	context := generationContext(params.Model, params.Config)
+	if rejected := s.rejectUnsupportedDatabaseType(params.DatabaseType); rejected != nil {
+		return rejected, nil
+	}
+	databaseType := s.resolveDatabaseType(params.DatabaseType, generationOverrides)
+	context["database_type"] = databaseType
+
+	ctx, done, err := s.trackGeneration(ctx, params.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	sqlResult, err := s.aiEngine.RegenerateSQL(ctx, &ai.RegenerateSQLRequest{
+		PreviousSQL:   params.PreviousSQL,
+		Feedback:      params.Feedback,
+		DatabaseType:  databaseType,
+		Context:       context,
+		RuntimeAPIKey: apiKeyFromContext(ctx),
+	})
+	if err != nil {
+		metrics.RecordRequest("regenerate", provider, "error")
+		logging.Logger.Error("SQL regeneration failed",
+			"error", err,
+			"database_type", databaseType)
+
+		return generationFailureResult(err), nil
+	}
+
+	metrics.RecordRequest("regenerate", provider, "success")
+
+	return generationSuccessResult(sqlResult, databaseType), nil
+}
+
+// handleAIGenerateTemplate handles ai.generate_template calls that fill a named query template
+func (s *AIPluginService) handleAIGenerateTemplate(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	start := time.Now()
+	provider := s.config.AI.DefaultService
+
+	defer func() {
+		metrics.RecordDuration("generate_template", provider, time.Since(start).Seconds())
+	}()
+
+	var params struct {
+		Template     string            `json:"template"`
+		Params       map[string]string `json:"params"`
+		Model        string            `json:"model"`
+		Config       string            `json:"config"`
+		DatabaseType string            `json:"database_type"`
+		RequestID    string            `json:"request_id"`
+	}
+
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "failed to parse template parameters: %v", err)
+		}
+	}
+
+	if params.Template == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "template is required")
+	}
+
+	templateInput := 0
+	for name, value := range params.Params {
+		templateInput += len(name) + len(value)
+	}
+	if rejected := s.rejectOversizedInput(templateInput, len(params.Config)); rejected != nil {
+		return rejected, nil
+	}
+
+	var generationOverrides GenerationConfigOverrides
+	if params.Config != "" {
+		if err := json.Unmarshal([]byte(params.Config), &generationOverrides); err != nil {
+			logging.Logger.Warn("Failed to parse config JSON", "error", err)
+		}
+	}
+
+	context := generationContext(params.Model, params.Config)
+	if rejected := s.rejectUnsupportedDatabaseType(params.DatabaseType); rejected != nil {
+		return rejected, nil
+	}
+	databaseType := s.resolveDatabaseType(params.DatabaseType, generationOverrides)
+	context["database_type"] = databaseType
+
+	ctx, done, err := s.trackGeneration(ctx, params.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	sqlResult, err := s.aiEngine.GenerateSQL(ctx, &ai.GenerateSQLRequest{
+		DatabaseType:   databaseType,
+		Context:        context,
+		RuntimeAPIKey:  apiKeyFromContext(ctx),
+		Template:       params.Template,
+		TemplateParams: params.Params,
+	})
+	if err != nil {
+		metrics.RecordRequest("generate_template", provider, "error")
+		if errors.Is(err, templates.ErrTemplateNotFound) || errors.Is(err, templates.ErrMissingParameters) {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+		}
+
+		logging.Logger.Error("Template SQL generation failed",
+			"error", err,
+			"template", params.Template,
+			"database_type", databaseType)
+
+		return generationFailureResult(err), nil
+	}
+
+	metrics.RecordRequest("generate_template", provider, "success")
+
+	return generationSuccessResult(sqlResult, databaseType), nil
+}
+
+// generationFailureResult reports a failed generation in the response data.
+// Unsupported database types carry their own error code, the supported list and a suggestion.
+func generationFailureResult(err error) *server.DataQueryResult {
+	var dialectErr *ai.UnsupportedDialectError
+	if !errors.As(err, &dialectErr) {
+		errorCode := "GENERATION_FAILED"
+		switch {
+		case errors.Is(err, ai.ErrEmptyQuery):
+			errorCode = "INVALID_PARAMS"
+		case errors.Is(err, ai.ErrQueryTooLong):
+			errorCode = "INPUT_TOO_LARGE"
+		case errors.Is(err, ai.ErrReadOnlyViolation):
+			errorCode = "READ_ONLY_VIOLATION"
+		case errors.Is(err, ai.ErrStatementTypeNotAllowed):
+			errorCode = "STATEMENT_TYPE_NOT_ALLOWED"
+		case errors.Is(err, ai.ErrModelNotAllowed):
+			errorCode = "MODEL_NOT_ALLOWED"
+		case errors.Is(err, ai.ErrInvalidResponseEncoding):
+			errorCode = "INVALID_RESPONSE_ENCODING"
+		case errors.Is(err, ai.ErrGeneratedSQLTooLarge):
+			errorCode = "SQL_TOO_LARGE"
+		case errors.Is(err, ai.ErrSessionNotFound):
+			errorCode = "SESSION_NOT_FOUND"
+		case errors.Is(err, context.Canceled):
+			errorCode = "CANCELLED"
+		case errors.Is(err, universal.ErrOllamaUnavailable):
+			errorCode = "OLLAMA_UNAVAILABLE"
+		}
 		return &server.DataQueryResult{
 			Data: []*server.Pair{
 				{Key: "api_version", Value: APIVersion},
 				{Key: "success", Value: "false"},
 				{Key: "error", Value: err.Error()},
-				{Key: "error_code", Value: "GENERATION_FAILED"},
+				{Key: "error_code", Value: errorCode},
 			},
-		}, nil
+		}
+	}
+
+	supported, marshalErr := json.Marshal(dialectErr.Supported)
+	if marshalErr != nil {
+		supported = []byte("[]")
+	}
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "api_version", Value: APIVersion},
+			{Key: "success", Value: "false"},
+			{Key: "error", Value: dialectErr.Error()},
+			{Key: "error_code", Value: "UNSUPPORTED_DIALECT"},
+			{Key: "supported_dialects", Value: string(supported)},
+			{Key: "suggestion", Value: dialectErr.Suggestion},
+		},
+	}
+}
+
+// rejectUnsupportedDatabaseType returns an UNSUPPORTED_DIALECT result for an explicit but unknown database type
+func (s *AIPluginService) rejectUnsupportedDatabaseType(databaseType string) *server.DataQueryResult {
+	if databaseType == "" || normalizeDatabaseType(databaseType) != "" || s.customDialect(databaseType) != "" {
+		return nil
+	}
+	return generationFailureResult(ai.NewUnsupportedDialectError(databaseType))
+}
+
+// rejectOversizedInput returns an INPUT_TOO_LARGE result when the prompt or context payload exceeds the configured caps
+func (s *AIPluginService) rejectOversizedInput(promptBytes, contextBytes int) *server.DataQueryResult {
+	limits := constants.InputLimits
+	maxPrompt, maxContext := limits.MaxPromptBytes, limits.MaxContextBytes
+	if s.config != nil {
+		if s.config.AI.Limits.MaxPromptBytes > 0 {
+			maxPrompt = s.config.AI.Limits.MaxPromptBytes
+		}
+		if s.config.AI.Limits.MaxContextBytes > 0 {
+			maxContext = s.config.AI.Limits.MaxContextBytes
+		}
+	}
+
+	var message string
+	switch {
+	case promptBytes > maxPrompt:
+		message = fmt.Sprintf("natural language input is %d bytes, exceeding the %d byte limit", promptBytes, maxPrompt)
+	case contextBytes > maxContext:
+		message = fmt.Sprintf("schema/context payload is %d bytes, exceeding the %d byte limit", contextBytes, maxContext)
+	default:
+		return nil
+	}
+
+	logging.Logger.Warn("Rejected oversized generation input", "reason", message)
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "api_version", Value: APIVersion},
+			{Key: "success", Value: "false"},
+			{Key: "error", Value: message},
+			{Key: "error_code", Value: "INPUT_TOO_LARGE"},
+		},
+	}
+}
+
+// generationContext builds the engine context shared by generation handlers
+func generationContext(model, runtimeConfig string) map[string]string {
+	context := map[string]string{}
+	if model != "" {
+		context["preferred_model"] = model
+		logging.Logger.Debug("Setting preferred model", "model", model)
+	}
+	if runtimeConfig != "" {
+		context["config"] = runtimeConfig
 	}
+	return context
+}
 
+// generationSuccessResult renders a successful generation in the AI interface format
+func generationSuccessResult(sqlResult *ai.GenerateSQLResponse, databaseType string) *server.DataQueryResult {
 	// Return in simplified format with line break
 	simpleFormat := fmt.Sprintf("sql:%s\nexplanation:%s", sqlResult.SQL, sqlResult.Explanation)
 
 	// Build minimal meta information for UI display
 	meta := GenerationMetadata{
-		Confidence: sqlResult.ConfidenceScore,
-		Model:      sqlResult.ModelUsed,
-		Dialect:    databaseType,
+		Confidence:     sqlResult.ConfidenceScore,
+		Model:          sqlResult.ModelUsed,
+		Dialect:        databaseType,
+		Truncated:      sqlResult.Truncated,
+		CacheHit:       sqlResult.CacheHit,
+		ServedBy:       sqlResult.ServedBy,
+		ABTestProvider: sqlResult.ABTestProvider,
+		RenderedPrompt: sqlResult.RenderedPrompt,
+		Alternatives:   sqlResult.Alternatives,
 	}
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
@@ -827,8 +1295,6 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 		"model", sqlResult.ModelUsed,
 		"sql_length", len(sqlResult.SQL))
 
-	metrics.RecordRequest("generate", provider, "success")
-
 	return &server.DataQueryResult{
 		Data: []*server.Pair{
 			{Key: "api_version", Value: APIVersion},
@@ -836,7 +1302,7 @@ func (s *AIPluginService) handleAIGenerate(ctx context.Context, req *server.Data
 			{Key: "success", Value: "true"},
 			{Key: "meta", Value: string(metaJSON)},
 		},
-	}, nil
+	}
 }
 
 // handleAICapabilities handles ai.capabilities calls
@@ -863,6 +1329,9 @@ func (s *AIPluginService) handleAICapabilities(ctx context.Context, req *server.
 			if checkHealth, ok := params["check_health"]; ok {
 				capReq.CheckHealth = checkHealth
 			}
+			if probeGeneration, ok := params["probe_generation"]; ok {
+				capReq.ProbeGeneration = probeGeneration
+			}
 		} else {
 			logging.Logger.Warn("Failed to parse capabilities request overrides", "error", err)
 		}
@@ -1124,9 +1593,15 @@ func (s *AIPluginService) handleLegacyQuery(ctx context.Context, req *server.Dat
 
 	// Build minimal meta information for UI display
 	meta := GenerationMetadata{
-		Confidence: sqlResult.ConfidenceScore,
-		Model:      sqlResult.ModelUsed,
-		Dialect:    databaseType,
+		Confidence:     sqlResult.ConfidenceScore,
+		Model:          sqlResult.ModelUsed,
+		Dialect:        databaseType,
+		Truncated:      sqlResult.Truncated,
+		CacheHit:       sqlResult.CacheHit,
+		ServedBy:       sqlResult.ServedBy,
+		ABTestProvider: sqlResult.ABTestProvider,
+		RenderedPrompt: sqlResult.RenderedPrompt,
+		Alternatives:   sqlResult.Alternatives,
 	}
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
@@ -1254,10 +1729,8 @@ func (s *AIPluginService) handleGetModels(ctx context.Context, req *server.DataQ
 
 	// Get models for specific provider
 	// Map frontend category names to backend provider names
-	providerName := params.Provider
-	switch params.Provider {
-	case "local":
-		providerName = "ollama"
+	providerName := providers.Normalize(params.Provider)
+	switch providerName {
 	case "online":
 		// Map "online" to default online provider (can be configured)
 		providerName = "deepseek"
@@ -1280,6 +1753,48 @@ func (s *AIPluginService) handleGetModels(ctx context.Context, req *server.DataQ
 	}, nil
 }
 
+// handleModelDetails returns the enriched capability of a single model offered by a provider,
+// including context size, features, cost and any ai.models overrides
+func (s *AIPluginService) handleModelDetails(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		Provider string `json:"provider"`
+		Model    string `json:"model"`
+	}
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+	if strings.TrimSpace(params.Provider) == "" || strings.TrimSpace(params.Model) == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "provider and model are required")
+	}
+	if s.capabilityDetector == nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrProviderNotAvailable, "capability detection is not available")
+	}
+
+	details, err := s.capabilityDetector.ModelDetails(ctx, params.Provider, params.Model)
+	switch {
+	case errors.Is(err, ai.ErrClientNotFound):
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrProviderNotConfigured, "provider %s is not configured", params.Provider)
+	case errors.Is(err, ai.ErrModelNotFound):
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrModelNotFound, "provider %s does not offer model %s", params.Provider, params.Model)
+	case err != nil:
+		logging.Logger.Error("Failed to get model details", "provider", params.Provider, "model", params.Model, "error", err)
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrProviderNotAvailable, "failed to get model details: %v", err)
+	}
+
+	detailsJSON, _ := json.Marshal(details)
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "model", Value: string(detailsJSON)},
+			{Key: "provider", Value: params.Provider},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
 // handleGetModelCatalog returns the static model catalog (either entire catalog or provider-specific slice)
 func (s *AIPluginService) handleGetModelCatalog(_ context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
 	var params struct {
@@ -1316,16 +1831,458 @@ func (s *AIPluginService) handleGetModelCatalog(_ context.Context, req *server.D
 	return result, nil
 }
 
-// handleTestConnection tests a connection with provided configuration
-func (s *AIPluginService) handleTestConnection(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
-	logging.Logger.Debug("Handling test connection request", "sql_length", len(req.Sql))
+// handleGetSchema returns JSON Schema documents for generation requests and responses
+func (s *AIPluginService) handleGetSchema(ctx context.Context, _ *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
 
-	// Parse configuration from SQL field
-	var config universal.Config
-	if req.Sql != "" {
-		var payload map[string]any
-		if err := json.Unmarshal([]byte(req.Sql), &payload); err != nil {
-			logging.Logger.Error("Failed to parse connection config", "error", err)
+	requestSchema, err := json.Marshal(jsonschema.RequestSchema())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode request schema: %v", err)
+	}
+	responseSchema, err := json.Marshal(jsonschema.ResponseSchema())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response schema: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "api_version", Value: APIVersion},
+			{Key: "request_schema", Value: string(requestSchema)},
+			{Key: "response_schema", Value: string(responseSchema)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleNormalizeSQL returns the canonical form of a SQL statement without calling a model
+func (s *AIPluginService) handleNormalizeSQL(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		SQL          string `json:"sql"`
+		DatabaseType string `json:"database_type"`
+	}
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+
+	dialect := normalizeDatabaseType(params.DatabaseType)
+	if dialect == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "unsupported database_type %q", params.DatabaseType)
+	}
+
+	normalized, err := sqlutil.Normalize(params.SQL, dialect)
+	if err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "cannot normalize sql: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "sql", Value: normalized},
+			{Key: "database_type", Value: dialect},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handlePaginate applies dialect-specific LIMIT/OFFSET syntax to a SELECT statement
+func (s *AIPluginService) handlePaginate(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		SQL          string `json:"sql"`
+		DatabaseType string `json:"database_type"`
+		Limit        int    `json:"limit"`
+		Offset       int    `json:"offset"`
+	}
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+
+	databaseType := normalizeDatabaseType(params.DatabaseType)
+	dialect, ok := ai.NewSQLDialect(databaseType)
+	if !ok {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest,
+			"unsupported database_type %q (supported: %s)", params.DatabaseType, strings.Join(ai.SupportedDialects(), ", "))
+	}
+
+	paginated, err := dialect.ApplyPagination(params.SQL, params.Limit, params.Offset)
+	if err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "sql", Value: paginated},
+			{Key: "database_type", Value: databaseType},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleDialectInfo returns the functions and data types of a SQL dialect for autocomplete
+func (s *AIPluginService) handleDialectInfo(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		DatabaseType string `json:"database_type"`
+	}
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+		}
+	}
+
+	databaseType := normalizeDatabaseType(params.DatabaseType)
+	dialect, ok := ai.NewSQLDialect(databaseType)
+	if !ok {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest,
+			"unsupported database_type %q (supported: %s)", params.DatabaseType, strings.Join(ai.SupportedDialects(), ", "))
+	}
+
+	functionsJSON, err := json.Marshal(dialect.GetFunctions())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode dialect functions: %v", err)
+	}
+	dataTypesJSON, err := json.Marshal(dialect.GetDataTypes())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode dialect data types: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "database_type", Value: databaseType},
+			{Key: "functions", Value: string(functionsJSON)},
+			{Key: "data_types", Value: string(dataTypesJSON)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// dialectPreview is the transformed form of a statement for one target dialect
+type dialectPreview struct {
+	SQL      string   `json:"sql,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// handleDialectPreview transforms a statement into every other built-in dialect
+func (s *AIPluginService) handleDialectPreview(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		SQL           string `json:"sql"`
+		SourceDialect string `json:"source_dialect"`
+	}
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+	if strings.TrimSpace(params.SQL) == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "sql must not be empty")
+	}
+
+	sourceDialect := normalizeDatabaseType(params.SourceDialect)
+	source, ok := ai.NewSQLDialect(sourceDialect)
+	if !ok {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest,
+			"unsupported source_dialect %q (supported: %s)", params.SourceDialect, strings.Join(ai.SupportedDialects(), ", "))
+	}
+
+	previews := make(map[string]dialectPreview)
+	for _, target := range ai.SupportedDialects() {
+		if target == sourceDialect {
+			continue
+		}
+		transformed, warnings, err := ai.TransformSQLWithFallback(source, params.SQL, target)
+		if err != nil {
+			previews[target] = dialectPreview{Warnings: []string{err.Error()}}
+			continue
+		}
+		previews[target] = dialectPreview{SQL: transformed, Warnings: warnings}
+	}
+
+	previewsJSON, err := json.Marshal(previews)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode dialect previews: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "previews", Value: string(previewsJSON)},
+			{Key: "source_dialect", Value: sourceDialect},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// benchmarkParams are the parameters of the benchmark query and the benchmark stream
+type benchmarkParams struct {
+	Prompts     []string `json:"prompts"`
+	Concurrency int      `json:"concurrency"`
+	MaxTokens   int      `json:"max_tokens"`
+}
+
+// parseBenchmarkParams decodes and checks the benchmark parameters carried in req.Sql
+func parseBenchmarkParams(req *server.DataQuery) (benchmarkParams, error) {
+	var params benchmarkParams
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return params, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+	if len(params.Prompts) == 0 {
+		return params, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "prompts must not be empty")
+	}
+	return params, nil
+}
+
+// benchmarkError converts a benchmark failure into a gRPC status error
+func benchmarkError(err error) error {
+	if errors.Is(err, ai.ErrNoHealthyClients) {
+		return apperrors.ToGRPCErrorf(apperrors.ErrProviderNotAvailable, "benchmark failed: %v", err)
+	}
+	return status.Errorf(codes.Internal, "benchmark failed: %v", err)
+}
+
+// handleBenchmark runs the given prompts against every healthy provider and reports per-provider results
+func (s *AIPluginService) handleBenchmark(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	params, err := parseBenchmarkParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.aiManager.Benchmark(ctx, params.Prompts, ai.BenchmarkOptions{
+		Concurrency: params.Concurrency,
+		MaxTokens:   params.MaxTokens,
+	})
+	if err != nil {
+		return nil, benchmarkError(err)
+	}
+
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode benchmark results: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "results", Value: string(resultsJSON)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleGetDefaultProvider returns the active default provider and the providers it can be switched to
+func (s *AIPluginService) handleGetDefaultProvider(ctx context.Context, _ *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for name := range s.aiManager.GetAllClients() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode providers: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "provider", Value: s.aiManager.DefaultProvider()},
+			{Key: "providers", Value: string(namesJSON)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleDoctor returns the self-diagnostic report used to triage support requests
+func (s *AIPluginService) handleDoctor(ctx context.Context, _ *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	reportJSON, err := json.Marshal(s.Doctor(ctx))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode doctor report: %v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "api_version", Value: APIVersion},
+			{Key: "report", Value: string(reportJSON)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleCancel cancels an in-flight generation started with the given request_id
+func (s *AIPluginService) handleCancel(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		RequestID string `json:"request_id"`
+	}
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "failed to parse cancel parameters: %v", err)
+		}
+	}
+	if params.RequestID == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "request_id is required")
+	}
+
+	cancelled := s.inflight.cancel(params.RequestID)
+	logging.Logger.Info("Generation cancel requested", "request_id", params.RequestID, "cancelled", cancelled)
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "request_id", Value: params.RequestID},
+			{Key: "cancelled", Value: strconv.FormatBool(cancelled)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleOpenSession prepares a schema once and returns a session id that generate requests can reuse
+func (s *AIPluginService) handleOpenSession(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		Schema json.RawMessage `json:"schema"`
+	}
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "failed to parse session parameters: %v", err)
+		}
+	}
+	if len(params.Schema) == 0 {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "schema is required")
+	}
+
+	schema, err := ai.ParseSchema(params.Schema)
+	if err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+	}
+	sessionID, err := s.aiEngine.OpenSession(schema)
+	if err != nil {
+		if errors.Is(err, ai.ErrTooManySessions) {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrResourceExhausted, "%v", err)
+		}
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+	}
+	logging.Logger.Info("Session opened", "session_id", sessionID, "tables", len(schema))
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "session_id", Value: sessionID},
+			{Key: "tables", Value: strconv.Itoa(len(schema))},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleCloseSession discards a session; closing an unknown or expired session reports closed=false
+func (s *AIPluginService) handleCloseSession(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		SessionID string `json:"session_id"`
+	}
+	if req.Sql != "" {
+		if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "failed to parse session parameters: %v", err)
+		}
+	}
+	if params.SessionID == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "session_id is required")
+	}
+
+	closed := s.aiEngine.CloseSession(params.SessionID) == nil
+	logging.Logger.Info("Session close requested", "session_id", params.SessionID, "closed", closed)
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "session_id", Value: params.SessionID},
+			{Key: "closed", Value: strconv.FormatBool(closed)},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleSetDefaultProvider switches the default provider to a configured, healthy one
+func (s *AIPluginService) handleSetDefaultProvider(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	var params struct {
+		Provider string `json:"provider"`
+	}
+	if err := json.Unmarshal([]byte(req.Sql), &params); err != nil {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "invalid parameters: %v", err)
+	}
+	provider := providers.Normalize(params.Provider)
+	if provider == "" {
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "provider is required")
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if err := s.aiEngine.SetDefaultProvider(ctx, provider); err != nil {
+		switch {
+		case errors.Is(err, ai.ErrClientNotFound):
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidRequest, "%v", err)
+		case errors.Is(err, ai.ErrProviderUnhealthy):
+			return nil, apperrors.ToGRPCErrorf(apperrors.ErrProviderNotAvailable, "%v", err)
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to set default provider: %v", err)
+		}
+	}
+
+	newAIConfig := s.config.AI
+	newAIConfig.DefaultService = provider
+	s.config.AI = newAIConfig
+	s.capabilityDetector = ai.NewCapabilityDetector(newAIConfig, s.aiManager)
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "provider", Value: provider},
+			{Key: "message", Value: "Default provider updated"},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// handleTestConnection tests a connection with provided configuration
+func (s *AIPluginService) handleTestConnection(ctx context.Context, req *server.DataQuery) (*server.DataQueryResult, error) {
+	logging.Logger.Debug("Handling test connection request", "sql_length", len(req.Sql))
+
+	// Parse configuration from SQL field
+	var config universal.Config
+	if req.Sql != "" {
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(req.Sql), &payload); err != nil {
+			logging.Logger.Error("Failed to parse connection config", "error", err)
 			return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidConfig, "invalid configuration: %v", err)
 		}
 
@@ -1343,10 +2300,7 @@ func (s *AIPluginService) handleTestConnection(ctx context.Context, req *server.
 		}
 	}
 
-	// Map "local" to "ollama" for backward compatibility
-	if config.Provider == "local" {
-		config.Provider = "ollama"
-	}
+	config.Provider = providers.Normalize(config.Provider)
 
 	if config.APIKey == "" {
 		if apiKey := apiKeyFromContext(ctx); apiKey != "" {
@@ -1428,13 +2382,8 @@ func (s *AIPluginService) handleUpdateConfig(ctx context.Context, req *server.Da
 		}
 	}
 
-	// Map "local" to "ollama" for backward compatibility
-	if updateReq.Provider == "local" {
-		updateReq.Provider = "ollama"
-	}
-	if updateReq.Config.Provider == "local" {
-		updateReq.Config.Provider = "ollama"
-	}
+	updateReq.Provider = providers.Normalize(updateReq.Provider)
+	updateReq.Config.Provider = providers.Normalize(updateReq.Config.Provider)
 
 	logging.Logger.Debug("Updating provider config", "provider", updateReq.Provider)
 
@@ -1451,7 +2400,8 @@ func (s *AIPluginService) handleUpdateConfig(ctx context.Context, req *server.Da
 		serviceConfig.Timeout = config.Duration{Duration: updateReq.Config.Timeout}
 	}
 
-	oldEngine := s.aiEngine
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
 
 	servicesCopy := make(map[string]config.AIService, len(s.config.AI.Services)+1)
 	for name, svc := range s.config.AI.Services {
@@ -1465,29 +2415,42 @@ func (s *AIPluginService) handleUpdateConfig(ctx context.Context, req *server.Da
 		newAIConfig.DefaultService = updateReq.Provider
 	}
 
-	manager, err := ai.NewAIManager(newAIConfig)
-	if err != nil {
-		logging.Logger.Error("Failed to rebuild AI manager",
+	if err := s.rebuildAIComponents(newAIConfig); err != nil {
+		logging.Logger.Error("Failed to apply provider config",
 			"provider", updateReq.Provider,
 			"error", err)
-		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidConfig, "failed to rebuild AI manager: %v", err)
+		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidConfig, "%v", err)
+	}
+
+	return &server.DataQueryResult{
+		Data: []*server.Pair{
+			{Key: "provider", Value: updateReq.Provider},
+			{Key: "message", Value: "Configuration updated successfully"},
+			{Key: "success", Value: "true"},
+		},
+	}, nil
+}
+
+// rebuildAIComponents builds a new manager, engine and capability detector for
+// the given AI configuration and swaps them in. The current components are left
+// untouched when the rebuild fails. Callers must hold reloadMu.
+func (s *AIPluginService) rebuildAIComponents(newAIConfig config.AIConfig) error {
+	manager, err := ai.NewAIManager(newAIConfig)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild AI manager: %w", err)
 	}
 
 	engine, err := ai.NewEngineWithManager(manager, newAIConfig)
 	if err != nil {
-		logging.Logger.Error("Failed to rebuild AI engine",
-			"provider", updateReq.Provider,
-			"error", err)
 		if closeErr := manager.Close(); closeErr != nil {
-			logging.Logger.Warn("Failed to close AI manager after rebuild error",
-				"provider", updateReq.Provider,
-				"error", closeErr)
+			logging.Logger.Warn("Failed to close AI manager after rebuild error", "error", closeErr)
 		}
-		return nil, apperrors.ToGRPCErrorf(apperrors.ErrInvalidConfig, "failed to rebuild AI engine: %v", err)
+		return fmt.Errorf("failed to rebuild AI engine: %w", err)
 	}
 
 	capabilityDetector := ai.NewCapabilityDetector(newAIConfig, manager)
 
+	oldEngine := s.aiEngine
 	s.config.AI = newAIConfig
 	s.aiManager = manager
 	s.aiEngine = engine
@@ -1497,14 +2460,30 @@ func (s *AIPluginService) handleUpdateConfig(ctx context.Context, req *server.Da
 	if oldEngine != nil {
 		oldEngine.Close()
 	}
+	return nil
+}
 
-	return &server.DataQueryResult{
-		Data: []*server.Pair{
-			{Key: "provider", Value: updateReq.Provider},
-			{Key: "message", Value: "Configuration updated successfully"},
-			{Key: "success", Value: "true"},
-		},
-	}, nil
+// ReloadConfig re-reads the configuration from disk and rebuilds the AI clients.
+// On failure, including an unreadable or unparsable file, the previous configuration keeps serving.
+func (s *AIPluginService) ReloadConfig() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	cfg, err := config.ReloadConfig(s.config.SourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := s.rebuildAIComponents(cfg.AI); err != nil {
+		return err
+	}
+	s.config = cfg
+	s.inflight.setLimit(cfg.Server.MaxConcurrentGenerations, cfg.Server.ShedRetryAfter.Value())
+
+	logging.Logger.Info("Configuration reloaded",
+		"default_service", cfg.AI.DefaultService,
+		"service_count", len(cfg.AI.Services))
+	return nil
 }
 
 // handleHealthCheck performs health check on specific AI service
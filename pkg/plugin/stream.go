@@ -0,0 +1,92 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/linuxsuren/api-testing/pkg/server"
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BenchmarkStreamServer streams benchmark results. The Loader service has no streaming
+// methods, so it is registered as a separate service on the same server.
+type BenchmarkStreamServer interface {
+	// StreamBenchmark takes the parameters of the benchmark query and sends one NDJSON
+	// line per provider as soon as that provider's prompts have completed
+	StreamBenchmark(*server.DataQuery, grpc.ServerStreamingServer[server.CommonResult]) error
+}
+
+// BenchmarkStreamServiceDesc describes the BenchmarkStream gRPC service
+var BenchmarkStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "atest.ext.ai.BenchmarkStream",
+	HandlerType: (*BenchmarkStreamServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Benchmark",
+		Handler:       streamBenchmarkHandler,
+		ServerStreams: true,
+	}},
+	Metadata: "pkg/plugin/stream.go",
+}
+
+// RegisterBenchmarkStreamServer registers the BenchmarkStream service on s
+func RegisterBenchmarkStreamServer(s grpc.ServiceRegistrar, srv BenchmarkStreamServer) {
+	s.RegisterService(&BenchmarkStreamServiceDesc, srv)
+}
+
+func streamBenchmarkHandler(srv any, stream grpc.ServerStream) error {
+	req := new(server.DataQuery)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(BenchmarkStreamServer).StreamBenchmark(req, &grpc.GenericServerStream[server.DataQuery, server.CommonResult]{ServerStream: stream})
+}
+
+// StreamBenchmark runs the benchmark and streams each provider's BenchmarkResult as an NDJSON line
+// in CommonResult.Message. Cancelling the stream cancels the outstanding benchmark requests.
+func (s *AIPluginService) StreamBenchmark(req *server.DataQuery, stream grpc.ServerStreamingServer[server.CommonResult]) error {
+	ctx := stream.Context()
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+
+	params, err := parseBenchmarkParams(req)
+	if err != nil {
+		return err
+	}
+
+	err = s.aiManager.BenchmarkEach(ctx, params.Prompts, ai.BenchmarkOptions{
+		Concurrency: params.Concurrency,
+		MaxTokens:   params.MaxTokens,
+	}, func(result ai.BenchmarkResult) error {
+		line, err := json.Marshal(result)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to encode benchmark result: %v", err)
+		}
+		return stream.Send(&server.CommonResult{Success: true, Message: string(line) + "\n"})
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return benchmarkError(err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// StringPlaceholder replaces string literals in anonymized SQL
+	StringPlaceholder = "'<string>'"
+	// NumberPlaceholder replaces numeric literals in anonymized SQL
+	NumberPlaceholder = "<number>"
+)
+
+// Anonymize replaces string and numeric literals with typed placeholders while
+// keeping keywords, identifiers, quoted identifiers and punctuation intact.
+func Anonymize(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+	runes := []rune(sql)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			b.WriteString(StringPlaceholder)
+			i, _ = ScanQuoted(runes, i, '\'', true)
+		case r == '"' || r == '`':
+			end, _ := ScanQuoted(runes, i, r, false)
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case isIdentifierStart(r):
+			start := i
+			for i < len(runes) && isIdentifierPart(runes[i]) {
+				i++
+			}
+			b.WriteString(string(runes[start:i]))
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i < len(runes) && (isIdentifierPart(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			b.WriteString(NumberPlaceholder)
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentifierStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '@' || r == '$'
+}
+
+func isIdentifierPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
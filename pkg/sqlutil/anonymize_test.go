@@ -0,0 +1,58 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "string and number literals",
+			sql:      "SELECT * FROM users WHERE email='a@b.com' AND age=30",
+			expected: "SELECT * FROM users WHERE email='<string>' AND age=<number>",
+		},
+		{
+			name:     "identifiers with digits are kept",
+			sql:      "SELECT col1 FROM t2 LIMIT 10;",
+			expected: "SELECT col1 FROM t2 LIMIT <number>;",
+		},
+		{
+			name:     "escaped quotes and decimals",
+			sql:      "UPDATE accounts SET note = 'it''s \\'ok\\'', balance = 12.50 WHERE id IN (1, 2)",
+			expected: "UPDATE accounts SET note = '<string>', balance = <number> WHERE id IN (<number>, <number>)",
+		},
+		{
+			name:     "quoted identifiers are preserved",
+			sql:      "SELECT \"first name\", `order` FROM people WHERE name = 'Bob'",
+			expected: "SELECT \"first name\", `order` FROM people WHERE name = '<string>'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Anonymize(tt.sql))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/ai/providers"
+)
+
+// ErrEmptySQL is returned when there is nothing to normalize
+var ErrEmptySQL = errors.New("sql is empty")
+
+// normalizeKeywords are bare words lowercased by Normalize; other words are left as written
+var normalizeKeywords = map[string]bool{
+	"ALL": true, "ALTER": true, "AND": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASE": true, "CREATE": true,
+	"CROSS": true, "DELETE": true, "DESC": true, "DISTINCT": true, "DROP": true, "ELSE": true,
+	"END": true, "EXISTS": true, "FALSE": true, "FROM": true, "FULL": true, "GROUP": true,
+	"HAVING": true, "ILIKE": true, "IN": true, "INNER": true, "INSERT": true, "INTERSECT": true,
+	"INTO": true, "IS": true, "JOIN": true, "LEFT": true, "LIKE": true, "LIMIT": true,
+	"NOT": true, "NULL": true, "OFFSET": true, "ON": true,
+	"OR": true, "ORDER": true, "OUTER": true, "RETURNING": true, "RIGHT": true, "SELECT": true,
+	"SET": true, "TABLE": true, "THEN": true, "TRUE": true, "UNION": true,
+	"UPDATE": true, "USING": true, "VALUES": true, "WHEN": true, "WHERE": true, "WITH": true,
+}
+
+// normalizeFunctions are built-in function names lowercased by Normalize
+var normalizeFunctions = map[string]bool{
+	"AVG": true, "CAST": true, "COALESCE": true, "COUNT": true, "LOWER": true,
+	"MAX": true, "MIN": true, "NOW": true, "SUM": true, "UPPER": true,
+}
+
+// IdentifierQuote returns the identifier quote character for a dialect name
+func IdentifierQuote(dialect string) (rune, error) {
+	switch providers.Normalize(dialect) {
+	case "mysql":
+		return '`', nil
+	case "postgresql", "sqlite", "snowflake":
+		return '"', nil
+	default:
+		return 0, fmt.Errorf("unsupported dialect %q", dialect)
+	}
+}
+
+// QuoteIdentifier quotes name for the dialect, doubling embedded quote characters
+func QuoteIdentifier(name, dialect string) (string, error) {
+	quote, err := IdentifierQuote(dialect)
+	if err != nil {
+		return "", err
+	}
+	q := string(quote)
+	return q + strings.ReplaceAll(name, q, q+q) + q, nil
+}
+
+// ScanQuoted returns the index just past the quote closing the token that starts at start and
+// whether the closing quote was found. A doubled quote is an escape, as is a backslash when
+// backslashEscapes is set (MySQL strings). Unterminated tokens end at len(runes).
+func ScanQuoted(runes []rune, start int, closing rune, backslashEscapes bool) (int, bool) {
+	for i := start + 1; i < len(runes); i++ {
+		if backslashEscapes && runes[i] == '\\' {
+			i++
+			continue
+		}
+		if runes[i] != closing {
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == closing {
+			i++
+			continue
+		}
+		return i + 1, true
+	}
+	return len(runes), false
+}
+
+// Normalize returns a canonical form of sql for caching and comparison.
+// Comments are dropped, whitespace is collapsed, keywords are lowercased and quoted
+// identifiers are re-quoted with the dialect's quote character (or unquoted when the
+// quotes are unnecessary). String and numeric literals are kept verbatim; in MySQL, where
+// the identifier quote is the backtick, double-quoted text is a string literal.
+func Normalize(sql, dialect string) (string, error) {
+	if _, err := IdentifierQuote(dialect); err != nil {
+		return "", err
+	}
+
+	tokens, err := normalizeTokens(sql, dialect)
+	if err != nil {
+		return "", err
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return "", ErrEmptySQL
+	}
+
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], token) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token)
+	}
+	return b.String(), nil
+}
+
+// normalizeTokens splits sql into canonical tokens
+func normalizeTokens(sql, dialect string) ([]string, error) {
+	var tokens []string
+	runes := []rune(sql)
+	quote, err := IdentifierQuote(dialect)
+	if err != nil {
+		return nil, err
+	}
+	backslashEscapes := quote == '`'
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated block comment")
+			}
+			i += 2
+		case r == '\'' || (r == '"' && quote != '"'):
+			end, closed := ScanQuoted(runes, i, r, backslashEscapes)
+			if !closed {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		case r == '"' || r == '`' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			end, closed := ScanQuoted(runes, i, closing, false)
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted identifier starting at offset %d", i)
+			}
+			name := string(runes[i+1 : end-1])
+			name = strings.ReplaceAll(name, string(closing)+string(closing), string(closing))
+			token, err := canonicalIdentifier(name, dialect)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+		case isIdentifierStart(r):
+			start := i
+			i++
+			for i < len(runes) && isIdentifierPart(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			if upper := strings.ToUpper(word); normalizeKeywords[upper] || normalizeFunctions[upper] {
+				word = strings.ToLower(word)
+			}
+			tokens = append(tokens, word)
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (isIdentifierPart(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case strings.ContainsRune(operatorRunes, r):
+			start := i
+			for i < len(runes) && strings.ContainsRune(operatorRunes, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// operatorRunes are characters that combine into multi-character operators such as >= or ::
+const operatorRunes = "<>=!|:+-*/%&^~"
+
+// canonicalIdentifier drops quotes from plain lowercase identifiers and re-quotes the rest for the dialect
+func canonicalIdentifier(name, dialect string) (string, error) {
+	if isPlainIdentifier(name) && !normalizeKeywords[strings.ToUpper(name)] {
+		return name, nil
+	}
+	return QuoteIdentifier(name, dialect)
+}
+
+// isPlainIdentifier reports whether name is safe to use unquoted in every supported dialect
+func isPlainIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// needsSpace decides whether a space separates two adjacent canonical tokens
+func needsSpace(prev, next string) bool {
+	switch next {
+	case ",", ")", ";", ".", "::":
+		return false
+	case "(":
+		return normalizeKeywords[strings.ToUpper(prev)] || strings.ContainsAny(prev, operatorRunes+",(")
+	}
+	switch prev {
+	case "(", ".", "::":
+		return false
+	}
+	return true
+}
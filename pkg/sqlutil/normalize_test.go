@@ -0,0 +1,110 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqlutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEquivalentQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		a       string
+		b       string
+		want    string
+	}{
+		{
+			name:    "keywords and whitespace",
+			dialect: "mysql",
+			a:       "SELECT id, name FROM users WHERE age >= 18 ORDER BY name;",
+			b:       "select   id ,name\n\tfrom users -- adults only\nwhere age>=18 order by name",
+			want:    "select id, name from users where age >= 18 order by name",
+		},
+		{
+			name:    "quoted identifiers",
+			dialect: "postgresql",
+			a:       `SELECT "id", "Order Date" FROM "orders"`,
+			b:       "select id, `Order Date` from [orders]",
+			want:    `select id, "Order Date" from orders`,
+		},
+		{
+			name:    "function calls and qualified names",
+			dialect: "mysql",
+			a:       "SELECT COUNT(*) FROM orders o WHERE o.status IN ('paid', 'shipped')",
+			b:       "select count( * ) from orders o where o . status in('paid','shipped')",
+			want:    "select count(*) from orders o where o.status in ('paid', 'shipped')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Normalize(tt.a, tt.dialect)
+			require.NoError(t, err)
+			b, err := Normalize(tt.b, tt.dialect)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a)
+			assert.Equal(t, a, b)
+		})
+	}
+}
+
+func TestNormalizeKeepsMySQLDoubleQuotedStrings(t *testing.T) {
+	got, err := Normalize(`SELECT id FROM users WHERE name = "bob"`, "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, `select id from users where name = "bob"`, got, "double quotes delimit a string in MySQL")
+
+	got, err = Normalize(`SELECT id FROM users WHERE note = "say \"hi\"" AND name = 'it\'s'`, "mysql")
+	require.NoError(t, err)
+	assert.Equal(t, `select id from users where note = "say \"hi\"" and name = 'it\'s'`, got)
+
+	got, err = Normalize(`SELECT "name" FROM users`, "postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, "select name from users", got, "double quotes delimit an identifier elsewhere")
+
+	_, err = Normalize(`SELECT "bob FROM users`, "mysql")
+	require.Error(t, err)
+}
+
+func TestNormalizeQuotesForDialect(t *testing.T) {
+	got, err := Normalize("SELECT `Total` FROM t", "postgresql")
+	require.NoError(t, err)
+	assert.Equal(t, `select "Total" from t`, got)
+
+	got, err = Normalize("SELECT `select` FROM t", "sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, `select "select" from t`, got)
+
+	got, err = Normalize(`SELECT 'It''s' FROM t`, "sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, `select 'It''s' from t`, got, "string literals are kept verbatim")
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	_, err := Normalize("SELECT 1", "oracle")
+	assert.Error(t, err)
+
+	_, err = Normalize(" ; -- nothing", "mysql")
+	assert.True(t, errors.Is(err, ErrEmptySQL))
+
+	_, err = Normalize("SELECT 'open", "mysql")
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+)
+
+// Backend names accepted by ai.storage.backend
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+// ErrUnsupportedBackend is returned for an ai.storage.backend this build does not provide
+var ErrUnsupportedBackend = errors.New("unsupported storage backend")
+
+// Backend stores values by key and appends records to named streams.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Get returns the value stored under key and whether it exists
+	Get(key string) ([]byte, bool, error)
+	// Set stores value under key, replacing any previous value
+	Set(key string, value []byte) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+	// Keys returns the stored keys that start with prefix, in no particular order
+	Keys(prefix string) ([]string, error)
+	// Append adds a record to the end of stream
+	Append(stream string, record []byte) error
+}
+
+// New creates the backend selected by cfg; an empty backend name selects memory
+func New(cfg config.StorageConfig) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", BackendMemory:
+		return NewMemoryBackend(), nil
+	case BackendFile:
+		return NewFileBackend(cfg.Path)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedBackend, cfg.Backend)
+	}
+}
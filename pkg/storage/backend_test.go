@@ -0,0 +1,168 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/config"
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// testBackendConformance checks the behaviour every Backend implementation must share
+func testBackendConformance(t *testing.T, backend Backend) {
+	t.Helper()
+
+	if _, ok, err := backend.Get("missing"); ok || err != nil {
+		t.Fatalf("Get(missing) = ok %v, err %v; want a miss", ok, err)
+	}
+
+	for _, key := range []string{"plain", "cache/3f2a", "../escape", "with spaces and ünïcode"} {
+		if err := backend.Set(key, []byte("v1:"+key)); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+		value, ok, err := backend.Get(key)
+		if err != nil || !ok || string(value) != "v1:"+key {
+			t.Fatalf("Get(%q) = %q, %v, %v", key, value, ok, err)
+		}
+	}
+
+	keys, err := backend.Keys("cache/")
+	if err != nil || len(keys) != 1 || keys[0] != "cache/3f2a" {
+		t.Errorf("Keys(cache/) = %q, %v; want [cache/3f2a]", keys, err)
+	}
+	if keys, _ := backend.Keys(""); len(keys) != 4 {
+		t.Errorf("Keys() returned %d keys, want 4", len(keys))
+	}
+
+	if err := backend.Set("plain", []byte("v2")); err != nil {
+		t.Fatalf("overwrite failed: %v", err)
+	}
+	value, _, _ := backend.Get("plain")
+	if string(value) != "v2" {
+		t.Errorf("Get after overwrite = %q, want v2", value)
+	}
+
+	value[0] = 'x'
+	if again, _, _ := backend.Get("plain"); string(again) != "v2" {
+		t.Errorf("mutating a returned value changed the stored value to %q", again)
+	}
+
+	if err := backend.Delete("plain"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := backend.Get("plain"); ok {
+		t.Error("deleted key is still present")
+	}
+	if err := backend.Delete("plain"); err != nil {
+		t.Errorf("deleting a missing key returned %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := backend.Append("audit", []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+				t.Errorf("Append failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMemoryBackendConformance(t *testing.T) {
+	backend := NewMemoryBackend()
+	testBackendConformance(t, backend)
+
+	if records := backend.Records("audit"); len(records) != 20 {
+		t.Errorf("memory backend holds %d audit records, want 20", len(records))
+	}
+}
+
+func TestFileBackendConformance(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFileBackend failed: %v", err)
+	}
+	testBackendConformance(t, backend)
+
+	data, err := os.ReadFile(filepath.Join(dir, "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("reading audit stream failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 20 {
+		t.Errorf("audit stream has %d lines, want 20", len(lines))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+		t.Error("a key containing ../ escaped the values directory")
+	}
+
+	reopened, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	if value, ok, _ := reopened.Get("cache/3f2a"); !ok || string(value) != "v1:cache/3f2a" {
+		t.Errorf("value did not survive reopening: %q, %v", value, ok)
+	}
+
+	if err := backend.Append("../audit", []byte("{}")); !errors.Is(err, ErrInvalidStream) {
+		t.Errorf("Append with a path stream returned %v, want ErrInvalidStream", err)
+	}
+}
+
+func TestMemoryBackendKeepsRecentRecords(t *testing.T) {
+	backend := NewMemoryBackend()
+	for i := 0; i < constants.Storage.MaxMemoryRecords+5; i++ {
+		_ = backend.Append("audit", []byte(fmt.Sprint(i)))
+	}
+	records := backend.Records("audit")
+	if len(records) != constants.Storage.MaxMemoryRecords {
+		t.Fatalf("kept %d records, want %d", len(records), constants.Storage.MaxMemoryRecords)
+	}
+	if string(records[0]) != "5" {
+		t.Errorf("oldest kept record = %s, want 5", records[0])
+	}
+}
+
+func TestNew(t *testing.T) {
+	if backend, err := New(config.StorageConfig{}); err != nil {
+		t.Errorf("default backend failed: %v", err)
+	} else if _, ok := backend.(*MemoryBackend); !ok {
+		t.Errorf("default backend is %T, want *MemoryBackend", backend)
+	}
+
+	if backend, err := New(config.StorageConfig{Backend: "File", Path: t.TempDir()}); err != nil {
+		t.Errorf("file backend failed: %v", err)
+	} else if _, ok := backend.(*FileBackend); !ok {
+		t.Errorf("file backend is %T, want *FileBackend", backend)
+	}
+
+	if _, err := New(config.StorageConfig{Backend: "file"}); err == nil {
+		t.Error("file backend without a path succeeded")
+	}
+	if _, err := New(config.StorageConfig{Backend: "redis"}); !errors.Is(err, ErrUnsupportedBackend) {
+		t.Errorf("redis backend returned %v, want ErrUnsupportedBackend", err)
+	}
+}
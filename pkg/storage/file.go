@@ -0,0 +1,141 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidStream is returned for stream names that are not plain file names
+var ErrInvalidStream = errors.New("invalid stream name")
+
+// FileBackend stores each value in its own file under dir/values and appends stream
+// records as lines of dir/<stream>.jsonl, so data survives restarts.
+type FileBackend struct {
+	dir string
+	// mu serializes appends so concurrent records are never interleaved
+	mu sync.Mutex
+}
+
+// NewFileBackend creates a file backend rooted at dir, creating the directory if needed
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("file storage backend requires a path")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "values"), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %w", dir, err)
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+// valuePath maps an arbitrary key to a file name that cannot escape the values directory
+func (f *FileBackend) valuePath(key string) string {
+	return filepath.Join(f.dir, "values", base64.RawURLEncoding.EncodeToString([]byte(key)))
+}
+
+// Get reads the value stored under key
+func (f *FileBackend) Get(key string) ([]byte, bool, error) {
+	value, err := os.ReadFile(f.valuePath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %q: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Set writes value through a temporary file so readers never see a partial value
+func (f *FileBackend) Set(key string, value []byte) error {
+	path := f.valuePath(key)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %q: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the file holding key
+func (f *FileBackend) Delete(key string) error {
+	if err := os.Remove(f.valuePath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete %q: %w", key, err)
+	}
+	return nil
+}
+
+// Keys decodes the file names under the values directory and returns those starting with prefix.
+// Temporary files of writes in progress are skipped.
+func (f *FileBackend) Keys(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(f.dir, "values"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored keys: %w", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		key, err := base64.RawURLEncoding.DecodeString(entry.Name())
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(string(key), prefix) {
+			keys = append(keys, string(key))
+		}
+	}
+	return keys, nil
+}
+
+// Append writes record as one line of the stream's file
+func (f *FileBackend) Append(stream string, record []byte) error {
+	if stream == "" || stream != filepath.Base(stream) || strings.HasPrefix(stream, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidStream, stream)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := filepath.Join(f.dir, stream+".jsonl")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 -- stream is a plain file name
+	if err != nil {
+		return fmt.Errorf("failed to open stream %s: %w", stream, err)
+	}
+	line := append(append([]byte(nil), record...), '\n')
+	if _, err := file.Write(line); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to append to stream %s: %w", stream, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 API Testing Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/linuxsuren/atest-ext-ai/pkg/constants"
+)
+
+// MemoryBackend keeps values and stream records in process memory.
+// Each stream keeps only its most recent constants.Storage.MaxMemoryRecords records.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	values  map[string][]byte
+	streams map[string][][]byte
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		values:  make(map[string][]byte),
+		streams: make(map[string][][]byte),
+	}
+}
+
+// Get returns a copy of the value stored under key
+func (m *MemoryBackend) Get(key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), value...), true, nil
+}
+
+// Set stores a copy of value under key
+func (m *MemoryBackend) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes key
+func (m *MemoryBackend) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// Keys returns the stored keys that start with prefix
+func (m *MemoryBackend) Keys(prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for key := range m.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Append adds a copy of record to stream, dropping the oldest record when the stream is full
+func (m *MemoryBackend) Append(stream string, record []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := append(m.streams[stream], append([]byte(nil), record...))
+	if excess := len(records) - constants.Storage.MaxMemoryRecords; excess > 0 {
+		records = records[excess:]
+	}
+	m.streams[stream] = records
+	return nil
+}
+
+// Records returns copies of the records currently held for stream
+func (m *MemoryBackend) Records(stream string) [][]byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	records := make([][]byte, 0, len(m.streams[stream]))
+	for _, record := range m.streams[stream] {
+		records = append(records, append([]byte(nil), record...))
+	}
+	return records
+}